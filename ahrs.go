@@ -0,0 +1,202 @@
+package pctl
+
+import "math"
+
+// AHRSMethod selects the sensor fusion algorithm of an AHRS
+type AHRSMethod int
+
+const (
+	// AHRSMahony is Mahony's complementary filter, which corrects the gyro
+	// with a PI controller on the angle between the measured and estimated
+	// reference directions.  The integral term learns the gyro bias.
+	AHRSMahony AHRSMethod = iota
+
+	// AHRSMadgwick is Madgwick's gradient descent filter, which steps the
+	// attitude down the gradient of the reference direction error at a rate
+	// of Beta.
+	AHRSMadgwick
+)
+
+// AHRS is an attitude and heading reference system, which fuses gyro and
+// accelerometer samples, and optionally magnetometer samples, into an
+// attitude.  Without a magnetometer, yaw is the integral of the gyro and
+// drifts.
+//
+// Gyro rates are in rad/s.  The accelerometer and magnetometer may be in any
+// units, as only their directions are used.  The earth frame is z up; at rest
+// and level the accelerometer reads +z.  The attitude is held as a unit
+// quaternion, rotating the body frame into the earth frame.
+type AHRS struct {
+	// Method is the fusion algorithm
+	Method AHRSMethod
+
+	// Kp and Ki are the proportional and integral gains of AHRSMahony, in
+	// rad/s per unit of error
+	Kp, Ki float64
+
+	// Beta is the gain of AHRSMadgwick, about sqrt(3/4) times the gyro
+	// noise, in rad/s
+	Beta float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	q     [4]float64
+	integ [3]float64
+}
+
+// NewMahony returns an AHRS using Mahony's filter, with proportional gain kp
+// and integral gain ki
+func NewMahony(kp, ki, dt float64) *AHRS {
+	return &AHRS{Method: AHRSMahony, Kp: kp, Ki: ki, DT: dt, q: [4]float64{1, 0, 0, 0}}
+}
+
+// NewMadgwick returns an AHRS using Madgwick's filter with gain beta
+func NewMadgwick(beta, dt float64) *AHRS {
+	return &AHRS{Method: AHRSMadgwick, Beta: beta, DT: dt, q: [4]float64{1, 0, 0, 0}}
+}
+
+// Update fuses a gyro and accelerometer sample
+func (a *AHRS) Update(gyro, accel [3]float64) {
+	a.UpdateMag(gyro, accel, [3]float64{})
+}
+
+// UpdateMag fuses a gyro, accelerometer, and magnetometer sample.  A zero
+// magnetometer sample is ignored, as is a zero accelerometer sample, in which
+// case the gyro is integrated alone.
+func (a *AHRS) UpdateMag(gyro, accel, mag [3]float64) {
+	q0, q1, q2, q3 := a.q[0], a.q[1], a.q[2], a.q[3]
+	g := gyro
+	// f is the error between the estimated and measured directions,
+	// gravity first and the magnetic field second, in the body frame
+	var f [6]float64
+	useAcc := normalize3(&accel)
+	useMag := useAcc && normalize3(&mag)
+	var bx, bz float64
+	if useAcc {
+		f[0] = 2*(q1*q3-q0*q2) - accel[0]
+		f[1] = 2*(q0*q1+q2*q3) - accel[1]
+		f[2] = 2*(0.5-q1*q1-q2*q2) - accel[2]
+	}
+	if useMag {
+		// the field in the earth frame, rotated about z to north
+		mx, my, mz := mag[0], mag[1], mag[2]
+		hx := 2*mx*(0.5-q2*q2-q3*q3) + 2*my*(q1*q2-q0*q3) + 2*mz*(q1*q3+q0*q2)
+		hy := 2*mx*(q1*q2+q0*q3) + 2*my*(0.5-q1*q1-q3*q3) + 2*mz*(q2*q3-q0*q1)
+		hz := 2*mx*(q1*q3-q0*q2) + 2*my*(q2*q3+q0*q1) + 2*mz*(0.5-q1*q1-q2*q2)
+		bx, bz = math.Hypot(hx, hy), hz
+		f[3] = 2*bx*(0.5-q2*q2-q3*q3) + 2*bz*(q1*q3-q0*q2) - mx
+		f[4] = 2*bx*(q1*q2-q0*q3) + 2*bz*(q0*q1+q2*q3) - my
+		f[5] = 2*bx*(q0*q2+q1*q3) + 2*bz*(0.5-q1*q1-q2*q2) - mz
+	}
+	var qdot [4]float64
+	switch a.Method {
+	case AHRSMahony:
+		// e = measured x estimated = estimated x f, as f = estimated - measured
+		var e [3]float64
+		if useAcc {
+			v := [3]float64{f[0] + accel[0], f[1] + accel[1], f[2] + accel[2]}
+			e = cross3(v, [3]float64{f[0], f[1], f[2]})
+		}
+		if useMag {
+			w := [3]float64{f[3] + mag[0], f[4] + mag[1], f[5] + mag[2]}
+			em := cross3(w, [3]float64{f[3], f[4], f[5]})
+			for i := range e {
+				e[i] += em[i]
+			}
+		}
+		for i := range g {
+			a.integ[i] += a.Ki * e[i] * a.DT
+			g[i] += a.Kp*e[i] + a.integ[i]
+		}
+		qdot = quatRate(a.q, g)
+	case AHRSMadgwick:
+		qdot = quatRate(a.q, g)
+		if useAcc {
+			// step = J' f
+			var s [4]float64
+			s[0] = -2*q2*f[0] + 2*q1*f[1]
+			s[1] = 2*q3*f[0] + 2*q0*f[1] - 4*q1*f[2]
+			s[2] = -2*q0*f[0] + 2*q3*f[1] - 4*q2*f[2]
+			s[3] = 2*q1*f[0] + 2*q2*f[1]
+			if useMag {
+				s[0] += -2*bz*q2*f[3] + (-2*bx*q3+2*bz*q1)*f[4] + 2*bx*q2*f[5]
+				s[1] += 2*bz*q3*f[3] + (2*bx*q2+2*bz*q0)*f[4] + (2*bx*q3-4*bz*q1)*f[5]
+				s[2] += (-4*bx*q2-2*bz*q0)*f[3] + (2*bx*q1+2*bz*q3)*f[4] + (2*bx*q0-4*bz*q2)*f[5]
+				s[3] += (-4*bx*q3+2*bz*q1)*f[3] + (-2*bx*q0+2*bz*q2)*f[4] + 2*bx*q1*f[5]
+			}
+			if n := math.Sqrt(s[0]*s[0] + s[1]*s[1] + s[2]*s[2] + s[3]*s[3]); n > 0 {
+				for i := range qdot {
+					qdot[i] -= a.Beta * s[i] / n
+				}
+			}
+		}
+	}
+	var n float64
+	for i := range a.q {
+		a.q[i] += qdot[i] * a.DT
+		n += a.q[i] * a.q[i]
+	}
+	n = math.Sqrt(n)
+	for i := range a.q {
+		a.q[i] /= n
+	}
+}
+
+// Quaternion returns the attitude as a unit quaternion, w, x, y, z
+func (a *AHRS) Quaternion() [4]float64 {
+	return a.q
+}
+
+// Euler returns the attitude as roll, pitch, and yaw in radians, in the
+// aerospace (z-y-x) sequence
+func (a *AHRS) Euler() (roll, pitch, yaw float64) {
+	q0, q1, q2, q3 := a.q[0], a.q[1], a.q[2], a.q[3]
+	roll = math.Atan2(2*(q0*q1+q2*q3), 1-2*(q1*q1+q2*q2))
+	pitch = math.Asin(math.Max(-1, math.Min(1, 2*(q0*q2-q3*q1))))
+	yaw = math.Atan2(2*(q0*q3+q1*q2), 1-2*(q2*q2+q3*q3))
+	return roll, pitch, yaw
+}
+
+// GyroBias returns the gyro bias learned by the integral term of AHRSMahony,
+// in rad/s.  It is zero for AHRSMadgwick.
+func (a *AHRS) GyroBias() [3]float64 {
+	return [3]float64{-a.integ[0], -a.integ[1], -a.integ[2]}
+}
+
+// Reset returns the attitude to level, facing along x
+func (a *AHRS) Reset() {
+	a.q = [4]float64{1, 0, 0, 0}
+	a.integ = [3]float64{}
+}
+
+// quatRate is the rate of change of unit quaternion q rotating at body rates
+// w, 1/2 q (x) (0, w)
+func quatRate(q [4]float64, w [3]float64) [4]float64 {
+	return [4]float64{
+		0.5 * (-q[1]*w[0] - q[2]*w[1] - q[3]*w[2]),
+		0.5 * (q[0]*w[0] + q[2]*w[2] - q[3]*w[1]),
+		0.5 * (q[0]*w[1] - q[1]*w[2] + q[3]*w[0]),
+		0.5 * (q[0]*w[2] + q[1]*w[1] - q[2]*w[0]),
+	}
+}
+
+func cross3(a, b [3]float64) [3]float64 {
+	return [3]float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+// normalize3 scales v to unit length, and returns false if it is zero
+func normalize3(v *[3]float64) bool {
+	n := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	if n == 0 {
+		return false
+	}
+	for i := range v {
+		v[i] /= n
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAHRSLevelsToGravity(t *testing.T) {
+	const roll, pitch = 0.3, -0.2
+	// gravity in the body frame after rolling, then pitching
+	acc := [3]float64{
+		-math.Sin(pitch),
+		math.Sin(roll) * math.Cos(pitch),
+		math.Cos(roll) * math.Cos(pitch),
+	}
+	for _, a := range []*AHRS{NewMahony(2, 0, 1e-3), NewMadgwick(0.1, 1e-3)} {
+		for i := 0; i < 20000; i++ {
+			a.Update([3]float64{}, acc)
+		}
+		r, p, _ := a.Euler()
+		if !approxEqualAbs(r, roll, 1e-3) || !approxEqualAbs(p, pitch, 1e-3) {
+			t.Errorf("method %d: expected roll %f and pitch %f, got %f and %f", a.Method, roll, pitch, r, p)
+		}
+	}
+}
+
+func TestAHRSYawFromMagnetometer(t *testing.T) {
+	const yaw, dip = 1.0, 1.1
+	mag := [3]float64{math.Cos(yaw) * math.Cos(dip), -math.Sin(yaw) * math.Cos(dip), -math.Sin(dip)}
+	for _, a := range []*AHRS{NewMahony(2, 0, 1e-3), NewMadgwick(0.1, 1e-3)} {
+		for i := 0; i < 50000; i++ {
+			a.UpdateMag([3]float64{}, [3]float64{0, 0, 9.81}, mag)
+		}
+		if _, _, y := a.Euler(); !approxEqualAbs(y, yaw, 1e-3) {
+			t.Errorf("method %d: expected yaw %f, got %f", a.Method, yaw, y)
+		}
+	}
+}
+
+func TestMahonyLearnsGyroBias(t *testing.T) {
+	a := NewMahony(1, 0.3, 1e-2)
+	bias := [3]float64{0.01, -0.02, 0}
+	for i := 0; i < 10000; i++ {
+		a.Update(bias, [3]float64{0, 0, 1})
+	}
+	r, p, _ := a.Euler()
+	got := a.GyroBias()
+	if !approxEqualAbs(r, 0, 1e-4) || !approxEqualAbs(p, 0, 1e-4) ||
+		!approxEqualAbs(got[0], bias[0], 1e-4) || !approxEqualAbs(got[1], bias[1], 1e-4) {
+		t.Errorf("expected level with bias %v, got roll %g, pitch %g, bias %v", bias, r, p, got)
+	}
+}
+
+func TestAHRSIntegratesGyro(t *testing.T) {
+	a := NewMadgwick(0.1, 1e-3)
+	for i := 0; i < 1000; i++ {
+		a.Update([3]float64{0, 0, 0.5}, [3]float64{0, 0, 1})
+	}
+	if _, _, y := a.Euler(); !approxEqualAbs(y, 0.5, 1e-6) {
+		t.Errorf("expected a yaw of 0.5 after 1s at 0.5 rad/s, got %f", y)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Package alarm raises process alarms on the signals of a control loop.
+
+Alarms follow the state model of ISA-18.2: an alarm whose condition is met
+becomes active and unacknowledged; acknowledging it, and its condition
+clearing, each move it one step back towards normal.  Every state change is
+reported as an Event.
+*/
+package alarm
+
+import (
+	"math"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// Kind is the condition an Alarm watches for
+type Kind int
+
+const (
+	// High alarms when the value exceeds Limit
+	High Kind = iota
+
+	// HighHigh is a High alarm of greater severity
+	HighHigh
+
+	// Low alarms when the value is below Limit
+	Low
+
+	// LowLow is a Low alarm of greater severity
+	LowLow
+
+	// Deviation alarms when the value differs from the setpoint by more
+	// than Limit
+	Deviation
+
+	// Rate alarms when the value changes faster than Limit per second
+	Rate
+)
+
+// String implements fmt.Stringer
+func (k Kind) String() string {
+	switch k {
+	case High:
+		return "high"
+	case HighHigh:
+		return "high-high"
+	case Low:
+		return "low"
+	case LowLow:
+		return "low-low"
+	case Deviation:
+		return "deviation"
+	case Rate:
+		return "rate"
+	}
+	return "unknown"
+}
+
+// State is the state of an Alarm
+type State int
+
+const (
+	// Normal alarms are inactive and acknowledged
+	Normal State = iota
+
+	// Unacked alarms are active and not yet acknowledged
+	Unacked
+
+	// Acked alarms are active and acknowledged
+	Acked
+
+	// Cleared alarms are no longer active, but were not acknowledged
+	Cleared
+)
+
+// String implements fmt.Stringer
+func (s State) String() string {
+	switch s {
+	case Normal:
+		return "normal"
+	case Unacked:
+		return "unacked"
+	case Acked:
+		return "acked"
+	case Cleared:
+		return "cleared"
+	}
+	return "unknown"
+}
+
+// Alarm is a single alarm condition
+type Alarm struct {
+	Name string
+	Kind Kind
+
+	// Limit is the alarm threshold, in process units, or process units per
+	// second for Rate
+	Limit float64
+
+	// Deadband is the hysteresis; an active alarm clears once the value is
+	// Deadband inside Limit
+	Deadband float64
+
+	// Delay is the time the condition must persist before the alarm is
+	// raised, debouncing noise around the limit
+	Delay time.Duration
+
+	state   State
+	pending bool
+	since   time.Time
+}
+
+// State returns the state of the alarm
+func (a *Alarm) State() State {
+	return a.state
+}
+
+// Active returns true if the alarm condition is present
+func (a *Alarm) Active() bool {
+	return a.state == Unacked || a.state == Acked
+}
+
+// condition returns true if the alarm condition is met, with hysteresis
+func (a *Alarm) condition(value, setpt, rate float64) bool {
+	lim := a.Limit
+	db := 0.
+	if a.Active() {
+		db = a.Deadband
+	}
+	switch a.Kind {
+	case High, HighHigh:
+		return value > lim-db
+	case Low, LowLow:
+		return value < lim+db
+	case Deviation:
+		return math.Abs(value-setpt) > lim-db
+	case Rate:
+		return math.Abs(rate) > lim-db
+	}
+	return false
+}
+
+// Event reports a change in the state of an alarm
+type Event struct {
+	Name  string
+	Kind  Kind
+	State State
+	Value float64
+	Time  time.Time
+}
+
+// Monitor evaluates a set of alarms against a signal
+type Monitor struct {
+	Alarms []*Alarm
+
+	// Setpt is the setpoint for Deviation alarms
+	Setpt float64
+
+	// Events receives each change of alarm state.  Events which do not fit
+	// are dropped; they can be recovered with the alarms' State.  If nil,
+	// no events are sent.
+	Events chan<- Event
+
+	// Clock times the alarm delays and rates.  If nil, pctl.SystemClock is
+	// used.
+	Clock pctl.Clock
+
+	prev    float64
+	prevT   time.Time
+	started bool
+}
+
+// Update checks the alarms against value, and returns it unchanged so that a
+// Monitor can be placed in a Cascade
+func (m *Monitor) Update(value float64) float64 {
+	m.Check(value, m.Setpt)
+	return value
+}
+
+// Check evaluates the alarms against value and the setpoint setpt
+func (m *Monitor) Check(value, setpt float64) {
+	c := m.Clock
+	if c == nil {
+		c = pctl.SystemClock
+	}
+	now := c.Now()
+	var rate float64
+	if m.started {
+		if dt := now.Sub(m.prevT).Seconds(); dt > 0 {
+			rate = (value - m.prev) / dt
+		}
+	}
+	m.prev, m.prevT, m.started = value, now, true
+
+	for _, a := range m.Alarms {
+		on := a.condition(value, setpt, rate)
+		switch {
+		case on && !a.Active():
+			if !a.pending {
+				a.pending = true
+				a.since = now
+			}
+			if now.Sub(a.since) >= a.Delay {
+				a.pending = false
+				m.set(a, Unacked, value, now)
+			}
+		case !on:
+			a.pending = false
+			switch a.state {
+			case Unacked:
+				m.set(a, Cleared, value, now)
+			case Acked:
+				m.set(a, Normal, value, now)
+			}
+		}
+	}
+}
+
+// Ack acknowledges the named alarm, returning false if there is no such
+// alarm awaiting acknowledgment
+func (m *Monitor) Ack(name string) bool {
+	for _, a := range m.Alarms {
+		if a.Name != name {
+			continue
+		}
+		switch a.state {
+		case Unacked:
+			m.set(a, Acked, m.prev, m.prevT)
+			return true
+		case Cleared:
+			m.set(a, Normal, m.prev, m.prevT)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Monitor) set(a *Alarm, s State, value float64, t time.Time) {
+	a.state = s
+	if m.Events == nil {
+		return
+	}
+	select {
+	case m.Events <- Event{Name: a.Name, Kind: a.Kind, State: s, Value: value, Time: t}:
+	default:
+	}
+}
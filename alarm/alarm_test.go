@@ -0,0 +1,60 @@
+package alarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestHighAlarmDelayHysteresisAck(t *testing.T) {
+	var clk pctl.ManualClock
+	events := make(chan Event, 10)
+	hi := &Alarm{Name: "TI-101 HI", Kind: High, Limit: 100, Deadband: 2, Delay: time.Second}
+	m := &Monitor{Alarms: []*Alarm{hi}, Events: events, Clock: &clk}
+	step := func(v float64) {
+		clk.Advance(500 * time.Millisecond)
+		m.Update(v)
+	}
+	step(101)
+	step(101)
+	if hi.State() != Normal {
+		t.Fatalf("raised before delay elapsed: %v", hi.State())
+	}
+	step(101)
+	if hi.State() != Unacked {
+		t.Fatalf("expected unacked after delay, got %v", hi.State())
+	}
+	step(99) // inside the deadband, still active
+	if hi.State() != Unacked {
+		t.Errorf("cleared within deadband: %v", hi.State())
+	}
+	step(97)
+	if hi.State() != Cleared {
+		t.Errorf("expected cleared, got %v", hi.State())
+	}
+	if !m.Ack("TI-101 HI") || hi.State() != Normal {
+		t.Errorf("ack of cleared alarm did not return it to normal: %v", hi.State())
+	}
+	if len(events) != 3 {
+		t.Errorf("expected 3 events, got %d", len(events))
+	}
+}
+
+func TestDeviationAndRateAlarms(t *testing.T) {
+	var clk pctl.ManualClock
+	dev := &Alarm{Name: "dev", Kind: Deviation, Limit: 5}
+	roc := &Alarm{Name: "roc", Kind: Rate, Limit: 10}
+	m := &Monitor{Alarms: []*Alarm{dev, roc}, Clock: &clk}
+	m.Check(50, 50)
+	clk.Advance(time.Second)
+	m.Check(56, 50)
+	if !dev.Active() || roc.Active() {
+		t.Errorf("expected only deviation active, got %v and %v", dev.State(), roc.State())
+	}
+	clk.Advance(100 * time.Millisecond)
+	m.Check(58, 58)
+	if dev.Active() || !roc.Active() {
+		t.Errorf("expected only rate active, got %v and %v", dev.State(), roc.State())
+	}
+}
@@ -0,0 +1,120 @@
+package pctl
+
+import "math"
+
+// The Allan deviation of a sensor's output versus the averaging time tau
+// characterizes its noise: white noise falls as tau^-1/2, flicker (bias
+// instability) is flat, and random walk rises as tau^1/2.  The tau at which
+// it is least is a natural averaging time, and so informs filter cutoffs, and
+// the white and random walk levels give Kalman noise parameters.
+
+// AllanDeviationAt returns the overlapping Allan deviation of the samples x at
+// an averaging time of m samples, or NaN if there are fewer than 2m+1 samples
+func AllanDeviationAt(x []float64, m int) float64 {
+	n := len(x)
+	if m < 1 || n < 2*m+1 {
+		return math.NaN()
+	}
+	// θ is the running sum of x; the difference of averages over adjacent
+	// windows of m is (θ[k+2m] - 2θ[k+m] + θ[k]) / m
+	theta := make([]float64, n+1)
+	for i, v := range x {
+		theta[i+1] = theta[i] + v
+	}
+	var ss float64
+	terms := n + 1 - 2*m
+	for k := 0; k < terms; k++ {
+		d := theta[k+2*m] - 2*theta[k+m] + theta[k]
+		ss += d * d
+	}
+	fm := float64(m)
+	return math.Sqrt(ss / (2 * fm * fm * float64(terms)))
+}
+
+// AllanDeviation returns the overlapping Allan deviation of the samples x,
+// taken every dt seconds, at octave spaced averaging times tau = dt, 2dt, 4dt,
+// ... up to a third of the record
+func AllanDeviation(x []float64, dt float64) (tau, adev []float64) {
+	for m := 1; 3*m <= len(x); m *= 2 {
+		tau = append(tau, float64(m)*dt)
+		adev = append(adev, AllanDeviationAt(x, m))
+	}
+	return tau, adev
+}
+
+// Allan computes the non-overlapping Allan deviation at octave spaced
+// averaging times of a streaming signal, with constant memory.  It passes its
+// input through unchanged.  The non-overlapping estimate has more variance
+// than that of AllanDeviation for the same length of record.
+type Allan struct {
+	// DT is the inter-update time in seconds
+	DT float64
+
+	// per octave j, the averaging is over 2^j samples
+	sum     []float64 // of the block in progress
+	cnt     []int     // samples in the block in progress
+	prev    []float64 // average of the previous block
+	hasPrev []bool
+	ss      []float64 // sum of the squared differences of block averages
+	n       []int     // number of differences in ss
+}
+
+// NewAllan returns a new streaming Allan deviation over the given number of
+// octaves, tau = dt to 2^(octaves-1) dt
+func NewAllan(octaves int, dt float64) *Allan {
+	return &Allan{
+		DT:      dt,
+		sum:     make([]float64, octaves),
+		cnt:     make([]int, octaves),
+		prev:    make([]float64, octaves),
+		hasPrev: make([]bool, octaves),
+		ss:      make([]float64, octaves),
+		n:       make([]int, octaves),
+	}
+}
+
+// Update adds a sample and returns it
+func (a *Allan) Update(input float64) float64 {
+	for j := range a.sum {
+		a.sum[j] += input
+		a.cnt[j]++
+		if a.cnt[j] < 1<<uint(j) {
+			continue
+		}
+		avg := a.sum[j] / float64(a.cnt[j])
+		if a.hasPrev[j] {
+			d := avg - a.prev[j]
+			a.ss[j] += d * d
+			a.n[j]++
+		}
+		a.prev[j], a.hasPrev[j] = avg, true
+		a.sum[j], a.cnt[j] = 0, 0
+	}
+	return input
+}
+
+// Octaves returns the number of averaging times
+func (a *Allan) Octaves() int {
+	return len(a.sum)
+}
+
+// Tau returns the j-th averaging time in seconds, 2^j DT
+func (a *Allan) Tau(j int) float64 {
+	return float64(int(1)<<uint(j)) * a.DT
+}
+
+// Deviation returns the Allan deviation at the j-th averaging time, or NaN
+// until two blocks of it have been seen
+func (a *Allan) Deviation(j int) float64 {
+	if a.n[j] == 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(a.ss[j] / (2 * float64(a.n[j])))
+}
+
+// Reset forgets all samples
+func (a *Allan) Reset() {
+	for j := range a.sum {
+		a.sum[j], a.cnt[j], a.prev[j], a.hasPrev[j], a.ss[j], a.n[j] = 0, 0, 0, false, 0, 0
+	}
+}
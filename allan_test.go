@@ -0,0 +1,29 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestAllanDeviationWhiteNoise(t *testing.T) {
+	// white noise of deviation s has an Allan deviation of s / sqrt(m)
+	const s, dt = 0.3, 0.01
+	r := rand.New(rand.NewSource(3))
+	x := make([]float64, 1<<16)
+	stream := NewAllan(8, dt)
+	for i := range x {
+		x[i] = s * r.NormFloat64()
+		stream.Update(x[i])
+	}
+	tau, adev := AllanDeviation(x, dt)
+	for i, m := 0, 1; m <= 128; i, m = i+1, m*2 {
+		want := s / math.Sqrt(float64(m))
+		if !approxEqualAbs(tau[i], float64(m)*dt, 1e-12) || math.Abs(adev[i]/want-1) > 0.05 {
+			t.Errorf("tau %g: expected %f, got %f", tau[i], want, adev[i])
+		}
+		if got := stream.Deviation(i); math.Abs(got/want-1) > 0.15 {
+			t.Errorf("streaming, tau %g: expected %f, got %f", stream.Tau(i), want, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pctl
+
+// BacklashComp is an inverse backlash compensator for gear driven positioners.
+// Placed after the controller, it tracks the direction of the command and
+// offsets it by half the play in that direction, so that a reversal takes up
+// the gap at once instead of losing Width of travel.
+//
+// The direction changes only once the command reverses by more than
+// Threshold from its extreme in the current direction, so that noise on the
+// command does not chatter the actuator across the gap.  Until the first
+// move of more than Threshold, the direction is unknown and no offset is
+// applied.
+type BacklashComp struct {
+	// Width is the total play of the drive, in output units
+	Width float64
+
+	// Threshold is the reversal of the command which changes the tracked
+	// direction, in output units
+	Threshold float64
+
+	dir     float64
+	extreme float64
+	started bool
+}
+
+// Update returns the compensated command
+func (b *BacklashComp) Update(input float64) float64 {
+	if !b.started {
+		b.started = true
+		b.extreme = input
+	}
+	switch {
+	case b.dir > 0 && input > b.extreme, b.dir < 0 && input < b.extreme:
+		b.extreme = input
+	case input-b.extreme > b.Threshold:
+		b.dir, b.extreme = 1, input
+	case b.extreme-input > b.Threshold:
+		b.dir, b.extreme = -1, input
+	}
+	return input + b.dir*b.Width/2
+}
+
+// Direction returns the tracked direction of the command: 1, -1, or 0 before
+// the first move
+func (b *BacklashComp) Direction() int {
+	return int(b.dir)
+}
+
+// Reset forgets the direction
+func (b *BacklashComp) Reset() {
+	b.dir = 0
+	b.started = false
+}
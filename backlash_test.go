@@ -0,0 +1,55 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+// play is a gear train with backlash of width w
+type play struct{ w, out float64 }
+
+func (p *play) Update(in float64) float64 {
+	p.out = math.Max(in-p.w/2, math.Min(in+p.w/2, p.out))
+	return p.out
+}
+
+func TestBacklashCompRemovesReversalError(t *testing.T) {
+	const w = 0.1
+	bare, comp := &play{w: w}, &play{w: w}
+	bc := &BacklashComp{Width: w}
+	var errBare, errComp float64
+	for i := 0; i < 1000; i++ {
+		cmd := math.Sin(2 * math.Pi * float64(i) / 200)
+		if i < 100 {
+			// let the compensator find its direction
+			Cascade(cmd, bc, comp)
+			bare.Update(cmd)
+			continue
+		}
+		errBare = math.Max(errBare, math.Abs(bare.Update(cmd)-cmd))
+		errComp = math.Max(errComp, math.Abs(Cascade(cmd, bc, comp)-cmd))
+	}
+	if !approxEqualAbs(errBare, w/2, 1e-9) {
+		t.Errorf("expected the bare drive to lose half the play, got %g", errBare)
+	}
+	if errComp > 0.01*w {
+		t.Errorf("expected the compensated drive to track, worst error %g", errComp)
+	}
+}
+
+func TestBacklashCompThreshold(t *testing.T) {
+	bc := &BacklashComp{Width: 0.2, Threshold: 0.05}
+	if out := bc.Update(0); out != 0 || bc.Direction() != 0 {
+		t.Errorf("expected no offset before the first move, got %g", out)
+	}
+	if out := bc.Update(0.5); !approxEqualAbs(out, 0.6, 1e-12) {
+		t.Errorf("expected an offset of +0.1 moving up, got %g", out)
+	}
+	// a small reversal is taken as noise
+	if bc.Update(0.47); bc.Direction() != 1 {
+		t.Errorf("expected a reversal within the threshold ignored")
+	}
+	if out := bc.Update(0.4); !approxEqualAbs(out, 0.3, 1e-12) || bc.Direction() != -1 {
+		t.Errorf("expected an offset of -0.1 after reversing, got %g", out)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Package calib stores per-channel calibration corrections and applies them to
+measurements.
+
+Corrections can be replaced while loops are running, e.g. during a field
+calibration; a Channel always applies the latest correction for its name, and
+never a partially updated one.  A Store is saved to and loaded from JSON.
+*/
+package calib
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// ErrSamePoint is returned by TwoPoint when the raw readings are equal
+var ErrSamePoint = errors.New("calib: calibration points have the same raw reading")
+
+// Correction is the calibration of one channel.  The corrected value is
+// Poly(Gain*raw + Offset), or Gain*raw + Offset if Poly is empty.
+type Correction struct {
+	Gain   float64         `json:"gain"`
+	Offset float64         `json:"offset"`
+	Poly   pctl.Polynomial `json:"poly,omitempty"`
+
+	// Time is when the correction was made
+	Time time.Time `json:"time"`
+}
+
+// Identity is the correction which leaves values unchanged
+var Identity = Correction{Gain: 1}
+
+// UnmarshalJSON implements json.Unmarshaler.  A correction without a gain has
+// a gain of 1, so that an offset alone may be written.
+func (c *Correction) UnmarshalJSON(b []byte) error {
+	type correction Correction // without the method
+	v := correction{Gain: 1}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*c = Correction(v)
+	return nil
+}
+
+// Apply corrects a raw value
+func (c *Correction) Apply(raw float64) float64 {
+	v := c.Gain*raw + c.Offset
+	if len(c.Poly) != 0 {
+		v = c.Poly.Update(v)
+	}
+	return v
+}
+
+// TwoPoint returns the gain and offset correction which maps the raw readings
+// raw0 and raw1 to the reference values ref0 and ref1
+func TwoPoint(raw0, ref0, raw1, ref1 float64) (Correction, error) {
+	if raw0 == raw1 {
+		return Correction{}, ErrSamePoint
+	}
+	gain := (ref1 - ref0) / (raw1 - raw0)
+	return Correction{Gain: gain, Offset: ref0 - gain*raw0, Time: time.Now()}, nil
+}
+
+// Store holds the corrections of named channels.  It is safe for concurrent
+// use.
+type Store struct {
+	mu       sync.Mutex
+	channels map[string]*Channel
+}
+
+// NewStore returns an empty store
+func NewStore() *Store {
+	return &Store{channels: map[string]*Channel{}}
+}
+
+// Channel applies the correction of one channel of a Store
+type Channel struct {
+	name string
+	c    atomic.Value // *Correction
+}
+
+// Update implements pctl.Updater, correcting input
+func (ch *Channel) Update(input float64) float64 {
+	return ch.c.Load().(*Correction).Apply(input)
+}
+
+// Correction returns the current correction
+func (ch *Channel) Correction() Correction {
+	return *ch.c.Load().(*Correction)
+}
+
+// Name returns the name of the channel
+func (ch *Channel) Name() string {
+	return ch.name
+}
+
+// Channel returns the named channel, creating it with the Identity correction
+// if it does not exist.  The channel follows later calls to Set.
+func (s *Store) Channel(name string) *Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(name)
+}
+
+// get returns the named channel, creating it if needed.  s.mu must be held.
+func (s *Store) get(name string) *Channel {
+	ch, ok := s.channels[name]
+	if !ok {
+		ch = &Channel{name: name}
+		id := Identity
+		ch.c.Store(&id)
+		s.channels[name] = ch
+	}
+	return ch
+}
+
+// Set replaces the correction of the named channel
+func (s *Store) Set(name string, c Correction) {
+	c.Poly = append(pctl.Polynomial(nil), c.Poly...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(name).c.Store(&c)
+}
+
+// Names returns the sorted names of all channels
+func (s *Store) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0, len(s.channels))
+	for k := range s.channels {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Save writes the corrections of every channel as JSON
+func (s *Store) Save(w io.Writer) error {
+	s.mu.Lock()
+	m := make(map[string]Correction, len(s.channels))
+	for k, ch := range s.channels {
+		m[k] = ch.Correction()
+	}
+	s.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Load reads corrections written by Save, replacing those of the channels it
+// names.  Channels already in use follow the new corrections.
+func (s *Store) Load(r io.Reader) error {
+	var m map[string]Correction
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return err
+	}
+	for k, c := range m {
+		s.Set(k, c)
+	}
+	return nil
+}
+
+// SaveFile saves the store to path.  The file is replaced atomically, so a
+// crash cannot leave a truncated calibration behind.
+func (s *Store) SaveFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := s.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile loads the store from path
+func (s *Store) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Load(f)
+}
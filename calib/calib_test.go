@@ -0,0 +1,65 @@
+package calib
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestChannelFollowsFieldCalibration(t *testing.T) {
+	s := NewStore()
+	ch := s.Channel("PT-3")
+	if got := ch.Update(7); got != 7 {
+		t.Errorf("uncalibrated channel should pass through, got %f", got)
+	}
+	c, err := TwoPoint(0.1, 0, 9.9, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Set("PT-3", c)
+	if got := ch.Update(5); math.Abs(got-5) > 1e-12 {
+		t.Errorf("expected corrected 5, got %f", got)
+	}
+}
+
+func TestSaveLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "calib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cal.json")
+	s := NewStore()
+	s.Set("TT-1", Correction{Gain: 2, Offset: 1, Poly: pctl.Polynomial{0, 1, 0.5}})
+	if err := s.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	r := NewStore()
+	ch := r.Channel("TT-1")
+	if err := r.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	// 2*1 + 1 = 3, then 3 + 0.5*9
+	if got := ch.Update(1); got != 7.5 {
+		t.Errorf("expected 7.5 after reload, got %f", got)
+	}
+}
+
+func TestLoadDefaultsGain(t *testing.T) {
+	s := NewStore()
+	err := s.Load(strings.NewReader(`{"TT-1": {"offset": -0.5}, "TT-2": {"gain": 0, "offset": 3}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Channel("TT-1").Update(2); got != 1.5 {
+		t.Errorf("expected a missing gain to default to 1, got %f", got)
+	}
+	if got := s.Channel("TT-2").Update(2); got != 3 {
+		t.Errorf("expected an explicit zero gain to be kept, got %f", got)
+	}
+}
@@ -0,0 +1,310 @@
+/*
+Package checkpoint saves the state of a running control loop to disk and
+restores it at startup, so that restarting the controller does not upset a
+slow process, such as a thermal loop whose integrator took hours to settle.
+
+Blocks implementing pctl.Stateful are registered by name with a Checkpointer.
+The control loop calls Tick between updates, which copies the state of every
+block when a checkpoint is due; Run writes the copies to disk in its own
+goroutine, so the loop never waits on the filesystem.  The file is replaced
+atomically, so a crash cannot leave a truncated checkpoint behind.
+*/
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// Version is the version of the checkpoint format
+const Version = 1
+
+// ErrVersion is returned when restoring a checkpoint of an unknown version
+var ErrVersion = errors.New("checkpoint: unsupported version")
+
+// Snapshot is the saved state of a set of blocks
+type Snapshot struct {
+	Version int                  `json:"version"`
+	Time    time.Time            `json:"time"`
+	Blocks  map[string][]float64 `json:"blocks"`
+}
+
+// Checkpointer periodically snapshots a set of blocks and writes them to a
+// file.  Add, Tick, Save, and Restore must be called from the goroutine which
+// updates the blocks; Run may be called from any.
+type Checkpointer struct {
+	// Path is the checkpoint file
+	Path string
+
+	// Period is the interval between checkpoints taken by Tick
+	Period time.Duration
+
+	names  []string
+	blocks map[string]pctl.Stateful
+	last   time.Time
+	queue  chan Snapshot
+}
+
+// New returns a Checkpointer which writes to path every period
+func New(path string, period time.Duration) *Checkpointer {
+	return &Checkpointer{
+		Path:   path,
+		Period: period,
+		blocks: make(map[string]pctl.Stateful),
+		queue:  make(chan Snapshot, 1),
+	}
+}
+
+// Add registers a block under name, replacing any block of the same name
+func (c *Checkpointer) Add(name string, s pctl.Stateful) {
+	if _, ok := c.blocks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.blocks[name] = s
+}
+
+// partial is implemented by adapters such as logic.Float, which are
+// Stateful only if the block they wrap is
+type partial interface {
+	Stateful() bool
+}
+
+// AddChain registers the blocks of a chain, such as a config.Chain, as name/i
+// for their index i in the chain.  Every block must be pctl.Stateful, so that
+// the checkpoint covers the whole chain; memoryless blocks save an empty
+// state.  If one is not, AddChain returns an error naming it and registers
+// none of them.
+func (c *Checkpointer) AddChain(name string, chain []pctl.Updater) error {
+	for i, u := range chain {
+		_, ok := u.(pctl.Stateful)
+		if p, isPartial := u.(partial); isPartial {
+			ok = ok && p.Stateful()
+		}
+		if !ok {
+			return fmt.Errorf("checkpoint: block %d of %q, %T, is not pctl.Stateful", i, name, u)
+		}
+	}
+	for i, u := range chain {
+		c.Add(name+"/"+strconv.Itoa(i), u.(pctl.Stateful))
+	}
+	return nil
+}
+
+// Snapshot returns the present state of every block
+func (c *Checkpointer) Snapshot(now time.Time) Snapshot {
+	snap := Snapshot{Version: Version, Time: now, Blocks: make(map[string][]float64, len(c.names))}
+	for _, n := range c.names {
+		snap.Blocks[n] = c.blocks[n].SaveState()
+	}
+	return snap
+}
+
+// Tick takes a snapshot if Period has elapsed since the last one, and queues
+// it for Run to write, returning true if it did.  If Run has not finished
+// writing the previous snapshot, it is replaced by the new one.
+func (c *Checkpointer) Tick(now time.Time) bool {
+	if !c.last.IsZero() && now.Sub(c.last) < c.Period {
+		return false
+	}
+	c.last = now
+	snap := c.Snapshot(now)
+	for {
+		select {
+		case c.queue <- snap:
+			return true
+		default:
+		}
+		select {
+		case <-c.queue:
+		default:
+		}
+	}
+}
+
+// Run writes the snapshots queued by Tick until ctx is done or writing one
+// fails.  It returns ctx.Err() when ctx is done.
+func (c *Checkpointer) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case snap := <-c.queue:
+			if err := WriteFile(c.Path, snap); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Save writes a snapshot immediately, e.g. at shutdown
+func (c *Checkpointer) Save() error {
+	return WriteFile(c.Path, c.Snapshot(time.Now()))
+}
+
+// Restore reads the checkpoint file and restores every registered block from
+// it.  No block is modified unless all of them restore: the blocks' present
+// states are kept, and if any block rejects its saved state, those already
+// restored are returned to them.  If there is no checkpoint, the error
+// satisfies os.IsNotExist, and the loop should start fresh.
+func (c *Checkpointer) Restore() (Snapshot, error) {
+	snap, err := ReadFile(c.Path)
+	if err != nil {
+		return snap, err
+	}
+	prior := make([][]float64, len(c.names))
+	for i, n := range c.names {
+		s, ok := snap.Blocks[n]
+		if !ok {
+			return snap, fmt.Errorf("checkpoint: no state for block %q", n)
+		}
+		prior[i] = c.blocks[n].SaveState()
+		if len(s) != len(prior[i]) {
+			return snap, fmt.Errorf("checkpoint: block %q: %w", n, pctl.ErrStateSize)
+		}
+	}
+	for i, n := range c.names {
+		if err := c.blocks[n].RestoreState(snap.Blocks[n]); err != nil {
+			for j := 0; j < i; j++ {
+				c.blocks[c.names[j]].RestoreState(prior[j])
+			}
+			return snap, fmt.Errorf("checkpoint: block %q: %w", n, err)
+		}
+	}
+	return snap, nil
+}
+
+// Write encodes a snapshot as JSON.  NaN and infinite states, which JSON
+// numbers cannot hold, are written as the strings "NaN", "+Inf", and "-Inf".
+func Write(w io.Writer, snap Snapshot) error {
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// Read decodes a snapshot written by Write
+func Read(r io.Reader) (Snapshot, error) {
+	var snap Snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return snap, err
+	}
+	if snap.Version != Version {
+		return snap, ErrVersion
+	}
+	return snap, nil
+}
+
+// state is a block's state in JSON, with non-finite values as strings
+type state []float64
+
+func (s state) MarshalJSON() ([]byte, error) {
+	b := []byte{'['}
+	for i, v := range s {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			b = strconv.AppendQuote(b, strconv.FormatFloat(v, 'g', -1, 64))
+			continue
+		}
+		b = strconv.AppendFloat(b, v, 'g', -1, 64)
+	}
+	return append(b, ']'), nil
+}
+
+func (s *state) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	out := make(state, len(raw))
+	for i, r := range raw {
+		if len(r) > 0 && r[0] == '"' {
+			var str string
+			if err := json.Unmarshal(r, &str); err != nil {
+				return err
+			}
+			v, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return fmt.Errorf("checkpoint: state value %q: %w", str, err)
+			}
+			out[i] = v
+			continue
+		}
+		if err := json.Unmarshal(r, &out[i]); err != nil {
+			return err
+		}
+	}
+	*s = out
+	return nil
+}
+
+// snapshotJSON is the encoding of a Snapshot
+type snapshotJSON struct {
+	Version int              `json:"version"`
+	Time    time.Time        `json:"time"`
+	Blocks  map[string]state `json:"blocks"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (snap Snapshot) MarshalJSON() ([]byte, error) {
+	out := snapshotJSON{Version: snap.Version, Time: snap.Time, Blocks: make(map[string]state, len(snap.Blocks))}
+	for n, s := range snap.Blocks {
+		out.Blocks[n] = s
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (snap *Snapshot) UnmarshalJSON(b []byte) error {
+	var in snapshotJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	snap.Version, snap.Time = in.Version, in.Time
+	snap.Blocks = make(map[string][]float64, len(in.Blocks))
+	for n, s := range in.Blocks {
+		snap.Blocks[n] = s
+	}
+	return nil
+}
+
+// WriteFile writes a snapshot to path.  The file is synced and replaced
+// atomically.
+func WriteFile(path string, snap Snapshot) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := Write(f, snap); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ReadFile reads a snapshot written by WriteFile
+func ReadFile(path string) (Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer f.Close()
+	return Read(f)
+}
@@ -0,0 +1,187 @@
+package checkpoint
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/logic"
+)
+
+// tempDir returns a new temporary directory and a function removing it
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func newLoop() (*pctl.PID, *pctl.LPF) {
+	return &pctl.PID{P: 1, I: 0.5, DT: 0.1, Setpt: 10}, pctl.NewLPF(1, 0.1)
+}
+
+func TestRestoreResumesLoop(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "loop.json")
+	pid, lpf := newLoop()
+	c := New(path, time.Second)
+	c.Add("pid", pid)
+	if err := c.AddChain("chain", []pctl.Updater{lpf, (*pctl.Setpoint)(new(float64))}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		pid.Update(lpf.Update(3))
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	pid2, lpf2 := newLoop()
+	c2 := New(path, time.Second)
+	c2.Add("pid", pid2)
+	c2.AddChain("chain", []pctl.Updater{lpf2, (*pctl.Setpoint)(new(float64))})
+	if _, err := c2.Restore(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if a, b := pid.Update(lpf.Update(4)), pid2.Update(lpf2.Update(4)); a != b {
+			t.Fatalf("restored loop diverged, %g != %g", b, a)
+		}
+	}
+}
+
+func TestRestoreWithoutCheckpoint(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	c := New(filepath.Join(dir, "none.json"), time.Second)
+	if _, err := c.Restore(); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestRestoreIsAllOrNothing(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "loop.json")
+	c := New(path, time.Second)
+	c.Add("a", pctl.NewLPF(1, 0.1))
+	c.Add("b", pctl.NewLPFOrder(2, 1, 0.1))
+	c.Save()
+
+	a := pctl.NewLPF(1, 0.1)
+	c2 := New(path, time.Second)
+	c2.Add("a", a)
+	c2.Add("b", pctl.NewLPFOrder(3, 1, 0.1))
+	a.Update(5)
+	if _, err := c2.Restore(); err == nil {
+		t.Fatal("expected an error restoring a block of a different order")
+	}
+	if a.SaveState()[0] == 0 {
+		t.Error("expected no block restored when one does not match")
+	}
+}
+
+func TestAddChainRejectsUnsupportedBlocks(t *testing.T) {
+	c := New("unused", time.Second)
+	smooth := pctl.NewSmoothBiquad(pctl.NewBiquadLowpass(1000, 50, 0.7, 0), 10)
+	err := c.AddChain("chain", []pctl.Updater{pctl.NewLPF(1, 0.1), smooth})
+	if err == nil || !strings.Contains(err.Error(), "SmoothBiquad") {
+		t.Errorf("expected an error naming the SmoothBiquad, got %v", err)
+	}
+	if snap := c.Snapshot(time.Now()); len(snap.Blocks) != 0 {
+		t.Errorf("expected no blocks registered, got %d", len(snap.Blocks))
+	}
+	// a logic adapter is only Stateful if the block it wraps is
+	if err := c.AddChain("logic", []pctl.Updater{logic.Float{B: &logic.OnDelay{Delay: 1, DT: 0.1}}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRestoreRollsBackOnRejectedState(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "loop.json")
+	a, fir := pctl.NewLPF(1, 0.1), pctl.NewFIRFilter([]float64{1, 2, 3})
+	c := New(path, time.Second)
+	c.Add("a", a)
+	c.Add("fir", fir)
+	a.Update(5)
+	snap := c.Snapshot(time.Now())
+	snap.Blocks["fir"][0] = 7 // an index past the end of the history
+	if err := WriteFile(path, snap); err != nil {
+		t.Fatal(err)
+	}
+	a.Update(5)
+	before := a.SaveState()[0]
+	if _, err := c.Restore(); err == nil {
+		t.Fatal("expected an error restoring an out of range FIR index")
+	}
+	if got := a.SaveState()[0]; got != before {
+		t.Errorf("expected the LPF rolled back to %g, got %g", before, got)
+	}
+}
+
+func TestNonFiniteStateRoundTrips(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "loop.json")
+	lpf := pctl.NewLPF(1, 0.1)
+	lpf.RestoreState([]float64{math.NaN()})
+	pid := &pctl.PID{P: 1, I: 1, DT: 0.1}
+	pid.RestoreState([]float64{math.Inf(1), math.Inf(-1), 0, 0, 0, 0})
+	c := New(path, time.Second)
+	c.Add("lpf", lpf)
+	c.Add("pid", pid)
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(snap.Blocks["lpf"][0]) || !math.IsInf(snap.Blocks["pid"][0], 1) || !math.IsInf(snap.Blocks["pid"][1], -1) {
+		t.Errorf("non-finite states did not round trip: %v", snap.Blocks)
+	}
+}
+
+func TestTickWritesOnPeriod(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "loop.json")
+	lpf := pctl.NewLPF(1, 0.1)
+	c := New(path, time.Minute)
+	c.Add("lpf", lpf)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	t0 := time.Unix(1000, 0)
+	if !c.Tick(t0) || c.Tick(t0.Add(time.Second)) {
+		t.Error("expected a checkpoint on the first tick only")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if snap, err := ReadFile(path); err == nil {
+			if !snap.Time.Equal(t0) {
+				t.Errorf("expected the snapshot from %v, got %v", t0, snap.Time)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("checkpoint was not written")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected Run to return context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package pctl
+
+import "time"
+
+// Clock is a source of time.  Types which measure the time between updates
+// take a Clock so that they may be driven by simulated time as well as real
+// time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock of the operating system, i.e. time.Now
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock which only moves when told to, for simulation and
+// tests.  The zero value reads the zero time.
+type ManualClock struct {
+	t time.Time
+}
+
+// Now returns the clock's time
+func (c *ManualClock) Now() time.Time {
+	return c.t
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// Set sets the clock's time
+func (c *ManualClock) Set(t time.Time) {
+	c.t = t
+}
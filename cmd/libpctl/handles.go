@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/brandondube/pctl"
+)
+
+// blocks maps the handles given to C to the blocks they name.  C may not hold
+// Go pointers, so blocks are referred to by integer handles, which start at 1
+// so that 0 can signal failure.
+var (
+	mu     sync.Mutex
+	blocks = map[int64]pctl.Updater{}
+	next   int64
+)
+
+func register(u pctl.Updater) int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	next++
+	blocks[next] = u
+	return next
+}
+
+// validator is implemented by blocks which check their parameters
+type validator interface {
+	Validate() error
+}
+
+// registerValid registers u, or returns 0 if its parameters are invalid
+func registerValid(u pctl.Updater) int64 {
+	if v, ok := u.(validator); ok && v.Validate() != nil {
+		return 0
+	}
+	return register(u)
+}
+
+// setPID changes the parameters of the PID h with set, and returns false if
+// there is no such PID or the new parameters are invalid, which are undone
+func setPID(h int64, set func(*pctl.PID)) bool {
+	pid, ok := lookup(h).(*pctl.PID)
+	if !ok {
+		return false
+	}
+	prev := *pid
+	set(pid)
+	if pid.Validate() != nil {
+		*pid = prev
+		return false
+	}
+	return true
+}
+
+func lookup(h int64) pctl.Updater {
+	mu.Lock()
+	defer mu.Unlock()
+	return blocks[h]
+}
+
+func release(h int64) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := blocks[h]
+	delete(blocks, h)
+	return ok
+}
+
+// newStateSpace builds a state space filter from A in row major order
+func newStateSpace(a, b, c []float64, d float64) *pctl.StateSpaceFilter {
+	n := len(b)
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = append([]float64(nil), a[i*n:(i+1)*n]...)
+	}
+	return pctl.NewStateSpaceFilter(rows, append([]float64(nil), b...), append([]float64(nil), c...), d, nil)
+}
+
+// updateBlock updates the block h with each of in, writing the outputs to
+// out, and returns false if there is no such block
+func updateBlock(h int64, in, out []float64) bool {
+	u := lookup(h)
+	if u == nil {
+		return false
+	}
+	for i, x := range in {
+		out[i] = u.Update(x)
+	}
+	return true
+}
+
+// resetBlock zeros the state of the block h, and returns false if there is
+// no such block
+func resetBlock(h int64) bool {
+	s, ok := lookup(h).(pctl.Stateful)
+	if !ok {
+		return false
+	}
+	s.RestoreState(make([]float64, len(s.SaveState())))
+	return true
+}
+
+func nan() float64 {
+	return math.NaN()
+}
+
+// main is required of a c-shared build, and is not called
+func main() {}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestHandlesMatchBlocks(t *testing.T) {
+	a := newStateSpace([]float64{0.5, 0.1, 0, 0.9}, []float64{1, 1}, []float64{1, -1}, 0.2)
+	h := register(newStateSpace([]float64{0.5, 0.1, 0, 0.9}, []float64{1, 1}, []float64{1, -1}, 0.2))
+	in := []float64{1, 0, -2, 3, 0.5}
+	out := make([]float64, len(in))
+	if !updateBlock(h, in, out) {
+		t.Fatal("expected the handle to be registered")
+	}
+	for i, x := range in {
+		if want := a.Update(x); out[i] != want {
+			t.Errorf("sample %d: got %g from the handle, expected %g", i, out[i], want)
+		}
+	}
+	if !resetBlock(h) {
+		t.Fatal("expected the block to reset")
+	}
+	a.Reset()
+	if got, want := lookup(h).Update(1), a.Update(1); got != want {
+		t.Errorf("expected a reset block to restart, got %g, expected %g", got, want)
+	}
+	if !release(h) || release(h) || updateBlock(h, in, out) {
+		t.Error("expected a released handle to be unknown")
+	}
+}
+
+func TestResetPID(t *testing.T) {
+	pid := &pctl.PID{P: 1, I: 1, DT: 0.1, Setpt: 1, OutMax: 5}
+	h := register(pid)
+	first := pid.Update(0)
+	pid.Update(0)
+	resetBlock(h)
+	if got := pid.Update(0); got != first || pid.OutMax != 5 {
+		t.Errorf("expected a reset PID to repeat its first output %g and keep its limits, got %g", first, got)
+	}
+	release(h)
+}
+
+func TestInvalidBlocksRejected(t *testing.T) {
+	if h := registerValid(&pctl.PID{P: 1, I: 0.5}); h != 0 {
+		t.Errorf("expected a PID with integral action and no DT to be rejected, got handle %d", h)
+	}
+	if h := registerValid(pctl.NewBiquad(1, 0, 0, 0, 1.5)); h != 0 {
+		t.Errorf("expected an unstable biquad to be rejected, got handle %d", h)
+	}
+	h := registerValid(&pctl.PID{P: 1, I: 0.5, DT: 1e-3})
+	if h == 0 {
+		t.Fatal("expected a valid PID to be registered")
+	}
+	if setPID(h, func(pid *pctl.PID) { pid.OutMin, pid.OutMax = 1, -1 }) {
+		t.Error("expected inverted limits to be rejected")
+	}
+	if pid := lookup(h).(*pctl.PID); pid.OutMin != 0 || pid.OutMax != 0 {
+		t.Errorf("expected rejected limits to be undone, got %f, %f", pid.OutMin, pid.OutMax)
+	}
+	if !setPID(h, func(pid *pctl.PID) { pid.OutMin, pid.OutMax = -1, 1 }) {
+		t.Error("expected valid limits to be accepted")
+	}
+	release(h)
+}
@@ -0,0 +1,126 @@
+/*
+Command libpctl is a C shared library exposing pctl's PID, biquad, FIR, and
+state space blocks, so that firmware test benches, LabVIEW, Python (ctypes),
+and other C callers run the same implementations validated in Go.
+
+Build it with
+
+	go build -buildmode=c-shared -o libpctl.so ./cmd/libpctl
+
+which also writes libpctl.h.  Each constructor returns a handle, or 0 on
+invalid arguments: those the block's Validate method rejects, such as a PID
+with integral action and no DT, or an unstable biquad.  Every other function
+takes a handle.  A handle must only be used by one thread at a time, and
+released with pctl_destroy.  Functions returning int return 1 on success
+and 0 for an unknown handle or invalid arguments; setters leave the block
+unchanged if they reject its new parameters.  Arrays are limited to 2^26
+elements, so that they may be addressed on 32-bit platforms.
+
+	long long h = pctl_pid_new(1.0, 0.5, 0.0, 1e-3);
+	pctl_pid_set_setpoint(h, 50.0);
+	double u = pctl_update(h, measurement);
+	pctl_destroy(h);
+*/
+package main
+
+// #include <stddef.h>
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/brandondube/pctl"
+)
+
+// maxDoubles is the largest array passed in, whose array type is small
+// enough for 32-bit platforms
+const maxDoubles = 1 << 26
+
+// doubles views n C doubles at p as a slice, without copying, or returns nil
+// if n is out of range
+func doubles(p *C.double, n C.int) []float64 {
+	if p == nil || n <= 0 || n > maxDoubles {
+		return nil
+	}
+	return (*[maxDoubles]float64)(unsafe.Pointer(p))[:n:n]
+}
+
+//export pctl_pid_new
+func pctl_pid_new(p, i, d, dt C.double) C.longlong {
+	return C.longlong(registerValid(&pctl.PID{P: float64(p), I: float64(i), D: float64(d), DT: float64(dt)}))
+}
+
+//export pctl_pid_set_setpoint
+func pctl_pid_set_setpoint(h C.longlong, setpt C.double) C.int {
+	if !setPID(int64(h), func(pid *pctl.PID) { pid.Setpt = float64(setpt) }) {
+		return 0
+	}
+	return 1
+}
+
+//export pctl_pid_set_limits
+func pctl_pid_set_limits(h C.longlong, min, max C.double) C.int {
+	if !setPID(int64(h), func(pid *pctl.PID) { pid.OutMin, pid.OutMax = float64(min), float64(max) }) {
+		return 0
+	}
+	return 1
+}
+
+//export pctl_biquad_new
+func pctl_biquad_new(a0, a1, a2, b1, b2 C.double) C.longlong {
+	return C.longlong(registerValid(pctl.NewBiquad(float64(a0), float64(a1), float64(a2), float64(b1), float64(b2))))
+}
+
+//export pctl_fir_new
+func pctl_fir_new(taps *C.double, n C.int) C.longlong {
+	t := doubles(taps, n)
+	if t == nil {
+		return 0
+	}
+	return C.longlong(registerValid(pctl.NewFIRFilter(t)))
+}
+
+//export pctl_ss_new
+func pctl_ss_new(a, b, c *C.double, d C.double, n C.int) C.longlong {
+	if a == nil || b == nil || c == nil || n <= 0 || int64(n)*int64(n) > maxDoubles {
+		return 0
+	}
+	av := doubles(a, n*n)
+	return C.longlong(registerValid(newStateSpace(av, doubles(b, n), doubles(c, n), float64(d))))
+}
+
+//export pctl_update
+func pctl_update(h C.longlong, input C.double) C.double {
+	u := lookup(int64(h))
+	if u == nil {
+		return C.double(nan())
+	}
+	return C.double(u.Update(float64(input)))
+}
+
+//export pctl_update_n
+func pctl_update_n(h C.longlong, in, out *C.double, n C.int) C.int {
+	if n > maxDoubles || n > 0 && (in == nil || out == nil) {
+		return 0
+	}
+	if !updateBlock(int64(h), doubles(in, n), doubles(out, n)) {
+		return 0
+	}
+	return 1
+}
+
+//export pctl_reset
+func pctl_reset(h C.longlong) C.int {
+	if !resetBlock(int64(h)) {
+		return 0
+	}
+	return 1
+}
+
+//export pctl_destroy
+func pctl_destroy(h C.longlong) C.int {
+	if !release(int64(h)) {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,270 @@
+/*
+Command pctl-filter designs filters and prints their coefficients as Go source
+or JSON, along with a table of their frequency response.  The gen format is Go
+source of a specialized type, with literal coefficients and unrolled taps,
+which does not import pctl; see package gen.
+
+Usage:
+
+	pctl-filter -type butterworth -band lowpass -order 4 -fs 1000 -fc 50
+	pctl-filter -type cheby1 -ripple 0.5 -band bandpass -order 2 -fs 1000 -fc 40,90 -format json
+	pctl-filter -type fir -taps 63 -window hann -band lowpass -fs 1000 -fc 50
+	pctl-filter -type fir -taps 31 -fs 1000 -fc 50 -format gen -name AntiAlias
+
+The response table has -points rows, logarithmically spaced from -fmin to
+Nyquist.
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/gen"
+)
+
+type options struct {
+	kind   string
+	band   string
+	order  int
+	taps   int
+	ripple float64
+	window string
+	fs     float64
+	fc     string
+	format string
+	pkg    string
+	name   string
+	points int
+	fmin   float64
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pctl-filter:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	var o options
+	fs := flag.NewFlagSet("pctl-filter", flag.ContinueOnError)
+	fs.StringVar(&o.kind, "type", "butterworth", "filter type: butterworth, cheby1, or fir")
+	fs.StringVar(&o.band, "band", "lowpass", "band: lowpass, highpass, bandpass, or bandstop")
+	fs.IntVar(&o.order, "order", 2, "order of IIR filters")
+	fs.IntVar(&o.taps, "taps", 31, "number of taps of FIR filters")
+	fs.Float64Var(&o.ripple, "ripple", 1, "pass band ripple of chebyshev filters, dB")
+	fs.StringVar(&o.window, "window", "hamming", "FIR window: rectangular, hann, hamming, or blackman")
+	fs.Float64Var(&o.fs, "fs", 0, "sample rate, Hz")
+	fs.StringVar(&o.fc, "fc", "", "corner frequency or comma separated pair of corners, Hz")
+	fs.StringVar(&o.format, "format", "go", "output format: go, gen, or json")
+	fs.StringVar(&o.pkg, "pkg", "main", "package clause of Go output")
+	fs.StringVar(&o.name, "name", "filter", "variable name of Go output, or type name of gen output")
+	fs.IntVar(&o.points, "points", 20, "number of rows in the frequency response table")
+	fs.Float64Var(&o.fmin, "fmin", 0, "lowest frequency of the response table, Hz; defaults to fs/1000")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	d, err := design(o)
+	if err != nil {
+		return err
+	}
+	switch o.format {
+	case "go":
+		return writeGo(w, o, d)
+	case "gen":
+		return writeGen(w, o, d)
+	case "json":
+		return writeJSON(w, o, d)
+	}
+	return fmt.Errorf("unknown format %q", o.format)
+}
+
+// designed is the result of a filter design
+type designed struct {
+	// sos is non-nil for IIR filters
+	sos *pctl.SOSFilter
+
+	// taps is non-nil for FIR filters
+	taps []float64
+
+	resp pctl.Responder
+}
+
+var bands = map[string]pctl.Band{
+	"lowpass":  pctl.Lowpass,
+	"highpass": pctl.Highpass,
+	"bandpass": pctl.Bandpass,
+	"bandstop": pctl.Bandstop,
+}
+
+var windows = map[string]pctl.Window{
+	"rectangular": pctl.Rectangular,
+	"hann":        pctl.Hann,
+	"hamming":     pctl.Hamming,
+	"blackman":    pctl.Blackman,
+}
+
+func design(o options) (designed, error) {
+	var d designed
+	band, ok := bands[o.band]
+	if !ok {
+		return d, fmt.Errorf("unknown band %q", o.band)
+	}
+	if o.fs <= 0 {
+		return d, fmt.Errorf("-fs must be positive")
+	}
+	var corners []float64
+	for _, s := range strings.Split(o.fc, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return d, fmt.Errorf("parsing -fc: %w", err)
+		}
+		corners = append(corners, f)
+	}
+	var err error
+	switch o.kind {
+	case "butterworth":
+		d.sos, err = pctl.Butterworth(o.order, band, o.fs, corners...)
+		d.resp = d.sos
+	case "cheby1":
+		d.sos, err = pctl.Chebyshev1(o.order, o.ripple, band, o.fs, corners...)
+		d.resp = d.sos
+	case "fir":
+		win, ok := windows[o.window]
+		if !ok {
+			return d, fmt.Errorf("unknown window %q", o.window)
+		}
+		d.taps, err = pctl.DesignFIR(o.taps, band, o.fs, win, corners...)
+		if err == nil {
+			d.resp = pctl.NewFIRFilter(d.taps)
+		}
+	default:
+		return d, fmt.Errorf("unknown filter type %q", o.kind)
+	}
+	return d, err
+}
+
+// responseRow is one frequency of the response table
+type responseRow struct {
+	Freq  float64 `json:"freq"`
+	MagDB float64 `json:"mag_db"`
+	Phase float64 `json:"phase_deg"`
+}
+
+// magFloor is the lowest magnitude reported in the response table, dB.
+// It keeps perfect zeros, e.g. at Nyquist, representable in JSON.
+const magFloor = -300
+
+func responseTable(o options, r pctl.Responder) []responseRow {
+	fmin := o.fmin
+	if fmin <= 0 {
+		fmin = o.fs / 1000
+	}
+	fmax := o.fs / 2
+	out := make([]responseRow, o.points)
+	for i := range out {
+		f := fmin
+		if o.points > 1 {
+			f = fmin * math.Pow(fmax/fmin, float64(i)/float64(o.points-1))
+		}
+		h := r.Response(f, o.fs)
+		out[i] = responseRow{Freq: f, MagDB: math.Max(pctl.MagDB(h), magFloor), Phase: pctl.PhaseDeg(h)}
+	}
+	return out
+}
+
+func writeGo(w io.Writer, o options, d designed) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by pctl-filter; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\nimport \"github.com/brandondube/pctl\"\n\n", o.pkg)
+	fmt.Fprintf(&b, "// %s is a %s %s filter at fs=%g Hz, corners %s Hz\n", o.name, o.kind, o.band, o.fs, o.fc)
+	if d.sos != nil {
+		fmt.Fprintf(&b, "var %s = pctl.NewSOSFilter(\n", o.name)
+		for _, s := range d.sos.Sections() {
+			a0, a1, a2, b1, b2 := s.Coefs()
+			fmt.Fprintf(&b, "\tpctl.NewBiquad(%s, %s, %s, %s, %s),\n", f(a0), f(a1), f(a2), f(b1), f(b2))
+		}
+		fmt.Fprintf(&b, ")\n")
+	} else {
+		fmt.Fprintf(&b, "var %s = pctl.NewFIRFilter([]float64{\n", o.name)
+		for _, t := range d.taps {
+			fmt.Fprintf(&b, "\t%s,\n", f(t))
+		}
+		fmt.Fprintf(&b, "})\n")
+	}
+	fmt.Fprintf(&b, "\n// frequency response\n//\n// %14s %12s %12s\n", "freq (Hz)", "mag (dB)", "phase (deg)")
+	for _, r := range responseTable(o, d.resp) {
+		fmt.Fprintf(&b, "// %14.6g %12.4f %12.4f\n", r.Freq, r.MagDB, r.Phase)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeGen(w io.Writer, o options, d designed) error {
+	g := gen.Generator{
+		Package: o.pkg,
+		Type:    o.name,
+		Doc:     fmt.Sprintf("%s is a %s %s filter at fs=%g Hz, corners %s Hz", o.name, o.kind, o.band, o.fs, o.fc),
+		Command: "pctl-filter",
+	}
+	var u pctl.Updater = d.sos
+	if d.sos == nil {
+		u = pctl.NewFIRFilter(d.taps)
+	}
+	src, err := g.Source(u)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func f(v float64) string {
+	if v == 0 {
+		v = 0 // no -0 in source
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeJSON(w io.Writer, o options, d designed) error {
+	type section struct {
+		A0 float64 `json:"a0"`
+		A1 float64 `json:"a1"`
+		A2 float64 `json:"a2"`
+		B1 float64 `json:"b1"`
+		B2 float64 `json:"b2"`
+	}
+	out := struct {
+		Type     string        `json:"type"`
+		Band     string        `json:"band"`
+		Fs       float64       `json:"fs"`
+		Corners  string        `json:"corners"`
+		Sections []section     `json:"sections,omitempty"`
+		Taps     []float64     `json:"taps,omitempty"`
+		Response []responseRow `json:"response"`
+	}{
+		Type:     o.kind,
+		Band:     o.band,
+		Fs:       o.fs,
+		Corners:  o.fc,
+		Taps:     d.taps,
+		Response: responseTable(o, d.resp),
+	}
+	if d.sos != nil {
+		for _, s := range d.sos.Sections() {
+			a0, a1, a2, b1, b2 := s.Coefs()
+			out.Sections = append(out.Sections, section{a0, a1, a2, b1, b2})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
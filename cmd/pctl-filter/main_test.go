@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONOutputHasSectionsAndResponse(t *testing.T) {
+	var buf bytes.Buffer
+	args := []string{"-type", "butterworth", "-order", "4", "-fs", "1000", "-fc", "50", "-format", "json", "-points", "5"}
+	if err := run(args, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Sections []map[string]float64
+		Response []responseRow
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Sections) != 2 {
+		t.Errorf("expected 2 sections for 4th order, got %d", len(out.Sections))
+	}
+	if len(out.Response) != 5 {
+		t.Errorf("expected 5 response rows, got %d", len(out.Response))
+	}
+}
+
+func TestGoOutputFIR(t *testing.T) {
+	var buf bytes.Buffer
+	args := []string{"-type", "fir", "-taps", "7", "-fs", "1000", "-fc", "100", "-name", "aa"}
+	if err := run(args, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "var aa = pctl.NewFIRFilter(") {
+		t.Errorf("go output missing FIR constructor:\n%s", buf.String())
+	}
+}
+
+func TestGenOutputUnrollsTaps(t *testing.T) {
+	var buf bytes.Buffer
+	args := []string{"-type", "fir", "-taps", "5", "-window", "rectangular", "-fs", "1000", "-fc", "100", "-format", "gen", "-name", "AA"}
+	if err := run(args, &buf); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	if !strings.Contains(src, "func (f *AA) Update(x float64) float64") || strings.Contains(src, "pctl.") {
+		t.Errorf("gen output is not a self-contained AA:\n%s", src)
+	}
+	if !strings.Contains(src, "(w[0]+w[4])") {
+		t.Errorf("gen output did not fold the symmetric taps:\n%s", src)
+	}
+}
+
+func TestBadBandErrors(t *testing.T) {
+	err := run([]string{"-band", "allpass", "-fs", "1000", "-fc", "10"}, &bytes.Buffer{})
+	if err == nil {
+		t.Error("expected error for unknown band")
+	}
+}
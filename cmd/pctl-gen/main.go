@@ -0,0 +1,69 @@
+/*
+Command pctl-gen writes specialized Go source for the chain of blocks in a
+config file, with literal coefficients and unrolled FIR taps; see package gen.
+The source imports nothing, for deployment on targets which cannot carry pctl
+or load a config.
+
+Usage:
+
+	pctl-gen -config notch.json -pkg main -type Notch -o notch_gen.go
+
+or, from a go:generate directive,
+
+	//go:generate pctl-gen -config notch.json -pkg $GOPACKAGE -type Notch -o notch_gen.go
+
+If -o is not given, the source is written to stdout.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/brandondube/pctl/config"
+	"github.com/brandondube/pctl/gen"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pctl-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("pctl-gen", flag.ContinueOnError)
+	cfgPath := fs.String("config", "", "path to the config of the chain (JSON)")
+	outPath := fs.String("o", "", "path to write the source; stdout if empty")
+	var g gen.Generator
+	fs.StringVar(&g.Package, "pkg", "main", "package clause of the source")
+	fs.StringVar(&g.Type, "type", "Chain", "name of the generated type")
+	fs.StringVar(&g.Doc, "doc", "", "doc comment of the type; lists the blocks if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cfgPath == "" {
+		return fmt.Errorf("-config is required")
+	}
+	raw, err := ioutil.ReadFile(*cfgPath)
+	if err != nil {
+		return err
+	}
+	c, err := config.Load(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	src, err := g.Config(c)
+	if err != nil {
+		return err
+	}
+	if *outPath == "" {
+		_, err = w.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*outPath, src, 0644)
+}
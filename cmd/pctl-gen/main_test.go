@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunWritesSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pctl-gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cfg := filepath.Join(dir, "notch.json")
+	body := `{"blocks": [{"type": "biquad", "params": {"design": "notch", "fs": 1000, "f": 60, "q": 5}}]}`
+	if err := ioutil.WriteFile(cfg, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "notch_gen.go")
+	if err := run([]string{"-config", cfg, "-pkg", "filters", "-type", "Notch", "-o", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	src, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package filters", "type Notch struct", "func (f *Notch) Update(x float64) float64"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("source missing %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestRunRequiresConfig(t *testing.T) {
+	if err := run(nil, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error without -config")
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Command pctl-sim runs a closed-loop simulation described by a config file and
+writes the time series to CSV or JSON, with summary metrics printed to stdout.
+The output carries the sample rate, blocks, and git blob hash of the config
+file as metadata; see package export.
+
+The config file is JSON of the form
+
+	{
+	  "controller": {"blocks": [{"type": "pid", "params": {"p": 2, "i": 20, "dt": 1e-3}}]},
+	  "plant":      {"blocks": [{"type": "lpf", "params": {"fc": 5, "dt": 1e-3}}]},
+	  "scenario": {
+	    "dt": 1e-3,
+	    "duration": 3,
+	    "setpoint": [{"t": 0.1, "value": 1}],
+	    "disturbance": [{"t": 1.5, "value": -0.2}]
+	  }
+	}
+
+where controller and plant are pctl configs (see package config).
+
+Usage:
+
+	pctl-sim -config loop.json -o results.csv
+
+If -o is not given, the results are written to stdout and the metrics to stderr.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/brandondube/pctl/config"
+	"github.com/brandondube/pctl/export"
+	"github.com/brandondube/pctl/sim"
+)
+
+// simConfig is the file format consumed by pctl-sim
+type simConfig struct {
+	Controller config.Config `json:"controller"`
+	Plant      config.Config `json:"plant"`
+	Scenario   sim.Scenario  `json:"scenario"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "pctl-sim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pctl-sim", flag.ContinueOnError)
+	cfgPath := fs.String("config", "", "path to the simulation config (JSON)")
+	outPath := fs.String("o", "", "path to write the results; stdout if empty")
+	format := fs.String("format", "csv", "output format: csv or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cfgPath == "" {
+		return fmt.Errorf("-config is required")
+	}
+	raw, err := ioutil.ReadFile(*cfgPath)
+	if err != nil {
+		return err
+	}
+	res, meta, err := simulate(raw)
+	if err != nil {
+		return err
+	}
+
+	csvOut := stdout
+	if *outPath != "" {
+		of, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer of.Close()
+		csvOut = of
+	}
+	switch *format {
+	case "csv":
+		err = export.WriteSimCSV(csvOut, res, meta)
+	case "json":
+		err = export.WriteSimJSON(csvOut, res, meta)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	// keep the metrics out of the results stream when it goes to stdout
+	if *outPath == "" {
+		printMetrics(stderr, res.Metrics())
+	} else {
+		printMetrics(stdout, res.Metrics())
+	}
+	return nil
+}
+
+// simulate decodes the config in raw and runs it.  The metadata identifies the
+// config by its git blob hash.
+func simulate(raw []byte) (*sim.Result, export.Metadata, error) {
+	var c simConfig
+	var meta export.Metadata
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, meta, fmt.Errorf("decoding config: %w", err)
+	}
+	if c.Scenario.DT <= 0 {
+		return nil, meta, fmt.Errorf("scenario dt must be positive, got %g", c.Scenario.DT)
+	}
+	ctl, err := c.Controller.Build()
+	if err != nil {
+		return nil, meta, fmt.Errorf("controller: %w", err)
+	}
+	plant, err := c.Plant.Build()
+	if err != nil {
+		return nil, meta, fmt.Errorf("plant: %w", err)
+	}
+	meta = export.Metadata{
+		SampleRate: 1 / c.Scenario.DT,
+		Blocks:     append(append([]config.Block{}, c.Controller.Blocks...), c.Plant.Blocks...),
+		ConfigHash: export.ConfigHash(raw),
+	}
+	return sim.Run(sim.Loop{Controller: ctl, Plant: plant}, c.Scenario), meta, nil
+}
+
+func printMetrics(w io.Writer, m sim.Metrics) {
+	fmt.Fprintf(w, "IAE           %g\n", m.IAE)
+	fmt.Fprintf(w, "ISE           %g\n", m.ISE)
+	fmt.Fprintf(w, "ITAE          %g\n", m.ITAE)
+	fmt.Fprintf(w, "overshoot     %.2f%%\n", 100*m.Overshoot)
+	fmt.Fprintf(w, "settling time %g s\n", m.SettlingTime)
+	fmt.Fprintf(w, "final error   %g\n", m.FinalError)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/brandondube/pctl/export"
+)
+
+const testSim = `{
+	"controller": {"blocks": [{"type": "pid", "params": {"p": 2, "i": 20, "dt": 1e-3}}]},
+	"plant": {"blocks": [{"type": "lpf", "params": {"fc": 5, "dt": 1e-3}}]},
+	"scenario": {"dt": 1e-3, "duration": 0.5, "setpoint": [{"t": 0, "value": 1}]}
+}`
+
+func TestSimulateWritesEverySample(t *testing.T) {
+	res, meta, err := simulate([]byte(testSim))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := export.WriteSimCSV(&buf, res, meta); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(&buf)
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 501 {
+		t.Errorf("expected header + 500 rows, got %d", len(rows))
+	}
+}
+
+func TestSimulateRejectsZeroDT(t *testing.T) {
+	_, _, err := simulate([]byte(`{"scenario": {"duration": 1}}`))
+	if err == nil {
+		t.Error("expected an error for a scenario without dt")
+	}
+}
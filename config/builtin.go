@@ -0,0 +1,294 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/logic"
+	"github.com/brandondube/pctl/units"
+)
+
+func init() {
+	RegisterBlock("setpoint", newSetpoint)
+	RegisterBlock("pid", newPID)
+	RegisterBlock("lpf", newLPF)
+	RegisterBlock("hpf", newHPF)
+	RegisterBlock("biquad", newBiquad)
+	RegisterBlock("fir", newFIR)
+	RegisterBlock("statespace", newStateSpace)
+	RegisterBlock("lut", newLUT)
+	RegisterBlock("piecewise", newPiecewise)
+	RegisterBlock("spline", newSpline)
+	RegisterBlock("polynomial", newPolynomial)
+	RegisterBlock("scale", newScale)
+	RegisterBlock("convert", newConvert)
+	RegisterBlock("sum", newSum)
+	RegisterBlock("not", newNot)
+	RegisterBlock("ondelay", newOnDelay)
+	RegisterBlock("offdelay", newOffDelay)
+}
+
+func newSetpoint(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		Value float64 `json:"value"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	s := pctl.Setpoint(p.Value)
+	return &s, nil
+}
+
+// newPID decodes directly into the exported fields of pctl.PID, e.g.
+// {"p": 1, "i": 0.5, "dt": 1e-3, "setpt": 50}
+func newPID(params json.RawMessage) (pctl.Updater, error) {
+	pid := new(pctl.PID)
+	if err := json.Unmarshal(params, pid); err != nil {
+		return nil, err
+	}
+	return pid, nil
+}
+
+type firstOrderParams struct {
+	// Fc is the corner frequency in Hz
+	Fc float64 `json:"fc"`
+
+	// DT is the inter-update time in seconds
+	DT float64 `json:"dt"`
+}
+
+func newLPF(params json.RawMessage) (pctl.Updater, error) {
+	var p firstOrderParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return pctl.NewLPF(p.Fc, p.DT), nil
+}
+
+// newHPF additionally accepts "order", 1 (the default) or 2
+func newHPF(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		firstOrderParams
+		Order int `json:"order"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	switch p.Order {
+	case 0, 1:
+		return pctl.NewHPF(p.Fc, p.DT), nil
+	case 2:
+		if err := pctl.CheckNyquist(p.Fc, 1/p.DT); err != nil {
+			return nil, err
+		}
+		return pctl.NewHPF2(p.Fc, p.DT), nil
+	}
+	return nil, fmt.Errorf("hpf order must be 1 or 2, got %d", p.Order)
+}
+
+var biquadDesigns = map[string]pctl.NewBiquadFunc{
+	"lowpass":   pctl.NewBiquadLowpass,
+	"highpass":  pctl.NewBiquadHighpass,
+	"bandpass":  pctl.NewBiquadBandpass,
+	"notch":     pctl.NewBiquadNotch,
+	"peak":      pctl.NewBiquadPeak,
+	"lowshelf":  pctl.NewBiquadLowShelf,
+	"highshelf": pctl.NewBiquadHighShelf,
+}
+
+// newBiquad accepts either raw coefficients {"a0": .., "b2": ..} or a design
+// {"design": "lowpass", "fs": 1000, "f": 50, "q": 0.707, "g": 0}
+func newBiquad(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		Design string  `json:"design"`
+		Fs     float64 `json:"fs"`
+		F      float64 `json:"f"`
+		Q      float64 `json:"q"`
+		G      float64 `json:"g"`
+		A0     float64 `json:"a0"`
+		A1     float64 `json:"a1"`
+		A2     float64 `json:"a2"`
+		B1     float64 `json:"b1"`
+		B2     float64 `json:"b2"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Design == "" {
+		return pctl.NewBiquad(p.A0, p.A1, p.A2, p.B1, p.B2), nil
+	}
+	f, ok := biquadDesigns[p.Design]
+	if !ok {
+		return nil, fmt.Errorf("unknown biquad design %q", p.Design)
+	}
+	if err := pctl.CheckNyquist(p.F, p.Fs); err != nil {
+		return nil, err
+	}
+	return f(p.Fs, p.F, p.Q, p.G), nil
+}
+
+func newFIR(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		Taps []float64 `json:"taps"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Taps) == 0 {
+		return nil, fmt.Errorf("fir requires at least one tap")
+	}
+	return pctl.NewFIRFilter(p.Taps), nil
+}
+
+func newStateSpace(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		A  [][]float64 `json:"a"`
+		B  []float64   `json:"b"`
+		C  []float64   `json:"c"`
+		D  float64     `json:"d"`
+		X0 []float64   `json:"x0"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.A) != len(p.B) || len(p.C) != len(p.B) {
+		return nil, fmt.Errorf("statespace dimensions of A, B, C do not agree")
+	}
+	return pctl.NewStateSpaceFilter(p.A, p.B, p.C, p.D, p.X0), nil
+}
+
+// newLUT accepts {"x": [..], "y": [..], "extrapolate": false}
+func newLUT(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		X           []float64 `json:"x"`
+		Y           []float64 `json:"y"`
+		Extrapolate bool      `json:"extrapolate"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	t := pctl.NewLookupTable(p.X, p.Y)
+	t.Extrapolate = p.Extrapolate
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// newPiecewise accepts the JSON form of pctl.Piecewise
+func newPiecewise(params json.RawMessage) (pctl.Updater, error) {
+	p := new(pctl.Piecewise)
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newSpline accepts {"points": [{"x": 0, "y": 0}, ...], "extrapolate": false}
+func newSpline(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		Points      []pctl.Point `json:"points"`
+		Extrapolate bool         `json:"extrapolate"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	s, err := pctl.NewSpline(p.Points...)
+	if err != nil {
+		return nil, err
+	}
+	s.Extrapolate = p.Extrapolate
+	return s, nil
+}
+
+// newPolynomial accepts {"coefs": [c0, c1, ...]} in ascending powers
+func newPolynomial(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		Coefs []float64 `json:"coefs"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return pctl.Polynomial(p.Coefs), nil
+}
+
+// newScale decodes directly into the exported fields of pctl.Scale, e.g.
+// {"raw0": 13107, "eng0": 0, "raw1": 65535, "eng1": 10}
+func newScale(params json.RawMessage) (pctl.Updater, error) {
+	s := new(pctl.Scale)
+	if err := json.Unmarshal(params, s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newConvert accepts {"from": "psi", "to": "kPa"}
+func newConvert(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return units.Convert(p.From, p.To)
+}
+
+// newSum accepts {"signs": "+-+", "inputs": ["meas", "setpt", "ff"],
+// "values": {"setpt": 50, "ff": 0}}.  The chain feeds the first input; the
+// others hold their initial values until set by the application.
+func newSum(params json.RawMessage) (pctl.Updater, error) {
+	var p struct {
+		Signs  string             `json:"signs"`
+		Inputs []string           `json:"inputs"`
+		Values map[string]float64 `json:"values"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	s, err := pctl.NewSum(p.Signs, p.Inputs...)
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range p.Values {
+		if !s.Set(name, v) {
+			return nil, fmt.Errorf("sum has no input %q", name)
+		}
+	}
+	return s, nil
+}
+
+// The logic blocks act on 0/1 signals; see logic.Float.  Chains are single
+// input, so And and Or must be composed in code.
+
+func newNot(params json.RawMessage) (pctl.Updater, error) {
+	return logic.Float{B: logic.Not{}}, nil
+}
+
+type delayParams struct {
+	// Delay is the delay in seconds
+	Delay float64 `json:"delay"`
+
+	// DT is the inter-update time in seconds
+	DT float64 `json:"dt"`
+}
+
+func newOnDelay(params json.RawMessage) (pctl.Updater, error) {
+	var p delayParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return logic.Float{B: &logic.OnDelay{Delay: p.Delay, DT: p.DT}}, nil
+}
+
+func newOffDelay(params json.RawMessage) (pctl.Updater, error) {
+	var p delayParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	return logic.Float{B: &logic.OffDelay{Delay: p.Delay, DT: p.DT}}, nil
+}
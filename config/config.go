@@ -0,0 +1,165 @@
+/*
+Package config loads declarative descriptions of pctl block chains.
+
+A config is a JSON document listing blocks in the order they are applied:
+
+	{
+	  "blocks": [
+	    {"type": "setpoint", "params": {"value": 50}},
+	    {"type": "lpf", "params": {"fc": 10, "dt": 1e-3}}
+	  ]
+	}
+
+Each block type is constructed by a Factory registered under its name.  The
+built-in pctl types are registered by this package; applications may expose
+their own Updaters with RegisterBlock.
+
+A config may also declare the units of its signals, in which case unit
+conversions are inserted at the ends of the chain:
+
+	"units": {"sensor": "F", "process": "C", "output": "%", "actuator": "fraction"}
+*/
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/units"
+)
+
+// Factory constructs an Updater from the raw JSON params of a block
+type Factory func(params json.RawMessage) (pctl.Updater, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// RegisterBlock makes a block type available to configs under name.
+// It panics if name is already registered or f is nil, in the same manner
+// as database/sql.Register; it is meant to be called from init functions.
+func RegisterBlock(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if f == nil {
+		panic("config: RegisterBlock factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("config: RegisterBlock called twice for " + name)
+	}
+	registry[name] = f
+}
+
+// Blocks returns the sorted names of all registered block types
+func Blocks() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]string, 0, len(registry))
+	for k := range registry {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Block is the declarative description of a single block
+type Block struct {
+	// Type is the registered name of the block
+	Type string `json:"type"`
+
+	// Params are passed verbatim to the block's Factory
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Units declares the engineering units of the signals around a chain, by
+// their names in package units.  Empty units are not converted.
+type Units struct {
+	// Sensor is the unit of the measurements fed to the chain, and Process
+	// the unit the blocks work in
+	Sensor  string `json:"sensor,omitempty"`
+	Process string `json:"process,omitempty"`
+
+	// Output is the unit of the output of the last block, and Actuator the
+	// unit the actuator expects
+	Output   string `json:"output,omitempty"`
+	Actuator string `json:"actuator,omitempty"`
+}
+
+// Config is a sequence of blocks, applied in order
+type Config struct {
+	Blocks []Block `json:"blocks"`
+
+	// Units, if not nil, inserts unit conversions at the ends of the chain
+	Units *Units `json:"units,omitempty"`
+}
+
+// Load decodes a Config from r
+func Load(r io.Reader) (*Config, error) {
+	var c Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return &c, nil
+}
+
+// NewBlock constructs the Updater described by b
+func NewBlock(b Block) (pctl.Updater, error) {
+	registryMu.RLock()
+	f, ok := registry[b.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("config: unknown block type %q", b.Type)
+	}
+	params := b.Params
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	u, err := f(params)
+	if err != nil {
+		return nil, fmt.Errorf("config: block %q: %w", b.Type, err)
+	}
+	return u, nil
+}
+
+// Build constructs every block in the config and returns them as a Chain,
+// with the conversions declared by Units at its ends
+func (c *Config) Build() (Chain, error) {
+	out := make(Chain, 0, len(c.Blocks)+2)
+	if u := c.Units; u != nil && u.Sensor != "" && u.Process != "" {
+		conv, err := units.Convert(u.Sensor, u.Process)
+		if err != nil {
+			return nil, fmt.Errorf("config: sensor units: %w", err)
+		}
+		out = append(out, conv)
+	}
+	for i, b := range c.Blocks {
+		u, err := NewBlock(b)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", i, err)
+		}
+		out = append(out, u)
+	}
+	if u := c.Units; u != nil && u.Output != "" && u.Actuator != "" {
+		conv, err := units.Convert(u.Output, u.Actuator)
+		if err != nil {
+			return nil, fmt.Errorf("config: actuator units: %w", err)
+		}
+		out = append(out, conv)
+	}
+	return out, nil
+}
+
+// Chain is a sequence of Updaters which is itself an Updater
+type Chain []pctl.Updater
+
+// Update applies each element of the chain in sequence
+func (c Chain) Update(input float64) float64 {
+	return pctl.Cascade(input, c...)
+}
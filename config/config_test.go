@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/brandondube/pctl"
+)
+
+const testConfig = `{
+	"blocks": [
+		{"type": "setpoint", "params": {"value": 1.5}},
+		{"type": "pid", "params": {"p": 2, "i": 0.5, "dt": 1e-3}},
+		{"type": "biquad", "params": {"design": "lowpass", "fs": 1000, "f": 50, "q": 0.7071}}
+	]
+}`
+
+func TestConfigMatchesManualChain(t *testing.T) {
+	c, err := Load(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := pctl.Setpoint(1.5)
+	pid := &pctl.PID{P: 2, I: 0.5, DT: 1e-3}
+	bq := pctl.NewBiquadLowpass(1000, 50, 0.7071, 0)
+	for i := 0; i < 10; i++ {
+		in := float64(i)
+		expect := pctl.Cascade(in, &s, pid, bq)
+		got := chain.Update(in)
+		if expect != got {
+			t.Errorf("sample %d: config chain %f != manual chain %f", i, got, expect)
+		}
+	}
+}
+
+type gain float64
+
+func (g gain) Update(input float64) float64 {
+	return float64(g) * input
+}
+
+func TestRegisterBlockCustomType(t *testing.T) {
+	RegisterBlock("test_gain", func(params json.RawMessage) (pctl.Updater, error) {
+		var p struct{ K float64 }
+		err := json.Unmarshal(params, &p)
+		return gain(p.K), err
+	})
+	c, err := Load(strings.NewReader(`{"blocks": [{"type": "test_gain", "params": {"k": 3}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := chain.Update(2); out != 6 {
+		t.Errorf("custom block produced %f, expected 6", out)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected duplicate registration to panic")
+		}
+	}()
+	RegisterBlock("test_gain", newSetpoint)
+}
+
+func TestUnknownBlockErrors(t *testing.T) {
+	_, err := NewBlock(Block{Type: "does-not-exist"})
+	if err == nil {
+		t.Error("expected error for unknown block type")
+	}
+}
+
+func TestAboveNyquistErrors(t *testing.T) {
+	params := json.RawMessage(`{"design": "notch", "fs": 1000, "f": 600, "q": 2}`)
+	_, err := NewBlock(Block{Type: "biquad", Params: params})
+	if !errors.Is(err, pctl.ErrNyquist) {
+		t.Errorf("expected ErrNyquist, got %v", err)
+	}
+}
+
+func TestInterlockBlocks(t *testing.T) {
+	b, err := NewBlock(Block{Type: "ondelay", Params: json.RawMessage(`{"delay": 0.25, "dt": 0.1}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []float64
+	for i := 0; i < 4; i++ {
+		out = append(out, b.Update(1))
+	}
+	if out[1] != 0 || out[2] != 1 {
+		t.Errorf("expected output to turn on at the third update, got %v", out)
+	}
+}
+
+func TestSumBlock(t *testing.T) {
+	params := json.RawMessage(`{"signs": "+-+", "inputs": ["meas", "setpt", "ff"], "values": {"setpt": 50}}`)
+	b, err := NewBlock(Block{Type: "sum", Params: params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.(*pctl.Sum).Set("ff", 2)
+	if got := b.Update(45); got != -3 {
+		t.Errorf("expected 45 - 50 + 2 = -3, got %g", got)
+	}
+	params = json.RawMessage(`{"signs": "+-", "inputs": ["meas", "setpt"], "values": {"bias": 1}}`)
+	if _, err := NewBlock(Block{Type: "sum", Params: params}); err == nil {
+		t.Error("expected an error for a value of an unknown input")
+	}
+}
+
+func TestUnitsInsertConversions(t *testing.T) {
+	c, err := Load(strings.NewReader(`{
+		"blocks": [{"type": "setpoint", "params": {"value": 100}}],
+		"units": {"sensor": "F", "process": "C", "output": "%", "actuator": "fraction"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 212 F is 100 C, zero error; 230 F is 110 C, 10% error
+	if got := chain.Update(212); !approxEqual(got, 0) {
+		t.Errorf("expected zero at setpoint, got %f", got)
+	}
+	if got := chain.Update(230); !approxEqual(got, 0.1) {
+		t.Errorf("expected 0.1 fraction, got %f", got)
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,282 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+)
+
+// ErrLeadingDen is returned when a transfer function's leading denominator
+// coefficient is zero, so that it cannot be realized
+var ErrLeadingDen = errors.New("pctl: transfer function leading denominator coefficient is zero")
+
+// TF is a discrete transfer function in ascending powers of z^-1,
+//
+//	H = (Num[0] + Num[1] z^-1 + ...) / (Den[0] + Den[1] z^-1 + ...)
+//
+// TF, ZPK, and StateSpaceFilter convert to one another, so that a system may
+// be designed in whichever representation is natural and run in the most
+// efficient one; for IIR filters that is usually an SOSFilter.
+type TF struct {
+	Num, Den []float64
+}
+
+// ZPK returns the zeros, poles, and gain of the transfer function.  The
+// difference in length of Num and Den appears as zeros or poles at the
+// origin.
+func (tf TF) ZPK() (ZPK, error) {
+	num, dn := trimLeading(tf.Num)
+	den, dd := trimLeading(tf.Den)
+	if len(den) == 0 {
+		return ZPK{}, ErrLeadingDen
+	}
+	if len(num) == 0 {
+		return ZPK{P: roots(den), K: 0}, nil
+	}
+	out := ZPK{Z: roots(num), P: roots(den), K: num[0] / den[0]}
+	// H = z^(n-m-dn+dd) B(z)/A(z) for B of degree m and A of degree n
+	shift := (len(den) - 1) - (len(num) - 1) - dn + dd
+	for ; shift > 0; shift-- {
+		out.Z = append(out.Z, 0)
+	}
+	for ; shift < 0; shift++ {
+		out.P = append(out.P, 0)
+	}
+	return out, nil
+}
+
+// SS returns a state space realization of the transfer function, in
+// controllable canonical form
+func (tf TF) SS() (*StateSpaceFilter, error) {
+	if len(tf.Den) == 0 || tf.Den[0] == 0 {
+		return nil, ErrLeadingDen
+	}
+	n := len(tf.Den) - 1
+	if len(tf.Num)-1 > n {
+		n = len(tf.Num) - 1
+	}
+	a := make([]float64, n+1)
+	b := make([]float64, n+1)
+	for i, v := range tf.Den {
+		a[i] = v / tf.Den[0]
+	}
+	for i, v := range tf.Num {
+		b[i] = v / tf.Den[0]
+	}
+	A := make([][]float64, n)
+	for i := range A {
+		A[i] = make([]float64, n)
+		if i > 0 {
+			A[i][i-1] = 1
+		}
+	}
+	B := make([]float64, n)
+	C := make([]float64, n)
+	for i := 0; i < n; i++ {
+		A[0][i] = -a[i+1]
+		C[i] = b[i+1] - a[i+1]*b[0]
+	}
+	if n > 0 {
+		B[0] = 1
+	}
+	return NewStateSpaceFilter(A, B, C, b[0], nil), nil
+}
+
+// TF returns the transfer function of the ZPK.  If there are more zeros than
+// poles, poles at the origin are added, as for SOS.
+func (zpk ZPK) TF() TF {
+	num := polyFromRoots(zpk.Z)
+	for i := range num {
+		num[i] *= zpk.K
+	}
+	if d := len(zpk.P) - len(zpk.Z); d > 0 {
+		num = append(make([]float64, d), num...)
+	}
+	return TF{Num: num, Den: polyFromRoots(zpk.P)}
+}
+
+// SS returns a state space realization of the ZPK
+func (zpk ZPK) SS() (*StateSpaceFilter, error) {
+	return zpk.TF().SS()
+}
+
+// TF returns the transfer function of the state space system, computed with
+// the Faddeev-LeVerrier algorithm
+func (s *StateSpaceFilter) TF() TF {
+	n := len(s.b)
+	den := make([]float64, n+1)
+	num := make([]float64, n+1)
+	den[0] = 1
+	num[0] = s.d
+	// M_1 = I; c_k = -tr(A M_k)/k; M_k+1 = A M_k + c_k I
+	m := identity(n)
+	am := make([][]float64, n)
+	for i := range am {
+		am[i] = make([]float64, n)
+	}
+	for k := 1; k <= n; k++ {
+		matMul(s.a, m, am)
+		var tr float64
+		for i := 0; i < n; i++ {
+			tr += am[i][i]
+		}
+		c := -tr / float64(k)
+		den[k] = c
+		// C M_k B
+		var cmb float64
+		for i := 0; i < n; i++ {
+			var mb float64
+			for j := 0; j < n; j++ {
+				mb += m[i][j] * s.b[j]
+			}
+			cmb += s.c[i] * mb
+		}
+		num[k] = cmb + s.d*c
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				m[i][j] = am[i][j]
+			}
+			m[i][i] += c
+		}
+	}
+	return TF{Num: num, Den: den}
+}
+
+// ZPK returns the zeros, poles, and gain of the state space system
+func (s *StateSpaceFilter) ZPK() (ZPK, error) {
+	return s.TF().ZPK()
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+// matMul computes out = a @ b for square a, b
+func matMul(a, b, out [][]float64) {
+	n := len(a)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			var v float64
+			for k := 0; k < n; k++ {
+				v += a[i][k] * b[k][j]
+			}
+			out[i][j] = v
+		}
+	}
+}
+
+// trimLeading strips the leading zeros of c, returning the count removed
+func trimLeading(c []float64) ([]float64, int) {
+	i := 0
+	for i < len(c) && c[i] == 0 {
+		i++
+	}
+	return c[i:], i
+}
+
+// polyFromRoots returns the coefficients of prod(1 - r[i] x) in ascending
+// powers of x, equivalently prod(z - r[i]) in descending powers of z
+func polyFromRoots(r []complex128) []float64 {
+	c := make([]complex128, len(r)+1)
+	c[0] = 1
+	for i, v := range r {
+		for j := i + 1; j > 0; j-- {
+			c[j] -= v * c[j-1]
+		}
+	}
+	out := make([]float64, len(c))
+	for i, v := range c {
+		out[i] = real(v)
+	}
+	return out
+}
+
+// roots returns the roots of the polynomial with coefficients c, in
+// descending powers, c[0] != 0, by the Durand-Kerner method.  Roots are made
+// exactly real or conjugate where they are nearly so, as they must be for
+// real coefficients.
+func roots(c []float64) []complex128 {
+	n := len(c) - 1
+	if n < 1 {
+		return nil
+	}
+	a := make([]complex128, n)
+	bound := 0.
+	for i := 1; i <= n; i++ {
+		a[i-1] = complex(c[i]/c[0], 0)
+		bound = math.Max(bound, math.Abs(c[i]/c[0]))
+	}
+	bound++ // Cauchy's bound on the magnitude of the roots
+	// start on a circle of that radius, at angles which are not symmetric
+	// about the real axis
+	r := make([]complex128, n)
+	for i := range r {
+		r[i] = cmplx.Rect(bound, 0.4+2*math.Pi*float64(i)/float64(n))
+	}
+	eval := func(x complex128) complex128 {
+		v := complex(1, 0)
+		for _, ai := range a {
+			v = v*x + ai
+		}
+		return v
+	}
+	for iter := 0; iter < 1000; iter++ {
+		var delta float64
+		for i := range r {
+			den := complex(1, 0)
+			for j := range r {
+				if j != i {
+					den *= r[i] - r[j]
+				}
+			}
+			if den == 0 {
+				den = complex(1e-300, 0)
+			}
+			step := eval(r[i]) / den
+			r[i] -= step
+			delta = math.Max(delta, cmplx.Abs(step)/math.Max(1, cmplx.Abs(r[i])))
+		}
+		if delta < 1e-15 {
+			break
+		}
+	}
+	return conjugate(r)
+}
+
+// conjugate snaps nearly real roots to the real axis and pairs the remainder
+// with exact complex conjugates
+func conjugate(r []complex128) []complex128 {
+	const tol = 1e-7
+	used := make([]bool, len(r))
+	for i, v := range r {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		if math.Abs(imag(v)) <= tol*math.Max(1, cmplx.Abs(v)) {
+			r[i] = complex(real(v), 0)
+			continue
+		}
+		best, bestDist := -1, math.Inf(1)
+		for j := range r {
+			if !used[j] {
+				if d := cmplx.Abs(r[j] - cmplx.Conj(v)); d < bestDist {
+					best, bestDist = j, d
+				}
+			}
+		}
+		if best < 0 {
+			r[i] = complex(real(v), 0)
+			continue
+		}
+		used[best] = true
+		mean := (v + cmplx.Conj(r[best])) / 2
+		r[i], r[best] = mean, cmplx.Conj(mean)
+	}
+	return r
+}
@@ -0,0 +1,78 @@
+package pctl
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+// testTF is a third order system with mixed real and complex roots
+var testTF = TF{
+	Num: []float64{0.2, 0.1, -0.05},
+	Den: []float64{2, -1.8, 1.1, -0.3},
+}
+
+func responsesMatch(t *testing.T, what string, a, b Responder) {
+	t.Helper()
+	for _, f := range []float64{0, 3, 50, 123, 250, 499} {
+		ha, hb := a.Response(f, 1000), b.Response(f, 1000)
+		if cmplx.Abs(ha-hb) > 1e-9*cmplx.Abs(ha)+1e-12 {
+			t.Errorf("%s: at %f Hz, %v != %v", what, f, ha, hb)
+		}
+	}
+}
+
+func TestTFStateSpaceRoundTrip(t *testing.T) {
+	ss, err := testTF.SS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	responsesMatch(t, "tf -> ss -> tf", testTF, ss.TF())
+
+	// the realization must run as the difference equation does
+	var x, y [8]float64
+	x[0] = 1
+	num, den := testTF.Num, testTF.Den
+	for n := range y {
+		v := 0.
+		for k := 0; k <= n && k < len(num); k++ {
+			v += num[k] * x[n-k]
+		}
+		for k := 1; k <= n && k < len(den); k++ {
+			v -= den[k] * y[n-k]
+		}
+		y[n] = v / den[0]
+		if got := ss.Update(x[n]); !approxEqualAbs(got, y[n], 1e-12) {
+			t.Errorf("impulse response sample %d: state space %f != tf %f", n, got, y[n])
+		}
+	}
+
+	// the realization must also run as the transfer function does
+	tf2 := NewStateSpaceFilter([][]float64{{0.5}}, []float64{1}, []float64{1}, 0, nil).TF()
+	if tf2.Den[1] != -0.5 || tf2.Num[1] != 1 {
+		t.Errorf("expected 1 z^-1 / (1 - 0.5 z^-1), got %v", tf2)
+	}
+}
+
+func TestTFZPKRoundTrip(t *testing.T) {
+	zpk, err := testTF.ZPK()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zpk.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	responsesMatch(t, "tf -> zpk -> tf", testTF, zpk.TF())
+	sos, err := zpk.SOS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	responsesMatch(t, "tf -> zpk -> sos", testTF, sos)
+	ss, err := zpk.SS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	responsesMatch(t, "zpk -> ss", testTF, ss.TF())
+	if _, err := (TF{Num: []float64{1}, Den: []float64{0}}).ZPK(); err != ErrLeadingDen {
+		t.Errorf("expected ErrLeadingDen, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package pctl
+
+import "math"
+
+// Crossfade runs two controllers on the same input and blends their outputs,
+// so that switching from one control strategy to the other online produces
+// no discontinuity in the output.  A selection fades the output linearly from
+// one controller to the other over Duration.  Both controllers are updated
+// every time; while one is fully inactive, it tracks the output if it is a
+// Tracker, so it is ready to take over from where the output is.
+//
+// The output is (1-w) A + w B, where the weight w moves towards 0 when A is
+// selected and towards 1 when B is.  A Crossfade starts on A.
+type Crossfade struct {
+	A, B Updater
+
+	// Duration is the time in seconds to fade from one controller to the
+	// other.  If zero, switches are immediate.
+	Duration float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	w      float64
+	target float64
+	out    float64
+}
+
+// NewCrossfade returns a Crossfade between a and b, fading over duration
+// seconds at an inter-update time of dt
+func NewCrossfade(a, b Updater, duration, dt float64) *Crossfade {
+	return &Crossfade{A: a, B: b, Duration: duration, DT: dt}
+}
+
+// Select begins fading to B if useB is true, or to A if false.  Selecting
+// mid-fade reverses it from where it has reached.
+func (c *Crossfade) Select(useB bool) {
+	c.target = 0
+	if useB {
+		c.target = 1
+	}
+}
+
+// SelectedB returns true if B is selected, whether or not the fade to it is
+// complete
+func (c *Crossfade) SelectedB() bool {
+	return c.target == 1
+}
+
+// Weight returns the weight of B in the output, 0 to 1
+func (c *Crossfade) Weight() float64 {
+	return c.w
+}
+
+// Fading returns true while the output is a blend of both controllers
+func (c *Crossfade) Fading() bool {
+	return c.w != c.target
+}
+
+// Update runs both controllers, advances the fade, and returns the blended
+// output
+func (c *Crossfade) Update(input float64) float64 {
+	a := c.A.Update(input)
+	b := c.B.Update(input)
+	if c.w != c.target {
+		step := 1.
+		if c.Duration > 0 {
+			step = c.DT / c.Duration
+		}
+		// land exactly on the target, free of accumulated rounding
+		if math.Abs(c.target-c.w) <= step*(1+1e-9) {
+			c.w = c.target
+		} else {
+			c.w = clamp(c.target, c.w-step, c.w+step)
+		}
+	}
+	c.out = (1-c.w)*a + c.w*b
+	switch c.w {
+	case 0:
+		if t, ok := c.B.(Tracker); ok {
+			t.Track(c.out)
+		}
+	case 1:
+		if t, ok := c.A.(Tracker); ok {
+			t.Track(c.out)
+		}
+	}
+	return c.out
+}
+
+// Output returns the last output
+func (c *Crossfade) Output() float64 {
+	return c.out
+}
@@ -0,0 +1,45 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+type constant float64
+
+func (c constant) Update(float64) float64 { return float64(c) }
+
+func TestCrossfadeBlendsOverDuration(t *testing.T) {
+	c := NewCrossfade(constant(0), constant(10), 1, 0.1)
+	c.Update(0)
+	c.Select(true)
+	prev := c.Output()
+	for i := 1; i <= 10; i++ {
+		out := c.Update(0)
+		if !approxEqualAbs(out-prev, 1, 1e-9) {
+			t.Errorf("step %d: expected the output to move by 1, moved %g", i, out-prev)
+		}
+		prev = out
+	}
+	if c.Fading() || c.Weight() != 1 {
+		t.Errorf("expected the fade complete after its duration, weight %g", c.Weight())
+	}
+}
+
+func TestCrossfadeInactiveTracks(t *testing.T) {
+	a := &PID{P: 1, I: 1, DT: 0.01}
+	b := &PID{P: 0.2, I: 5, DT: 0.01}
+	c := NewCrossfade(a, b, 0.5, 0.01)
+	var prev float64
+	for i := 0; i < 100; i++ {
+		prev = c.Update(-1)
+	}
+	c.Select(true)
+	for i := 0; i < 100; i++ {
+		out := c.Update(-1)
+		if math.Abs(out-prev) > 0.1 {
+			t.Fatalf("output jumped from %g to %g at step %d of the fade", prev, out, i)
+		}
+		prev = out
+	}
+}
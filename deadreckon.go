@@ -0,0 +1,58 @@
+package pctl
+
+// DeadReckon integrates a velocity signal to estimate position, for loops in
+// which only a rate sensor runs fast enough.  Integration drifts with any bias
+// on the velocity, which is limited in either or both of two ways:
+//
+// Leak makes the integrator leaky, decaying the position towards the last
+// anchor with a time constant of Leak seconds.  This bounds the drift, at the
+// cost of also forgetting true motion over times longer than Leak.
+//
+// Anchor re-anchors the position to an absolute reference, such as a slow
+// encoder or an index mark, whenever one is available.
+type DeadReckon struct {
+	// DT is the inter-update time in seconds
+	DT float64
+
+	// Leak is the time constant of the leak in seconds.  If zero, the
+	// integrator does not leak.
+	Leak float64
+
+	// AnchorGain is the fraction of the error to the reference removed by
+	// each Anchor, so values below 1 smooth noisy references.  If zero, 1 is
+	// used and Anchor snaps to the reference.
+	AnchorGain float64
+
+	pos, ref float64
+}
+
+// Update integrates a velocity and returns the estimated position
+func (d *DeadReckon) Update(input float64) float64 {
+	d.pos += input * d.DT
+	if d.Leak != 0 {
+		d.pos -= (d.pos - d.ref) * d.DT / d.Leak
+	}
+	return d.pos
+}
+
+// Anchor corrects the position towards an absolute reference, which also
+// becomes the point the leak decays towards
+func (d *DeadReckon) Anchor(ref float64) {
+	g := d.AnchorGain
+	if g == 0 {
+		g = 1
+	}
+	d.pos += g * (ref - d.pos)
+	d.ref = ref
+}
+
+// Position returns the estimated position
+func (d *DeadReckon) Position() float64 {
+	return d.pos
+}
+
+// Reset sets the position and the anchor to pos
+func (d *DeadReckon) Reset(pos float64) {
+	d.pos = pos
+	d.ref = pos
+}
@@ -0,0 +1,31 @@
+package pctl
+
+import "testing"
+
+func TestDeadReckonLeakBoundsDrift(t *testing.T) {
+	const bias = 0.01
+	plain := &DeadReckon{DT: 0.01}
+	leaky := &DeadReckon{DT: 0.01, Leak: 1}
+	for i := 0; i < 100000; i++ {
+		plain.Update(bias)
+		leaky.Update(bias)
+	}
+	if !approxEqualAbs(plain.Position(), 10, 1e-6) {
+		t.Errorf("expected unbounded drift of 10, got %f", plain.Position())
+	}
+	// the leak settles near bias*Leak
+	if !approxEqualAbs(leaky.Position(), bias, 2e-4) {
+		t.Errorf("expected leaky drift bounded at %f, got %f", bias, leaky.Position())
+	}
+}
+
+func TestDeadReckonAnchor(t *testing.T) {
+	d := &DeadReckon{DT: 0.1, AnchorGain: 0.5}
+	for i := 0; i < 10; i++ {
+		d.Update(1.1) // true velocity is 1
+	}
+	d.Anchor(1)
+	if !approxEqualAbs(d.Position(), 1.05, 1e-12) {
+		t.Errorf("expected half of the error removed, got %f", d.Position())
+	}
+}
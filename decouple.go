@@ -0,0 +1,123 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrSingular is returned when a matrix which must be inverted is singular
+var ErrSingular = errors.New("pctl: matrix is singular")
+
+// ErrNotSquare is returned when a matrix which must be square is not
+var ErrNotSquare = errors.New("pctl: matrix is not square")
+
+// Decoupler is a static decoupling matrix placed between the controllers and
+// actuators of a multivariable plant, so that each controller moves only its
+// own process variable.  Without it, interacting loops, such as temperature
+// and humidity in a chamber, fight one another.
+//
+// The actuator commands are u = M c for controller outputs c.
+type Decoupler struct {
+	// M is the decoupling matrix, with one row per actuator and one column
+	// per controller
+	M [][]float64
+
+	out []float64
+}
+
+// NewDecoupler returns a Decoupler applying the matrix m
+func NewDecoupler(m [][]float64) *Decoupler {
+	return &Decoupler{M: m, out: make([]float64, len(m))}
+}
+
+// NewStaticDecoupler returns a Decoupler for a plant whose steady state gain
+// is the square matrix g, with g[i][j] the gain from actuator j to process
+// variable i.  The decoupled plant is diagonal with the same diagonal as g,
+// M = inv(g) diag(g), so that controllers tuned on the individual loops need
+// no retuning.
+func NewStaticDecoupler(g [][]float64) (*Decoupler, error) {
+	inv, err := matInverse(g)
+	if err != nil {
+		return nil, err
+	}
+	for i := range inv {
+		for j := range inv[i] {
+			inv[i][j] *= g[j][j]
+		}
+	}
+	return NewDecoupler(inv), nil
+}
+
+// Update maps the controller outputs c to actuator commands.  The returned
+// slice is reused by the next call.
+func (d *Decoupler) Update(c []float64) []float64 {
+	for i, row := range d.M {
+		d.out[i] = vectorDot(row, c)
+	}
+	return d.out
+}
+
+// RGA returns the relative gain array of the square steady state gain matrix
+// g, the elementwise product of g and the transpose of its inverse.  Element
+// [i][j] is the ratio of the gain from actuator j to process variable i with
+// all other loops open to that with them closed.  Loops are best paired on
+// elements near 1; negative elements indicate pairings that become unstable
+// when the other loops close.
+func RGA(g [][]float64) ([][]float64, error) {
+	inv, err := matInverse(g)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]float64, len(g))
+	for i := range g {
+		out[i] = make([]float64, len(g))
+		for j := range g {
+			out[i][j] = g[i][j] * inv[j][i]
+		}
+	}
+	return out, nil
+}
+
+// matInverse returns the inverse of square a by Gauss-Jordan elimination with
+// partial pivoting, leaving a unmodified
+func matInverse(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	w := make([][]float64, n)
+	for i := range w {
+		if len(a[i]) != n {
+			return nil, ErrNotSquare
+		}
+		w[i] = make([]float64, n)
+		copy(w[i], a[i])
+	}
+	inv := identity(n)
+	for c := 0; c < n; c++ {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(w[r][c]) > math.Abs(w[p][c]) {
+				p = r
+			}
+		}
+		if w[p][c] == 0 {
+			return nil, ErrSingular
+		}
+		w[c], w[p] = w[p], w[c]
+		inv[c], inv[p] = inv[p], inv[c]
+		s := 1 / w[c][c]
+		for j := 0; j < n; j++ {
+			w[c][j] *= s
+			inv[c][j] *= s
+		}
+		for r := 0; r < n; r++ {
+			if r == c || w[r][c] == 0 {
+				continue
+			}
+			f := w[r][c]
+			for j := 0; j < n; j++ {
+				w[r][j] -= f * w[c][j]
+				inv[r][j] -= f * inv[c][j]
+			}
+		}
+	}
+	return inv, nil
+}
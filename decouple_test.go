@@ -0,0 +1,48 @@
+package pctl
+
+import "testing"
+
+func TestStaticDecouplerDiagonalizes(t *testing.T) {
+	g := [][]float64{{2, 0.8}, {-0.5, 1.5}}
+	d, err := NewStaticDecoupler(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// g M is diag(g)
+	for j := range g {
+		c := make([]float64, len(g))
+		c[j] = 1
+		u := d.Update(c)
+		for i := range g {
+			y := vectorDot(g[i], u)
+			want := 0.
+			if i == j {
+				want = g[i][i]
+			}
+			if !approxEqualAbs(y, want, 1e-12) {
+				t.Errorf("decoupled gain [%d][%d] is %f, expected %f", i, j, y, want)
+			}
+		}
+	}
+}
+
+func TestRGA(t *testing.T) {
+	// rows and columns of an RGA sum to one
+	rga, err := RGA([][]float64{{1, 2, 0}, {0.5, 3, 1}, {0, 1, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rga {
+		var row, col float64
+		for j := range rga {
+			row += rga[i][j]
+			col += rga[j][i]
+		}
+		if !approxEqualAbs(row, 1, 1e-12) || !approxEqualAbs(col, 1, 1e-12) {
+			t.Errorf("row and column %d of the RGA sum to %f and %f, expected 1", i, row, col)
+		}
+	}
+	if _, err := RGA([][]float64{{1, 2}, {2, 4}}); err != ErrSingular {
+		t.Errorf("expected ErrSingular for a singular gain matrix, got %v", err)
+	}
+}
@@ -0,0 +1,546 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// The IIR design routines in this file follow the classic approach of e.g.
+// scipy.signal: an analog prototype is designed in zero-pole-gain form,
+// transformed to the desired band, mapped to discrete time with the bilinear
+// transform (with prewarping), and split into second order sections.
+
+// Band is the pass band shape of a designed filter
+type Band int
+
+const (
+	// Lowpass passes frequencies below a corner
+	Lowpass Band = iota
+
+	// Highpass passes frequencies above a corner
+	Highpass
+
+	// Bandpass passes frequencies between two corners
+	Bandpass
+
+	// Bandstop rejects frequencies between two corners
+	Bandstop
+)
+
+// String returns the lowercase name of the band, e.g. "lowpass"
+func (b Band) String() string {
+	switch b {
+	case Lowpass:
+		return "lowpass"
+	case Highpass:
+		return "highpass"
+	case Bandpass:
+		return "bandpass"
+	case Bandstop:
+		return "bandstop"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrFilterOrder is returned by design functions for an order < 1
+	ErrFilterOrder = errors.New("pctl: filter order must be at least 1")
+
+	// ErrCorners is returned by design functions when the number of corner
+	// frequencies does not match the band (one for low/highpass, two for
+	// bandpass/stop), or they are not ascending
+	ErrCorners = errors.New("pctl: wrong number or order of corner frequencies for band")
+
+	// ErrNyquist is returned by design functions when a corner frequency is
+	// not between zero and half the sample rate
+	ErrNyquist = errors.New("pctl: corner frequency must be between zero and Nyquist")
+)
+
+// ZPK is a zero-pole-gain representation of a transfer function,
+//
+//	H = K * (s - Z[0])(s - Z[1])... / (s - P[0])(s - P[1])...
+//
+// in s for analog or z for digital systems.  Complex zeros and poles must
+// appear in conjugate pairs, so that the system is real.
+type ZPK struct {
+	Z []complex128
+	P []complex128
+	K float64
+}
+
+// SOS converts a digital ZPK to a cascade of second order sections.  If there
+// are more zeros than poles, poles at the origin are added so that the filter
+// is causal, delaying the output by the difference in samples.
+func (zpk ZPK) SOS() (*SOSFilter, error) {
+	if err := zpk.Validate(); err != nil {
+		return nil, err
+	}
+	// normalize the sections at DC, unless there is a zero there
+	w0 := 0.
+	for _, z := range zpk.Z {
+		if cmplx.Abs(z-1) < 1e-6 {
+			w0 = math.Pi
+		}
+	}
+	return zpk2sos(zpk, w0), nil
+}
+
+// Butterworth designs a digital Butterworth filter (maximally flat pass band)
+// of the given order.  fs is the sample rate and corners are the -3dB
+// frequencies in Hz; one for Lowpass and Highpass, two for Bandpass and
+// Bandstop.  Band filters have twice the order of their prototype.
+func Butterworth(order int, band Band, fs float64, corners ...float64) (*SOSFilter, error) {
+	if order < 1 {
+		return nil, ErrFilterOrder
+	}
+	p := make([]complex128, order)
+	for k := 0; k < order; k++ {
+		theta := math.Pi * float64(2*k+order+1) / float64(2*order)
+		p[k] = cmplx.Rect(1, theta)
+	}
+	return designIIR(ZPK{P: p, K: 1}, band, fs, corners)
+}
+
+// Chebyshev1 designs a digital Chebyshev type I filter of the given order,
+// with rippleDB of ripple in the pass band.  The corners are the frequencies at
+// which the response leaves the ripple band.  See Butterworth for the meaning
+// of the other arguments.
+func Chebyshev1(order int, rippleDB float64, band Band, fs float64, corners ...float64) (*SOSFilter, error) {
+	if order < 1 {
+		return nil, ErrFilterOrder
+	}
+	eps := math.Sqrt(math.Pow(10, rippleDB/10) - 1)
+	mu := math.Asinh(1/eps) / float64(order)
+	p := make([]complex128, order)
+	k := complex(1, 0)
+	for i := 0; i < order; i++ {
+		theta := math.Pi * float64(2*i+1) / float64(2*order)
+		p[i] = complex(-math.Sinh(mu)*math.Sin(theta), math.Cosh(mu)*math.Cos(theta))
+		k *= -p[i]
+	}
+	g := real(k)
+	if order%2 == 0 {
+		g /= math.Sqrt(1 + eps*eps)
+	}
+	return designIIR(ZPK{P: p, K: g}, band, fs, corners)
+}
+
+// designIIR converts an analog lowpass prototype with a corner of 1 rad/s
+// into a digital filter of the given band
+func designIIR(proto ZPK, band Band, fs float64, corners []float64) (*SOSFilter, error) {
+	if err := checkCorners(band, fs, corners); err != nil {
+		return nil, err
+	}
+	// prewarp
+	fs2 := 2 * fs
+	w := make([]float64, len(corners))
+	for i, f := range corners {
+		w[i] = fs2 * math.Tan(math.Pi*f/fs)
+	}
+	var analog ZPK
+	switch band {
+	case Lowpass:
+		analog = lp2lp(proto, w[0])
+	case Highpass:
+		analog = lp2hp(proto, w[0])
+	case Bandpass:
+		analog = lp2bp(proto, math.Sqrt(w[0]*w[1]), w[1]-w[0])
+	case Bandstop:
+		analog = lp2bs(proto, math.Sqrt(w[0]*w[1]), w[1]-w[0])
+	}
+	digital := bilinear(analog, fs2)
+	return zpk2sos(digital, refOmega(band, fs, corners)), nil
+}
+
+func checkCorners(band Band, fs float64, corners []float64) error {
+	want := 1
+	if band == Bandpass || band == Bandstop {
+		want = 2
+	}
+	if len(corners) != want || (want == 2 && corners[0] >= corners[1]) {
+		return ErrCorners
+	}
+	for _, f := range corners {
+		if f <= 0 || f >= fs/2 {
+			return ErrNyquist
+		}
+	}
+	return nil
+}
+
+// refOmega is a frequency (rad/sample) in the pass band of a filter, which
+// second order sections are normalized at
+func refOmega(band Band, fs float64, corners []float64) float64 {
+	switch band {
+	case Highpass:
+		return math.Pi
+	case Bandpass:
+		return 2 * math.Pi * math.Sqrt(corners[0]*corners[1]) / fs
+	}
+	return 0
+}
+
+func prodNeg(x []complex128) complex128 {
+	out := complex(1, 0)
+	for _, v := range x {
+		out *= -v
+	}
+	return out
+}
+
+func lp2lp(in ZPK, wo float64) ZPK {
+	out := ZPK{Z: make([]complex128, len(in.Z)), P: make([]complex128, len(in.P))}
+	for i, v := range in.Z {
+		out.Z[i] = v * complex(wo, 0)
+	}
+	for i, v := range in.P {
+		out.P[i] = v * complex(wo, 0)
+	}
+	out.K = in.K * math.Pow(wo, float64(len(in.P)-len(in.Z)))
+	return out
+}
+
+func lp2hp(in ZPK, wo float64) ZPK {
+	out := ZPK{}
+	for _, v := range in.Z {
+		out.Z = append(out.Z, complex(wo, 0)/v)
+	}
+	for _, v := range in.P {
+		out.P = append(out.P, complex(wo, 0)/v)
+	}
+	// zeros at infinity move to the origin
+	for i := len(in.Z); i < len(in.P); i++ {
+		out.Z = append(out.Z, 0)
+	}
+	out.K = in.K * real(prodNeg(in.Z)/prodNeg(in.P))
+	return out
+}
+
+func lp2bp(in ZPK, wo, bw float64) ZPK {
+	out := ZPK{}
+	half := complex(bw/2, 0)
+	wo2 := complex(wo*wo, 0)
+	split := func(v complex128) (complex128, complex128) {
+		a := v * half
+		r := cmplx.Sqrt(a*a - wo2)
+		return a + r, a - r
+	}
+	for _, v := range in.Z {
+		a, b := split(v)
+		out.Z = append(out.Z, a, b)
+	}
+	for _, v := range in.P {
+		a, b := split(v)
+		out.P = append(out.P, a, b)
+	}
+	for i := len(in.Z); i < len(in.P); i++ {
+		out.Z = append(out.Z, 0)
+	}
+	out.K = in.K * math.Pow(bw, float64(len(in.P)-len(in.Z)))
+	return out
+}
+
+func lp2bs(in ZPK, wo, bw float64) ZPK {
+	out := ZPK{}
+	half := complex(bw/2, 0)
+	wo2 := complex(wo*wo, 0)
+	split := func(v complex128) (complex128, complex128) {
+		a := half / v
+		r := cmplx.Sqrt(a*a - wo2)
+		return a + r, a - r
+	}
+	for _, v := range in.Z {
+		a, b := split(v)
+		out.Z = append(out.Z, a, b)
+	}
+	for _, v := range in.P {
+		a, b := split(v)
+		out.P = append(out.P, a, b)
+	}
+	for i := len(in.Z); i < len(in.P); i++ {
+		out.Z = append(out.Z, complex(0, wo), complex(0, -wo))
+	}
+	out.K = in.K * real(prodNeg(in.Z)/prodNeg(in.P))
+	return out
+}
+
+// bilinear maps an analog ZPK to discrete time; fs2 is twice the sample rate
+func bilinear(in ZPK, fs2 float64) ZPK {
+	out := ZPK{}
+	f := complex(fs2, 0)
+	num := complex(1, 0)
+	den := complex(1, 0)
+	for _, v := range in.Z {
+		out.Z = append(out.Z, (f+v)/(f-v))
+		num *= f - v
+	}
+	for _, v := range in.P {
+		out.P = append(out.P, (f+v)/(f-v))
+		den *= f - v
+	}
+	// zeros at infinity map to Nyquist
+	for i := len(in.Z); i < len(in.P); i++ {
+		out.Z = append(out.Z, -1)
+	}
+	out.K = in.K * real(num/den)
+	return out
+}
+
+// rootPair is a pair of roots of a polynomial.  If single, r[1] is padding
+// at the origin and the pair represents a first order factor.
+type rootPair struct {
+	r      [2]complex128
+	single bool
+}
+
+// pairRoots groups roots into conjugate pairs, then pairs of real roots,
+// padding an odd real root with a root at the origin.  Roots are considered
+// real if their imaginary part is within tol.
+func pairRoots(r []complex128) []rootPair {
+	const tol = 1e-10
+	var cplx, reals []complex128
+	for _, v := range r {
+		switch {
+		case math.Abs(imag(v)) <= tol*math.Max(1, cmplx.Abs(v)):
+			reals = append(reals, complex(real(v), 0))
+		case imag(v) > 0:
+			cplx = append(cplx, v)
+		}
+	}
+	out := make([]rootPair, 0, (len(r)+1)/2)
+	for _, v := range cplx {
+		out = append(out, rootPair{r: [2]complex128{v, cmplx.Conj(v)}})
+	}
+	sort.Slice(reals, func(i, j int) bool { return real(reals[i]) < real(reals[j]) })
+	for i := 0; i < len(reals); i += 2 {
+		if i+1 < len(reals) {
+			out = append(out, rootPair{r: [2]complex128{reals[i], reals[i+1]}})
+		} else {
+			out = append(out, rootPair{r: [2]complex128{reals[i], 0}, single: true})
+		}
+	}
+	return out
+}
+
+// zpk2sos converts a digital ZPK to second order sections, each normalized to
+// unit gain at w0 (rad/sample) with the residual gain in the first section
+func zpk2sos(in ZPK, w0 float64) *SOSFilter {
+	poles := pairRoots(in.P)
+	zeros := pairRoots(in.Z)
+	for len(zeros) < len(poles) {
+		zeros = append(zeros, rootPair{single: true})
+	}
+	for len(poles) < len(zeros) {
+		poles = append(poles, rootPair{single: true})
+	}
+	// poles closest to the unit circle are paired first, with the nearest
+	// zeros.  First order factors are kept together so their padding cancels.
+	sort.Slice(poles, func(i, j int) bool {
+		return cmplx.Abs(poles[i].r[0]) > cmplx.Abs(poles[j].r[0])
+	})
+	used := make([]bool, len(zeros))
+	zm1 := cmplx.Rect(1, -w0)
+	zm2 := zm1 * zm1
+	sections := make([]*Biquad, len(poles))
+	residual := in.K
+	for i, pp := range poles {
+		best, bestDist := -1, math.Inf(1)
+		for j, zz := range zeros {
+			if used[j] {
+				continue
+			}
+			d := math.Min(cmplx.Abs(zz.r[0]-pp.r[0]), cmplx.Abs(zz.r[1]-pp.r[0]))
+			if zz.single != pp.single {
+				d += 1e6
+			}
+			if d < bestDist {
+				best, bestDist = j, d
+			}
+		}
+		used[best] = true
+		zz := zeros[best].r
+		p := pp.r
+		a1 := -real(zz[0] + zz[1])
+		a2 := real(zz[0] * zz[1])
+		b1 := -real(p[0] + p[1])
+		b2 := real(p[0] * p[1])
+		h := (1 + complex(a1, 0)*zm1 + complex(a2, 0)*zm2) / (1 + complex(b1, 0)*zm1 + complex(b2, 0)*zm2)
+		g := 1.
+		if m := cmplx.Abs(h); m > 1e-300 {
+			g = 1 / m
+		}
+		residual /= g
+		sections[i] = NewBiquad(g, g*a1, g*a2, b1, b2)
+	}
+	if len(sections) == 0 {
+		// no roots: the filter is its gain alone
+		return NewSOSFilter(NewBiquad(residual, 0, 0, 0, 0))
+	}
+	s := sections[0]
+	s.a0 *= residual
+	s.a1 *= residual
+	s.a2 *= residual
+	return NewSOSFilter(sections...)
+}
+
+// Window is a window function for FIR design, returning the weight of the
+// n-th of length taps
+type Window func(n, length int) float64
+
+// Rectangular is the rectangular (boxcar) window
+func Rectangular(n, length int) float64 {
+	return 1
+}
+
+// Hann is the Hann window
+func Hann(n, length int) float64 {
+	if length == 1 {
+		return 1
+	}
+	return 0.5 - 0.5*math.Cos(2*math.Pi*float64(n)/float64(length-1))
+}
+
+// Hamming is the Hamming window
+func Hamming(n, length int) float64 {
+	if length == 1 {
+		return 1
+	}
+	return 0.54 - 0.46*math.Cos(2*math.Pi*float64(n)/float64(length-1))
+}
+
+// Blackman is the Blackman window
+func Blackman(n, length int) float64 {
+	if length == 1 {
+		return 1
+	}
+	x := 2 * math.Pi * float64(n) / float64(length-1)
+	return 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+}
+
+// ErrFIRTaps is returned by DesignFIR when the number of taps is invalid for
+// the band.  Highpass and Bandstop filters require an odd number of taps.
+var ErrFIRTaps = errors.New("pctl: invalid number of FIR taps for band")
+
+// DesignFIR designs a linear phase FIR filter with the window method.
+// fs is the sample rate and corners are the -6dB frequencies in Hz, as for
+// Butterworth.  If w is nil, the Hamming window is used.  The returned taps may
+// be passed to NewFIRFilter.
+func DesignFIR(taps int, band Band, fs float64, w Window, corners ...float64) ([]float64, error) {
+	if taps < 1 || ((band == Highpass || band == Bandstop) && taps%2 == 0) {
+		return nil, ErrFIRTaps
+	}
+	if err := checkCorners(band, fs, corners); err != nil {
+		return nil, err
+	}
+	if w == nil {
+		w = Hamming
+	}
+	// ideal responses are built from lowpass sincs; fc normalized to Nyquist
+	lp := func(f float64, n float64) float64 {
+		fc := 2 * f / fs
+		if n == 0 {
+			return fc
+		}
+		return math.Sin(math.Pi*fc*n) / (math.Pi * n)
+	}
+	h := make([]float64, taps)
+	mid := float64(taps-1) / 2
+	for i := range h {
+		n := float64(i) - mid
+		var ideal float64
+		var delta float64
+		if n == 0 {
+			delta = 1
+		}
+		switch band {
+		case Lowpass:
+			ideal = lp(corners[0], n)
+		case Highpass:
+			ideal = delta - lp(corners[0], n)
+		case Bandpass:
+			ideal = lp(corners[1], n) - lp(corners[0], n)
+		case Bandstop:
+			ideal = delta - lp(corners[1], n) + lp(corners[0], n)
+		}
+		h[i] = ideal * w(i, taps)
+	}
+	// normalize to unit gain in the pass band
+	var w0 float64
+	switch band {
+	case Highpass:
+		w0 = math.Pi
+	case Bandpass:
+		w0 = math.Pi * (corners[0] + corners[1]) / fs
+	}
+	var g complex128
+	for i, v := range h {
+		g += complex(v, 0) * cmplx.Rect(1, -w0*float64(i))
+	}
+	if m := cmplx.Abs(g); m > 0 {
+		for i := range h {
+			h[i] /= m
+		}
+	}
+	return h, nil
+}
+
+// Kaiser returns the Kaiser window with shape parameter beta; see KaiserOrder
+func Kaiser(beta float64) Window {
+	return func(n, length int) float64 {
+		if length == 1 {
+			return 1
+		}
+		r := 2*float64(n)/float64(length-1) - 1
+		return besselI0(beta*math.Sqrt(1-r*r)) / besselI0(beta)
+	}
+}
+
+// besselI0 is the modified Bessel function of the first kind of order zero,
+// by its power series
+func besselI0(x float64) float64 {
+	sum, term := 1., 1.
+	q := x * x / 4
+	for k := 1; k < 500 && term > 1e-17*sum; k++ {
+		term *= q / float64(k*k)
+		sum += term
+	}
+	return sum
+}
+
+// KaiserOrder estimates the number of taps and the Kaiser window beta a window
+// method FIR needs to meet a specification, by Kaiser's formulas.  ripple is
+// the largest deviation from unity gain in the pass band and from zero gain in
+// the stop band (e.g. 0.001 for 60 dB attenuation), and width is the width in
+// Hz of the transition band, centered on the corner.
+func KaiserOrder(ripple, width, fs float64) (taps int, beta float64) {
+	a := -20 * math.Log10(ripple)
+	switch {
+	case a > 50:
+		beta = 0.1102 * (a - 8.7)
+	case a >= 21:
+		beta = 0.5842*math.Pow(a-21, 0.4) + 0.07886*(a-21)
+	}
+	dw := 2 * math.Pi * width / fs
+	taps = int(math.Ceil((a-7.95)/(2.285*dw))) + 1
+	if taps < 1 {
+		taps = 1
+	}
+	return taps, beta
+}
+
+// DesignFIRKaiser designs a linear phase FIR filter meeting the ripple and
+// transition width of KaiserOrder, with the Kaiser window.  The number of taps
+// is rounded up to an odd number where the band requires it.  See DesignFIR
+// for the meaning of the other arguments.
+func DesignFIRKaiser(band Band, fs, ripple, width float64, corners ...float64) ([]float64, error) {
+	if !(ripple > 0 && ripple < 1) || !(width > 0) {
+		return nil, ErrFIRTaps
+	}
+	taps, beta := KaiserOrder(ripple, width, fs)
+	if (band == Highpass || band == Bandstop) && taps%2 == 0 {
+		taps++
+	}
+	return DesignFIR(taps, band, fs, Kaiser(beta), corners...)
+}
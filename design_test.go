@@ -0,0 +1,157 @@
+package pctl
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestButterworthMatchesBiquadDesign(t *testing.T) {
+	// a second order butterworth is a biquad with Q = 1/sqrt(2)
+	sos, err := Butterworth(2, Lowpass, 44100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bq := NewBiquadLowpass(44100, 100, 1/math.Sqrt2, 0)
+	for _, f := range []float64{10, 100, 1000, 10000} {
+		got := sos.Response(f, 44100)
+		expect := bq.Response(f, 44100)
+		if cmplx.Abs(got-expect) > 1e-9 {
+			t.Errorf("at %f Hz, butterworth %v != biquad %v", f, got, expect)
+		}
+	}
+}
+
+func TestButterworthCornersAreHalfPower(t *testing.T) {
+	const fs = 1000
+	cases := []struct {
+		band    Band
+		order   int
+		corners []float64
+		pass    float64
+	}{
+		{Lowpass, 5, []float64{50}, 0},
+		{Highpass, 4, []float64{50}, 500},
+		{Bandpass, 3, []float64{40, 90}, 60},
+		{Bandstop, 3, []float64{40, 90}, 0},
+	}
+	for _, c := range cases {
+		sos, err := Butterworth(c.order, c.band, fs, c.corners...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := cmplx.Abs(sos.Response(c.pass, fs)); !approxEqualAbs(g, 1, 1e-6) {
+			t.Errorf("%v: pass band gain %f, expected 1", c.band, g)
+		}
+		for _, f := range c.corners {
+			if g := MagDB(sos.Response(f, fs)); !approxEqualAbs(g, -3.0103, 1e-3) {
+				t.Errorf("%v: gain at corner %f is %f dB, expected -3dB", c.band, f, g)
+			}
+		}
+	}
+}
+
+func TestChebyshev1Ripple(t *testing.T) {
+	sos, err := Chebyshev1(4, 1, Lowpass, 1000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// even order chebyshev starts at the bottom of the ripple band
+	if g := MagDB(sos.Response(0, 1000)); !approxEqualAbs(g, -1, 1e-6) {
+		t.Errorf("DC gain %f dB, expected -1dB", g)
+	}
+	if g := MagDB(sos.Response(100, 1000)); !approxEqualAbs(g, -1, 1e-6) {
+		t.Errorf("corner gain %f dB, expected -1dB", g)
+	}
+	if g := MagDB(sos.Response(300, 1000)); g > -40 {
+		t.Errorf("stop band gain %f dB is too high", g)
+	}
+}
+
+func TestDesignRejectsBadCorners(t *testing.T) {
+	if _, err := Butterworth(2, Lowpass, 1000, 600); err != ErrNyquist {
+		t.Errorf("expected ErrNyquist, got %v", err)
+	}
+	if _, err := Butterworth(2, Bandpass, 1000, 100); err != ErrCorners {
+		t.Errorf("expected ErrCorners, got %v", err)
+	}
+	if _, err := DesignFIR(10, Highpass, 1000, nil, 100); err != ErrFIRTaps {
+		t.Errorf("expected ErrFIRTaps, got %v", err)
+	}
+}
+
+func TestDesignFIRLowpass(t *testing.T) {
+	taps, err := DesignFIR(63, Lowpass, 1000, Hamming, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFIRFilter(taps)
+	if g := cmplx.Abs(f.Response(0, 1000)); !approxEqualAbs(g, 1, 1e-12) {
+		t.Errorf("DC gain %f, expected 1", g)
+	}
+	if g := MagDB(f.Response(100, 1000)); !approxEqualAbs(g, -6.02, 0.5) {
+		t.Errorf("corner gain %f dB, expected about -6dB", g)
+	}
+	if g := MagDB(f.Response(250, 1000)); g > -40 {
+		t.Errorf("stop band gain %f dB is too high", g)
+	}
+}
+
+func TestZPKSOSMatchesPolynomial(t *testing.T) {
+	const fs = 1000
+	w0 := 2 * math.Pi * 60 / fs
+	zpk := ZPK{
+		Z: []complex128{cmplx.Rect(1, w0), cmplx.Rect(1, -w0), -0.5},
+		P: []complex128{cmplx.Rect(0.95, w0), cmplx.Rect(0.95, -w0), 0.3},
+		K: 2,
+	}
+	sos, err := zpk.SOS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []float64{0, 10, 60, 200, 499} {
+		z := cmplx.Rect(1, 2*math.Pi*f/fs)
+		expect := complex(zpk.K, 0)
+		for i := range zpk.Z {
+			expect *= (z - zpk.Z[i]) / (z - zpk.P[i])
+		}
+		if got := sos.Response(f, fs); cmplx.Abs(got-expect) > 1e-9 {
+			t.Errorf("at %f Hz, sos %v != zpk %v", f, got, expect)
+		}
+	}
+	if _, err := (ZPK{P: []complex128{0.5 + 0.5i}, K: 1}).SOS(); err == nil {
+		t.Error("expected an unpaired complex pole to be rejected")
+	}
+}
+
+func TestZPKSOSGainOnly(t *testing.T) {
+	sos, err := ZPK{K: 2}.SOS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := sos.Update(3); out != 6 {
+		t.Errorf("expected a gain of 2 with no roots, got %f for 3", out)
+	}
+}
+
+func TestDesignFIRKaiserMeetsSpec(t *testing.T) {
+	const fs, fc, width, ripple = 1000, 100, 20, 1e-3
+	taps, beta := KaiserOrder(ripple, width, fs)
+	if taps != 183 || !approxEqualAbs(beta, 5.653, 1e-3) {
+		t.Errorf("expected 183 taps and beta 5.653, got %d and %f", taps, beta)
+	}
+	h, err := DesignFIRKaiser(Lowpass, fs, ripple, width, fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fir := NewFIRFilter(h)
+	for f := 0.; f <= fs/2; f += 1 {
+		g := cmplx.Abs(fir.Response(f, fs))
+		switch {
+		case f <= fc-width/2 && math.Abs(g-1) > 2*ripple:
+			t.Errorf("pass band gain %f at %f Hz", g, f)
+		case f >= fc+width/2 && g > 2*ripple:
+			t.Errorf("stop band gain %f at %f Hz", g, f)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package pctl
+
+import (
+	"errors"
+	"math/cmplx"
+)
+
+var (
+	// ErrPlantDelay is returned by NewDOB when the nominal plant has no delay,
+	// Num[0] != 0.  Plants discretized with a zero order hold have at least one
+	// sample.
+	ErrPlantDelay = errors.New("pctl: DOB plant model must have at least one sample of delay")
+
+	// ErrNonMinimumPhase is returned by NewDOB when the nominal plant has a
+	// zero on or outside the unit circle, so that its inverse is unstable
+	ErrNonMinimumPhase = errors.New("pctl: DOB plant model is not minimum phase and cannot be inverted")
+)
+
+// DOB is a disturbance observer.  It compares the measured output of the
+// plant against what its nominal model predicts from the applied control,
+// attributes the difference to a disturbance at the plant input, and
+// subtracts its estimate from the output of the controller.  The loop then
+// behaves as the nominal plant within the bandwidth of the Q filter,
+// rejecting load disturbances and model error, and making motion loops much
+// stiffer without retuning the controller.
+//
+// With the nominal plant Pn = z^-d N(z^-1) / D(z^-1), the estimate is
+//
+//	dhat = Q D/N y - Q z^-d u
+//
+// Q is a low pass of unity DC gain; it sets the bandwidth of the observer,
+// which is limited by the model error and sensor noise at high frequency.
+type DOB struct {
+	// Controller is the feedback controller, whose output is compensated
+	Controller Updater
+
+	// Limit is the magnitude limit of the disturbance estimate which is
+	// cancelled.  If zero, it is unlimited.
+	Limit float64
+
+	inv  *TFFilter // Q D/N, on the measurement
+	q    *TFFilter // Q z^-(d-1), on the previous output
+	dhat float64
+	u    float64
+}
+
+// NewDOB returns a new disturbance observer around ctrl, for a nominal plant
+// model and Q filter, both discrete TFs at the loop's sample rate
+func NewDOB(ctrl Updater, plant, q TF) (*DOB, error) {
+	plant, err := plant.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	q, err = q.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	num, d := trimLeading(plant.Num)
+	if len(num) == 0 || d == 0 {
+		return nil, ErrPlantDelay
+	}
+	// num in ascending powers of z^-1 is N in descending powers of z
+	if len(num) > 1 {
+		for _, z := range roots(num) {
+			if cmplx.Abs(z) >= 1 {
+				return nil, ErrNonMinimumPhase
+			}
+		}
+	}
+	inv, err := NewTFFilter(TF{Num: polyMul(q.Num, plant.Den), Den: polyMul(q.Den, num)})
+	if err != nil {
+		return nil, err
+	}
+	// the previous output is fed in, which accounts for one sample of delay
+	qd, err := NewTFFilter(TF{Num: append(make([]float64, d-1), q.Num...), Den: q.Den})
+	if err != nil {
+		return nil, err
+	}
+	return &DOB{Controller: ctrl, inv: inv, q: qd}, nil
+}
+
+// polyMul returns the product of two polynomials
+func polyMul(a, b []float64) []float64 {
+	out := make([]float64, len(a)+len(b)-1)
+	for i, x := range a {
+		for j, y := range b {
+			out[i+j] += x * y
+		}
+	}
+	return out
+}
+
+// Update runs the controller on input, which is passed to it as is, and
+// returns its output less the disturbance estimate.  meas is the measured
+// plant output the estimate is formed from; usually the controller's input
+// is formed from it too.
+func (o *DOB) Update(input, meas float64) float64 {
+	o.dhat = o.inv.Update(meas) - o.q.Update(o.u)
+	if l := o.Limit; l != 0 {
+		o.dhat = clamp(o.dhat, -l, l)
+	}
+	o.u = o.Controller.Update(input) - o.dhat
+	return o.u
+}
+
+// Estimate returns the disturbance estimate of the last update
+func (o *DOB) Estimate() float64 {
+	return o.dhat
+}
+
+// Output returns the output of the last update
+func (o *DOB) Output() float64 {
+	return o.u
+}
+
+// Reset zeros the observer's state
+func (o *DOB) Reset() {
+	o.inv.Reset()
+	o.q.Reset()
+	o.dhat, o.u = 0, 0
+}
@@ -0,0 +1,51 @@
+package pctl
+
+import "testing"
+
+func TestDOBRejectsLoadDisturbance(t *testing.T) {
+	// a first order lag plant, y[k] = a y[k-1] + b u[k-1], under a proportional
+	// controller, whose steady error the load changes unless the DOB cancels it
+	const a, b = 0.95, 0.05
+	plant := TF{Num: []float64{0, b}, Den: []float64{1, -a}}
+	q := TF{Num: []float64{0.2}, Den: []float64{1, -0.8}}
+	run := func(withDOB bool, load float64) float64 {
+		sp := Setpoint(1)
+		ctrl := &PID{P: 2, DT: 1e-3}
+		o, err := NewDOB(ctrl, plant, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var y, u float64
+		for k := 0; k < 2000; k++ {
+			y = a*y + b*(u+load)
+			e := sp.Update(y)
+			if withDOB {
+				u = o.Update(e, y)
+			} else {
+				u = ctrl.Update(e)
+			}
+		}
+		if withDOB && !approxEqualAbs(o.Estimate(), load, 1e-6) {
+			t.Errorf("expected the disturbance estimated as %f, got %f", load, o.Estimate())
+		}
+		return 1 - y
+	}
+	nominal := run(false, 0)
+	if e := run(false, 0.5); approxEqualAbs(e, nominal, 0.1) {
+		t.Fatalf("expected the load to change the steady error, got %f and %f", e, nominal)
+	}
+	if e := run(true, 0.5); !approxEqualAbs(e, nominal, 1e-6) {
+		t.Errorf("expected the nominal steady error %f with the DOB, got %f", nominal, e)
+	}
+}
+
+func TestDOBRejectsBadModels(t *testing.T) {
+	q := TF{Num: []float64{0.2}, Den: []float64{1, -0.8}}
+	if _, err := NewDOB(nil, TF{Num: []float64{1}, Den: []float64{1, -0.5}}, q); err != ErrPlantDelay {
+		t.Errorf("expected ErrPlantDelay, got %v", err)
+	}
+	// zero at z = 2
+	if _, err := NewDOB(nil, TF{Num: []float64{0, 1, -2}, Den: []float64{1, -0.5}}, q); err != ErrNonMinimumPhase {
+		t.Errorf("expected ErrNonMinimumPhase, got %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package pctl
+
+import "math"
+
+// quadStep is the change in count for each transition of the quadrature state,
+// indexed by previous<<2 | current, where the state is A<<1 | B.  Forward is
+// A leading B: 00, 10, 11, 01.  Transitions of both channels at once are
+// illegal and counted as errors, marked 2.
+var quadStep = [16]int8{
+	0, -1, 1, 2,
+	1, 0, 2, -1,
+	-1, 2, 0, 1,
+	2, 1, -1, 0,
+}
+
+// QuadDecoder decodes the channels of a quadrature encoder into a count, at
+// four counts per line.  It must be updated at least once per edge; a
+// transition of both channels at once means edges were missed, and is counted
+// as an error without changing the count.
+type QuadDecoder struct {
+	count   int64
+	errors  int
+	state   uint8
+	started bool
+}
+
+// Update decodes the levels of channels A and B and returns the count
+func (q *QuadDecoder) Update(a, b bool) int64 {
+	var s uint8
+	if a {
+		s = 2
+	}
+	if b {
+		s |= 1
+	}
+	if !q.started {
+		q.started = true
+		q.state = s
+		return q.count
+	}
+	switch d := quadStep[q.state<<2|s]; d {
+	case 2:
+		q.errors++
+	default:
+		q.count += int64(d)
+	}
+	q.state = s
+	return q.count
+}
+
+// Count returns the count
+func (q *QuadDecoder) Count() int64 {
+	return q.count
+}
+
+// SetCount sets the count, e.g. to zero at an index mark
+func (q *QuadDecoder) SetCount(n int64) {
+	q.count = n
+}
+
+// Errors returns the number of illegal transitions seen
+func (q *QuadDecoder) Errors() int {
+	return q.errors
+}
+
+// VelocityMethod selects how a VelocityEstimator differentiates position
+type VelocityMethod int
+
+const (
+	// VelocityDifference is the first difference of position, low pass
+	// filtered.  It is simple, but trades quantization noise for lag through
+	// its cutoff.
+	VelocityDifference VelocityMethod = iota
+
+	// VelocityFixedTime is the change in position over a fixed window of
+	// samples, which divides the quantization noise by the window length and
+	// lags by half of it.
+	VelocityFixedTime
+
+	// VelocityObserver is a second order Luenberger observer of position and
+	// velocity, which tracks position as a PLL tracks phase.  It follows ramps
+	// in position without lag and smooths quantization above its bandwidth.
+	VelocityObserver
+
+	// VelocityAlphaBeta is an alpha-beta tracker, the fixed gain, discrete
+	// form of the observer.  Alpha and beta trade noise for response directly
+	// and may be tuned by hand.
+	VelocityAlphaBeta
+
+	// VelocityKalman is a constant velocity Kalman filter, whose gains are
+	// set by the process and measurement noise rather than a bandwidth.
+	VelocityKalman
+)
+
+// VelocityEstimator estimates the velocity of a position signal, such as the
+// count of a QuadDecoder, which is quantized.  Its Update takes a position and
+// returns the velocity, in position units per second.
+//
+// All methods share the one type, so estimators may be swapped by changing
+// only the constructor, and compared side by side on the same signal.
+type VelocityEstimator struct {
+	// Method is the estimation method
+	Method VelocityMethod
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	lpf  *LPF
+	hist []float64
+	head int
+
+	// observer and alpha-beta gains
+	kp, ki float64
+
+	kalman *Kalman
+
+	pos, vel float64
+	started  bool
+}
+
+// NewDifferenceVelocity returns a VelocityDifference estimator, filtered with
+// a first order low pass at cutoff Hz
+func NewDifferenceVelocity(cutoff, dt float64) *VelocityEstimator {
+	return &VelocityEstimator{Method: VelocityDifference, DT: dt, lpf: NewLPF(cutoff, dt)}
+}
+
+// NewFixedTimeVelocity returns a VelocityFixedTime estimator over a window of
+// n samples
+func NewFixedTimeVelocity(n int, dt float64) *VelocityEstimator {
+	return &VelocityEstimator{Method: VelocityFixedTime, DT: dt, hist: make([]float64, n+1)}
+}
+
+// NewObserverVelocity returns a VelocityObserver estimator, critically damped
+// with a bandwidth of bw Hz
+func NewObserverVelocity(bw, dt float64) *VelocityEstimator {
+	wn := 2 * math.Pi * bw
+	return &VelocityEstimator{Method: VelocityObserver, DT: dt, kp: 2 * wn, ki: wn * wn}
+}
+
+// NewAlphaBetaVelocity returns a VelocityAlphaBeta estimator with position
+// gain alpha and velocity gain beta, both dimensionless.  For stability,
+// 0 < alpha < 1 and 0 < beta < 4-2*alpha; beta = alpha^2/(2-alpha) gives a
+// well damped response.
+func NewAlphaBetaVelocity(alpha, beta, dt float64) *VelocityEstimator {
+	return &VelocityEstimator{Method: VelocityAlphaBeta, DT: dt, kp: alpha, ki: beta}
+}
+
+// NewKalmanVelocity returns a VelocityKalman estimator with process noise
+// (acceleration) spectral density q and measurement noise variance r.  For a
+// position quantized to counts, r is about 1/12 count^2.
+func NewKalmanVelocity(q, r, dt float64) *VelocityEstimator {
+	return &VelocityEstimator{Method: VelocityKalman, DT: dt, kalman: NewKalman(q, r, dt)}
+}
+
+// Update takes a position and returns the estimated velocity
+func (v *VelocityEstimator) Update(pos float64) float64 {
+	if v.Method == VelocityKalman {
+		v.kalman.UpdateDT(pos, v.DT)
+		v.started = true
+		v.pos, v.vel = v.kalman.Value(), v.kalman.Rate()
+		return v.vel
+	}
+	if !v.started {
+		v.started = true
+		v.pos = pos
+		for i := range v.hist {
+			v.hist[i] = pos
+		}
+		return 0
+	}
+	switch v.Method {
+	case VelocityDifference:
+		v.vel = v.lpf.Update((pos - v.pos) / v.DT)
+		v.pos = pos
+	case VelocityFixedTime:
+		n := len(v.hist)
+		v.hist[v.head] = pos
+		v.head = (v.head + 1) % n
+		v.vel = (pos - v.hist[v.head]) / (float64(n-1) * v.DT)
+		v.pos = pos
+	case VelocityObserver:
+		e := pos - v.pos
+		v.vel += v.ki * e * v.DT
+		v.pos += (v.vel + v.kp*e) * v.DT
+	case VelocityAlphaBeta:
+		pred := v.pos + v.vel*v.DT
+		r := pos - pred
+		v.pos = pred + v.kp*r
+		v.vel += v.ki * r / v.DT
+	}
+	return v.vel
+}
+
+// Velocity returns the last velocity estimate
+func (v *VelocityEstimator) Velocity() float64 {
+	return v.vel
+}
+
+// Position returns the position estimate, which is the last input for
+// VelocityDifference and VelocityFixedTime, and smoothed for the others
+func (v *VelocityEstimator) Position() float64 {
+	return v.pos
+}
+
+// Reset clears the estimate; the next update restarts from its position
+func (v *VelocityEstimator) Reset() {
+	v.started = false
+	v.vel = 0
+	v.head = 0
+	if v.lpf != nil {
+		v.lpf.prev = 0
+	}
+	if v.kalman != nil {
+		v.kalman.Reset()
+	}
+}
@@ -0,0 +1,102 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+// quadrature returns the levels of A and B at count n, A leading B
+func quadrature(n int) (a, b bool) {
+	switch ((n % 4) + 4) % 4 {
+	case 0:
+		return false, false
+	case 1:
+		return true, false
+	case 2:
+		return true, true
+	}
+	return false, true
+}
+
+func TestQuadDecoderCounts(t *testing.T) {
+	var q QuadDecoder
+	for n := 0; n <= 10; n++ {
+		q.Update(quadrature(n))
+	}
+	if q.Count() != 10 {
+		t.Errorf("expected 10 counts forward, got %d", q.Count())
+	}
+	for n := 9; n >= -3; n-- {
+		q.Update(quadrature(n))
+	}
+	if q.Count() != -3 || q.Errors() != 0 {
+		t.Errorf("expected -3 counts with no errors after reversing, got %d and %d", q.Count(), q.Errors())
+	}
+	// skipping a state flips both channels
+	q.Update(quadrature(-1))
+	if q.Count() != -3 || q.Errors() != 1 {
+		t.Errorf("expected a missed edge counted as an error, got %d and %d", q.Count(), q.Errors())
+	}
+}
+
+func TestVelocityEstimatorsTrackRamp(t *testing.T) {
+	const dt = 1e-3
+	const vel = 1234.5 // counts per second
+	ests := map[string]*VelocityEstimator{
+		"difference": NewDifferenceVelocity(20, dt),
+		"fixed time": NewFixedTimeVelocity(50, dt),
+		"observer":   NewObserverVelocity(20, dt),
+		"alpha-beta": NewAlphaBetaVelocity(0.1, 0.1*0.1/1.9, dt),
+		"kalman":     NewKalmanVelocity(1e3, 1./12, dt),
+	}
+	for name, v := range ests {
+		var sum float64
+		for i := 0; i < 2000; i++ {
+			pos := math.Floor(vel * float64(i) * dt) // quantized to counts
+			got := v.Update(pos)
+			if i >= 1000 {
+				sum += got
+			}
+		}
+		if mean := sum / 1000; math.Abs(mean-vel)/vel > 1e-3 {
+			t.Errorf("%s: expected a mean velocity of %g, got %g", name, vel, mean)
+		}
+	}
+}
+
+func TestFixedTimeVelocitySmoothsQuantization(t *testing.T) {
+	const dt = 1e-3
+	// 0.3 counts per sample: the raw difference is 0 or 1 count per sample
+	v := NewFixedTimeVelocity(100, dt)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := 0; i < 500; i++ {
+		got := v.Update(math.Floor(0.3 * float64(i)))
+		if i > 200 {
+			lo, hi = math.Min(lo, got), math.Max(hi, got)
+		}
+	}
+	// a window of 100 samples quantizes velocity to 10 counts per second
+	if hi-lo > 10+1e-9 || math.Abs((hi+lo)/2-300) > 10 {
+		t.Errorf("expected about 300 counts per second, within 10, got %g to %g", lo, hi)
+	}
+}
+
+func TestVelocityEstimatorsSwapOnOneSignal(t *testing.T) {
+	const dt = 1e-3
+	// every method is an Updater, and settles to zero on a constant position
+	for _, u := range []Updater{
+		NewDifferenceVelocity(20, dt),
+		NewFixedTimeVelocity(10, dt),
+		NewObserverVelocity(20, dt),
+		NewAlphaBetaVelocity(0.2, 0.02, dt),
+		NewKalmanVelocity(1e3, 1./12, dt),
+	} {
+		var got float64
+		for i := 0; i < 2000; i++ {
+			got = u.Update(42)
+		}
+		if math.Abs(got) > 1e-6 {
+			t.Errorf("%v: expected zero velocity on a constant position, got %g", u.(*VelocityEstimator).Method, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package pctl
+
+import "time"
+
+// EventPID is a PID driven by timestamped samples rather than a fixed tick,
+// for loops that run on the arrival of asynchronous messages.  The time step
+// of each update is computed from the timestamps, and clamped to guard against
+// outliers such as bursts of queued messages or a sensor that went quiet.
+//
+// The embedded PID supplies the gains and limits; its DT is used for the first
+// sample, which has no predecessor.
+type EventPID struct {
+	PID
+
+	// MinDT and MaxDT bound the time step computed from the timestamps, in
+	// seconds.  Either is ignored if zero.
+	MinDT, MaxDT float64
+
+	last     time.Time
+	started  bool
+	outliers int
+}
+
+// UpdateAt runs the loop once for a measurement taken at time t and returns
+// the new output value.  A sample which is not newer than the previous one is
+// ignored, returning the previous output, unless MinDT is set.
+func (e *EventPID) UpdateAt(input float64, t time.Time) float64 {
+	dt := e.DT
+	if e.started {
+		dt = t.Sub(e.last).Seconds()
+		if min := e.MinDT; min != 0 && dt < min {
+			dt = min
+			e.outliers++
+		} else if max := e.MaxDT; max != 0 && dt > max {
+			dt = max
+			e.outliers++
+		}
+		if dt <= 0 {
+			return e.prevOut
+		}
+	}
+	if !e.started || t.After(e.last) {
+		e.last = t
+	}
+	e.started = true
+	return e.UpdateDT(input, dt)
+}
+
+// Outliers returns the number of samples whose time step was clamped to
+// MinDT or MaxDT
+func (e *EventPID) Outliers() int {
+	return e.outliers
+}
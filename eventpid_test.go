@@ -0,0 +1,36 @@
+package pctl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventPIDUsesTimestamps(t *testing.T) {
+	e := &EventPID{PID: PID{I: 1, DT: 0.1}}
+	t0 := time.Unix(0, 0)
+	e.UpdateAt(-1, t0)                           // first sample, DT
+	e.UpdateAt(-1, t0.Add(500*time.Millisecond)) // 0.5 s
+	if !approxEqualAbs(e.IErr(), 0.6, 1e-12) {
+		t.Errorf("expected integral error 0.6, got %f", e.IErr())
+	}
+}
+
+func TestEventPIDClampsOutliers(t *testing.T) {
+	e := &EventPID{PID: PID{I: 1, DT: 0.1}, MinDT: 0.01, MaxDT: 0.2}
+	t0 := time.Unix(0, 0)
+	e.UpdateAt(-1, t0)
+	e.UpdateAt(-1, t0.Add(10*time.Second)) // stale, clamps to 0.2
+	e.UpdateAt(-1, t0.Add(10*time.Second)) // duplicate, clamps to 0.01
+	if !approxEqualAbs(e.IErr(), 0.31, 1e-12) {
+		t.Errorf("expected integral error 0.31, got %f", e.IErr())
+	}
+	if e.Outliers() != 2 {
+		t.Errorf("expected 2 outliers, got %d", e.Outliers())
+	}
+
+	e = &EventPID{PID: PID{P: 1, DT: 0.1}}
+	out := e.UpdateAt(-1, t0)
+	if got := e.UpdateAt(-5, t0); got != out {
+		t.Errorf("duplicate timestamp without MinDT should be ignored, got %f", got)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Package export writes frequency responses and simulation results as tidy CSV
+or JSON, with metadata enough to reproduce them.
+
+CSV output begins with the metadata as comment lines prefixed with '#', which
+e.g. pandas.read_csv(path, comment='#') skips, followed by a header row and
+one row per observation.  JSON output is an object with "metadata" and "data"
+members, where data is an array of row objects.
+*/
+package export
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/config"
+	"github.com/brandondube/pctl/sim"
+)
+
+// Metadata describes how a set of results was produced
+type Metadata struct {
+	// SampleRate is the sample rate of the results, in Hz
+	SampleRate float64 `json:"sample_rate"`
+
+	// Blocks are the parameters of the blocks which produced the results
+	Blocks []config.Block `json:"blocks,omitempty"`
+
+	// ConfigHash identifies the config which produced the results
+	ConfigHash string `json:"config_hash,omitempty"`
+
+	// Extra holds any other annotations, e.g. the operator or a commit
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// ConfigHash returns the hash git would assign to data as a blob, so a
+// config file's hash can be compared directly against git ls-files -s or
+// git hash-object.
+func ConfigHash(data []byte) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ForConfig returns metadata describing the given config at sample rate fs.
+// The hash is of the canonical JSON encoding of c.
+func ForConfig(c *config.Config, fs float64) (Metadata, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{SampleRate: fs, Blocks: c.Blocks, ConfigHash: ConfigHash(b)}, nil
+}
+
+// comments returns the metadata as CSV comment lines
+func (m Metadata) comments() []string {
+	out := []string{"# sample_rate: " + fmtFloat(m.SampleRate)}
+	if m.ConfigHash != "" {
+		out = append(out, "# config_hash: "+m.ConfigHash)
+	}
+	if len(m.Blocks) > 0 {
+		b, _ := json.Marshal(m.Blocks)
+		out = append(out, "# blocks: "+string(b))
+	}
+	keys := make([]string, 0, len(m.Extra))
+	for k := range m.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out = append(out, "# "+k+": "+m.Extra[k])
+	}
+	return out
+}
+
+func fmtFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// table is a set of named columns of equal length
+type table struct {
+	names []string
+	cols  [][]float64
+}
+
+func (t table) writeCSV(w io.Writer, m Metadata) error {
+	for _, c := range m.comments() {
+		if _, err := io.WriteString(w, c+"\n"); err != nil {
+			return err
+		}
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.names); err != nil {
+		return err
+	}
+	row := make([]string, len(t.names))
+	for i := 0; i < len(t.cols[0]); i++ {
+		for j := range t.cols {
+			row[j] = fmtFloat(t.cols[j][i])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (t table) writeJSON(w io.Writer, m Metadata) error {
+	rows := make([]map[string]float64, len(t.cols[0]))
+	for i := range rows {
+		r := make(map[string]float64, len(t.names))
+		for j, n := range t.names {
+			r[n] = jsonSafe(t.cols[j][i])
+		}
+		rows[i] = r
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Metadata Metadata             `json:"metadata"`
+		Data     []map[string]float64 `json:"data"`
+	}{m, rows})
+}
+
+// jsonSafe replaces infinities, which JSON cannot represent, with the largest
+// finite values.  NaN is left as an error for the encoder.
+func jsonSafe(v float64) float64 {
+	if math.IsInf(v, 1) {
+		return math.MaxFloat64
+	}
+	if math.IsInf(v, -1) {
+		return -math.MaxFloat64
+	}
+	return v
+}
+
+// FrequencyResponse is the response of a filter at a set of frequencies
+type FrequencyResponse struct {
+	Freq []float64
+	H    []complex128
+}
+
+// Response evaluates r at each frequency (Hz) for sample rate fs
+func Response(r pctl.Responder, fs float64, freqs []float64) *FrequencyResponse {
+	out := &FrequencyResponse{Freq: freqs, H: make([]complex128, len(freqs))}
+	for i, f := range freqs {
+		out.H[i] = r.Response(f, fs)
+	}
+	return out
+}
+
+// LogFreqs returns n frequencies logarithmically spaced from fmin to fmax
+func LogFreqs(fmin, fmax float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		if n == 1 {
+			out[i] = fmin
+			break
+		}
+		out[i] = fmin * math.Pow(fmax/fmin, float64(i)/float64(n-1))
+	}
+	return out
+}
+
+func (fr *FrequencyResponse) table() table {
+	n := len(fr.Freq)
+	t := table{
+		names: []string{"freq_hz", "mag_db", "phase_deg", "re", "im"},
+		cols:  [][]float64{fr.Freq, make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)},
+	}
+	for i, h := range fr.H {
+		t.cols[1][i] = pctl.MagDB(h)
+		t.cols[2][i] = pctl.PhaseDeg(h)
+		t.cols[3][i] = real(h)
+		t.cols[4][i] = imag(h)
+	}
+	return t
+}
+
+// WriteCSV writes the response as CSV with columns freq_hz, mag_db, phase_deg,
+// re, and im
+func (fr *FrequencyResponse) WriteCSV(w io.Writer, m Metadata) error {
+	return fr.table().writeCSV(w, m)
+}
+
+// WriteJSON writes the response as JSON with the same fields as WriteCSV
+func (fr *FrequencyResponse) WriteJSON(w io.Writer, m Metadata) error {
+	return fr.table().writeJSON(w, m)
+}
+
+func simTable(r *sim.Result) table {
+	return table{
+		names: []string{"t", "setpoint", "measurement", "command", "disturbance"},
+		cols:  [][]float64{r.T, r.Setpoint, r.Measurement, r.Command, r.Disturbance},
+	}
+}
+
+// WriteSimCSV writes a simulation result as CSV with columns t, setpoint,
+// measurement, command, and disturbance
+func WriteSimCSV(w io.Writer, r *sim.Result, m Metadata) error {
+	return simTable(r).writeCSV(w, m)
+}
+
+// WriteSimJSON writes a simulation result as JSON with the same fields as
+// WriteSimCSV
+func WriteSimJSON(w io.Writer, r *sim.Result, m Metadata) error {
+	return simTable(r).writeJSON(w, m)
+}
@@ -0,0 +1,65 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/config"
+)
+
+func TestConfigHashMatchesGit(t *testing.T) {
+	// printf 'hello\n' | git hash-object --stdin
+	const expect = "ce013625030ba8dba906f756967f9e9ca394464a"
+	if got := ConfigHash([]byte("hello\n")); got != expect {
+		t.Errorf("hash %s != git's %s", got, expect)
+	}
+}
+
+func TestResponseCSVIsReadable(t *testing.T) {
+	cfg := &config.Config{Blocks: []config.Block{{Type: "lpf", Params: json.RawMessage(`{"fc":10,"dt":0.001}`)}}}
+	m, err := ForConfig(cfg, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bq := pctl.NewBiquadLowpass(1000, 50, 0.7071, 0)
+	fr := Response(bq, 1000, LogFreqs(1, 400, 8))
+	var buf bytes.Buffer
+	if err := fr.WriteCSV(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "# config_hash: "+m.ConfigHash) {
+		t.Errorf("csv missing config hash:\n%s", buf.String())
+	}
+	r := csv.NewReader(&buf)
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 9 || rows[0][0] != "freq_hz" {
+		t.Errorf("expected header + 8 rows, got %v", rows)
+	}
+}
+
+func TestResponseJSONRoundTrips(t *testing.T) {
+	bq := pctl.NewBiquadLowpass(1000, 50, 0.7071, 0)
+	fr := Response(bq, 1000, []float64{500})
+	var buf bytes.Buffer
+	if err := fr.WriteJSON(&buf, Metadata{SampleRate: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Metadata Metadata
+		Data     []map[string]float64
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Metadata.SampleRate != 1000 || len(out.Data) != 1 {
+		t.Errorf("unexpected decoded output %+v", out)
+	}
+}
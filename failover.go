@@ -0,0 +1,113 @@
+package pctl
+
+import "math"
+
+// Tracker is implemented by controllers which can be preloaded so that their
+// next output continues from a given value, for bumpless transfer when they
+// take over from another source
+type Tracker interface {
+	// Track adjusts internal state as if the last output had been output
+	Track(output float64)
+}
+
+// Track implements Tracker by back calculating the integral error.  If I is
+// zero, only the slew limit reference is moved.
+func (pid *PID) Track(output float64) {
+	if pid.I != 0 {
+		pid.integralErr += (output - pid.prevOut) / pid.I
+	}
+	pid.prevOut = output
+}
+
+// tripper is implemented by blocks such as Watchdog which can flag their own
+// output unsafe
+type tripper interface {
+	Tripped() bool
+}
+
+// Failover runs a primary and a backup controller and switches to the backup
+// when the primary is faulty: its output is NaN or infinite, it reports
+// Tripped (e.g. a Watchdog), or SetFault was called.  Both controllers are
+// updated every time, so the backup is warm.  If the backup is a Tracker, it
+// tracks the primary's output while idle so the switch is bumpless.
+//
+// Once switched, Failover stays on the backup until Restore.
+type Failover struct {
+	Primary, Backup Updater
+
+	// OnSwitch, if not nil, is called when the output source changes, with
+	// true when switching to the backup
+	OnSwitch func(backup bool)
+
+	fault    bool
+	onBackup bool
+	out      float64
+}
+
+// Update runs both controllers and returns the output of the active one
+func (f *Failover) Update(input float64) float64 {
+	p := f.Primary.Update(input)
+	b := f.Backup.Update(input)
+	if !f.onBackup {
+		faulty := f.fault || math.IsNaN(p) || math.IsInf(p, 0)
+		if t, ok := f.Primary.(tripper); ok && t.Tripped() {
+			faulty = true
+		}
+		if !faulty {
+			if t, ok := f.Backup.(Tracker); ok {
+				t.Track(p)
+			}
+			f.out = p
+			return p
+		}
+		f.onBackup = true
+		if f.OnSwitch != nil {
+			f.OnSwitch(true)
+		}
+	}
+	if t, ok := f.Primary.(Tracker); ok {
+		t.Track(b)
+	}
+	f.out = b
+	return b
+}
+
+// SetFault flags the primary faulty from an external signal.  Clearing the
+// flag does not switch back; see Restore.
+func (f *Failover) SetFault(fault bool) {
+	f.fault = fault
+}
+
+// OnBackup returns true if the backup is the active output source
+func (f *Failover) OnBackup() bool {
+	return f.onBackup
+}
+
+// Restore returns control to the primary and clears the external fault flag
+func (f *Failover) Restore() {
+	f.fault = false
+	if f.onBackup {
+		f.onBackup = false
+		if f.OnSwitch != nil {
+			f.OnSwitch(false)
+		}
+	}
+}
+
+// Achieved implements ResetFeedback, passing output on to the active
+// controller
+func (f *Failover) Achieved(output float64) {
+	active := f.Primary
+	if f.onBackup {
+		active = f.Backup
+	}
+	if r, ok := active.(ResetFeedback); ok {
+		r.Achieved(output)
+	}
+	f.out = output
+}
+
+// Output returns the last output
+func (f *Failover) Output() float64 {
+	return f.out
+}
@@ -0,0 +1,52 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+type nanAfter struct {
+	n int
+}
+
+func (u *nanAfter) Update(input float64) float64 {
+	if u.n--; u.n < 0 {
+		return math.NaN()
+	}
+	return -input
+}
+
+func TestFailoverSwitchesBumplessly(t *testing.T) {
+	backup := &PID{P: 1, I: 1, DT: 0.1}
+	switched := 0
+	f := &Failover{Primary: &nanAfter{n: 5}, Backup: backup, OnSwitch: func(bool) { switched++ }}
+	var last float64
+	for i := 0; i < 5; i++ {
+		last = f.Update(-2)
+	}
+	if f.OnBackup() || last != 2 {
+		t.Fatalf("expected primary output 2, got %f", last)
+	}
+	out := f.Update(-2)
+	if !f.OnBackup() || switched != 1 {
+		t.Fatal("did not switch to backup on NaN output")
+	}
+	// the backup tracked the primary's 2; its integral moves it by I*err*DT
+	if !approxEqualAbs(out, 2.2, 1e-9) {
+		t.Errorf("expected bumpless transfer near 2.2, got %f", out)
+	}
+}
+
+func TestFailoverExternalFault(t *testing.T) {
+	p, b := Setpoint(0), Setpoint(1)
+	f := &Failover{Primary: &p, Backup: &b}
+	f.Update(3)
+	f.SetFault(true)
+	if out := f.Update(3); out != 2 {
+		t.Errorf("expected backup output 2, got %f", out)
+	}
+	f.Restore()
+	if out := f.Update(3); out != 3 {
+		t.Errorf("expected primary output 3 after Restore, got %f", out)
+	}
+}
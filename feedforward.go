@@ -0,0 +1,51 @@
+package pctl
+
+// Feedforward sums a function of a measured disturbance into the output of a
+// controller, the usual pattern for e.g. combustion air following fuel flow
+// or chilled water following load.  The disturbance is mapped through Map,
+// typically a LookupTable, Piecewise, or Spline, and scaled by the trim Gain.
+//
+// The controller's own output limits apply before the feedforward is added;
+// limit the sum downstream if needed.
+type Feedforward struct {
+	// Controller is the feedback controller
+	Controller Updater
+
+	// Map maps the disturbance to an output contribution.  If nil, the
+	// disturbance is used as is.
+	Map Updater
+
+	// Gain is the trim gain on the feedforward contribution; 1 applies the
+	// map as is, and 0 disables feedforward
+	Gain float64
+
+	dist float64
+	ff   float64
+}
+
+// SetDisturbance sets the measured disturbance used by Update
+func (f *Feedforward) SetDisturbance(d float64) {
+	f.dist = d
+}
+
+// Update runs the controller and returns its output plus the feedforward of
+// the last disturbance set
+func (f *Feedforward) Update(input float64) float64 {
+	ff := f.dist
+	if f.Map != nil {
+		ff = f.Map.Update(ff)
+	}
+	f.ff = f.Gain * ff
+	return f.Controller.Update(input) + f.ff
+}
+
+// UpdateDist sets the disturbance to dist and then updates with input
+func (f *Feedforward) UpdateDist(input, dist float64) float64 {
+	f.dist = dist
+	return f.Update(input)
+}
+
+// Contribution returns the feedforward term of the last update
+func (f *Feedforward) Contribution() float64 {
+	return f.ff
+}
@@ -0,0 +1,16 @@
+package pctl
+
+import "testing"
+
+func TestFeedforwardFromTable(t *testing.T) {
+	var sp Setpoint
+	air := NewLookupTable([]float64{0, 10, 20}, []float64{0, 30, 50})
+	f := &Feedforward{Controller: &sp, Map: air, Gain: 1.1}
+	if got := f.UpdateDist(2, 15); !approxEqualAbs(got, 2+1.1*40, 1e-12) {
+		t.Errorf("expected feedback plus trimmed feedforward %f, got %f", 2+1.1*40, got)
+	}
+	f.Gain = 0
+	if got := f.Update(2); got != 2 || f.Contribution() != 0 {
+		t.Errorf("zero trim gain should disable feedforward, got %f", got)
+	}
+}
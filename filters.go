@@ -33,17 +33,35 @@ func (l *LPF) Update(input float64) float64 {
 	return l.prev
 }
 
-// HPF is a digital discrete-time single pole / first order high pass filter.
+// SetCutoff changes the corner frequency in Hertz, preserving the filter's
+// state, so the bandwidth may be scheduled while the filter runs
+func (l *LPF) SetCutoff(cutoffFreq float64) {
+	l.fc = cutoffFreq
+	l.rc = 1 / (2 * math.Pi * cutoffFreq)
+}
+
+// Cutoff returns the corner frequency in Hertz
+func (l *LPF) Cutoff() float64 {
+	return l.fc
+}
+
+// HPF is a digital discrete-time high pass filter.  It is first order / single
+// pole when made with NewHPF, or a second order Butterworth when made with
+// NewHPF2.
 type HPF struct {
 	// DT is the inter-update time in seconds
-	DT   float64
-	rc   float64
-	fc   float64
-	prev float64
+	DT     float64
+	rc     float64
+	fc     float64
+	prev   float64
+	prevIn float64
+
+	// bq implements the second order filter, if non-nil
+	bq *Biquad
 }
 
-// NewHPF returns a new low pass filter with the specified corner frequency
-// in Hertz
+// NewHPF returns a new first order high pass filter with the specified corner
+// frequency in Hertz
 func NewHPF(cutoffFreq, dT float64) *HPF {
 	return &HPF{
 		fc: cutoffFreq,
@@ -51,15 +69,107 @@ func NewHPF(cutoffFreq, dT float64) *HPF {
 		DT: dT}
 }
 
+// NewHPF2 returns a new second order (-12dB/octave) Butterworth high pass
+// filter with the specified corner frequency in Hertz.  Changing DT after
+// construction does not affect it.
+func NewHPF2(cutoffFreq, dT float64) *HPF {
+	h := NewHPF(cutoffFreq, dT)
+	h.bq = NewBiquadHighpass(1/dT, cutoffFreq, 1/math.Sqrt2, 0)
+	return h
+}
+
 // Update processes an input value, returning the filtered output
 func (h *HPF) Update(input float64) float64 {
+	if h.bq != nil {
+		return h.bq.Update(input)
+	}
+	// y[n] = a * (y[n-1] + x[n] - x[n-1])
 	alpha := h.rc / (h.rc + h.DT)
-	h.prev = alpha * (h.prev + h.DT)
+	h.prev = alpha * (h.prev + input - h.prevIn)
+	h.prevIn = input
 	return h.prev
 }
 
+// Cutoff returns the corner frequency in Hertz
+func (h *HPF) Cutoff() float64 {
+	return h.fc
+}
+
+// Section returns the biquad which implements a second order filter made
+// with NewHPF2, or nil for a first order filter.  It must not be modified.
+func (h *HPF) Section() *Biquad {
+	return h.bq
+}
+
+// cascadeCorrection is the factor between the corner of each of n identical
+// first order stages and the -3dB corner of their cascade
+func cascadeCorrection(n int) float64 {
+	return math.Sqrt(math.Pow(2, 1/float64(n)) - 1)
+}
+
+// LPFCascade is a cascade of identical first order low pass filters, for
+// rolloff of -6n dB/octave
+type LPFCascade struct {
+	stages []LPF
+}
+
+// NewLPFOrder returns a cascade of n first order low pass filters.  The corner
+// of each stage is raised so that the cascade is -3dB at cutoffFreq.  n < 1 is
+// treated as 1.
+func NewLPFOrder(n int, cutoffFreq, dT float64) *LPFCascade {
+	if n < 1 {
+		n = 1
+	}
+	fc := cutoffFreq / cascadeCorrection(n)
+	stages := make([]LPF, n)
+	for i := range stages {
+		stages[i] = *NewLPF(fc, dT)
+	}
+	return &LPFCascade{stages: stages}
+}
+
+// Update processes an input value, returning the filtered output
+func (c *LPFCascade) Update(input float64) float64 {
+	for i := range c.stages {
+		input = c.stages[i].Update(input)
+	}
+	return input
+}
+
+// HPFCascade is a cascade of identical first order high pass filters, for
+// rolloff of -6n dB/octave
+type HPFCascade struct {
+	stages []HPF
+}
+
+// NewHPFOrder returns a cascade of n first order high pass filters.  The corner
+// of each stage is lowered so that the cascade is -3dB at cutoffFreq.  n < 1 is
+// treated as 1.
+func NewHPFOrder(n int, cutoffFreq, dT float64) *HPFCascade {
+	if n < 1 {
+		n = 1
+	}
+	fc := cutoffFreq * cascadeCorrection(n)
+	stages := make([]HPF, n)
+	for i := range stages {
+		stages[i] = *NewHPF(fc, dT)
+	}
+	return &HPFCascade{stages: stages}
+}
+
+// Update processes an input value, returning the filtered output
+func (c *HPFCascade) Update(input float64) float64 {
+	for i := range c.stages {
+		input = c.stages[i].Update(input)
+	}
+	return input
+}
+
 // NewBigQuadXXXX code adapted from Nigel Redmon's C++ Biquad implementation
 // see https://www.earlevel.com/main/2012/11/26/biquad-c-source-code/
+//
+// The designs clamp f to 0.49 Fs at or above half the sample rate; see
+// CheckedBiquad for an error instead.
 type NewBiquadFunc func(float64, float64, float64, float64) *Biquad
 
 // NewBiquadLowPass creates a new Low-Pass Biquad filter.  The input parameters are
@@ -69,7 +179,7 @@ type NewBiquadFunc func(float64, float64, float64, float64) *Biquad
 //  Q = quality factor
 //  g = gain (dB)  (not used; here for homogenaeity of NewBiquadFunc interface)
 func NewBiquadLowpass(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	Fc := clampNyquist(f, Fs) / Fs
 	K := math.Tan(math.Pi * Fc)
 	Ksq := K * K
 	norm := 1 / (1 + K/Q + Ksq)
@@ -88,7 +198,7 @@ func NewBiquadLowpass(Fs, f, Q, g float64) *Biquad {
 //  Q = quality factor
 //  g = gain (dB)  (not used; here for homogenaeity of NewBiquadFunc interface)
 func NewBiquadHighpass(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	Fc := clampNyquist(f, Fs) / Fs
 	K := math.Tan(math.Pi * Fc)
 	Ksq := K * K
 	norm := 1 / (1 + K/Q + Ksq)
@@ -107,7 +217,7 @@ func NewBiquadHighpass(Fs, f, Q, g float64) *Biquad {
 //  Q = quality factor
 //  g = gain (dB) (not used; here for homogenaeity of NewBiquadFunc interface)
 func NewBiquadBandpass(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	Fc := clampNyquist(f, Fs) / Fs
 	K := math.Tan(math.Pi * Fc)
 	Ksq := K * K
 	norm := 1 / (1 + K/Q + Ksq)
@@ -126,7 +236,20 @@ func NewBiquadBandpass(Fs, f, Q, g float64) *Biquad {
 //  Q = quality factor
 //  g = gain (not used; here for homogenaeity of NewBiquadFunc interface)
 func NewBiquadNotch(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	c := notchCoefs(Fs, f, Q)
+	return NewBiquad(c[0], c[1], c[2], c[3], c[4])
+}
+
+// minNotchFcDT is the lowest notch frequency, as a fraction of the sample
+// rate; a notch at or below DC has poles on or outside the unit circle
+const minNotchFcDT = 1e-6
+
+// notchCoefs returns the coefficients of NewBiquadNotch, in the order of
+// NewBiquad.  The notch is at |f|, and no lower than minNotchFcDT*Fs, so that
+// it stays stable for a signed or zero frequency such as a measured speed.
+func notchCoefs(Fs, f, Q float64) [5]float64 {
+	f = math.Max(math.Abs(f), minNotchFcDT*Fs)
+	Fc := clampNyquist(f, Fs) / Fs
 	K := math.Tan(math.Pi * Fc)
 	Ksq := K * K
 	norm := 1 / (1 + K/Q + Ksq)
@@ -135,7 +258,7 @@ func NewBiquadNotch(Fs, f, Q, g float64) *Biquad {
 	a2 := a0
 	b1 := a1
 	b2 := (1 - K/Q + Ksq) * norm
-	return NewBiquad(a0, a1, a2, b1, b2)
+	return [5]float64{a0, a1, a2, b1, b2}
 }
 
 // NewBiquadPeak creates a new peaking Biquad filter.  The input parameters are
@@ -145,7 +268,7 @@ func NewBiquadNotch(Fs, f, Q, g float64) *Biquad {
 //  Q = quality factor
 //  g = gain (dB)
 func NewBiquadPeak(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	Fc := clampNyquist(f, Fs) / Fs
 	V := math.Pow(10, math.Abs(g)/20)
 	K := math.Tan(math.Pi * Fc)
 	var norm, a0, a1, a2, b1, b2 float64
@@ -176,7 +299,7 @@ func NewBiquadPeak(Fs, f, Q, g float64) *Biquad {
 //  Q = quality factor
 //  g = gain (dB)
 func NewBiquadLowShelf(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	Fc := clampNyquist(f, Fs) / Fs
 	V := math.Pow(10, math.Abs(g)/20)
 	K := math.Tan(math.Pi * Fc)
 	var norm, a0, a1, a2, b1, b2 float64
@@ -207,7 +330,7 @@ func NewBiquadLowShelf(Fs, f, Q, g float64) *Biquad {
 //  Q = quality factor
 //  g = gain (dB)
 func NewBiquadHighShelf(Fs, f, Q, g float64) *Biquad {
-	Fc := f / Fs
+	Fc := clampNyquist(f, Fs) / Fs
 	V := math.Pow(10, math.Abs(g)/20)
 	K := math.Tan(math.Pi * Fc)
 	var norm, a0, a1, a2, b1, b2 float64
@@ -359,6 +482,12 @@ func (s *StateSpaceFilter) Update(input float64) float64 {
 	return out
 }
 
+// Matrices returns the A, B, C, and D of the filter.  They must not be
+// modified.
+func (s *StateSpaceFilter) Matrices() (A [][]float64, B, C []float64, D float64) {
+	return s.a, s.b, s.c, s.d
+}
+
 // Reset zeros the filter's internal state
 func (s *StateSpaceFilter) Reset() {
 	for i := 0; i < len(s.x); i++ {
@@ -371,7 +500,14 @@ func (s *StateSpaceFilter) Reset() {
 // Josh Beecher Snyder and Egon Elbre via the #performance channel on
 // gophers slack
 
-// FIRFilter is a Finite Impulse Response Filter
+// FIRFilter is a Finite Impulse Response Filter.
+//
+// Filters with symmetric taps, such as the linear phase designs of DesignFIR,
+// are detected and run in a folded form which pairs the samples sharing a tap,
+// halving the multiplies per update.  Taps are taken as symmetric if each
+// pair agrees to within symTol of the largest tap, which absorbs the rounding
+// of windowing and normalization; the pairs are then averaged.
+// NewSymmetricFIRFilter declares the taps symmetric instead.
 type FIRFilter struct {
 	// sample index
 	j int
@@ -381,10 +517,40 @@ type FIRFilter struct {
 
 	// update input history
 	x []float64
+
+	// sym is true for symmetric taps, in which case the history is kept in
+	// xx, two copies of x in sequence, so that it can be read contiguously
+	sym bool
+	xx  []float64
 }
 
+// symTol is the tolerance of symmetry detection, relative to the largest tap
+const symTol = 1e-9
+
 // NewFIRFilter creates a new Finite Impulse Response Filter
 func NewFIRFilter(taps []float64) *FIRFilter {
+	var peak float64
+	for _, t := range taps {
+		peak = math.Max(peak, math.Abs(t))
+	}
+	sym := len(taps) > 1
+	for i, j := 0, len(taps)-1; i < j; i, j = i+1, j-1 {
+		if math.Abs(taps[i]-taps[j]) > symTol*peak {
+			sym = false
+			break
+		}
+	}
+	return newFIRFilter(taps, sym)
+}
+
+// NewSymmetricFIRFilter creates a new FIR filter run in the folded form,
+// declaring its taps symmetric.  Each pair of taps, i and len(taps)-1-i, is
+// replaced by their mean.
+func NewSymmetricFIRFilter(taps []float64) *FIRFilter {
+	return newFIRFilter(taps, len(taps) > 1)
+}
+
+func newFIRFilter(taps []float64, sym bool) *FIRFilter {
 	// copy and take exclusive possession of taps
 	// reverse it and store two copies as a performance optimization,
 	// avoiding having to jump backwards in memory
@@ -392,13 +558,43 @@ func NewFIRFilter(taps []float64) *FIRFilter {
 	h := make([]float64, len(taps), 2*len(taps))
 	copy(h, taps)
 	reverse(h)
-	return &FIRFilter{
-		h: append(h, h...),
-		x: make([]float64, len(taps))} // zero initialization
+	if sym {
+		for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+			m := (h[i] + h[j]) / 2
+			h[i], h[j] = m, m
+		}
+	}
+	f := &FIRFilter{
+		h:   append(h, h...),
+		x:   make([]float64, len(taps)), // zero initialization
+		sym: sym}
+	if f.sym {
+		f.xx = make([]float64, 2*len(taps))
+	}
+	return f
+}
+
+// Symmetric returns true if the filter's taps are symmetric, and it is run in
+// the folded form
+func (f *FIRFilter) Symmetric() bool {
+	return f.sym
+}
+
+// Taps returns a copy of the filter's taps, in the order given to
+// NewFIRFilter
+func (f *FIRFilter) Taps() []float64 {
+	l := len(f.x)
+	out := make([]float64, l)
+	copy(out, f.h[:l])
+	reverse(out)
+	return out
 }
 
 // Update iterates the filter one sample, returning the processed output
 func (f *FIRFilter) Update(input float64) float64 {
+	if f.sym {
+		return f.updateFolded(input)
+	}
 	// dereference everything one time (~doubles the performance!)
 	l := len(f.x)
 	j := f.j
@@ -426,11 +622,40 @@ func (f *FIRFilter) Update(input float64) float64 {
 	return out
 }
 
+// updateFolded is Update for symmetric taps.  Tap k and tap l-1-k are equal,
+// so their samples are summed before multiplying.
+func (f *FIRFilter) updateFolded(input float64) float64 {
+	l := len(f.x)
+	j := f.j
+	xx := f.xx
+	xx[j] = input
+	xx[j+l] = input
+	if j++; j >= l {
+		j = 0
+	}
+	f.j = j
+	// w is the history, oldest first
+	w := xx[j : j+l]
+	h := f.h[:l]
+	var out float64
+	half := l / 2
+	for i := 0; i < half; i++ {
+		out += h[i] * (w[i] + w[l-1-i])
+	}
+	if l%2 == 1 {
+		out += h[half] * w[half]
+	}
+	return out
+}
+
 // Reset clears the filter's internal state
 func (f *FIRFilter) Reset() {
 	for i := 0; i < len(f.x); i++ {
 		f.x[i] = 0
 	}
+	for i := range f.xx {
+		f.xx[i] = 0
+	}
 }
 
 // reverse reverses x in place
@@ -439,3 +664,125 @@ func reverse(x []float64) {
 		x[i], x[j] = x[j], x[i]
 	}
 }
+
+// SmoothBiquad is a biquad filter whose coefficients ramp linearly to new
+// values over a number of samples when retuned, rather than jumping.  This
+// prevents the transients caused by changing filter parameters on a live
+// signal.
+//
+// Unlike Biquad, SmoothBiquad is implemented in direct form I.  Its state is
+// the input and output history alone; the state of the transposed form depends
+// on the coefficients and would itself cause a transient when they change.
+type SmoothBiquad struct {
+	// Ramp is the number of samples over which Retune moves the coefficients.
+	// If zero, retuning is immediate.
+	Ramp int
+
+	// c holds a0, a1, a2, b1, b2
+	c         [5]float64
+	target    [5]float64
+	delta     [5]float64
+	remaining int
+
+	x1, x2, y1, y2 float64
+}
+
+// NewSmoothBiquad returns a new smoothly retunable biquad with the
+// coefficients of b, which ramps over ramp samples
+func NewSmoothBiquad(b *Biquad, ramp int) *SmoothBiquad {
+	return &SmoothBiquad{
+		Ramp: ramp,
+		c:    [5]float64{b.a0, b.a1, b.a2, b.b1, b.b2},
+	}
+}
+
+// Retune begins ramping the coefficients to those of b, e.g. the output of
+// NewBiquadNotch at a new frequency.  A retune in progress is abandoned from
+// wherever it has reached.
+func (s *SmoothBiquad) Retune(b *Biquad) {
+	s.target = [5]float64{b.a0, b.a1, b.a2, b.b1, b.b2}
+	if s.Ramp <= 0 {
+		s.c = s.target
+		s.remaining = 0
+		return
+	}
+	n := float64(s.Ramp)
+	for i := range s.delta {
+		s.delta[i] = (s.target[i] - s.c[i]) / n
+	}
+	s.remaining = s.Ramp
+}
+
+// Retuning returns true while the coefficients are ramping
+func (s *SmoothBiquad) Retuning() bool {
+	return s.remaining > 0
+}
+
+// Coefs returns the present coefficients, in the same order as NewBiquad
+func (s *SmoothBiquad) Coefs() (a0, a1, a2, b1, b2 float64) {
+	return s.c[0], s.c[1], s.c[2], s.c[3], s.c[4]
+}
+
+// Update steps the coefficient ramp if one is in progress, then processes an
+// input value, returning the filtered output
+func (s *SmoothBiquad) Update(input float64) float64 {
+	if s.remaining > 0 {
+		if s.remaining--; s.remaining == 0 {
+			// land exactly on the target, free of accumulated rounding
+			s.c = s.target
+		} else {
+			for i := range s.c {
+				s.c[i] += s.delta[i]
+			}
+		}
+	}
+	c := &s.c
+	out := c[0]*input + c[1]*s.x1 + c[2]*s.x2 - c[3]*s.y1 - c[4]*s.y2
+	s.x2, s.x1 = s.x1, input
+	s.y2, s.y1 = s.y1, out
+	return out
+}
+
+// Coefs returns the coefficients of the biquad, in the same order as NewBiquad
+func (b *Biquad) Coefs() (a0, a1, a2, b1, b2 float64) {
+	return b.a0, b.a1, b.a2, b.b1, b.b2
+}
+
+// SOSFilter is a cascade of second order sections (Biquads).  Higher order
+// IIR filters are more numerically robust implemented this way than as a
+// single high order difference equation.
+type SOSFilter struct {
+	sections []Biquad
+}
+
+// NewSOSFilter returns a new filter from the given sections.  The
+// coefficients of the sections are copied, their state is not.
+func NewSOSFilter(sections ...*Biquad) *SOSFilter {
+	s := make([]Biquad, len(sections))
+	for i, b := range sections {
+		s[i] = Biquad{a0: b.a0, a1: b.a1, a2: b.a2, b1: b.b1, b2: b.b2}
+	}
+	return &SOSFilter{sections: s}
+}
+
+// Update processes an input value, returning the filtered output
+func (s *SOSFilter) Update(input float64) float64 {
+	for i := range s.sections {
+		input = s.sections[i].Update(input)
+	}
+	return input
+}
+
+// Sections returns the sections of the filter.  Updating them is the same as
+// updating the filter.
+func (s *SOSFilter) Sections() []Biquad {
+	return s.sections
+}
+
+// Reset zeros the filter's internal state
+func (s *SOSFilter) Reset() {
+	for i := range s.sections {
+		s.sections[i].z1 = 0
+		s.sections[i].z2 = 0
+	}
+}
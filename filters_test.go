@@ -222,3 +222,208 @@ func TestLagFIRFilterLags(t *testing.T) {
 		}
 	}
 }
+
+func TestFIRFilterTapsRoundTrip(t *testing.T) {
+	taps := []float64{1, 2, 3, 4}
+	f := NewFIRFilter(taps)
+	f.Update(5) // taps are independent of the history
+	got := f.Taps()
+	for i := range taps {
+		if got[i] != taps[i] {
+			t.Fatalf("expected taps %v, got %v", taps, got)
+		}
+	}
+}
+
+func TestSOSFilterAsymptotic(t *testing.T) {
+	sos, err := Butterworth(5, Lowpass, 1000, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var process float64
+	for i := 0; i < 500; i++ {
+		process = sos.Update(1)
+	}
+	if err := 1 - process; math.Abs(err) > 1e-5 {
+		t.Errorf("process of %f has error of %f, expected to converge to target=1", process, err)
+	}
+}
+
+func TestHighPassPassesSteps(t *testing.T) {
+	// the output of a first order HPF jumps with a step of input, then decays
+	// with time constant rc
+	const fc, dt = 1., 1e-3
+	hpf := NewHPF(fc, dt)
+	out := hpf.Update(1)
+	alpha := hpf.rc / (hpf.rc + dt)
+	if !approxEqualAbs(out, alpha, 1e-12) {
+		t.Errorf("expected step response to start at %f, got %f", alpha, out)
+	}
+	// after one time constant, ~1/e remains
+	n := int(hpf.rc / dt)
+	for i := 1; i < n; i++ {
+		out = hpf.Update(1)
+	}
+	if !approxEqualAbs(out, 1/math.E, 1e-2) {
+		t.Errorf("expected decay to 1/e after rc, got %f", out)
+	}
+	// a step down mirrors the step up
+	if out = hpf.Update(0); out > -0.5 {
+		t.Errorf("expected negative output for a step down, got %f", out)
+	}
+}
+
+func TestSecondOrderHighPass(t *testing.T) {
+	hpf := NewHPF2(10, 1e-3)
+	var out float64
+	for i := 0; i < 5000; i++ {
+		out = hpf.Update(1)
+	}
+	if math.Abs(out) > 1e-5 {
+		t.Errorf("expected second order HPF to reject DC, got %f", out)
+	}
+	if err := hpf.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// measureGain drives u with a sinusoid of frequency f and returns the peak
+// output after settling
+func measureGain(u Updater, f, dt float64) float64 {
+	n := int(20/(f*dt)) + 1
+	var peak float64
+	for i := 0; i < n; i++ {
+		out := u.Update(math.Sin(2 * math.Pi * f * float64(i) * dt))
+		if i > n/2 && math.Abs(out) > peak {
+			peak = math.Abs(out)
+		}
+	}
+	return peak
+}
+
+func TestCascadedFirstOrderCorners(t *testing.T) {
+	const fc, dt = 5., 1e-4
+	for _, n := range []int{1, 2, 4} {
+		if g := measureGain(NewLPFOrder(n, fc, dt), fc, dt); !approxEqualAbs(g, 1/math.Sqrt2, 0.02) {
+			t.Errorf("order %d LPF has gain %f at the corner, expected -3dB", n, g)
+		}
+		if g := measureGain(NewHPFOrder(n, fc, dt), fc, dt); !approxEqualAbs(g, 1/math.Sqrt2, 0.02) {
+			t.Errorf("order %d HPF has gain %f at the corner, expected -3dB", n, g)
+		}
+	}
+	// steeper rolloff with order
+	if measureGain(NewLPFOrder(4, fc, dt), 10*fc, dt) >= measureGain(NewLPFOrder(1, fc, dt), 10*fc, dt) {
+		t.Error("expected 4th order LPF to attenuate more than 1st order above the corner")
+	}
+}
+
+func TestLPFSetCutoffPreservesState(t *testing.T) {
+	lpf := NewLPF(1, 1e-3)
+	for i := 0; i < 100; i++ {
+		lpf.Update(1)
+	}
+	before := lpf.prev
+	lpf.SetCutoff(100)
+	if lpf.prev != before {
+		t.Errorf("SetCutoff changed the filter state from %f to %f", before, lpf.prev)
+	}
+	ref := NewLPF(100, 1e-3)
+	ref.prev = before
+	if a, b := lpf.Update(1), ref.Update(1); a != b {
+		t.Errorf("retuned filter output %f != fresh filter output %f", a, b)
+	}
+}
+
+func TestSmoothBiquadRampsToTarget(t *testing.T) {
+	from := NewBiquadLowpass(1000, 50, 0.7071, 0)
+	to := NewBiquadLowpass(1000, 200, 0.7071, 0)
+	s := NewSmoothBiquad(from, 10)
+	// settle on a step so a coefficient jump would produce a transient
+	var prev float64
+	for i := 0; i < 500; i++ {
+		prev = s.Update(1)
+	}
+	s.Retune(to)
+	for i := 0; i < 10; i++ {
+		if !s.Retuning() {
+			t.Fatalf("retune finished early, after %d samples", i)
+		}
+		out := s.Update(1)
+		if math.Abs(out-prev) > 0.01 {
+			t.Errorf("sample %d: output jumped from %f to %f during retune", i, prev, out)
+		}
+		prev = out
+	}
+	if s.Retuning() {
+		t.Error("expected retune to be complete after Ramp samples")
+	}
+	if a0, _, _, _, b2 := s.Coefs(); a0 != to.a0 || b2 != to.b2 {
+		t.Error("coefficients did not land on the target")
+	}
+}
+
+func TestSymmetricFIRFilterMatchesConvolution(t *testing.T) {
+	for _, taps := range [][]float64{
+		{1, 2, 3, 2, 1},
+		{0.5, -1, 4, 4, -1, 0.5},
+	} {
+		f := NewFIRFilter(taps)
+		if !f.Symmetric() {
+			t.Fatalf("%v not detected as symmetric", taps)
+		}
+		var x []float64
+		for n := 0; n < 20; n++ {
+			in := math.Sin(float64(n)) + float64(n%4)
+			x = append(x, in)
+			var expect float64
+			for k := range taps {
+				if n-k >= 0 {
+					expect += taps[k] * x[n-k]
+				}
+			}
+			if got := f.Update(in); !approxEqualAbs(got, expect, 1e-12) {
+				t.Errorf("%v sample %d: folded %f != convolution %f", taps, n, got, expect)
+			}
+		}
+	}
+	if NewFIRFilter([]float64{1, 2, 3}).Symmetric() {
+		t.Error("asymmetric taps detected as symmetric")
+	}
+}
+
+func TestDesignedFIRIsFolded(t *testing.T) {
+	for _, n := range []int{31, 64} {
+		taps, err := DesignFIR(n, Lowpass, 1000, Hann, 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := NewFIRFilter(taps)
+		if !f.Symmetric() {
+			t.Fatalf("%d taps from DesignFIR not detected as symmetric", n)
+		}
+		var x []float64
+		for i := 0; i < 200; i++ {
+			in := math.Sin(0.3*float64(i)) + float64(i%5)
+			x = append(x, in)
+			var expect float64
+			for k := range taps {
+				if i-k >= 0 {
+					expect += taps[k] * x[i-k]
+				}
+			}
+			if got := f.Update(in); !approxEqualAbs(got, expect, 1e-12) {
+				t.Fatalf("%d taps sample %d: folded %g != convolution %g", n, i, got, expect)
+			}
+		}
+	}
+}
+
+func TestNewSymmetricFIRFilterDeclares(t *testing.T) {
+	f := NewSymmetricFIRFilter([]float64{1, 2, 3.5, 2.5, 1})
+	if !f.Symmetric() {
+		t.Fatal("expected a declared symmetric filter to run folded")
+	}
+	if taps := f.Taps(); taps[1] != 2.25 || taps[3] != 2.25 {
+		t.Errorf("expected the tap pairs averaged, got %v", taps)
+	}
+}
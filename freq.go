@@ -0,0 +1,84 @@
+package pctl
+
+import "math"
+
+// FreqEstimator estimates the frequency of a periodic input from the interval
+// between its rising zero crossings, each located by linear interpolation
+// between samples.  It suits feeding gain schedules and resonance trackers;
+// where the phase is needed too, or the input is noisy, use a PLL.
+//
+// The estimate is updated once per cycle and smoothed by a first order lag.
+type FreqEstimator struct {
+	// DT is the inter-update time in seconds
+	DT float64
+
+	// Smoothing is the time constant of the lag applied to the per cycle
+	// estimates, in seconds.  If zero, the latest cycle is reported.
+	Smoothing float64
+
+	// Hysteresis is the amount the input must fall below zero to arm the
+	// next rising crossing, rejecting the extra crossings noise makes near
+	// zero.  It is in input units.
+	Hysteresis float64
+
+	// Timeout is the time without a crossing, in seconds, after which the
+	// frequency is reported as zero.  If zero, the last estimate is held.
+	Timeout float64
+
+	freq    float64 // smoothed estimate, Hz
+	prev    float64 // previous input
+	since   float64 // samples from the last crossing to the previous input
+	armed   bool    // the input has gone below -Hysteresis
+	crossed bool    // a crossing has been seen, so since is meaningful
+	valid   bool    // freq holds an estimate
+}
+
+// NewFreqEstimator returns a new frequency estimator with the given smoothing
+// time constant, both in seconds
+func NewFreqEstimator(smoothing, dt float64) *FreqEstimator {
+	return &FreqEstimator{DT: dt, Smoothing: smoothing}
+}
+
+// Update processes an input sample and returns the estimated frequency in Hz
+func (f *FreqEstimator) Update(input float64) float64 {
+	if f.armed && f.prev < 0 && input >= 0 {
+		// fraction of a sample from the previous input to the crossing
+		frac := f.prev / (f.prev - input)
+		if f.crossed {
+			period := (f.since + frac) * f.DT
+			f.smooth(1/period, period)
+		}
+		f.crossed = true
+		f.armed = false
+		f.since = 1 - frac
+	} else {
+		f.since++
+	}
+	if input < -f.Hysteresis {
+		f.armed = true
+	}
+	f.prev = input
+	if f.Timeout > 0 && f.crossed && f.since*f.DT > f.Timeout {
+		f.freq, f.valid, f.crossed = 0, false, false
+	}
+	return f.freq
+}
+
+// smooth lags the estimate towards hz, over an interval of dt seconds
+func (f *FreqEstimator) smooth(hz, dt float64) {
+	if !f.valid || f.Smoothing <= 0 {
+		f.freq, f.valid = hz, true
+		return
+	}
+	f.freq += (1 - math.Exp(-dt/f.Smoothing)) * (hz - f.freq)
+}
+
+// Freq returns the estimated frequency in Hz
+func (f *FreqEstimator) Freq() float64 {
+	return f.freq
+}
+
+// Reset forgets the input and the estimate
+func (f *FreqEstimator) Reset() {
+	*f = FreqEstimator{DT: f.DT, Smoothing: f.Smoothing, Hysteresis: f.Hysteresis, Timeout: f.Timeout}
+}
@@ -0,0 +1,37 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFreqEstimatorInterpolatesCrossings(t *testing.T) {
+	const fs, f = 1000., 37.3
+	e := NewFreqEstimator(0, 1/fs)
+	var got float64
+	for n := 0; n < fs; n++ {
+		got = e.Update(math.Sin(2*math.Pi*f*float64(n)/fs + 0.3))
+	}
+	// a bare count of samples per cycle would only resolve 1000/26 or 1000/27
+	if !approxEqualAbs(got, f, 1e-2) {
+		t.Errorf("expected %f Hz, got %f", f, got)
+	}
+}
+
+func TestFreqEstimatorHysteresisAndTimeout(t *testing.T) {
+	const fs, f = 1000., 10.
+	e := &FreqEstimator{DT: 1 / fs, Smoothing: 0.5, Hysteresis: 0.2, Timeout: 0.5}
+	for n := 0; n < 5*fs; n++ {
+		x := math.Sin(2*math.Pi*f*float64(n)/fs) + 0.05*math.Sin(2*math.Pi*240*float64(n)/fs)
+		e.Update(x)
+	}
+	if !approxEqualAbs(e.Freq(), f, 0.05) {
+		t.Errorf("expected %f Hz through ripple, got %f", f, e.Freq())
+	}
+	for n := 0; n < fs; n++ {
+		e.Update(0.01)
+	}
+	if e.Freq() != 0 {
+		t.Errorf("expected zero after the timeout, got %f", e.Freq())
+	}
+}
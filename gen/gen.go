@@ -0,0 +1,476 @@
+/*
+Package gen writes specialized Go source for fixed chains of pctl blocks.
+
+The generated type is an Updater equivalent to the chain, with every
+coefficient a literal in its Update method: FIR taps are unrolled, zero terms
+are dropped, and nothing is computed from a design at runtime.  It imports
+nothing, so it may be vendored into firmware or other constrained targets
+which cannot carry the pctl module or a config file.
+
+Supported blocks are pctl.Setpoint, LPF, HPF, Biquad, SOSFilter, FIRFilter,
+StateSpaceFilter, Polynomial, Scale, and PID, units.Conversion, and
+config.Chain, which is flattened.  Blocks are generated from rest: the
+initial condition of a StateSpaceFilter is not carried over, and the range
+flags of a Scale are not reported.  A PID is generated with every gain,
+limit, and its setpoint as literals; as its setpoint is fixed, SetptRate has
+no effect.
+
+Command pctl-gen generates from a config file, for use with go generate:
+
+	//go:generate pctl-gen -config loop.json -pkg main -type Loop -o loop_gen.go
+*/
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/config"
+	"github.com/brandondube/pctl/units"
+)
+
+// ErrUnsupported is returned for blocks which cannot be generated
+var ErrUnsupported = errors.New("gen: unsupported block")
+
+// ErrNotFinite is returned for blocks with NaN or infinite coefficients
+var ErrNotFinite = errors.New("gen: coefficient is not finite")
+
+// Generator writes the source of a type implementing a chain of blocks
+type Generator struct {
+	// Package is the package clause of the source
+	Package string
+
+	// Type is the name of the generated type
+	Type string
+
+	// Doc is the doc comment of the type, without comment markers.  If
+	// empty, the blocks of the chain are listed.
+	Doc string
+
+	// Command names the generator in the header of the source.  It defaults
+	// to pctl-gen.
+	Command string
+}
+
+// Source returns the gofmt'd source of the chain of blocks
+func (g Generator) Source(blocks ...pctl.Updater) ([]byte, error) {
+	if !token.IsIdentifier(g.Package) {
+		return nil, fmt.Errorf("gen: invalid package name %q", g.Package)
+	}
+	if !token.IsIdentifier(g.Type) {
+		return nil, fmt.Errorf("gen: invalid type name %q", g.Type)
+	}
+	var e emitter
+	for _, u := range flatten(blocks) {
+		if err := e.block(u); err != nil {
+			return nil, err
+		}
+	}
+	cmd := g.Command
+	if cmd == "" {
+		cmd = "pctl-gen"
+	}
+	doc := g.Doc
+	if doc == "" {
+		doc = fmt.Sprintf("%s is a fixed chain of pctl blocks: %s.", g.Type, strings.Join(e.kinds, ", "))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by %s; DO NOT EDIT.\n\npackage %s\n\n", cmd, g.Package)
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(&b, "// %s\n", line)
+	}
+	fmt.Fprintf(&b, "type %s struct {\n%s}\n\n", g.Type, e.fields.String())
+	fmt.Fprintf(&b, "// Update processes an input value, returning the output of the chain\n")
+	fmt.Fprintf(&b, "func (f *%s) Update(x float64) float64 {\n%s\treturn x\n}\n\n", g.Type, e.body.String())
+	fmt.Fprintf(&b, "// Reset zeros the state of the chain\n")
+	fmt.Fprintf(&b, "func (f *%s) Reset() {\n\t*f = %s{}\n}\n", g.Type, g.Type)
+	return format.Source([]byte(b.String()))
+}
+
+// Config returns the source of the chain built from c
+func (g Generator) Config(c *config.Config) ([]byte, error) {
+	chain, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	return g.Source(chain)
+}
+
+// flatten expands config.Chains into their blocks
+func flatten(blocks []pctl.Updater) []pctl.Updater {
+	var out []pctl.Updater
+	for _, u := range blocks {
+		if c, ok := u.(config.Chain); ok {
+			out = append(out, flatten(c)...)
+			continue
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+// emitter accumulates the state fields and the Update body of a chain
+type emitter struct {
+	fields, body strings.Builder
+	kinds        []string
+	n            int // number of stateful blocks, which name the fields
+	err          error
+}
+
+// state declares a field of type typ for the next stateful block and returns
+// its name
+func (e *emitter) state(typ, kind string) string {
+	name := "s" + strconv.Itoa(e.n)
+	e.n++
+	fmt.Fprintf(&e.fields, "\t%s %s // %s\n", name, typ, kind)
+	return name
+}
+
+func (e *emitter) line(format string, args ...interface{}) {
+	fmt.Fprintf(&e.body, "\t"+format+"\n", args...)
+}
+
+// lit formats a coefficient as a Go literal
+func (e *emitter) lit(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		e.err = ErrNotFinite
+		return "0"
+	}
+	if v == 0 {
+		v = 0 // no -0 in source
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// term is a coefficient times a variable; a constant if v is empty
+type term struct {
+	c float64
+	v string
+}
+
+// sum formats the sum of terms, dropping zeros, in order.  If wrap is
+// positive, a line is broken every wrap terms.
+func (e *emitter) sum(wrap int, terms ...term) string {
+	var b strings.Builder
+	n := 0
+	for _, t := range terms {
+		if t.c == 0 {
+			continue
+		}
+		c := t.c
+		switch {
+		case n == 0 && c < 0:
+			b.WriteString("-")
+			c = -c
+		case n > 0 && c < 0:
+			b.WriteString(" - ")
+			c = -c
+		case n > 0:
+			b.WriteString(" + ")
+		}
+		if wrap > 0 && n > 0 && n%wrap == 0 {
+			b.WriteString("\n\t\t")
+		}
+		switch {
+		case t.v == "":
+			b.WriteString(e.lit(c))
+		case c == 1:
+			b.WriteString(t.v)
+		default:
+			b.WriteString(e.lit(c) + "*" + t.v)
+		}
+		n++
+	}
+	if n == 0 {
+		return "0"
+	}
+	return b.String()
+}
+
+// block emits the state and update of one block
+func (e *emitter) block(u pctl.Updater) error {
+	switch b := u.(type) {
+	case *pctl.Setpoint:
+		e.kinds = append(e.kinds, "setpoint")
+		if *b != 0 {
+			e.line("x -= %s // setpoint", e.lit(float64(*b)))
+		}
+	case units.Conversion:
+		e.kinds = append(e.kinds, "conversion")
+		e.line("x = %s // conversion", e.sum(0, term{b.Scale, "x"}, term{b.Offset, ""}))
+	case *pctl.Scale:
+		e.scale(b)
+	case pctl.Polynomial:
+		e.polynomial(b)
+	case *pctl.LPF:
+		e.kinds = append(e.kinds, "lpf")
+		rc := 1 / (2 * math.Pi * b.Cutoff())
+		s := e.state("float64", "lpf")
+		e.line("f.%s += %s * (x - f.%s)", s, e.lit(b.DT/(rc+b.DT)), s)
+		e.line("x = f.%s", s)
+	case *pctl.HPF:
+		if bq := b.Section(); bq != nil {
+			e.kinds = append(e.kinds, "hpf2")
+			e.biquads("hpf2", []pctl.Biquad{*bq})
+			break
+		}
+		e.kinds = append(e.kinds, "hpf")
+		rc := 1 / (2 * math.Pi * b.Cutoff())
+		s := e.state("[2]float64", "hpf output and input")
+		e.line("f.%s[0] = %s * (f.%s[0] + x - f.%s[1])", s, e.lit(rc/(rc+b.DT)), s, s)
+		e.line("f.%s[1] = x", s)
+		e.line("x = f.%s[0]", s)
+	case *pctl.Biquad:
+		e.kinds = append(e.kinds, "biquad")
+		e.biquads("biquad", []pctl.Biquad{*b})
+	case *pctl.SOSFilter:
+		secs := b.Sections()
+		e.kinds = append(e.kinds, fmt.Sprintf("sos(%d)", len(secs)))
+		e.biquads("sos", secs)
+	case *pctl.FIRFilter:
+		e.fir(b)
+	case *pctl.StateSpaceFilter:
+		e.stateSpace(b)
+	case *pctl.PID:
+		if err := b.Validate(); err != nil {
+			return err
+		}
+		e.pid(b)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupported, u)
+	}
+	return e.err
+}
+
+// biquads emits a cascade of transposed direct form II sections
+func (e *emitter) biquads(kind string, secs []pctl.Biquad) {
+	if len(secs) == 0 {
+		return
+	}
+	s := e.state(fmt.Sprintf("[%d][2]float64", len(secs)), kind+" state")
+	for i, sec := range secs {
+		a0, a1, a2, b1, b2 := sec.Coefs()
+		z := fmt.Sprintf("f.%s[%d]", s, i)
+		e.line("{")
+		e.line("\ty := %s", e.sum(0, term{a0, "x"}, term{1, z + "[0]"}))
+		e.line("\t%s[0] = %s", z, e.sum(0, term{a1, "x"}, term{1, z + "[1]"}, term{-b1, "y"}))
+		e.line("\t%s[1] = %s", z, e.sum(0, term{a2, "x"}, term{-b2, "y"}))
+		e.line("\tx = y")
+		e.line("}")
+	}
+}
+
+// fir emits an unrolled FIR filter.  The history is kept twice in sequence,
+// as in the folded form of pctl.FIRFilter, so that it is read contiguously,
+// oldest first.
+func (e *emitter) fir(f *pctl.FIRFilter) {
+	taps := f.Taps()
+	n := len(taps)
+	e.kinds = append(e.kinds, fmt.Sprintf("fir(%d)", n))
+	if n == 1 {
+		e.line("x = %s // fir", e.sum(0, term{taps[0], "x"}))
+		return
+	}
+	s := e.state(fmt.Sprintf("[%d]float64", 2*n), "fir history")
+	j := e.state("int", "fir index")
+	var terms []term
+	if f.Symmetric() {
+		for i := 0; i < n/2; i++ {
+			terms = append(terms, term{taps[n-1-i], fmt.Sprintf("(w[%d] + w[%d])", i, n-1-i)})
+		}
+		if n%2 == 1 {
+			terms = append(terms, term{taps[n/2], fmt.Sprintf("w[%d]", n/2)})
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			terms = append(terms, term{taps[n-1-i], fmt.Sprintf("w[%d]", i)})
+		}
+	}
+	e.line("{")
+	e.line("\tj := f.%s", j)
+	e.line("\tf.%s[j] = x", s)
+	e.line("\tf.%s[j+%d] = x", s, n)
+	e.line("\tif j++; j == %d {", n)
+	e.line("\t\tj = 0")
+	e.line("\t}")
+	e.line("\tf.%s = j", j)
+	e.line("\tw := f.%s[j : j+%d]", s, n)
+	e.line("\t_ = w[%d]", n-1)
+	e.line("\tx = %s", e.sum(4, terms...))
+	e.line("}")
+}
+
+// stateSpace emits an unrolled state space filter
+func (e *emitter) stateSpace(ss *pctl.StateSpaceFilter) {
+	a, b, c, d := ss.Matrices()
+	n := len(b)
+	e.kinds = append(e.kinds, fmt.Sprintf("statespace(%d)", n))
+	if n == 0 {
+		e.line("x = %s // statespace", e.sum(0, term{d, "x"}))
+		return
+	}
+	s := e.state(fmt.Sprintf("[%d]float64", n), "statespace state")
+	e.line("{")
+	e.line("\tq := f.%s", s)
+	for i := 0; i < n; i++ {
+		terms := make([]term, 0, n+1)
+		for j := 0; j < n; j++ {
+			terms = append(terms, term{a[i][j], fmt.Sprintf("q[%d]", j)})
+		}
+		terms = append(terms, term{b[i], "x"})
+		e.line("\tf.%s[%d] = %s", s, i, e.sum(4, terms...))
+	}
+	terms := make([]term, 0, n+1)
+	for j := 0; j < n; j++ {
+		terms = append(terms, term{c[j], fmt.Sprintf("q[%d]", j)})
+	}
+	terms = append(terms, term{d, "x"})
+	e.line("\tx = %s", e.sum(4, terms...))
+	e.line("}")
+}
+
+// polynomial emits the polynomial by Horner's method
+func (e *emitter) polynomial(p pctl.Polynomial) {
+	e.kinds = append(e.kinds, fmt.Sprintf("polynomial(%d)", len(p)))
+	if len(p) == 0 {
+		e.line("x = 0 // polynomial")
+		return
+	}
+	y := e.lit(p[len(p)-1])
+	for i := len(p) - 2; i >= 0; i-- {
+		if strings.Contains(y, " ") {
+			y = "(" + y + ")"
+		}
+		y = e.sum(0, term{1, y + "*x"}, term{p[i], ""})
+	}
+	e.line("x = %s // polynomial", y)
+}
+
+// scale emits the scaling of a Scale, clamped if it clamps
+func (e *emitter) scale(s *pctl.Scale) {
+	e.kinds = append(e.kinds, "scale")
+	if s.Clamp && s.Max > s.Min {
+		e.line("if x > %s {", e.lit(s.Max))
+		e.line("\tx = %s", e.lit(s.Max))
+		e.line("} else if x < %s {", e.lit(s.Min))
+		e.line("\tx = %s", e.lit(s.Min))
+		e.line("}")
+	}
+	k := (s.Eng1 - s.Eng0) / (s.Raw1 - s.Raw0)
+	e.line("x = %s // scale", e.sum(0, term{k, "x"}, term{s.Eng0 - k*s.Raw0, ""}))
+}
+
+// pid emits a PID controller, with only the terms and limits it uses.  Its
+// state is the integral error, previous error and output, and the rounding
+// residue of OutputLSB.
+func (e *emitter) pid(p *pctl.PID) {
+	e.kinds = append(e.kinds, "pid")
+	s := e.state("struct{ ie, e, out, res float64 }", "pid state")
+	dt := e.lit(p.DT)
+	kt := p.Kt
+	if kt == 0 {
+		if p.P != 0 {
+			kt = p.I / p.P
+		} else {
+			kt = 1 / p.DT
+		}
+	}
+	limits := p.OutMax > p.OutMin || p.SlewMax != 0
+	hold := limits && p.Windup != pctl.WindupBackCalc
+	e.line("{")
+	e.line("\ts := &f.%s", s)
+	e.line("\terr := %s", e.sum(0, term{p.Setpt, ""}, term{-1, "x"}))
+	if hold {
+		e.line("\tie := s.ie")
+	}
+	in := "\t"
+	if b := p.IBand; b != 0 {
+		e.line("\tif !(err > %s || err < %s) {", e.lit(b), e.lit(-b))
+		in = "\t\t"
+	}
+	switch max := p.IErrMax; {
+	case max == 0:
+		e.line("%ss.ie += err * %s", in, dt)
+	case p.Windup == pctl.WindupBackCalc:
+		e.line("%sexcess := 0.", in)
+		e.line("%sif s.ie > %s {", in, e.lit(max))
+		e.line("%s\texcess = s.ie - %s", in, e.lit(max))
+		e.line("%s} else if s.ie < %s {", in, e.lit(-max))
+		e.line("%s\texcess = s.ie + %s", in, e.lit(max))
+		e.line("%s}", in)
+		e.line("%ss.ie += (err - %s*excess) * %s", in, e.lit(kt), dt)
+	case p.Windup == pctl.WindupClamp:
+		e.line("%sif s.ie += err * %s; s.ie > %s {", in, dt, e.lit(max))
+		e.line("%s\ts.ie = %s", in, e.lit(max))
+		e.line("%s}", in)
+	default:
+		if p.Windup == pctl.WindupConditional {
+			e.line("%sif !(s.ie >= %s && err > 0 || s.ie <= %s && err < 0) {", in, e.lit(max), e.lit(-max))
+			in += "\t"
+		}
+		e.line("%sif s.ie += err * %s; s.ie > %s {", in, dt, e.lit(max))
+		e.line("%s\ts.ie = %s", in, e.lit(max))
+		e.line("%s} else if s.ie < %s {", in, e.lit(-max))
+		e.line("%s\ts.ie = %s", in, e.lit(-max))
+		e.line("%s}", in)
+		if p.Windup == pctl.WindupConditional {
+			e.line("%s}", in[:len(in)-1])
+		}
+	}
+	if p.IBand != 0 {
+		e.line("\t}")
+	}
+	e.line("\tout := %s", e.sum(0, term{p.P, "err"}, term{p.I, "s.ie"}, term{p.D, "((err - s.e) / " + dt + ")"}))
+	if limits {
+		e.line("\ty := out")
+		if p.OutMax > p.OutMin {
+			e.line("\tif y > %s {", e.lit(p.OutMax))
+			e.line("\t\ty = %s", e.lit(p.OutMax))
+			e.line("\t} else if y < %s {", e.lit(p.OutMin))
+			e.line("\t\ty = %s", e.lit(p.OutMin))
+			e.line("\t}")
+		}
+		if p.SlewMax != 0 {
+			step := e.lit(p.SlewMax * p.DT)
+			e.line("\tif y > s.out+%s {", step)
+			e.line("\t\ty = s.out + %s", step)
+			e.line("\t} else if y < s.out-%s {", step)
+			e.line("\t\ty = s.out - %s", step)
+			e.line("\t}")
+		}
+		e.line("\tif y != out {")
+		switch {
+		case p.Windup == pctl.WindupBackCalc && p.I != 0:
+			e.line("\t\ts.ie += %s * (y - out) / %s * %s", e.lit(kt), e.lit(p.I), dt)
+		case hold:
+			e.line("\t\tif (out > y) == (%s*err > 0) {", e.lit(p.I))
+			e.line("\t\t\ts.ie = ie")
+			e.line("\t\t}")
+		}
+		e.line("\t\tout = y")
+		e.line("\t}")
+	}
+	if lsb := p.OutputLSB; lsb != 0 {
+		// math.Round, for outputs within 2^63 LSBs
+		e.line("\tv := out + s.res")
+		e.line("\tr := v / %s", e.lit(lsb))
+		e.line("\tq := float64(int64(r))")
+		e.line("\tif r-q >= 0.5 {")
+		e.line("\t\tq++")
+		e.line("\t} else if r-q <= -0.5 {")
+		e.line("\t\tq--")
+		e.line("\t}")
+		e.line("\tout = q * %s", e.lit(lsb))
+		e.line("\ts.res = v - out")
+	}
+	e.line("\ts.e = err")
+	e.line("\ts.out = out")
+	e.line("\tx = out")
+	e.line("}")
+}
@@ -0,0 +1,167 @@
+package gen
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/config"
+)
+
+const loopConfig = `{
+  "blocks": [
+    {"type": "setpoint", "params": {"value": 2}},
+    {"type": "lpf", "params": {"fc": 10, "dt": 1e-3}},
+    {"type": "hpf", "params": {"fc": 1, "dt": 1e-3}},
+    {"type": "hpf", "params": {"fc": 1, "dt": 1e-3, "order": 2}},
+    {"type": "biquad", "params": {"design": "notch", "fs": 1000, "f": 60, "q": 5}},
+    {"type": "fir", "params": {"taps": [0.25, 0.5, 0.25]}},
+    {"type": "fir", "params": {"taps": [1, 0, -0.5, 0.2]}},
+    {"type": "statespace", "params": {"a": [[0.9, 0.1], [0, 0.8]], "b": [1, 0], "c": [0, 1], "d": 0.5}},
+    {"type": "polynomial", "params": {"coefs": [1, 0, -2e-3, 5e-6]}},
+    {"type": "scale", "params": {"raw0": 0, "eng0": 0, "raw1": 10, "eng1": 100, "min": -10, "max": 10, "clamp": true}},
+    {"type": "pid", "params": {"p": 0.5, "i": 20, "d": 1e-3, "dt": 1e-3, "setpt": 1, "ierrmax": 0.5, "iband": 50, "outmin": -30, "outmax": 30, "slewmax": 2e4, "outputlsb": 0.01}},
+    {"type": "pid", "params": {"p": 1, "i": 30, "dt": 1e-3, "ierrmax": 1, "windup": 1, "kt": 5, "outmin": -2, "outmax": 2}},
+    {"type": "pid", "params": {"p": 0.1, "i": 10, "dt": 1e-3, "ierrmax": 0.1, "windup": 2, "outmin": -1, "outmax": 1}}
+  ],
+  "units": {"sensor": "F", "process": "C"}
+}`
+
+// input is the test signal on sample i
+func input(i int) float64 {
+	return 100*math.Sin(0.05*float64(i)) + 50
+}
+
+const driver = `package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+func main() {
+	var l Loop
+	for i := 0; i < 500; i++ {
+		fmt.Println(strconv.FormatFloat(l.Update(100*math.Sin(0.05*float64(i))+50), 'g', -1, 64))
+	}
+}
+`
+
+func loadLoop(t *testing.T) *config.Config {
+	c, err := config.Load(strings.NewReader(loopConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestGeneratedSourceParses(t *testing.T) {
+	src, err := Generator{Package: "loop", Type: "Loop"}.Config(loadLoop(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "loop_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+	if len(f.Imports) != 0 {
+		t.Errorf("expected no imports, got %d", len(f.Imports))
+	}
+	if !strings.HasPrefix(string(src), "// Code generated by pctl-gen; DO NOT EDIT.") {
+		t.Errorf("missing generated code header:\n%s", src)
+	}
+}
+
+func TestGeneratedSourceMatchesChain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	c := loadLoop(t)
+	src, err := Generator{Package: "main", Type: "Loop"}.Config(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"go.mod":      "module loop\n\ngo 1.13\n",
+		"loop_gen.go": string(src),
+		"main.go":     driver,
+	}
+	for name, body := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gocmd, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=", "GOWORK=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("running generated source: %v\n%s\n%s", err, out, src)
+	}
+	chain, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) != 500 {
+		t.Fatalf("expected 500 outputs, got %d", len(lines))
+	}
+	for i, l := range lines {
+		got, err := strconv.ParseFloat(l, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := chain.Update(input(i))
+		if math.Abs(got-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			t.Fatalf("sample %d: generated %g, chain %g", i, got, want)
+		}
+	}
+}
+
+func TestUnrolledFIRHasLiteralTaps(t *testing.T) {
+	src, err := Generator{Package: "p", Type: "F"}.Source(pctl.NewFIRFilter([]float64{0.5, 0, 0.25}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(src)
+	if !strings.Contains(s, "x = 0.25*w[0] + 0.5*w[2]") {
+		t.Errorf("expected the unrolled taps, without the zero tap:\n%s", s)
+	}
+	if strings.Contains(s, "for ") {
+		t.Errorf("expected no loops:\n%s", s)
+	}
+}
+
+func TestUnsupportedBlocksError(t *testing.T) {
+	_, err := Generator{Package: "p", Type: "F"}.Source(&pctl.IncrementalPID{P: 1})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for an IncrementalPID, got %v", err)
+	}
+	_, err = Generator{Package: "p", Type: "F"}.Source(pctl.NewBiquad(math.NaN(), 0, 0, 0, 0))
+	if !errors.Is(err, ErrNotFinite) {
+		t.Errorf("expected ErrNotFinite for a NaN coefficient, got %v", err)
+	}
+	_, err = Generator{Package: "p", Type: "not a name"}.Source()
+	if err == nil {
+		t.Error("expected an error for an invalid type name")
+	}
+}
@@ -0,0 +1,105 @@
+package pctl
+
+// Harmonic configures one resonant term of a HarmonicBank
+type Harmonic struct {
+	// Order is the multiple of the fundamental frequency, 1 for the
+	// fundamental itself
+	Order int
+
+	// Kr and Cutoff are as PR.Kr and the bandwidth of NewPR
+	Kr, Cutoff float64
+}
+
+// HarmonicBank is a proportional gain plus resonant terms at the fundamental
+// and selected harmonics of a frequency which may vary, e.g. the speed of a
+// machine, for suppressing a periodic disturbance together with its
+// harmonics.  Its input is the error, as PR.
+//
+// Harmonics at or above the Nyquist frequency are switched out, with their
+// state cleared, until the fundamental falls far enough to bring them back.
+type HarmonicBank struct {
+	// P is the proportional gain, unitless
+	P float64
+
+	fs     float64
+	res    []PR
+	orders []int
+	active []bool
+}
+
+// NewHarmonicBank returns a new harmonic compensator with the given resonant
+// terms, tuned to a fundamental of freq Hertz and updated every dt seconds
+func NewHarmonicBank(p, freq, dt float64, harmonics ...Harmonic) *HarmonicBank {
+	h := &HarmonicBank{
+		P:      p,
+		fs:     1 / dt,
+		res:    make([]PR, len(harmonics)),
+		orders: make([]int, len(harmonics)),
+		active: make([]bool, len(harmonics)),
+	}
+	for i, hm := range harmonics {
+		h.res[i] = PR{Kr: hm.Kr, cutoff: hm.Cutoff, dt: dt}
+		h.orders[i] = hm.Order
+	}
+	h.SetFreq(freq)
+	return h
+}
+
+// SetFreq retunes every resonant term to a new fundamental frequency in Hertz,
+// preserving their state
+func (h *HarmonicBank) SetFreq(freq float64) {
+	for i := range h.res {
+		f := freq * float64(h.orders[i])
+		if active := f < h.fs/2; !active {
+			if h.active[i] {
+				h.res[i].Reset()
+			}
+			h.active[i] = false
+			continue
+		}
+		h.active[i] = true
+		h.res[i].SetFreq(f)
+	}
+}
+
+// Freq returns the fundamental frequency in Hertz
+func (h *HarmonicBank) Freq() float64 {
+	for i := range h.res {
+		if h.active[i] {
+			return h.res[i].Freq() / float64(h.orders[i])
+		}
+	}
+	return 0
+}
+
+// Update runs the compensator once on the error and returns the output
+func (h *HarmonicBank) Update(err float64) float64 {
+	out := h.P * err
+	for i := range h.res {
+		if h.active[i] {
+			out += h.res[i].Update(err)
+		}
+	}
+	return out
+}
+
+// UpdateFreq retunes the compensator to the fundamental frequency freq, then
+// runs it once on the error and returns the output.  freq is typically from a
+// PLL or FreqEstimator tracking the machine.
+func (h *HarmonicBank) UpdateFreq(err, freq float64) float64 {
+	h.SetFreq(freq)
+	return h.Update(err)
+}
+
+// Active returns true if the term for the i-th harmonic given to
+// NewHarmonicBank is below the Nyquist frequency and in use
+func (h *HarmonicBank) Active(i int) bool {
+	return h.active[i]
+}
+
+// Reset zeros the state of every resonant term
+func (h *HarmonicBank) Reset() {
+	for i := range h.res {
+		h.res[i].Reset()
+	}
+}
@@ -0,0 +1,38 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHarmonicBankRejectsHarmonics(t *testing.T) {
+	const fs, f = 5000., 40.
+	h := NewHarmonicBank(1, f, 1/fs,
+		Harmonic{Order: 1, Kr: 50},
+		Harmonic{Order: 3, Kr: 50})
+	plant := NewLPF(200, 1/fs)
+	var y, peak float64
+	for n := 0; n < 3*fs; n++ {
+		tt := float64(n) / fs
+		d := math.Sin(2*math.Pi*f*tt) + 0.5*math.Sin(2*math.Pi*3*f*tt+1)
+		e := -(y + d)
+		y = plant.Update(h.Update(e))
+		if n > 5*fs/2 {
+			peak = math.Max(peak, math.Abs(e))
+		}
+	}
+	if peak > 1e-3 {
+		t.Errorf("expected both harmonics rejected, residual %g", peak)
+	}
+}
+
+func TestHarmonicBankSwitchesOutAboveNyquist(t *testing.T) {
+	h := NewHarmonicBank(0, 100, 1e-3, Harmonic{Order: 1, Kr: 1}, Harmonic{Order: 7, Kr: 1})
+	if !h.Active(0) || h.Active(1) {
+		t.Errorf("expected only the fundamental active at 100 Hz, got %v %v", h.Active(0), h.Active(1))
+	}
+	h.SetFreq(50)
+	if !h.Active(1) || h.Freq() != 50 {
+		t.Errorf("expected the 7th harmonic active at 50 Hz, got %v, freq %f", h.Active(1), h.Freq())
+	}
+}
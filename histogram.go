@@ -0,0 +1,140 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrEdges is returned when histogram bin edges are fewer than two or not
+// strictly ascending
+var ErrEdges = errors.New("pctl: histogram needs at least two strictly ascending bin edges")
+
+// Histogram counts samples of a signal into bins, so that its distribution,
+// e.g. of the error or of time spent at an output limit, can be characterized
+// over a long deployment without storing the samples.  It passes its input
+// through unchanged.
+//
+// Samples below the first edge or at or beyond the last are counted as under
+// and overflow.  Bins are closed below and open above.
+type Histogram struct {
+	edges       []float64
+	counts      []uint64
+	under, over uint64
+}
+
+// NewHistogram returns a new histogram with the given bin edges; n edges make
+// n-1 bins
+func NewHistogram(edges ...float64) (*Histogram, error) {
+	if len(edges) < 2 {
+		return nil, ErrEdges
+	}
+	for i := 1; i < len(edges); i++ {
+		if !(edges[i] > edges[i-1]) {
+			return nil, ErrEdges
+		}
+	}
+	e := make([]float64, len(edges))
+	copy(e, edges)
+	return &Histogram{edges: e, counts: make([]uint64, len(e)-1)}, nil
+}
+
+// NewHistogramLinear returns a new histogram of n equal bins spanning
+// [lo, hi)
+func NewHistogramLinear(lo, hi float64, n int) (*Histogram, error) {
+	if n < 1 {
+		return nil, ErrEdges
+	}
+	e := make([]float64, n+1)
+	for i := range e {
+		e[i] = lo + (hi-lo)*float64(i)/float64(n)
+	}
+	return NewHistogram(e...)
+}
+
+// Update counts a sample and returns it
+func (h *Histogram) Update(input float64) float64 {
+	// binary search for i, the index of the first edge > input
+	i, j := 0, len(h.edges)
+	for i < j {
+		m := int(uint(i+j) >> 1)
+		if h.edges[m] > input {
+			j = m
+		} else {
+			i = m + 1
+		}
+	}
+	switch {
+	case i == 0:
+		h.under++
+	case i == len(h.edges):
+		h.over++
+	default:
+		h.counts[i-1]++
+	}
+	return input
+}
+
+// Edges returns the bin edges.  The slice must not be modified.
+func (h *Histogram) Edges() []float64 {
+	return h.edges
+}
+
+// Counts returns the count of each bin.  The slice must not be modified.
+func (h *Histogram) Counts() []uint64 {
+	return h.counts
+}
+
+// Underflow returns the count of samples below the first edge
+func (h *Histogram) Underflow() uint64 {
+	return h.under
+}
+
+// Overflow returns the count of samples at or beyond the last edge
+func (h *Histogram) Overflow() uint64 {
+	return h.over
+}
+
+// Total returns the number of samples counted, including under and overflow
+func (h *Histogram) Total() uint64 {
+	n := h.under + h.over
+	for _, c := range h.counts {
+		n += c
+	}
+	return n
+}
+
+// Percentile returns an estimate of the p-th percentile (0 to 100) of the
+// samples, interpolating linearly within the bin it falls in.  Percentiles in
+// the under or overflow are reported as the first or last edge, and NaN as
+// the percentile of no samples.
+func (h *Histogram) Percentile(p float64) float64 {
+	total := h.Total()
+	if total == 0 {
+		return math.NaN()
+	}
+	rank := clamp(p, 0, 100) / 100 * float64(total)
+	cum := float64(h.under)
+	if rank <= cum && h.under > 0 {
+		return h.edges[0]
+	}
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		next := cum + float64(c)
+		if rank <= next {
+			frac := (rank - cum) / float64(c)
+			return h.edges[i] + frac*(h.edges[i+1]-h.edges[i])
+		}
+		cum = next
+	}
+	return h.edges[len(h.edges)-1]
+}
+
+// Reset zeros the counts
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.under, h.over = 0, 0
+}
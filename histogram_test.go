@@ -0,0 +1,45 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramCountsAndPercentiles(t *testing.T) {
+	h, err := NewHistogramLinear(0, 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		h.Update(float64(i) / 100) // uniform on [0, 10)
+	}
+	h.Update(-1)
+	h.Update(10)
+	for i, c := range h.Counts() {
+		if c != 100 {
+			t.Errorf("bin %d: expected 100, got %d", i, c)
+		}
+	}
+	if h.Underflow() != 1 || h.Overflow() != 1 || h.Total() != 1002 {
+		t.Errorf("expected 1 under, 1 over, 1002 total, got %d %d %d", h.Underflow(), h.Overflow(), h.Total())
+	}
+	if p := h.Percentile(50); !approxEqualAbs(p, 5, 0.02) {
+		t.Errorf("expected a median of 5, got %f", p)
+	}
+	if p := h.Percentile(90); !approxEqualAbs(p, 9, 0.02) {
+		t.Errorf("expected a 90th percentile of 9, got %f", p)
+	}
+	h.Reset()
+	if h.Total() != 0 || !math.IsNaN(h.Percentile(50)) {
+		t.Error("expected an empty histogram after Reset")
+	}
+}
+
+func TestHistogramRejectsBadEdges(t *testing.T) {
+	if _, err := NewHistogram(0, 1, 1); err != ErrEdges {
+		t.Errorf("expected ErrEdges for repeated edges, got %v", err)
+	}
+	if _, err := NewHistogram(1); err != ErrEdges {
+		t.Errorf("expected ErrEdges for one edge, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+package pctl
+
+// SampleHold is a track-and-hold.  While Hold is false, the output tracks the
+// input; while it is true, the output is frozen at the last tracked value.
+// The zero value tracks.
+//
+// Hold may be set directly, e.g. on a fault or during calibration, or the
+// gate may be supplied with each sample through UpdateGate.
+type SampleHold struct {
+	// Hold freezes the output when true
+	Hold bool
+
+	out float64
+}
+
+// Update returns the input, or the held value if Hold is true
+func (s *SampleHold) Update(input float64) float64 {
+	if !s.Hold {
+		s.out = input
+	}
+	return s.out
+}
+
+// UpdateGate sets Hold = !track and then updates with input.  It suits gates
+// which are themselves signals, such as a run permissive.
+func (s *SampleHold) UpdateGate(input float64, track bool) float64 {
+	s.Hold = !track
+	return s.Update(input)
+}
+
+// Output returns the current output without updating
+func (s *SampleHold) Output() float64 {
+	return s.out
+}
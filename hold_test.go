@@ -0,0 +1,20 @@
+package pctl
+
+import "testing"
+
+func TestSampleHoldTracksAndHolds(t *testing.T) {
+	var s SampleHold
+	if out := s.Update(1); out != 1 {
+		t.Errorf("zero value should track, got %f", out)
+	}
+	s.Hold = true
+	if out := s.Update(2); out != 1 {
+		t.Errorf("expected held output of 1, got %f", out)
+	}
+	if out := s.UpdateGate(3, true); out != 3 {
+		t.Errorf("expected tracked output of 3, got %f", out)
+	}
+	if out := s.UpdateGate(4, false); out != 3 {
+		t.Errorf("expected held output of 3, got %f", out)
+	}
+}
@@ -0,0 +1,116 @@
+package io
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoSignal is returned when reading a signal which has never been published
+var ErrNoSignal = errors.New("io: signal has not been published")
+
+// Bus is an in-process publish/subscribe bus of named float64 signals.
+// The last value of each signal is cached, so readers need not subscribe.
+// A Bus is safe for concurrent use.
+type Bus struct {
+	mu      sync.RWMutex
+	signals map[string]*signal
+}
+
+type signal struct {
+	last Sample
+	set  bool
+	subs map[chan float64]struct{}
+}
+
+// NewBus returns an empty bus
+func NewBus() *Bus {
+	return &Bus{signals: map[string]*signal{}}
+}
+
+// get returns the named signal, creating it if needed.  b.mu must be held
+// for writing.
+func (b *Bus) get(name string) *signal {
+	s, ok := b.signals[name]
+	if !ok {
+		s = &signal{subs: map[chan float64]struct{}{}}
+		b.signals[name] = s
+	}
+	return s
+}
+
+// Publish sets the value of the named signal and sends it to subscribers.
+// Subscribers whose channel is full miss the value; they can recover it with
+// Last.
+func (b *Bus) Publish(name string, v float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.get(name)
+	s.last = Sample{Value: v, Time: time.Now()}
+	s.set = true
+	for c := range s.subs {
+		select {
+		case c <- v:
+		default:
+		}
+	}
+}
+
+// Last returns the most recent sample of the named signal.  The sample's Err
+// is ErrNoSignal if it has never been published.
+func (b *Bus) Last(name string) Sample {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	s, ok := b.signals[name]
+	if !ok || !s.set {
+		return Sample{Err: ErrNoSignal}
+	}
+	return s.last
+}
+
+// Subscribe returns a channel of buf capacity which receives each value
+// published to the named signal, and a function which unsubscribes and
+// closes the channel.
+func (b *Bus) Subscribe(name string, buf int) (<-chan float64, func()) {
+	c := make(chan float64, buf)
+	b.mu.Lock()
+	b.get(name).subs[c] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.signals[name].subs, c)
+			b.mu.Unlock()
+			close(c)
+		})
+	}
+}
+
+// Names returns the names of all signals which have been published or
+// subscribed to, in no particular order
+func (b *Bus) Names() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := make([]string, 0, len(b.signals))
+	for k := range b.signals {
+		out = append(out, k)
+	}
+	return out
+}
+
+// Sensor returns a Sensor which reads the last value of the named signal
+func (b *Bus) Sensor(name string) Sensor {
+	return SensorFunc(func() (float64, time.Time, error) {
+		s := b.Last(name)
+		return s.Value, s.Time, s.Err
+	})
+}
+
+// Actuator returns an Actuator which publishes to the named signal
+func (b *Bus) Actuator(name string) Actuator {
+	return ActuatorFunc(func(v float64) error {
+		b.Publish(name, v)
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+package io
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBusCachesLastValue(t *testing.T) {
+	b := NewBus()
+	if s := b.Last("temp"); !errors.Is(s.Err, ErrNoSignal) {
+		t.Errorf("expected ErrNoSignal before publishing, got %v", s.Err)
+	}
+	b.Publish("temp", 20)
+	b.Publish("temp", 21.5)
+	if s := b.Last("temp"); s.Err != nil || s.Value != 21.5 {
+		t.Errorf("expected last value 21.5, got %+v", s)
+	}
+}
+
+func TestBusSubscribers(t *testing.T) {
+	b := NewBus()
+	c, cancel := b.Subscribe("flow", 2)
+	b.Publish("flow", 1)
+	b.Publish("flow", 2)
+	b.Publish("flow", 3) // dropped, subscriber is full
+	if v := <-c; v != 1 {
+		t.Errorf("expected 1, got %f", v)
+	}
+	if v := <-c; v != 2 {
+		t.Errorf("expected 2, got %f", v)
+	}
+	cancel()
+	if _, ok := <-c; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	b.Publish("flow", 4) // must not panic on the closed channel
+}
+
+func TestBusSensorActuator(t *testing.T) {
+	b := NewBus()
+	if err := b.Actuator("cmd").Write(3); err != nil {
+		t.Fatal(err)
+	}
+	v, _, err := b.Sensor("cmd").Read()
+	if err != nil || v != 3 {
+		t.Errorf("expected to read back 3, got %f, %v", v, err)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Package io defines interfaces for hardware sensors and actuators, and adaptors
+which connect them to pctl Updaters through channels or a LoopRunner.
+
+Drivers for DAQ cards, SPI ADCs, PWM outputs, and so on need only implement
+Sensor or Actuator to be used with the rest of the package.
+
+The name shadows the standard library's io; import it under another name if
+both are needed.
+*/
+package io
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// Sensor is a source of timestamped measurements
+type Sensor interface {
+	// Read returns the current measurement and the time it was taken
+	Read() (float64, time.Time, error)
+}
+
+// Actuator is a sink of commands
+type Actuator interface {
+	// Write applies a command
+	Write(float64) error
+}
+
+// SensorFunc adapts a function to a Sensor
+type SensorFunc func() (float64, time.Time, error)
+
+// Read calls f
+func (f SensorFunc) Read() (float64, time.Time, error) {
+	return f()
+}
+
+// ActuatorFunc adapts a function to an Actuator
+type ActuatorFunc func(float64) error
+
+// Write calls f
+func (f ActuatorFunc) Write(v float64) error {
+	return f(v)
+}
+
+// Sample is a measurement read from a Sensor
+type Sample struct {
+	Value float64
+	Time  time.Time
+	Err   error
+}
+
+// Source reads s every period and sends the samples on the returned channel,
+// which is closed when ctx is done.  Samples which cannot be sent before the
+// next read are dropped, so a slow consumer always sees fresh data.
+func Source(ctx context.Context, s Sensor, period time.Duration) <-chan Sample {
+	out := make(chan Sample, 1)
+	go func() {
+		defer close(out)
+		tick := time.NewTicker(period)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+			}
+			var smp Sample
+			smp.Value, smp.Time, smp.Err = s.Read()
+			select {
+			case out <- smp:
+			default:
+			}
+		}
+	}()
+	return out
+}
+
+// Sink writes each value received on in to a, until in is closed or ctx is
+// done.  Errors from a are sent on the returned channel if there is room; it
+// is closed when Sink exits.
+func Sink(ctx context.Context, a Actuator, in <-chan float64) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if err := a.Write(v); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return errs
+}
+
+// LoopRunner closes a control loop from a Sensor through a controller to an
+// Actuator, at a fixed period
+type LoopRunner struct {
+	// Sensor provides the measurement fed to Controller
+	Sensor Sensor
+
+	// Controller maps measurements to commands; use pctl.Cascade or a
+	// config.Chain for more than one block
+	Controller pctl.Updater
+
+	// Actuator receives the output of Controller
+	Actuator Actuator
+
+	// Period is the inter-update time
+	Period time.Duration
+
+	// OnError is called with errors from Sensor or Actuator.  If nil, Run
+	// returns the first error.
+	OnError func(error)
+}
+
+// Step runs the loop once: read, update, write.  The controller is not updated
+// if the sensor fails.
+func (l *LoopRunner) Step() error {
+	v, _, err := l.Sensor.Read()
+	if err != nil {
+		return fmt.Errorf("io: sensor: %w", err)
+	}
+	cmd := l.Controller.Update(v)
+	if err := l.Actuator.Write(cmd); err != nil {
+		return fmt.Errorf("io: actuator: %w", err)
+	}
+	return nil
+}
+
+// Run calls Step every Period until ctx is done or an error occurs with a nil
+// OnError.  It returns ctx.Err() when ctx is done.
+func (l *LoopRunner) Run(ctx context.Context) error {
+	tick := time.NewTicker(l.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+		if err := l.Step(); err != nil {
+			if l.OnError == nil {
+				return err
+			}
+			l.OnError(err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestLoopRunnerStep(t *testing.T) {
+	var written float64
+	s := pctl.Setpoint(1)
+	l := LoopRunner{
+		Sensor:     SensorFunc(func() (float64, time.Time, error) { return 3, time.Now(), nil }),
+		Controller: &s,
+		Actuator:   ActuatorFunc(func(v float64) error { written = v; return nil }),
+	}
+	if err := l.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if written != 2 {
+		t.Errorf("actuator received %f, expected 2", written)
+	}
+}
+
+func TestLoopRunnerReturnsSensorError(t *testing.T) {
+	bad := errors.New("adc timeout")
+	l := LoopRunner{
+		Sensor:     SensorFunc(func() (float64, time.Time, error) { return 0, time.Time{}, bad }),
+		Controller: new(pctl.Setpoint),
+		Actuator:   ActuatorFunc(func(float64) error { t.Error("actuator written after sensor error"); return nil }),
+		Period:     time.Millisecond,
+	}
+	if err := l.Run(context.Background()); !errors.Is(err, bad) {
+		t.Errorf("expected sensor error, got %v", err)
+	}
+}
+
+func TestSourceToSink(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	samples := Source(ctx, SensorFunc(func() (float64, time.Time, error) { return 7, time.Now(), nil }), time.Millisecond)
+	got := make(chan float64, 1)
+	cmds := make(chan float64)
+	Sink(ctx, ActuatorFunc(func(v float64) error { got <- v; return nil }), cmds)
+	smp := <-samples
+	cmds <- smp.Value
+	if v := <-got; v != 7 {
+		t.Errorf("sink received %f, expected 7", v)
+	}
+}
@@ -0,0 +1,117 @@
+package io
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/brandondube/pctl"
+)
+
+// PipeOptions configures a Pipe
+type PipeOptions struct {
+	// Buffer is the capacity of the output channel
+	Buffer int
+
+	// Block makes the pipe wait for room on the output channel.  If false,
+	// outputs which do not fit are dropped and counted.
+	Block bool
+}
+
+// PipeStats counts the samples handled by a Pipe
+type PipeStats struct {
+	// In is the number of inputs processed
+	In uint64
+
+	// Out is the number of outputs sent
+	Out uint64
+
+	// Dropped is the number of outputs discarded because the output channel
+	// was full
+	Dropped uint64
+
+	// Overruns is the number of times an input was already waiting when the
+	// previous one finished processing, i.e. the pipe was not keeping up.
+	// It is only counted for buffered input channels.
+	Overruns uint64
+}
+
+// Pipe runs an Updater in its own goroutine, between an input and output
+// channel
+type Pipe struct {
+	out      chan float64
+	done     chan struct{}
+	in       uint64
+	sent     uint64
+	dropped  uint64
+	overruns uint64
+}
+
+// NewPipe starts a goroutine which updates u with each value received on in
+// and sends the output on the pipe's Out channel.  The goroutine exits and
+// Out is closed when in is closed or ctx is done.  u must not be used by
+// anything else while the pipe runs.
+func NewPipe(ctx context.Context, u pctl.Updater, in <-chan float64, opts PipeOptions) *Pipe {
+	p := &Pipe{
+		out:  make(chan float64, opts.Buffer),
+		done: make(chan struct{}),
+	}
+	go p.run(ctx, u, in, opts.Block)
+	return p
+}
+
+func (p *Pipe) run(ctx context.Context, u pctl.Updater, in <-chan float64, block bool) {
+	defer close(p.done)
+	defer close(p.out)
+	for {
+		var v float64
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case v, ok = <-in:
+			if !ok {
+				return
+			}
+		}
+		atomic.AddUint64(&p.in, 1)
+		v = u.Update(v)
+		if len(in) > 0 {
+			atomic.AddUint64(&p.overruns, 1)
+		}
+		if block {
+			select {
+			case <-ctx.Done():
+				return
+			case p.out <- v:
+				atomic.AddUint64(&p.sent, 1)
+			}
+			continue
+		}
+		select {
+		case p.out <- v:
+			atomic.AddUint64(&p.sent, 1)
+		default:
+			atomic.AddUint64(&p.dropped, 1)
+		}
+	}
+}
+
+// Out is the channel of outputs
+func (p *Pipe) Out() <-chan float64 {
+	return p.out
+}
+
+// Done is closed when the pipe's goroutine has exited
+func (p *Pipe) Done() <-chan struct{} {
+	return p.done
+}
+
+// Stats returns the pipe's counters.  It is safe to call concurrently.
+func (p *Pipe) Stats() PipeStats {
+	return PipeStats{
+		In:       atomic.LoadUint64(&p.in),
+		Out:      atomic.LoadUint64(&p.sent),
+		Dropped:  atomic.LoadUint64(&p.dropped),
+		Overruns: atomic.LoadUint64(&p.overruns),
+	}
+}
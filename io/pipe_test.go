@@ -0,0 +1,57 @@
+package io
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestPipeAppliesUpdater(t *testing.T) {
+	s := pctl.Setpoint(1)
+	in := make(chan float64)
+	p := NewPipe(context.Background(), &s, in, PipeOptions{Block: true})
+	go func() {
+		for i := 0; i < 3; i++ {
+			in <- float64(i)
+		}
+		close(in)
+	}()
+	var got []float64
+	for v := range p.Out() {
+		got = append(got, v)
+	}
+	if len(got) != 3 || got[0] != -1 || got[2] != 1 {
+		t.Errorf("pipe produced %v, expected [-1 0 1]", got)
+	}
+	if st := p.Stats(); st.In != 3 || st.Out != 3 || st.Dropped != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestPipeDropsWhenFull(t *testing.T) {
+	in := make(chan float64, 4)
+	for i := 0; i < 4; i++ {
+		in <- float64(i)
+	}
+	close(in)
+	p := NewPipe(context.Background(), new(pctl.Setpoint), in, PipeOptions{Buffer: 1})
+	<-p.Done()
+	st := p.Stats()
+	if st.Out != 1 || st.Dropped != 3 {
+		t.Errorf("expected 1 sent and 3 dropped, got %+v", st)
+	}
+	if st.Overruns == 0 {
+		t.Errorf("expected a backlog of input to count overruns")
+	}
+}
+
+func TestPipeStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPipe(ctx, new(pctl.Setpoint), make(chan float64), PipeOptions{})
+	cancel()
+	<-p.Done()
+	if _, ok := <-p.Out(); ok {
+		t.Error("expected Out to be closed after cancellation")
+	}
+}
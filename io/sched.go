@@ -0,0 +1,100 @@
+package io
+
+import (
+	"context"
+	"time"
+)
+
+// Task is a loop run by a Scheduler every Divider master ticks, on the ticks
+// where tick % Divider == Phase
+type Task struct {
+	Loop *LoopRunner
+
+	// Divider is the number of master ticks per update of Loop.  Zero is
+	// treated as one.
+	Divider int
+
+	// Phase offsets the updates of Loop within its Divider, so that slow
+	// loops can be spread over different ticks
+	Phase int
+}
+
+// Scheduler runs several LoopRunners at rates derived from one master tick,
+// e.g. an inner loop every tick at 1 kHz and an outer loop every tenth tick at
+// 100 Hz.  Loops due on the same tick are stepped in the order of Tasks, so
+// the phase relationship between them is deterministic.  The Period of each
+// LoopRunner is not used.
+//
+// Scheduler is also a pctl.Clock which reads the time of the current tick, so
+// that timed blocks within the loops see time consistent with the schedule
+// and not the jitter of the operating system.
+type Scheduler struct {
+	// Tick is the master period
+	Tick time.Duration
+
+	// Tasks are the loops to run
+	Tasks []Task
+
+	// Epoch is the time of tick zero.  If zero, Run sets it to the time it
+	// starts.
+	Epoch time.Time
+
+	// OnError is called with the index of the task and the error when a loop
+	// fails.  If nil, Step and Run return the first error.
+	OnError func(task int, err error)
+
+	ticks uint64
+}
+
+// Step advances one master tick and steps the loops which are due
+func (s *Scheduler) Step() error {
+	n := s.ticks
+	for i, t := range s.Tasks {
+		div := uint64(t.Divider)
+		if div == 0 {
+			div = 1
+		}
+		if n%div != uint64(t.Phase)%div {
+			continue
+		}
+		if err := t.Loop.Step(); err != nil {
+			if s.OnError == nil {
+				s.ticks++
+				return err
+			}
+			s.OnError(i, err)
+		}
+	}
+	s.ticks++
+	return nil
+}
+
+// Run calls Step every Tick until ctx is done or an error occurs with a nil
+// OnError.  It returns ctx.Err() when ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if s.Epoch.IsZero() {
+		s.Epoch = time.Now()
+	}
+	tick := time.NewTicker(s.Tick)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+		if err := s.Step(); err != nil {
+			return err
+		}
+	}
+}
+
+// Ticks returns the number of master ticks elapsed
+func (s *Scheduler) Ticks() uint64 {
+	return s.ticks
+}
+
+// Now returns the time of the current tick, Epoch + Ticks*Tick
+func (s *Scheduler) Now() time.Time {
+	return s.Epoch.Add(time.Duration(s.ticks) * s.Tick)
+}
@@ -0,0 +1,59 @@
+package io
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestSchedulerRatesAndOrder(t *testing.T) {
+	var trace []string
+	loop := func(name string) *LoopRunner {
+		var s pctl.Setpoint
+		return &LoopRunner{
+			Sensor:     SensorFunc(func() (float64, time.Time, error) { trace = append(trace, name); return 0, time.Time{}, nil }),
+			Controller: &s,
+			Actuator:   ActuatorFunc(func(float64) error { return nil }),
+		}
+	}
+	s := Scheduler{Tick: time.Millisecond, Tasks: []Task{
+		{Loop: loop("inner")},
+		{Loop: loop("outer"), Divider: 3, Phase: 1},
+	}}
+	for i := 0; i < 6; i++ {
+		if err := s.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := []string{"inner", "inner", "outer", "inner", "inner", "inner", "outer", "inner"}
+	if len(trace) != len(exp) {
+		t.Fatalf("expected %v, got %v", exp, trace)
+	}
+	for i := range exp {
+		if trace[i] != exp[i] {
+			t.Fatalf("expected %v, got %v", exp, trace)
+		}
+	}
+	if s.Now() != s.Epoch.Add(6*time.Millisecond) {
+		t.Errorf("clock reads %v after 6 ticks", s.Now().Sub(s.Epoch))
+	}
+}
+
+func TestSchedulerOnError(t *testing.T) {
+	bad := errors.New("stale")
+	var failed []int
+	s := Scheduler{
+		Tasks: []Task{
+			{Loop: &LoopRunner{Sensor: SensorFunc(func() (float64, time.Time, error) { return 0, time.Time{}, bad })}},
+		},
+		OnError: func(i int, err error) { failed = append(failed, i) },
+	}
+	if err := s.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0] != 0 {
+		t.Errorf("expected OnError for task 0, got %v", failed)
+	}
+}
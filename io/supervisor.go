@@ -0,0 +1,234 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDuplicateLoop is returned when adding a loop under a name already in use
+var ErrDuplicateLoop = errors.New("io: duplicate loop name")
+
+// LoopState is the run state of a supervised loop
+type LoopState int
+
+const (
+	// Stopped loops are not running
+	Stopped LoopState = iota
+
+	// Running loops are stepped every period
+	Running
+
+	// Paused loops keep their schedule but skip their steps
+	Paused
+)
+
+// String implements fmt.Stringer
+func (s LoopState) String() string {
+	switch s {
+	case Stopped:
+		return "stopped"
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	}
+	return "unknown"
+}
+
+// LoopStatus is a snapshot of the health of a supervised loop
+type LoopStatus struct {
+	Name  string
+	State LoopState
+
+	// Steps is the number of steps attempted
+	Steps uint64
+
+	// Faults is the number of steps which returned an error, and LastErr the
+	// most recent of them
+	Faults  uint64
+	LastErr error
+
+	// Overruns is the number of steps which took longer than the loop's
+	// Period
+	Overruns uint64
+
+	// LastOK is the time of the last step which succeeded
+	LastOK time.Time
+
+	// Stale is true if the loop is running and has not succeeded within the
+	// supervisor's StaleAfter, counted from when it started if it has never
+	// succeeded, e.g. because its first step hangs
+	Stale bool
+}
+
+// Supervisor owns a set of named LoopRunners, running each in its own
+// goroutine with aggregate start, stop, and pause, and collects their health.
+// Errors from a loop are counted and passed to its OnError, if any; they do
+// not stop it.  A Supervisor is safe for concurrent use.
+type Supervisor struct {
+	// StaleAfter is the time without a successful step after which a
+	// running loop is reported stale.  If zero, it is three periods.
+	StaleAfter time.Duration
+
+	mu     sync.Mutex
+	loops  map[string]*supervised
+	paused bool
+}
+
+type supervised struct {
+	loop    *LoopRunner
+	cancel  context.CancelFunc
+	done    chan struct{}
+	started time.Time
+	status  LoopStatus
+}
+
+// NewSupervisor returns an empty supervisor
+func NewSupervisor() *Supervisor {
+	return &Supervisor{loops: map[string]*supervised{}}
+}
+
+// Add places a loop under supervision.  It is started by the next call to
+// Start.
+func (s *Supervisor) Add(name string, l *LoopRunner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.loops[name]; ok {
+		return ErrDuplicateLoop
+	}
+	s.loops[name] = &supervised{loop: l, status: LoopStatus{Name: name}}
+	return nil
+}
+
+// Start starts every loop which is not running.  The loops stop when ctx is
+// done or Stop is called.
+func (s *Supervisor) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sl := range s.loops {
+		if sl.status.State != Stopped {
+			continue
+		}
+		var lctx context.Context
+		lctx, sl.cancel = context.WithCancel(ctx)
+		sl.done = make(chan struct{})
+		sl.started = time.Now()
+		sl.status.State = Running
+		if s.paused {
+			sl.status.State = Paused
+		}
+		go s.run(lctx, sl)
+	}
+}
+
+// run steps sl every period until ctx is done
+func (s *Supervisor) run(ctx context.Context, sl *supervised) {
+	defer close(sl.done)
+	l := sl.loop
+	tick := time.NewTicker(l.Period)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			sl.status.State = Stopped
+			s.mu.Unlock()
+			return
+		case <-tick.C:
+		}
+		s.mu.Lock()
+		paused := sl.status.State == Paused
+		s.mu.Unlock()
+		if paused {
+			continue
+		}
+		start := time.Now()
+		err := l.Step()
+		took := time.Since(start)
+
+		s.mu.Lock()
+		st := &sl.status
+		st.Steps++
+		if took > l.Period {
+			st.Overruns++
+		}
+		if err != nil {
+			st.Faults++
+			st.LastErr = err
+		} else {
+			st.LastOK = start
+		}
+		s.mu.Unlock()
+		if err != nil && l.OnError != nil {
+			l.OnError(err)
+		}
+	}
+}
+
+// Stop stops every loop and waits for them to exit
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	var wait []chan struct{}
+	for _, sl := range s.loops {
+		if sl.cancel != nil {
+			sl.cancel()
+			sl.cancel = nil
+			wait = append(wait, sl.done)
+		}
+	}
+	s.mu.Unlock()
+	for _, done := range wait {
+		<-done
+	}
+}
+
+// Pause suspends stepping of every running loop
+func (s *Supervisor) Pause() {
+	s.setPaused(true)
+}
+
+// Resume resumes stepping of every paused loop
+func (s *Supervisor) Resume() {
+	s.setPaused(false)
+}
+
+func (s *Supervisor) setPaused(p bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = p
+	from, to := Running, Paused
+	if !p {
+		from, to = Paused, Running
+	}
+	for _, sl := range s.loops {
+		if sl.status.State == from {
+			sl.status.State = to
+		}
+	}
+}
+
+// Status returns a snapshot of the health of every loop, sorted by name
+func (s *Supervisor) Status() []LoopStatus {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]LoopStatus, 0, len(s.loops))
+	for _, sl := range s.loops {
+		st := sl.status
+		stale := s.StaleAfter
+		if stale == 0 {
+			stale = 3 * sl.loop.Period
+		}
+		since := st.LastOK
+		if since.Before(sl.started) {
+			since = sl.started
+		}
+		st.Stale = st.State == Running && now.Sub(since) > stale
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,78 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestSupervisorHealth(t *testing.T) {
+	bad := errors.New("no carrier")
+	var s0, s1 pctl.Setpoint
+	nop := ActuatorFunc(func(float64) error { return nil })
+	sup := NewSupervisor()
+	sup.StaleAfter = 5 * time.Millisecond
+	sup.Add("good", &LoopRunner{
+		Sensor:     SensorFunc(func() (float64, time.Time, error) { return 0, time.Now(), nil }),
+		Controller: &s0, Actuator: nop, Period: time.Millisecond,
+	})
+	sup.Add("bad", &LoopRunner{
+		Sensor:     SensorFunc(func() (float64, time.Time, error) { return 0, time.Time{}, bad }),
+		Controller: &s1, Actuator: nop, Period: time.Millisecond,
+	})
+	if err := sup.Add("good", &LoopRunner{}); !errors.Is(err, ErrDuplicateLoop) {
+		t.Errorf("expected ErrDuplicateLoop, got %v", err)
+	}
+	sup.Start(context.Background())
+	time.Sleep(30 * time.Millisecond)
+	st := sup.Status()
+	if st[0].Name != "bad" || st[1].Name != "good" {
+		t.Fatalf("status not sorted by name: %v", st)
+	}
+	if st[0].Faults == 0 || !errors.Is(st[0].LastErr, bad) || !st[0].Stale {
+		t.Errorf("failing loop not reported faulted and stale: %+v", st[0])
+	}
+	if st[1].Faults != 0 || st[1].Steps == 0 || st[1].State != Running {
+		t.Errorf("healthy loop reported unhealthy: %+v", st[1])
+	}
+
+	sup.Pause()
+	steps := sup.Status()[1].Steps
+	time.Sleep(10 * time.Millisecond)
+	if st := sup.Status()[1]; st.State != Paused || st.Steps > steps+1 {
+		t.Errorf("paused loop kept stepping: %+v", st)
+	}
+	sup.Stop()
+	for _, st := range sup.Status() {
+		if st.State != Stopped {
+			t.Errorf("loop %s not stopped: %v", st.Name, st.State)
+		}
+	}
+}
+
+func TestSupervisorHungFirstStepIsStale(t *testing.T) {
+	hang := make(chan struct{})
+	var sp pctl.Setpoint
+	sup := NewSupervisor()
+	sup.StaleAfter = 5 * time.Millisecond
+	sup.Add("hung", &LoopRunner{
+		Sensor: SensorFunc(func() (float64, time.Time, error) {
+			<-hang
+			return 0, time.Now(), nil
+		}),
+		Controller: &sp, Actuator: ActuatorFunc(func(float64) error { return nil }), Period: time.Millisecond,
+	})
+	sup.Start(context.Background())
+	if st := sup.Status()[0]; st.Stale {
+		t.Errorf("loop reported stale as it started: %+v", st)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if st := sup.Status()[0]; st.Steps != 0 || !st.Stale {
+		t.Errorf("loop hung in its first step not reported stale: %+v", st)
+	}
+	close(hang)
+	sup.Stop()
+}
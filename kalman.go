@@ -0,0 +1,122 @@
+package pctl
+
+import "time"
+
+// kalmanInitVar is the initial variance of the velocity estimate, large enough
+// to be uninformative
+const kalmanInitVar = 1e12
+
+// Kalman is a constant velocity Kalman filter.  It estimates the value and
+// rate of change of a signal from noisy measurements of the value, which may
+// arrive at irregular intervals: the predict step uses the actual time elapsed
+// since the previous measurement.
+//
+// The model is a value driven by white noise acceleration of spectral density
+// Q, measured with noise of variance R.  The first measurement initializes the
+// value estimate; the rate is learned from the following measurements.
+type Kalman struct {
+	// Q is the spectral density of the process noise (acceleration), in
+	// units^2/s^3.  Larger values track maneuvers faster, but filter less.
+	Q float64
+
+	// R is the variance of the measurement noise, in units^2
+	R float64
+
+	// DT is the inter-update time in seconds used by Update
+	DT float64
+
+	x       [2]float64
+	p       [2][2]float64
+	last    time.Time
+	started bool
+}
+
+// NewKalman returns a new Kalman filter with the given process noise density
+// q, measurement noise variance r, and nominal inter-update time dt
+func NewKalman(q, r, dt float64) *Kalman {
+	return &Kalman{Q: q, R: r, DT: dt}
+}
+
+// Update processes a measurement taken DT after the previous one, returning
+// the estimated value
+func (k *Kalman) Update(z float64) float64 {
+	return k.UpdateDT(z, k.DT)
+}
+
+// UpdateAt processes a measurement taken at time t, returning the estimated
+// value.  The interval is computed from the timestamp of the previous call to
+// UpdateAt.
+func (k *Kalman) UpdateAt(z float64, t time.Time) float64 {
+	var dt float64
+	if k.started && !k.last.IsZero() {
+		dt = t.Sub(k.last).Seconds()
+	}
+	k.last = t
+	return k.UpdateDT(z, dt)
+}
+
+// UpdateDT processes a measurement taken dt seconds after the previous one,
+// returning the estimated value
+func (k *Kalman) UpdateDT(z, dt float64) float64 {
+	if !k.started {
+		k.started = true
+		k.x = [2]float64{z, 0}
+		k.p = [2][2]float64{{k.R, 0}, {0, kalmanInitVar}}
+		return z
+	}
+	k.Predict(dt)
+	// correct; H = [1 0]
+	s := k.p[0][0] + k.R
+	k0 := k.p[0][0] / s
+	k1 := k.p[1][0] / s
+	innov := z - k.x[0]
+	k.x[0] += k0 * innov
+	k.x[1] += k1 * innov
+	p00, p01, p11 := k.p[0][0], k.p[0][1], k.p[1][1]
+	k.p[0][0] = (1 - k0) * p00
+	k.p[0][1] = (1 - k0) * p01
+	k.p[1][0] = k.p[0][1]
+	k.p[1][1] = p11 - k1*p01
+	return k.x[0]
+}
+
+// Predict advances the estimate dt seconds without a measurement, returning
+// the predicted value.  It may be used to coast through dropouts.
+func (k *Kalman) Predict(dt float64) float64 {
+	if dt <= 0 {
+		return k.x[0]
+	}
+	// F = [1 dt; 0 1], Q integrated over the interval
+	k.x[0] += dt * k.x[1]
+	p00, p01, p11 := k.p[0][0], k.p[0][1], k.p[1][1]
+	dt2 := dt * dt
+	q := k.Q
+	k.p[0][0] = p00 + dt*(2*p01+dt*p11) + q*dt2*dt/3
+	k.p[0][1] = p01 + dt*p11 + q*dt2/2
+	k.p[1][0] = k.p[0][1]
+	k.p[1][1] = p11 + q*dt
+	return k.x[0]
+}
+
+// Value returns the estimated value
+func (k *Kalman) Value() float64 {
+	return k.x[0]
+}
+
+// Rate returns the estimated rate of change of the value, per second
+func (k *Kalman) Rate() float64 {
+	return k.x[1]
+}
+
+// Variance returns the variance of the value estimate
+func (k *Kalman) Variance() float64 {
+	return k.p[0][0]
+}
+
+// Reset returns the filter to its initial state; the next measurement
+// initializes it
+func (k *Kalman) Reset() {
+	k.started = false
+	k.x = [2]float64{}
+	k.p = [2][2]float64{}
+}
@@ -0,0 +1,44 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestKalmanTracksRampWithIrregularSamples(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	k := NewKalman(1e-4, 0.01, 0)
+	t0 := time.Unix(0, 0)
+	var tt float64
+	const slope = 2.
+	var sqErr, sqNoise float64
+	for i := 0; i < 2000; i++ {
+		tt += 0.02 + 0.02*r.Float64() // 20-40ms spacing
+		noise := 0.1 * r.NormFloat64()
+		est := k.UpdateAt(slope*tt+noise, t0.Add(time.Duration(tt*float64(time.Second))))
+		if i > 1000 {
+			sqErr += math.Pow(est-slope*tt, 2)
+			sqNoise += noise * noise
+		}
+	}
+	if sqErr >= sqNoise/4 {
+		t.Errorf("estimate error power %f not well below measurement noise power %f", sqErr, sqNoise)
+	}
+	if !approxEqualAbs(k.Rate(), slope, 0.05) {
+		t.Errorf("estimated rate %f, expected %f", k.Rate(), slope)
+	}
+}
+
+func TestKalmanPredictCoasts(t *testing.T) {
+	k := NewKalman(1e-3, 1e-6, 0.1)
+	for i := 0; i < 50; i++ {
+		k.Update(float64(i) * 0.1) // rate of 1/s
+	}
+	before := k.Value()
+	pred := k.Predict(1)
+	if !approxEqualAbs(pred-before, 1, 1e-3) {
+		t.Errorf("expected to coast forward by 1, moved %f", pred-before)
+	}
+}
@@ -0,0 +1,152 @@
+package pctl
+
+import "time"
+
+// Kinematic is a constant acceleration Kalman filter.  It estimates the
+// position, velocity, and acceleration of a signal from noisy measurements of
+// position, which may arrive at irregular intervals, for feedforward and
+// derivative terms that need clean higher derivatives.
+//
+// The model is a position driven by white noise jerk of spectral density Q,
+// measured with noise of variance R.  The first measurement initializes the
+// position; velocity and acceleration are learned from the following
+// measurements.
+type Kinematic struct {
+	// Q is the spectral density of the process noise (jerk), in
+	// units^2/s^5.  Larger values track changes in acceleration faster, but
+	// filter less.
+	Q float64
+
+	// R is the variance of the measurement noise, in units^2
+	R float64
+
+	// DT is the inter-update time in seconds used by Update
+	DT float64
+
+	x       [3]float64
+	p       [3][3]float64
+	last    time.Time
+	started bool
+}
+
+// NewKinematic returns a new Kinematic filter with the given process noise
+// density q, measurement noise variance r, and nominal inter-update time dt
+func NewKinematic(q, r, dt float64) *Kinematic {
+	return &Kinematic{Q: q, R: r, DT: dt}
+}
+
+// Update processes a measurement taken DT after the previous one, returning
+// the estimated position
+func (k *Kinematic) Update(z float64) float64 {
+	return k.UpdateDT(z, k.DT)
+}
+
+// UpdateAt processes a measurement taken at time t, returning the estimated
+// position.  The interval is computed from the timestamp of the previous call
+// to UpdateAt.
+func (k *Kinematic) UpdateAt(z float64, t time.Time) float64 {
+	var dt float64
+	if k.started {
+		dt = t.Sub(k.last).Seconds()
+	}
+	k.last = t
+	return k.UpdateDT(z, dt)
+}
+
+// UpdateDT processes a measurement taken dt seconds after the previous one,
+// returning the estimated position
+func (k *Kinematic) UpdateDT(z, dt float64) float64 {
+	if !k.started {
+		k.started = true
+		k.x = [3]float64{z, 0, 0}
+		k.p = [3][3]float64{{k.R, 0, 0}, {0, kalmanInitVar, 0}, {0, 0, kalmanInitVar}}
+		return z
+	}
+	k.Predict(dt)
+	// correct; H = [1 0 0], so the gain is the first column of P over S
+	s := k.p[0][0] + k.R
+	var g [3]float64
+	for i := range g {
+		g[i] = k.p[i][0] / s
+	}
+	innov := z - k.x[0]
+	for i := range k.x {
+		k.x[i] += g[i] * innov
+	}
+	// P = (I - GH) P, where row 0 of P is subtracted scaled by each gain
+	row := k.p[0]
+	for i := range k.p {
+		for j := range k.p[i] {
+			k.p[i][j] -= g[i] * row[j]
+		}
+	}
+	return k.x[0]
+}
+
+// Predict advances the estimate dt seconds without a measurement, returning
+// the predicted position.  It may be used to coast through dropouts.
+func (k *Kinematic) Predict(dt float64) float64 {
+	if dt <= 0 {
+		return k.x[0]
+	}
+	dt2 := dt * dt
+	f := [3][3]float64{{1, dt, dt2 / 2}, {0, 1, dt}, {0, 0, 1}}
+	k.x[0] += dt*k.x[1] + dt2/2*k.x[2]
+	k.x[1] += dt * k.x[2]
+	// P = F P F' + Q, with Q integrated over the interval
+	var fp, p [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for m := 0; m < 3; m++ {
+				fp[i][j] += f[i][m] * k.p[m][j]
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for m := 0; m < 3; m++ {
+				p[i][j] += fp[i][m] * f[j][m]
+			}
+		}
+	}
+	q := k.Q
+	dt3 := dt2 * dt
+	p[0][0] += q * dt3 * dt2 / 20
+	p[0][1] += q * dt2 * dt2 / 8
+	p[0][2] += q * dt3 / 6
+	p[1][1] += q * dt3 / 3
+	p[1][2] += q * dt2 / 2
+	p[2][2] += q * dt
+	p[1][0], p[2][0], p[2][1] = p[0][1], p[0][2], p[1][2]
+	k.p = p
+	return k.x[0]
+}
+
+// Position returns the estimated position
+func (k *Kinematic) Position() float64 {
+	return k.x[0]
+}
+
+// Velocity returns the estimated velocity, per second
+func (k *Kinematic) Velocity() float64 {
+	return k.x[1]
+}
+
+// Acceleration returns the estimated acceleration, per second squared
+func (k *Kinematic) Acceleration() float64 {
+	return k.x[2]
+}
+
+// Variance returns the variances of the position, velocity, and acceleration
+// estimates
+func (k *Kinematic) Variance() (pos, vel, acc float64) {
+	return k.p[0][0], k.p[1][1], k.p[2][2]
+}
+
+// Reset returns the filter to its initial state; the next measurement
+// initializes it
+func (k *Kinematic) Reset() {
+	k.started = false
+	k.x = [3]float64{}
+	k.p = [3][3]float64{}
+}
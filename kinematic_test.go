@@ -0,0 +1,44 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestKinematicTracksParabola(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const dt = 1e-3
+	const acc = 3.
+	k := NewKinematic(1e-2, 1e-6, dt)
+	var tt float64
+	for i := 0; i < 5000; i++ {
+		tt = float64(i) * dt
+		k.Update(acc/2*tt*tt + 1e-3*r.NormFloat64())
+	}
+	if !approxEqualAbs(k.Velocity(), acc*tt, 0.05) {
+		t.Errorf("estimated velocity %f, expected %f", k.Velocity(), acc*tt)
+	}
+	if !approxEqualAbs(k.Acceleration(), acc, 0.1) {
+		t.Errorf("estimated acceleration %f, expected %f", k.Acceleration(), acc)
+	}
+}
+
+func TestKinematicIrregularSamples(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	k := NewKinematic(1e-3, 1e-8, 0)
+	t0 := time.Unix(0, 0)
+	var tt float64
+	for i := 0; i < 2000; i++ {
+		tt += 0.01 + 0.01*r.Float64()
+		k.UpdateAt(2*tt*tt-tt, t0.Add(time.Duration(tt*float64(time.Second))))
+	}
+	if !approxEqualAbs(k.Velocity(), 4*tt-1, 1e-2) || !approxEqualAbs(k.Acceleration(), 4, 1e-2) {
+		t.Errorf("expected velocity %f and acceleration 4, got %f and %f", 4*tt-1, k.Velocity(), k.Acceleration())
+	}
+	pos, vel, acc := k.Position(), k.Velocity(), k.Acceleration()
+	if got := k.Predict(0.5) - pos; math.Abs(got-(vel*0.5+acc*0.125)) > 1e-9 {
+		t.Errorf("prediction moved %f, not along the estimated trajectory", got)
+	}
+}
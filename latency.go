@@ -0,0 +1,103 @@
+package pctl
+
+import "math"
+
+// LatencyEstimator continuously estimates the delay between a command and the
+// measured response to it, from the peak of their cross correlation, for
+// diagnosing loop latency and tuning delay compensation.
+//
+// The cross correlation is at lags of 0 to MaxLag samples, with the means
+// removed, and averaged with exponential forgetting so that the estimate
+// follows a latency which changes.  The peak is located to a fraction of a
+// sample by fitting a parabola through it and its neighbours.  The command
+// must be exciting enough to correlate; a constant setpoint is not.
+type LatencyEstimator struct {
+	// DT is the inter-update time in seconds
+	DT float64
+
+	// Smoothing is the time constant of the forgetting, in seconds
+	Smoothing float64
+
+	hist    []float64 // command history, a ring
+	head    int
+	n       int
+	r       []float64 // cross correlation by lag
+	mc, mm  float64   // means of the command and measurement
+	started bool
+	lag     float64
+}
+
+// NewLatencyEstimator returns a new latency estimator over delays of up to
+// maxLag samples, forgetting with a time constant of smoothing seconds
+func NewLatencyEstimator(maxLag int, smoothing, dt float64) *LatencyEstimator {
+	return &LatencyEstimator{
+		DT:        dt,
+		Smoothing: smoothing,
+		hist:      make([]float64, maxLag+1),
+		r:         make([]float64, maxLag+1),
+	}
+}
+
+// Update adds a sample of the command and the measurement and returns the
+// estimated latency in seconds
+func (l *LatencyEstimator) Update(cmd, meas float64) float64 {
+	alpha := 1.
+	if l.Smoothing > 0 {
+		alpha = 1 - math.Exp(-l.DT/l.Smoothing)
+	}
+	if !l.started {
+		l.mc, l.mm, l.started = cmd, meas, true
+	}
+	l.mc += alpha * (cmd - l.mc)
+	l.mm += alpha * (meas - l.mm)
+	c := len(l.hist)
+	l.head = (l.head + c - 1) % c
+	l.hist[l.head] = cmd - l.mc
+	if l.n < c {
+		l.n++
+	}
+	m := meas - l.mm
+	best := 0
+	for k := 0; k < len(l.r); k++ {
+		var v float64
+		if k < l.n {
+			v = l.hist[(l.head+k)%c]
+		}
+		l.r[k] += alpha * (m*v - l.r[k])
+		if l.r[k] > l.r[best] {
+			best = k
+		}
+	}
+	l.lag = float64(best)
+	if best > 0 && best < len(l.r)-1 {
+		y0, y1, y2 := l.r[best-1], l.r[best], l.r[best+1]
+		if d := y0 - 2*y1 + y2; d < 0 {
+			l.lag += 0.5 * (y0 - y2) / d
+		}
+	}
+	return l.lag * l.DT
+}
+
+// Samples returns the estimated latency in samples
+func (l *LatencyEstimator) Samples() float64 {
+	return l.lag
+}
+
+// Latency returns the estimated latency in seconds
+func (l *LatencyEstimator) Latency() float64 {
+	return l.lag * l.DT
+}
+
+// Correlation returns the cross correlation of the measurement with the
+// command, by lag in samples.  The slice must not be modified.
+func (l *LatencyEstimator) Correlation() []float64 {
+	return l.r
+}
+
+// Reset forgets the history and the estimate
+func (l *LatencyEstimator) Reset() {
+	for i := range l.hist {
+		l.hist[i], l.r[i] = 0, 0
+	}
+	l.head, l.n, l.mc, l.mm, l.started, l.lag = 0, 0, 0, 0, false, 0
+}
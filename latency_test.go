@@ -0,0 +1,29 @@
+package pctl
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLatencyEstimatorFindsDelay(t *testing.T) {
+	// random command, measured through a 7 sample delay, a light low pass
+	// and noise
+	const dt, delay = 1e-3, 7
+	l := NewLatencyEstimator(20, 2, dt)
+	r := rand.New(rand.NewSource(5))
+	lpf := NewLPF(200, dt)
+	line := make([]float64, delay)
+	for n := 0; n < 20000; n++ {
+		cmd := r.NormFloat64()
+		meas := lpf.Update(line[n%delay]) + 0.1*r.NormFloat64()
+		line[n%delay] = cmd
+		l.Update(cmd, meas)
+	}
+	// the low pass adds its own half a sample or so of group delay
+	if s := l.Samples(); s < delay || s > delay+1.5 {
+		t.Errorf("expected a latency of %d to %.1f samples, got %f", delay, delay+1.5, s)
+	}
+	if !approxEqualAbs(l.Latency(), l.Samples()*dt, 1e-15) {
+		t.Errorf("expected the latency in seconds to be samples*DT")
+	}
+}
@@ -0,0 +1,106 @@
+package pctl
+
+// LMS is an adaptive FIR filter whose taps converge online by the least mean
+// squares algorithm, the basis of adaptive feedforward disturbance
+// cancellation.
+//
+// Update filters an input with the current taps.  The taps then adapt to the
+// error of that output through Adapt, or in one step with UpdateDesired when a
+// desired signal is available.  The error sign convention is e = desired -
+// output.
+type LMS struct {
+	// Mu is the step size.  Larger steps converge faster but are noisier, and
+	// the filter diverges if Mu is too large for the input power; Normalized
+	// removes that dependence.
+	Mu float64
+
+	// Leak is the leakage factor, which decays the taps towards zero by
+	// Mu*Leak of their value on each adaptation.  It keeps the taps bounded
+	// when the input does not excite all frequencies.  If zero, the filter
+	// does not leak.
+	Leak float64
+
+	// Normalized divides the step by the power in the tap history (NLMS),
+	// making convergence independent of the input level; Mu is then between
+	// zero and two
+	Normalized bool
+
+	w []float64
+
+	// x is the input history, newest first, kept twice in sequence so that
+	// it is read contiguously from j
+	x []float64
+	j int
+}
+
+// NewLMS returns a new adaptive filter with the given number of taps,
+// initially zero, and step size mu.  taps < 1 is treated as 1.
+func NewLMS(taps int, mu float64) *LMS {
+	if taps < 1 {
+		taps = 1
+	}
+	return &LMS{Mu: mu, w: make([]float64, taps), x: make([]float64, 2*taps)}
+}
+
+// history returns the input history, newest first
+func (l *LMS) history() []float64 {
+	return l.x[l.j : l.j+len(l.w)]
+}
+
+// Update processes an input value, returning the filtered output
+func (l *LMS) Update(input float64) float64 {
+	n := len(l.w)
+	if l.j--; l.j < 0 {
+		l.j = n - 1
+	}
+	l.x[l.j] = input
+	l.x[l.j+n] = input
+	var out float64
+	for i, x := range l.history() {
+		out += l.w[i] * x
+	}
+	return out
+}
+
+// Adapt adjusts the taps by the error err of the last output
+func (l *LMS) Adapt(err float64) {
+	x := l.history()
+	mu := l.Mu
+	if l.Normalized {
+		const eps = 1e-12
+		var p float64
+		for _, v := range x {
+			p += v * v
+		}
+		mu /= p + eps
+	}
+	decay := 1 - l.Mu*l.Leak
+	for i := range l.w {
+		l.w[i] = decay*l.w[i] + mu*err*x[i]
+	}
+}
+
+// UpdateDesired filters input, adapts to the error from desired, and returns
+// the output before adaptation and the error
+func (l *LMS) UpdateDesired(input, desired float64) (output, err float64) {
+	output = l.Update(input)
+	err = desired - output
+	l.Adapt(err)
+	return output, err
+}
+
+// Taps returns the current taps.  They must not be modified while the filter
+// is in use.
+func (l *LMS) Taps() []float64 {
+	return l.w
+}
+
+// Reset zeros the taps and the input history
+func (l *LMS) Reset() {
+	for i := range l.w {
+		l.w[i] = 0
+	}
+	for i := range l.x {
+		l.x[i] = 0
+	}
+}
@@ -0,0 +1,59 @@
+package pctl
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLMSIdentifiesSystem(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	plant := NewFIRFilter([]float64{0.5, -0.3, 0.2, 0.1})
+	for _, norm := range []bool{false, true} {
+		plant.Reset()
+		l := NewLMS(6, 0.05)
+		l.Normalized = norm
+		if norm {
+			l.Mu = 0.5
+		}
+		var err float64
+		for i := 0; i < 5000; i++ {
+			x := r.NormFloat64()
+			_, err = l.UpdateDesired(x, plant.Update(x))
+		}
+		if !approxEqualAbs(err, 0, 1e-6) {
+			t.Errorf("normalized=%v: residual error %g", norm, err)
+		}
+		expect := []float64{0.5, -0.3, 0.2, 0.1, 0, 0}
+		for i, w := range l.Taps() {
+			if !approxEqualAbs(w, expect[i], 1e-6) {
+				t.Errorf("normalized=%v: tap %d converged to %f, expected %f", norm, i, w, expect[i])
+			}
+		}
+	}
+}
+
+func TestLMSLeakDecaysTaps(t *testing.T) {
+	l := NewLMS(2, 0.1)
+	l.Leak = 1
+	l.UpdateDesired(1, 1)
+	w0 := l.Taps()[0]
+	for i := 0; i < 100; i++ {
+		l.Update(0)
+		l.Adapt(0)
+	}
+	if !(l.Taps()[0] < w0*0.01) {
+		t.Errorf("leak did not decay the tap: %f from %f", l.Taps()[0], w0)
+	}
+}
+
+func TestLMSNoTapsTreatedAsOne(t *testing.T) {
+	for _, n := range []int{0, -3} {
+		l := NewLMS(n, 0.1)
+		if out, _ := l.UpdateDesired(1, 1); out != 0 {
+			t.Errorf("%d taps: expected the first output from zero taps, got %f", n, out)
+		}
+		if len(l.w) != 1 {
+			t.Errorf("%d taps: expected 1 tap, got %d", n, len(l.w))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package logic
+
+// Debounce filters contact bounce from a discrete input; its output only
+// changes once the input has held a new value for Time
+type Debounce struct {
+	// Time is the time the input must be stable, in seconds
+	Time float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	out bool
+	n   int
+}
+
+// Update processes an input value, returning the debounced output
+func (d *Debounce) Update(in bool) bool {
+	if in == d.out {
+		d.n = 0
+		return d.out
+	}
+	if d.n++; d.n >= updates(d.Time, d.DT) {
+		d.out = in
+		d.n = 0
+	}
+	return d.out
+}
+
+// Edge polarities detected by EdgeDetect
+const (
+	Rising = 1 << iota
+	Falling
+	Both = Rising | Falling
+)
+
+// EdgeDetect is true for the single update on which its input changes with
+// the chosen polarity
+type EdgeDetect struct {
+	// Edges is Rising, Falling, or Both
+	Edges int
+
+	prev    bool
+	started bool
+}
+
+// Update processes an input value, returning true on an edge.  The first
+// update is not an edge.
+func (e *EdgeDetect) Update(in bool) bool {
+	prev := e.prev
+	e.prev = in
+	if !e.started {
+		e.started = true
+		return false
+	}
+	return (e.Edges&Rising != 0 && in && !prev) ||
+		(e.Edges&Falling != 0 && !in && prev)
+}
+
+// PulseStretch holds its output on for at least Width after the input turns
+// on, so that short pulses are not missed by slower logic.  The output stays
+// on for as long as the input does.
+type PulseStretch struct {
+	// Width is the minimum pulse width in seconds
+	Width float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	n int
+}
+
+// Update processes an input value, returning the stretched output
+func (p *PulseStretch) Update(in bool) bool {
+	if in {
+		p.n = updates(p.Width, p.DT)
+		return true
+	}
+	if p.n > 0 {
+		p.n--
+	}
+	return p.n > 0
+}
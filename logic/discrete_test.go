@@ -0,0 +1,42 @@
+package logic
+
+import "testing"
+
+func run(u Updater, in ...bool) []bool {
+	out := make([]bool, len(in))
+	for i, b := range in {
+		out[i] = u.Update(b)
+	}
+	return out
+}
+
+func equal(a, b []bool) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestDebounce(t *testing.T) {
+	d := &Debounce{Time: 0.25, DT: 0.1}
+	got := run(d, true, false, true, true, true, true)
+	exp := []bool{false, false, false, false, true, true}
+	if !equal(got, exp) {
+		t.Errorf("expected %v, got %v", exp, got)
+	}
+}
+
+func TestEdgeDetectAndPulseStretch(t *testing.T) {
+	got := run(&EdgeDetect{Edges: Both}, true, true, false, false, true)
+	exp := []bool{false, false, true, false, true}
+	if !equal(got, exp) {
+		t.Errorf("edge detect: expected %v, got %v", exp, got)
+	}
+	got = run(&PulseStretch{Width: 0.3, DT: 0.1}, true, false, false, false, false)
+	exp = []bool{true, true, true, false, false}
+	if !equal(got, exp) {
+		t.Errorf("pulse stretch: expected %v, got %v", exp, got)
+	}
+}
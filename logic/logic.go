@@ -0,0 +1,144 @@
+/*
+Package logic provides boolean signal blocks for interlocks and permissives,
+such as "only enable the heater if the flow switch has been on for 2 s".
+
+Timers count updates of a fixed inter-update time DT, like the blocks of
+package pctl.  Float wraps a block as a pctl.Updater on 0/1 signals, so that
+it can be placed in a Cascade or a configuration.
+*/
+package logic
+
+import "math"
+
+// Updater is the boolean counterpart of pctl.Updater
+type Updater interface {
+	Update(bool) bool
+}
+
+// updates returns the number of updates of dt in t seconds, rounded up so
+// that delays are never shorter than asked for
+func updates(t, dt float64) int {
+	if dt <= 0 {
+		return 0
+	}
+	return int(math.Ceil(t/dt - 1e-9))
+}
+
+// And returns true if every input is true
+func And(in ...bool) bool {
+	for _, b := range in {
+		if !b {
+			return false
+		}
+	}
+	return true
+}
+
+// Or returns true if any input is true
+func Or(in ...bool) bool {
+	for _, b := range in {
+		if b {
+			return true
+		}
+	}
+	return false
+}
+
+// Not inverts its input
+type Not struct{}
+
+// Update returns !in
+func (Not) Update(in bool) bool {
+	return !in
+}
+
+// Latch is a set-reset flip flop
+type Latch struct {
+	// SetDominant makes set win when set and reset are both true; by default
+	// reset wins, the fail safe choice for interlocks
+	SetDominant bool
+
+	q bool
+}
+
+// Update applies set and reset and returns the latched value
+func (l *Latch) Update(set, reset bool) bool {
+	switch {
+	case set && reset:
+		l.q = l.SetDominant
+	case set:
+		l.q = true
+	case reset:
+		l.q = false
+	}
+	return l.q
+}
+
+// Output returns the latched value
+func (l *Latch) Output() bool {
+	return l.q
+}
+
+// OnDelay is an on delay timer (TON); its output turns on once its input has
+// been on continuously for Delay, and turns off with the input
+type OnDelay struct {
+	// Delay is the on delay in seconds
+	Delay float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	n int
+}
+
+// Update processes an input value, returning the delayed output
+func (d *OnDelay) Update(in bool) bool {
+	if !in {
+		d.n = 0
+		return false
+	}
+	lim := updates(d.Delay, d.DT)
+	if d.n < lim {
+		d.n++
+	}
+	return d.n >= lim
+}
+
+// OffDelay is an off delay timer (TOF); its output turns on with its input,
+// and turns off once the input has been off continuously for Delay
+type OffDelay struct {
+	// Delay is the off delay in seconds
+	Delay float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	n int
+}
+
+// Update processes an input value, returning the delayed output
+func (d *OffDelay) Update(in bool) bool {
+	if in {
+		d.n = 0
+		return true
+	}
+	lim := updates(d.Delay, d.DT)
+	if d.n < lim {
+		d.n++
+	}
+	return d.n < lim
+}
+
+// Float adapts a boolean block to a pctl.Updater.  Inputs above 0.5 are
+// true, and the output is 1 for true and 0 for false.
+type Float struct {
+	B Updater
+}
+
+// Update implements pctl.Updater
+func (f Float) Update(input float64) float64 {
+	if f.B.Update(input > 0.5) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package logic
+
+import "testing"
+
+func TestOnDelayPermissive(t *testing.T) {
+	// heater enabled only after the flow switch is on for 2 s, at 10 Hz
+	ton := &OnDelay{Delay: 2, DT: 0.1}
+	flow := func(n int, on bool) (enabled bool) {
+		for i := 0; i < n; i++ {
+			enabled = And(ton.Update(on), true)
+		}
+		return enabled
+	}
+	if flow(15, true) {
+		t.Error("enabled before 2 s")
+	}
+	if flow(1, false) || flow(15, true) {
+		t.Error("the timer did not restart when the flow switch dropped out")
+	}
+	if !flow(6, true) {
+		t.Error("not enabled after 2 s")
+	}
+}
+
+func TestOffDelayAndLatch(t *testing.T) {
+	tof := &OffDelay{Delay: 0.3, DT: 0.1}
+	out := []bool{tof.Update(true), tof.Update(false), tof.Update(false), tof.Update(false)}
+	if !out[0] || !out[1] || !out[2] || out[3] {
+		t.Errorf("unexpected off delay sequence %v", out)
+	}
+	var l Latch
+	if !l.Update(true, false) || !l.Update(false, false) || l.Update(true, true) {
+		t.Error("latch should set, hold, and be reset dominant")
+	}
+}
+
+func TestFloat(t *testing.T) {
+	f := Float{Not{}}
+	if f.Update(1) != 0 || f.Update(0) != 1 {
+		t.Error("Float(Not) did not invert 0/1 signals")
+	}
+}
@@ -0,0 +1,114 @@
+package logic
+
+import "github.com/brandondube/pctl"
+
+// SaveState returns the timer's count of updates
+func (d *OnDelay) SaveState() []float64 {
+	return []float64{float64(d.n)}
+}
+
+// RestoreState restores the timer's count
+func (d *OnDelay) RestoreState(s []float64) error {
+	if len(s) != 1 || s[0] < 0 {
+		return pctl.ErrStateSize
+	}
+	d.n = int(s[0])
+	return nil
+}
+
+// SaveState returns the timer's count of updates
+func (d *OffDelay) SaveState() []float64 {
+	return []float64{float64(d.n)}
+}
+
+// RestoreState restores the timer's count
+func (d *OffDelay) RestoreState(s []float64) error {
+	if len(s) != 1 || s[0] < 0 {
+		return pctl.ErrStateSize
+	}
+	d.n = int(s[0])
+	return nil
+}
+
+// SaveState returns no state; Not is memoryless
+func (Not) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (Not) RestoreState(s []float64) error {
+	if len(s) != 0 {
+		return pctl.ErrStateSize
+	}
+	return nil
+}
+
+// SaveState returns the debouncer's output and count of updates
+func (d *Debounce) SaveState() []float64 {
+	return []float64{b2f(d.out), float64(d.n)}
+}
+
+// RestoreState restores the debouncer's state
+func (d *Debounce) RestoreState(s []float64) error {
+	if len(s) != 2 || s[1] < 0 {
+		return pctl.ErrStateSize
+	}
+	d.out, d.n = s[0] != 0, int(s[1])
+	return nil
+}
+
+// SaveState returns the detector's previous input
+func (e *EdgeDetect) SaveState() []float64 {
+	return []float64{b2f(e.prev), b2f(e.started)}
+}
+
+// RestoreState restores the detector's state
+func (e *EdgeDetect) RestoreState(s []float64) error {
+	if len(s) != 2 {
+		return pctl.ErrStateSize
+	}
+	e.prev, e.started = s[0] != 0, s[1] != 0
+	return nil
+}
+
+// SaveState returns the remaining updates of the pulse
+func (p *PulseStretch) SaveState() []float64 {
+	return []float64{float64(p.n)}
+}
+
+// RestoreState restores the remaining updates of the pulse
+func (p *PulseStretch) RestoreState(s []float64) error {
+	if len(s) != 1 || s[0] < 0 {
+		return pctl.ErrStateSize
+	}
+	p.n = int(s[0])
+	return nil
+}
+
+// Stateful returns true if B is pctl.Stateful, and so Float's state is B's
+func (f Float) Stateful() bool {
+	_, ok := f.B.(pctl.Stateful)
+	return ok
+}
+
+// SaveState returns the state of B, or nil if it is not pctl.Stateful
+func (f Float) SaveState() []float64 {
+	if st, ok := f.B.(pctl.Stateful); ok {
+		return st.SaveState()
+	}
+	return nil
+}
+
+// RestoreState restores the state of B
+func (f Float) RestoreState(s []float64) error {
+	st, ok := f.B.(pctl.Stateful)
+	if !ok {
+		return pctl.ErrStateSize
+	}
+	return st.RestoreState(s)
+}
+
+func b2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
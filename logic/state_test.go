@@ -0,0 +1,22 @@
+package logic
+
+import "testing"
+
+func TestOnDelayRestoreResumes(t *testing.T) {
+	a := Float{B: &OnDelay{Delay: 0.5, DT: 0.1}}
+	b := Float{B: &OnDelay{Delay: 0.5, DT: 0.1}}
+	for i := 0; i < 3; i++ {
+		a.Update(1)
+	}
+	if !a.Stateful() {
+		t.Fatal("expected a Float of an OnDelay to be Stateful")
+	}
+	if err := b.RestoreState(a.SaveState()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if ya, yb := a.Update(1), b.Update(1); ya != yb {
+			t.Fatalf("update %d: restored timer diverged, %g != %g", i, yb, ya)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+)
+
+// ErrLoopShape is returned by ShapeLoop when the phase margin cannot be met
+// with a single lead stage
+var ErrLoopShape = errors.New("pctl: target phase margin needs more than 65 degrees of lead at the crossover")
+
+const (
+	// loopPIRatio is the ratio of the crossover to the PI zero
+	loopPIRatio = 5
+
+	// loopRolloffRatio is the ratio of the roll off corner to the crossover
+	loopRolloffRatio = 5
+
+	// loopMaxLead is the most phase lead ShapeLoop will use, in degrees
+	loopMaxLead = 65
+)
+
+// LoopShape is a controller designed by ShapeLoop: a PI, an optional lead,
+// and a first order roll off, in series
+type LoopShape struct {
+	// PID is the PI stage, with the loop gain.  Its setpoint, limits, and so
+	// on may be set as usual.
+	PID *PID
+
+	// Lead is the lead stage, or nil if none was needed
+	Lead *TFFilter
+
+	// Rolloff is the first order low pass which limits the gain to noise
+	// above the crossover
+	Rolloff *TFFilter
+
+	// Margins are those achieved with the plant model
+	Margins Margins
+}
+
+// Chain returns the stages of the controller in the order to update them,
+// e.g. with Cascade
+func (l *LoopShape) Chain() []Updater {
+	if l.Lead == nil {
+		return []Updater{l.PID, l.Rolloff}
+	}
+	return []Updater{l.PID, l.Lead, l.Rolloff}
+}
+
+// Response returns the complex gain of the controller from the error to the
+// output at frequency f (Hz) for sample rate fs
+func (l *LoopShape) Response(f, fs float64) complex128 {
+	h := l.PID.Response(f, fs) * l.Rolloff.Response(f, fs)
+	if l.Lead != nil {
+		h *= l.Lead.Response(f, fs)
+	}
+	return h
+}
+
+// ShapeLoop designs a controller for the plant model at sample rate fs which
+// crosses over at fc Hz with a phase margin of pm degrees, by classical loop
+// shaping:
+//
+//   - a PI, with its zero a factor of 5 below fc for gain at low frequency
+//   - a lead, centered on fc, supplying whatever phase the plant, the PI,
+//     and the roll off lack
+//   - a first order roll off a factor of 5 above fc
+//   - a gain placing the crossover at fc
+//
+// The lead and roll off are discretized by the bilinear transform prewarped to
+// fc.  The phase lacking is measured from the discrete responses, so the
+// phase margin is met closely.  The gain margin is whatever results, and
+// should be checked in the reported Margins.
+func ShapeLoop(plant Responder, fs, fc, pm float64) (*LoopShape, error) {
+	if err := CheckNyquist(fc*loopRolloffRatio, fs); err != nil {
+		return nil, err
+	}
+	wc := 2 * math.Pi * fc
+	c := wc / math.Tan(wc/(2*fs)) // prewarped bilinear constant
+	dt := 1 / fs
+
+	pid := &PID{P: 1, I: wc / loopPIRatio, DT: dt}
+	roll, err := NewTFFilter(bilinear1(0, 1, 1/(loopRolloffRatio*wc), 1, c))
+	if err != nil {
+		return nil, err
+	}
+	l := &LoopShape{PID: pid, Rolloff: roll}
+
+	// phase lacking at the crossover, in degrees, for the margin
+	have := PhaseDeg(Series{plant, l}.Response(fc, fs))
+	lead := pm - 180 - have
+	lead -= 360 * math.Floor((lead+180)/360) // to (-180, 180]
+	if lead > loopMaxLead {
+		return nil, ErrLoopShape
+	}
+	if lead > 0 {
+		// a lead of maximum phase phi at wc: zero at wc sqrt(a), pole at
+		// wc / sqrt(a)
+		s := math.Sin(lead * math.Pi / 180)
+		a := (1 - s) / (1 + s)
+		wz, wp := wc*math.Sqrt(a), wc/math.Sqrt(a)
+		l.Lead, err = NewTFFilter(bilinear1(1/wz, 1, 1/wp, 1, c))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	k := 1 / cmplx.Abs(Series{plant, l}.Response(fc, fs))
+	pid.P *= k
+	pid.I *= k
+	l.Margins = LoopMargins(Series{plant, l}, fs, fc/1000, fs/2)
+	return l, nil
+}
+
+// bilinear1 returns the discretization of (b1 s + b0) / (a1 s + a0) by the
+// bilinear transform s = c (1 - z^-1) / (1 + z^-1)
+func bilinear1(b1, b0, a1, a0, c float64) TF {
+	return TF{
+		Num: []float64{b1*c + b0, b0 - b1*c},
+		Den: []float64{a1*c + a0, a0 - a1*c},
+	}
+}
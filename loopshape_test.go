@@ -0,0 +1,34 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShapeLoopMeetsTargets(t *testing.T) {
+	// a motor: an integrator and a 10 ms lag, discretized with a zero order
+	// hold at 1 kHz
+	const fs, tau = 1000., 0.01
+	a := math.Exp(-1 / (fs * tau))
+	plant := Series{
+		TF{Num: []float64{0, 1 / fs}, Den: []float64{1, -1}},
+		TF{Num: []float64{0, 1 - a}, Den: []float64{1, -a}},
+	}
+	l, err := ShapeLoop(plant, fs, 20, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := l.Margins
+	if !approxEqualAbs(m.GainCrossover, 20, 1e-6) || !approxEqualAbs(m.PhaseMargin, 50, 1e-6) {
+		t.Errorf("expected the crossover at 20 Hz with 50 degrees, got %+v", m)
+	}
+	if err := m.Meets(6, 45); err != nil {
+		t.Error(err)
+	}
+	if l.Lead == nil || len(l.Chain()) != 3 {
+		t.Error("expected the lag of the motor to need a lead")
+	}
+	if _, err := ShapeLoop(plant, fs, 20, 150); err != ErrLoopShape {
+		t.Errorf("expected ErrLoopShape for an unreachable margin, got %v", err)
+	}
+}
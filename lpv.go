@@ -0,0 +1,120 @@
+package pctl
+
+// LPVBiquad is a linear parameter varying biquad, whose coefficients are a
+// function of an external parameter updated every step, such as a notch
+// whose center frequency tracks a measured spindle speed.
+//
+// The coefficients come either from a design function evaluated whenever the
+// parameter changes, or from a table of designs precomputed at a grid of
+// parameters and linearly interpolated, which does not allocate or call the
+// design in the loop.  Like SmoothBiquad, it is implemented in direct form I,
+// so that changing the coefficients does not disturb its state.
+type LPVBiquad struct {
+	// design returns the coefficients at a parameter
+	design func(float64) [5]float64
+
+	// grid and table hold the tabulated designs; table[j][i] is coefficient
+	// j at grid[i]
+	grid  []float64
+	table [5][]float64
+
+	param  float64
+	primed bool
+	c      [5]float64
+
+	x1, x2, y1, y2 float64
+}
+
+// NewLPVBiquad returns a new LPV biquad whose coefficients at parameter p are
+// those of design(p).  The parameter starts at p0.
+func NewLPVBiquad(design func(p float64) *Biquad, p0 float64) *LPVBiquad {
+	return newLPVBiquad(func(p float64) [5]float64 {
+		b := design(p)
+		return [5]float64{b.a0, b.a1, b.a2, b.b1, b.b2}
+	}, p0)
+}
+
+func newLPVBiquad(design func(float64) [5]float64, p0 float64) *LPVBiquad {
+	l := &LPVBiquad{design: design}
+	l.SetParam(p0)
+	return l
+}
+
+// NewLPVBiquadTable returns a new LPV biquad whose coefficients are those of
+// design tabulated at the increasing parameters grid and linearly
+// interpolated between them, clamped beyond the ends.  The parameter starts
+// at grid[0].  The grid must be fine enough that the interpolated filters are
+// close to the designs between the points.
+func NewLPVBiquadTable(design func(p float64) *Biquad, grid []float64) *LPVBiquad {
+	l := &LPVBiquad{grid: append([]float64(nil), grid...)}
+	for j := range l.table {
+		l.table[j] = make([]float64, len(grid))
+	}
+	for i, p := range grid {
+		b := design(p)
+		c := [5]float64{b.a0, b.a1, b.a2, b.b1, b.b2}
+		for j := range c {
+			l.table[j][i] = c[j]
+		}
+	}
+	if len(grid) > 0 {
+		l.SetParam(grid[0])
+	}
+	return l
+}
+
+// NewTrackingNotch returns an LPV notch at sample rate fs with quality factor
+// q, whose parameter is the center frequency in Hz.  The coefficients are
+// those of NewBiquadNotch, computed in place, so SetParam does not allocate.
+// The notch is at the magnitude of the parameter, so it stays stable as a
+// signed speed passes through zero.
+func NewTrackingNotch(fs, q, f0 float64) *LPVBiquad {
+	return newLPVBiquad(func(f float64) [5]float64 { return notchCoefs(fs, f, q) }, f0)
+}
+
+// SetParam sets the scheduling parameter, recomputing the coefficients if it
+// has changed
+func (l *LPVBiquad) SetParam(p float64) {
+	if l.primed && p == l.param {
+		return
+	}
+	l.param, l.primed = p, true
+	if l.design != nil {
+		l.c = l.design(p)
+		return
+	}
+	for j := range l.c {
+		l.c[j] = interp(l.grid, l.table[j], p, false)
+	}
+}
+
+// Param returns the scheduling parameter
+func (l *LPVBiquad) Param() float64 {
+	return l.param
+}
+
+// Coefs returns the present coefficients, in the same order as NewBiquad
+func (l *LPVBiquad) Coefs() (a0, a1, a2, b1, b2 float64) {
+	return l.c[0], l.c[1], l.c[2], l.c[3], l.c[4]
+}
+
+// Update processes an input value at the present parameter, returning the
+// filtered output
+func (l *LPVBiquad) Update(input float64) float64 {
+	c := &l.c
+	out := c[0]*input + c[1]*l.x1 + c[2]*l.x2 - c[3]*l.y1 - c[4]*l.y2
+	l.x2, l.x1 = l.x1, input
+	l.y2, l.y1 = l.y1, out
+	return out
+}
+
+// UpdateParam sets the parameter to p, then processes an input value
+func (l *LPVBiquad) UpdateParam(input, p float64) float64 {
+	l.SetParam(p)
+	return l.Update(input)
+}
+
+// Reset zeros the filter's history
+func (l *LPVBiquad) Reset() {
+	l.x1, l.x2, l.y1, l.y2 = 0, 0, 0, 0
+}
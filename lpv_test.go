@@ -0,0 +1,80 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrackingNotchFollowsTone(t *testing.T) {
+	const fs = 1000.
+	n := NewTrackingNotch(fs, 2, 50)
+	// a tone sweeping 50 to 100 Hz, with the notch told its frequency
+	var phase, peak float64
+	for i := 0; i < 5000; i++ {
+		f := 50 + 50*float64(i)/5000
+		phase += 2 * math.Pi * f / fs
+		out := n.UpdateParam(math.Sin(phase), f)
+		if i > 500 {
+			peak = math.Max(peak, math.Abs(out))
+		}
+	}
+	if peak > 0.05 {
+		t.Errorf("expected the tracking notch to reject the tone, peak output %f", peak)
+	}
+}
+
+func TestLPVBiquadTableInterpolates(t *testing.T) {
+	design := func(p float64) *Biquad { return NewBiquadLowpass(1000, p, math.Sqrt2/2, 0) }
+	l := NewLPVBiquadTable(design, []float64{10, 20, 30})
+	l.SetParam(20)
+	a0, _, _, _, b2 := l.Coefs()
+	want := design(20)
+	if a0 != want.a0 || b2 != want.b2 {
+		t.Errorf("expected the tabulated design on a grid point")
+	}
+	l.SetParam(15)
+	a0, _, _, _, _ = l.Coefs()
+	if mid := (design(10).a0 + design(20).a0) / 2; !approxEqualAbs(a0, mid, 1e-15) {
+		t.Errorf("expected a0 interpolated to %g, got %g", mid, a0)
+	}
+	l.SetParam(100)
+	if a0, _, _, _, _ = l.Coefs(); a0 != design(30).a0 {
+		t.Errorf("expected the coefficients clamped beyond the grid")
+	}
+}
+
+func TestTrackingNotchSetParamDoesNotAllocate(t *testing.T) {
+	l := NewTrackingNotch(1000, 5, 50)
+	f := 50.
+	allocs := testing.AllocsPerRun(100, func() {
+		f++
+		l.SetParam(f)
+	})
+	if allocs != 0 {
+		t.Errorf("expected SetParam not to allocate, got %v allocations", allocs)
+	}
+	b := NewBiquadNotch(1000, f, 5, 0)
+	if a0, a1, a2, b1, b2 := l.Coefs(); [5]float64{a0, a1, a2, b1, b2} != [5]float64{b.a0, b.a1, b.a2, b.b1, b.b2} {
+		t.Errorf("expected the coefficients of NewBiquadNotch at %f Hz", f)
+	}
+}
+
+func TestTrackingNotchStableThroughZero(t *testing.T) {
+	l := NewTrackingNotch(1000, 5, 2)
+	var pos [5]float64
+	pos[0], pos[1], pos[2], pos[3], pos[4] = l.Coefs()
+	l.SetParam(-2)
+	if a0, a1, a2, b1, b2 := l.Coefs(); [5]float64{a0, a1, a2, b1, b2} != pos {
+		t.Errorf("expected a notch at -2 Hz to equal one at 2 Hz")
+	}
+	for _, f := range []float64{-2, 0} {
+		l := NewTrackingNotch(1000, 5, f)
+		var out float64
+		for i := 0; i < 20000; i++ {
+			out = l.Update(1 + math.Sin(0.3*float64(i)))
+		}
+		if math.IsNaN(out) || math.Abs(out) > 10 {
+			t.Errorf("notch at %f Hz diverged to %g", f, out)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package pctl
+
+import "sort"
+
+// LookupTable is a one dimensional table with linear interpolation, for
+// linearizing valve characteristics, thermistor curves, and other nonlinear
+// sensors and actuators.  Inputs beyond the table are clamped to its ends, or
+// extrapolated from the end segments if Extrapolate is true.
+type LookupTable struct {
+	// Extrapolate extends the end segments beyond the table instead of
+	// clamping
+	Extrapolate bool
+
+	x, y []float64
+}
+
+// NewLookupTable returns a new table through the points (x[i], y[i]).  x must
+// be strictly increasing and the same length as y; see Validate.  The slices
+// are copied.
+func NewLookupTable(x, y []float64) *LookupTable {
+	return &LookupTable{
+		x: append([]float64(nil), x...),
+		y: append([]float64(nil), y...),
+	}
+}
+
+// Update returns the table value at input
+func (t *LookupTable) Update(input float64) float64 {
+	return interp(t.x, t.y, input, t.Extrapolate)
+}
+
+// Points returns the breakpoints of the table.  They must not be modified.
+func (t *LookupTable) Points() (x, y []float64) {
+	return t.x, t.y
+}
+
+// interp linearly interpolates y(x) at v, clamping or extrapolating beyond
+// the ends
+func interp(x, y []float64, v float64, extrapolate bool) float64 {
+	n := len(x)
+	switch {
+	case n == 0:
+		return 0
+	case n == 1:
+		return y[0]
+	}
+	i := sort.SearchFloat64s(x, v) // x[i-1] < v <= x[i]
+	switch {
+	case i == 0:
+		if !extrapolate {
+			return y[0]
+		}
+		i = 1
+	case i == n:
+		if !extrapolate {
+			return y[n-1]
+		}
+		i = n - 1
+	}
+	x0, x1 := x[i-1], x[i]
+	return y[i-1] + (v-x0)*(y[i]-y[i-1])/(x1-x0)
+}
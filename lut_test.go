@@ -0,0 +1,26 @@
+package pctl
+
+import "testing"
+
+func TestLookupTableInterpolates(t *testing.T) {
+	lut := NewLookupTable([]float64{0, 1, 3}, []float64{0, 10, 20})
+	cases := []struct{ in, clamp, extrap float64 }{
+		{-1, 0, -10},
+		{0.5, 5, 5},
+		{2, 15, 15},
+		{5, 20, 30},
+	}
+	for _, c := range cases {
+		lut.Extrapolate = false
+		if got := lut.Update(c.in); got != c.clamp {
+			t.Errorf("clamped lookup at %f: expected %f, got %f", c.in, c.clamp, got)
+		}
+		lut.Extrapolate = true
+		if got := lut.Update(c.in); got != c.extrap {
+			t.Errorf("extrapolated lookup at %f: expected %f, got %f", c.in, c.extrap, got)
+		}
+	}
+	if err := NewLookupTable([]float64{0, 0}, []float64{1, 2}).Validate(); err == nil {
+		t.Error("expected repeated breakpoint to fail validation")
+	}
+}
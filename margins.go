@@ -0,0 +1,127 @@
+package pctl
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Series is a chain of systems whose frequency response is the product of
+// theirs, e.g. a controller and a plant model forming a loop transfer
+// function
+type Series []Responder
+
+// Response returns the complex gain of the chain at frequency f (Hz) for
+// sample rate fs
+func (s Series) Response(f, fs float64) complex128 {
+	out := complex(1, 0)
+	for _, r := range s {
+		out *= r.Response(f, fs)
+	}
+	return out
+}
+
+// Margins are the stability margins of a loop transfer function L, for
+// negative feedback
+type Margins struct {
+	// GainMargin is the factor, in dB, by which the gain of L may rise before
+	// the loop is unstable, at the phase crossover.  It is +Inf if the phase
+	// never crosses -180 degrees, and negative if the loop is unstable.
+	GainMargin float64
+
+	// PhaseMargin is the additional phase lag, in degrees, the loop tolerates
+	// at the gain crossover.  It is +Inf if |L| never crosses 1.
+	PhaseMargin float64
+
+	// PhaseCrossover is the frequency in Hz of the GainMargin, where the phase
+	// of L is -180 degrees, or zero if there is none
+	PhaseCrossover float64
+
+	// GainCrossover is the frequency in Hz of the PhaseMargin, where |L| = 1,
+	// or zero if there is none
+	GainCrossover float64
+}
+
+// Meets returns an error describing the first margin below the minimum gain
+// margin gm (dB) or phase margin pm (degrees), or nil if both are met, so that
+// a tuning change may be gated in a test
+func (m Margins) Meets(gm, pm float64) error {
+	if m.GainMargin < gm {
+		return fmt.Errorf("pctl: gain margin of %.2f dB at %g Hz is below %.2f dB", m.GainMargin, m.PhaseCrossover, gm)
+	}
+	if m.PhaseMargin < pm {
+		return fmt.Errorf("pctl: phase margin of %.2f degrees at %g Hz is below %.2f degrees", m.PhaseMargin, m.GainCrossover, pm)
+	}
+	return nil
+}
+
+// marginPoints is the number of log spaced frequencies LoopMargins scans
+const marginPoints = 2000
+
+// LoopMargins returns the gain and phase margins of the loop transfer
+// function L at sample rate fs, e.g. Series{controller, plant}, searched
+// between lo and hi Hz.  lo must be positive; hi is limited to Nyquist.
+//
+// L is scanned at log spaced frequencies and each crossover is refined by
+// bisection.  Where there are several crossovers, the smallest margin is
+// reported.  Crossovers between scan points that are closer than the scan
+// resolves, e.g. of a very sharp resonance, may be missed.
+func LoopMargins(loop Responder, fs, lo, hi float64) Margins {
+	if hi > fs/2 {
+		hi = fs / 2
+	}
+	m := Margins{GainMargin: math.Inf(1), PhaseMargin: math.Inf(1)}
+	ratio := math.Pow(hi/lo, 1/float64(marginPoints-1))
+	f0 := lo
+	h0 := loop.Response(f0, fs)
+	phase0 := PhaseDeg(h0)
+	for i := 1; i < marginPoints; i++ {
+		f1 := f0 * ratio
+		if i == marginPoints-1 {
+			f1 = hi
+		}
+		h1 := loop.Response(f1, fs)
+		phase1 := phase0 + PhaseDeg(h1/h0)
+		// phase crossover: phase passing -180 + 360k
+		k0 := math.Floor((phase0 + 180) / 360)
+		k1 := math.Floor((phase1 + 180) / 360)
+		if k0 != k1 {
+			target := 360*math.Max(k0, k1) - 180
+			f := bisect(f0, f1, func(f float64) float64 {
+				return phase0 + PhaseDeg(loop.Response(f, fs)/h0) - target
+			})
+			if gm := -MagDB(loop.Response(f, fs)); gm < m.GainMargin {
+				m.GainMargin, m.PhaseCrossover = gm, f
+			}
+		}
+		// gain crossover
+		if (cmplx.Abs(h0) >= 1) != (cmplx.Abs(h1) >= 1) {
+			f := bisect(f0, f1, func(f float64) float64 {
+				return MagDB(loop.Response(f, fs))
+			})
+			// the phase relative to the nearest -180 + 360k
+			p := phase0 + PhaseDeg(loop.Response(f, fs)/h0)
+			pm := p + 180 - 360*math.Round((p+180)/360)
+			if pm < m.PhaseMargin {
+				m.PhaseMargin, m.GainCrossover = pm, f
+			}
+		}
+		f0, h0, phase0 = f1, h1, phase1
+	}
+	return m
+}
+
+// bisect returns the root of g between a and b, where g changes sign
+func bisect(a, b float64, g func(float64) float64) float64 {
+	ga := g(a)
+	for i := 0; i < 60; i++ {
+		c := (a + b) / 2
+		gc := g(c)
+		if (gc < 0) == (ga < 0) {
+			a, ga = c, gc
+		} else {
+			b = c
+		}
+	}
+	return (a + b) / 2
+}
@@ -0,0 +1,53 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoopMarginsDelayedIntegrator(t *testing.T) {
+	// L = k T z^-1 / (1 - z^-1) z^-d has |L| = k T / (2 sin(w/2)) and a phase
+	// of -90 degrees - (1/2 + d) w, for w in rad/sample
+	const fs, k, d = 1000., 100., 4
+	integ := TF{Num: []float64{0, k / fs}, Den: []float64{1, -1}}
+	delay := TF{Num: []float64{0, 0, 0, 0, 1}, Den: []float64{1}}
+	m := LoopMargins(Series{integ, delay}, fs, 0.1, fs/2)
+
+	wgc := 2 * math.Asin(k/fs/2)
+	pm := 90 - (0.5+d)*wgc*180/math.Pi
+	wpc := math.Pi / (1 + 2*d)
+	gm := 20 * math.Log10(2*math.Sin(wpc/2)/(k/fs))
+	hz := fs / (2 * math.Pi)
+	if !approxEqualAbs(m.PhaseMargin, pm, 1e-6) || !approxEqualAbs(m.GainCrossover, wgc*hz, 1e-6) {
+		t.Errorf("expected PM %f at %f Hz, got %f at %f", pm, wgc*hz, m.PhaseMargin, m.GainCrossover)
+	}
+	if !approxEqualAbs(m.GainMargin, gm, 1e-6) || !approxEqualAbs(m.PhaseCrossover, wpc*hz, 1e-6) {
+		t.Errorf("expected GM %f at %f Hz, got %f at %f", gm, wpc*hz, m.GainMargin, m.PhaseCrossover)
+	}
+	if err := m.Meets(6, 45); err != nil {
+		t.Error(err)
+	}
+	if err := m.Meets(12, 45); err == nil {
+		t.Error("expected a gain margin below 12 dB to fail")
+	}
+}
+
+func TestPIDResponseMatchesUpdate(t *testing.T) {
+	// drive the PID with a sinusoidal error of period 40 samples and compare
+	// the output against the response.  The integral of the startup leaves a
+	// DC offset, which the difference of outputs half a period apart cancels.
+	const fs, f, half = 1000., 25., 20
+	pid := &PID{P: 1.5, I: 20, D: 0.01, DT: 1 / fs}
+	h := pid.Response(f, fs)
+	out := make([]float64, 200)
+	for n := range out {
+		out[n] = pid.Update(-math.Sin(2 * math.Pi * f * float64(n) / fs)) // err = sin
+	}
+	for n := 100; n < 150; n++ {
+		w := 2 * math.Pi * f * float64(n) / fs
+		want := real(h)*math.Sin(w) + imag(h)*math.Cos(w)
+		if got := (out[n] - out[n+half]) / 2; !approxEqualAbs(got, want, 1e-9) {
+			t.Fatalf("sample %d: expected %f, got %f", n, want, got)
+		}
+	}
+}
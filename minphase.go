@@ -0,0 +1,93 @@
+package pctl
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// MinimumPhase converts FIR taps, typically a linear phase design, to the
+// minimum phase filter of the same length and magnitude response.  A minimum
+// phase filter has the least group delay of any with its magnitude response,
+// which suits latency sensitive loops that do not need linear phase; a
+// lowpass's delay in its pass band falls well below the (len-1)/2 samples of
+// the linear phase design.
+//
+// The conversion is by the homomorphic (cepstral) method.  Zeros on the unit
+// circle are approximated, with the stop band floored at -160 dB relative to
+// the peak of the response.
+func MinimumPhase(taps []float64) []float64 {
+	n := 1
+	for n < 16*len(taps) {
+		n <<= 1
+	}
+	x := make([]complex128, n)
+	for i, v := range taps {
+		x[i] = complex(v, 0)
+	}
+	fft(x, false)
+	var peak float64
+	for _, v := range x {
+		peak = math.Max(peak, cmplx.Abs(v))
+	}
+	floor := peak * 1e-8
+	for i, v := range x {
+		x[i] = complex(math.Log(math.Max(cmplx.Abs(v), floor)), 0)
+	}
+	// the real cepstrum, folded onto positive quefrencies, is the complex
+	// cepstrum of the minimum phase filter
+	fft(x, true)
+	for i := 1; i < n/2; i++ {
+		x[i] *= 2
+	}
+	for i := n/2 + 1; i < n; i++ {
+		x[i] = 0
+	}
+	fft(x, false)
+	for i, v := range x {
+		x[i] = cmplx.Exp(v)
+	}
+	fft(x, true)
+	out := make([]float64, len(taps))
+	for i := range out {
+		out[i] = real(x[i])
+	}
+	return out
+}
+
+// fft computes the discrete Fourier transform of x in place, or its inverse
+// (including the 1/n scaling) if inverse is true.  len(x) must be a power of
+// two.
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	sign := -1.
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			wk := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], x[start+k+size/2]*wk
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				wk *= w
+			}
+		}
+	}
+	if inverse {
+		s := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= s
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMinimumPhaseHalvesDelay(t *testing.T) {
+	const fs = 1000
+	lin, err := DesignFIR(63, Lowpass, fs, Hamming, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	min := MinimumPhase(lin)
+	lf, mf := NewFIRFilter(lin), NewFIRFilter(min)
+	for _, f := range []float64{0, 10, 25, 40} {
+		if d := math.Abs(MagDB(lf.Response(f, fs)) - MagDB(mf.Response(f, fs))); d > 0.05 {
+			t.Errorf("at %f Hz, magnitude differs by %f dB", f, d)
+		}
+	}
+	linDelay := GroupDelay(lf, 10, fs) * fs
+	minDelay := GroupDelay(mf, 10, fs) * fs
+	if !approxEqualAbs(linDelay, 31, 1e-6) || minDelay > linDelay/2 {
+		t.Errorf("expected minimum phase delay below half of %f samples, got %f", linDelay, minDelay)
+	}
+}
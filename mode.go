@@ -0,0 +1,174 @@
+package pctl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrModeTransition is returned when requesting an illegal mode transition
+var ErrModeTransition = errors.New("pctl: illegal mode transition")
+
+// Mode is the operating mode of a ModeManager
+type Mode int
+
+const (
+	// ModeManual outputs a value set by the operator
+	ModeManual Mode = iota
+
+	// ModeAuto closes the loop on the local setpoint
+	ModeAuto
+
+	// ModeCascade closes the loop on a remote setpoint, e.g. the output of an
+	// outer loop
+	ModeCascade
+
+	// ModeTracking outputs an externally supplied value, e.g. from an interlock
+	// or another controller
+	ModeTracking
+)
+
+// String implements fmt.Stringer
+func (m Mode) String() string {
+	switch m {
+	case ModeManual:
+		return "manual"
+	case ModeAuto:
+		return "auto"
+	case ModeCascade:
+		return "cascade"
+	case ModeTracking:
+		return "tracking"
+	}
+	return "unknown"
+}
+
+// legal[from] is the set of modes reachable from from.  ModeCascade is entered
+// from ModeAuto only, so the local setpoint has been established first.
+var legal = [...][4]bool{
+	ModeManual:   {ModeAuto: true, ModeTracking: true},
+	ModeAuto:     {ModeManual: true, ModeCascade: true, ModeTracking: true},
+	ModeCascade:  {ModeManual: true, ModeAuto: true, ModeTracking: true},
+	ModeTracking: {ModeManual: true, ModeAuto: true},
+}
+
+// ModeManager wraps a controller with the manual / auto / cascade / tracking
+// mode state machine of industrial controllers.
+//
+// ModeManager takes measurements and forms the error fed to the controller as
+// meas - setpoint, in the convention of Setpoint; a PID should be left with a
+// zero Setpt.  In ModeManual and ModeTracking the controller is still updated,
+// and if it is a Tracker it tracks the output so the return to automatic
+// control is bumpless.  The manager starts in ModeManual.
+type ModeManager struct {
+	// Controller is the wrapped controller
+	Controller Updater
+
+	// Setpt is the local setpoint, used in ModeAuto
+	Setpt float64
+
+	// PVTrack makes the local setpoint follow the measurement in ModeManual
+	// and ModeTracking, so that switching to ModeAuto does not step the error
+	PVTrack bool
+
+	// OnModeChange, if not nil, is called after each mode change
+	OnModeChange func(from, to Mode)
+
+	mode   Mode
+	manual float64
+	remote float64
+	track  float64
+	out    float64
+}
+
+// Update processes a measurement and returns the output of the current mode
+func (m *ModeManager) Update(meas float64) float64 {
+	sp := m.Setpt
+	if m.mode == ModeCascade {
+		sp = m.remote
+	}
+	if m.PVTrack && (m.mode == ModeManual || m.mode == ModeTracking) {
+		m.Setpt = meas
+		sp = meas
+	}
+	out := m.Controller.Update(meas - sp)
+	switch m.mode {
+	case ModeManual:
+		out = m.manual
+	case ModeTracking:
+		out = m.track
+	}
+	if m.mode == ModeManual || m.mode == ModeTracking {
+		if t, ok := m.Controller.(Tracker); ok {
+			t.Track(out)
+		}
+	}
+	m.out = out
+	return out
+}
+
+// SetMode requests a mode change, returning ErrModeTransition if it is not
+// legal.  Leaving ModeManual or ModeTracking carries the output into the
+// controller.  Entering ModeCascade seeds the remote setpoint with the local
+// one, so that the entry is bumpless until the outer loop calls SetRemote,
+// and leaving it carries the remote setpoint into the local one.
+func (m *ModeManager) SetMode(to Mode) error {
+	from := m.mode
+	if from == to {
+		return nil
+	}
+	if to < ModeManual || to > ModeTracking || !legal[from][to] {
+		return fmt.Errorf("%w: %v to %v", ErrModeTransition, from, to)
+	}
+	if from == ModeCascade {
+		m.Setpt = m.remote
+	}
+	switch to {
+	case ModeManual:
+		m.manual = m.out
+	case ModeCascade:
+		m.remote = m.Setpt
+	}
+	m.mode = to
+	if m.OnModeChange != nil {
+		m.OnModeChange(from, to)
+	}
+	return nil
+}
+
+// Mode returns the current mode
+func (m *ModeManager) Mode() Mode {
+	return m.mode
+}
+
+// SetManual sets the output used in ModeManual
+func (m *ModeManager) SetManual(output float64) {
+	m.manual = output
+}
+
+// SetRemote sets the setpoint used in ModeCascade
+func (m *ModeManager) SetRemote(setpt float64) {
+	m.remote = setpt
+}
+
+// SetTrack sets the output used in ModeTracking
+func (m *ModeManager) SetTrack(output float64) {
+	m.track = output
+}
+
+// Achieved implements ResetFeedback.  In ModeAuto and ModeCascade output is
+// passed on to the controller; in the other modes the controller already
+// tracks the output.
+func (m *ModeManager) Achieved(output float64) {
+	if m.mode != ModeAuto && m.mode != ModeCascade {
+		return
+	}
+	if r, ok := m.Controller.(ResetFeedback); ok {
+		r.Achieved(output)
+	}
+	m.out = output
+}
+
+// Output returns the last output
+func (m *ModeManager) Output() float64 {
+	return m.out
+}
@@ -0,0 +1,80 @@
+package pctl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestModeManagerTransitions(t *testing.T) {
+	var events []Mode
+	m := &ModeManager{Controller: &PID{P: 1, I: 1, DT: 0.1},
+		OnModeChange: func(_, to Mode) { events = append(events, to) }}
+	if err := m.SetMode(ModeCascade); !errors.Is(err, ErrModeTransition) {
+		t.Errorf("expected manual to cascade to be illegal, got %v", err)
+	}
+	for _, to := range []Mode{ModeAuto, ModeCascade, ModeTracking, ModeManual} {
+		if err := m.SetMode(to); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(events) != 4 || events[3] != ModeManual {
+		t.Errorf("unexpected events %v", events)
+	}
+}
+
+func TestModeManagerBumpless(t *testing.T) {
+	m := &ModeManager{Controller: &PID{P: 1, I: 1, DT: 0.1}, PVTrack: true}
+	m.SetManual(5)
+	for i := 0; i < 10; i++ {
+		if out := m.Update(3); out != 5 {
+			t.Fatalf("manual output %f, expected 5", out)
+		}
+	}
+	if m.Setpt != 3 {
+		t.Errorf("PVTrack did not track the measurement, setpoint %f", m.Setpt)
+	}
+	m.SetMode(ModeAuto)
+	if out := m.Update(3); !approxEqualAbs(out, 5, 1e-9) {
+		t.Errorf("expected bumpless transfer to auto at 5, got %f", out)
+	}
+	m.SetMode(ModeCascade)
+	m.SetRemote(4)
+	if out := m.Update(3); !(out > 5) {
+		t.Errorf("expected output to rise towards remote setpoint, got %f", out)
+	}
+}
+
+func TestModeManagerCascadeEntryBumpless(t *testing.T) {
+	auto := &ModeManager{Controller: &PID{P: 1, I: 1, DT: 0.1}, Setpt: 2}
+	casc := &ModeManager{Controller: &PID{P: 1, I: 1, DT: 0.1}, Setpt: 2}
+	casc.SetRemote(10) // stale, from before the outer loop took over
+	for _, m := range []*ModeManager{auto, casc} {
+		m.SetMode(ModeAuto)
+		for i := 0; i < 5; i++ {
+			m.Update(3)
+		}
+	}
+	casc.SetMode(ModeCascade)
+	if a, c := auto.Update(3), casc.Update(3); !approxEqualAbs(a, c, 1e-12) {
+		t.Errorf("expected entry to cascade to continue the auto output %f, got %f", a, c)
+	}
+}
+
+func TestModeManagerCascadeToManualCarriesSetpoint(t *testing.T) {
+	m := &ModeManager{Controller: &PID{P: 1, I: 1, DT: 0.1}, Setpt: 10}
+	m.SetMode(ModeAuto)
+	m.SetMode(ModeCascade)
+	m.SetRemote(50)
+	for i := 0; i < 5; i++ {
+		m.Update(50)
+	}
+	m.SetMode(ModeManual)
+	if m.Setpt != 50 {
+		t.Errorf("expected cascade to manual to carry the remote setpoint 50, got %f", m.Setpt)
+	}
+	held := m.Update(50)
+	m.SetMode(ModeAuto)
+	if out := m.Update(50); !approxEqualAbs(out, held, 1e-9) {
+		t.Errorf("expected manual to auto at the carried setpoint to hold %f, got %f", held, out)
+	}
+}
@@ -0,0 +1,54 @@
+package pctl
+
+import "math"
+
+// MoveLimiter reduces the number of moves made by an actuator whose life is
+// limited by move count, such as a motorized valve.  The command of Controller
+// is held until it differs from the last move by at least MinMove and
+// MinInterval has passed since it, then it is issued as one larger move.
+type MoveLimiter struct {
+	// Controller produces the command to be limited
+	Controller Updater
+
+	// MinMove is the smallest move issued, in output units
+	MinMove float64
+
+	// MinInterval is the shortest time between moves, in seconds
+	MinInterval float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	out     float64
+	since   float64
+	moves   int
+	started bool
+}
+
+// Update runs the controller and returns the limited command
+func (m *MoveLimiter) Update(input float64) float64 {
+	cmd := m.Controller.Update(input)
+	m.since += m.DT
+	if !m.started {
+		m.started = true
+		m.out = cmd
+		m.since = 0
+		return m.out
+	}
+	if math.Abs(cmd-m.out) >= m.MinMove && m.since >= m.MinInterval {
+		m.out = cmd
+		m.since = 0
+		m.moves++
+	}
+	return m.out
+}
+
+// Moves returns the number of moves issued after the first command
+func (m *MoveLimiter) Moves() int {
+	return m.moves
+}
+
+// Output returns the current command without updating
+func (m *MoveLimiter) Output() float64 {
+	return m.out
+}
@@ -0,0 +1,25 @@
+package pctl
+
+import "testing"
+
+type ramp struct{ v, step float64 }
+
+func (r *ramp) Update(float64) float64 {
+	r.v += r.step
+	return r.v
+}
+
+func TestMoveLimiterBatchesSmallChanges(t *testing.T) {
+	m := &MoveLimiter{Controller: &ramp{step: 0.01}, MinMove: 0.095, MinInterval: 0.5, DT: 0.1}
+	var out float64
+	for i := 0; i < 100; i++ {
+		out = m.Update(0)
+	}
+	// 0.01 per update batches into a move of 0.1 every tenth update
+	if m.Moves() != 9 {
+		t.Errorf("expected 9 moves after the first command, got %d", m.Moves())
+	}
+	if !approxEqualAbs(out, 0.91, 1e-9) {
+		t.Errorf("expected output 0.91, got %f", out)
+	}
+}
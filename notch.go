@@ -0,0 +1,96 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrNoResonance is returned by FindResonance when the spectrum has no peak
+// inside the band searched
+var ErrNoResonance = errors.New("pctl: no resonant peak in the band")
+
+// Resonance describes a structural resonance identified from measured data
+type Resonance struct {
+	// Freq is the frequency of the peak, in Hz
+	Freq float64
+
+	// Q is the quality factor, Freq over the half power bandwidth
+	Q float64
+
+	// Prominence is the height of the peak above the median of the band, in
+	// dB, as a measure of confidence in it
+	Prominence float64
+}
+
+// FindResonance identifies the largest resonance of a signal recorded from a
+// loop, e.g. its error or the controller output, between lo and hi Hz.  The
+// spectrum is estimated by Welch with segments of seg samples and a Hann
+// window.  The peak frequency is refined between bins by a parabola through
+// the log spectrum, and the bandwidth is measured between the interpolated
+// half power points.
+func FindResonance(x []float64, fs float64, seg int, lo, hi float64) (Resonance, error) {
+	f, p, err := Welch(x, fs, seg, Hann)
+	if err != nil {
+		return Resonance{}, err
+	}
+	// local maxima only; the band edges may be on a slope
+	best := -1
+	for k := 1; k < len(p)-1; k++ {
+		if f[k] < lo || f[k] > hi || p[k] < p[k-1] || p[k] < p[k+1] {
+			continue
+		}
+		if best < 0 || p[k] > p[best] {
+			best = k
+		}
+	}
+	if best < 0 {
+		return Resonance{}, ErrNoResonance
+	}
+	df := f[1] - f[0]
+	y0, y1, y2 := math.Log(p[best-1]), math.Log(p[best]), math.Log(p[best+1])
+	freq := f[best]
+	if d := y0 - 2*y1 + y2; d < 0 {
+		freq += 0.5 * (y0 - y2) / d * df
+	}
+	half := p[best] / 2
+	below, above := f[0], f[len(f)-1]
+	for k := best; k > 0; k-- {
+		if p[k-1] < half {
+			below = f[k-1] + (half-p[k-1])/(p[k]-p[k-1])*df
+			break
+		}
+	}
+	for k := best; k < len(p)-1; k++ {
+		if p[k+1] < half {
+			above = f[k] + (p[k]-half)/(p[k]-p[k+1])*df
+			break
+		}
+	}
+	var band []float64
+	for k := range p {
+		if f[k] >= lo && f[k] <= hi {
+			band = append(band, p[k])
+		}
+	}
+	sort.Float64s(band)
+	median := band[len(band)/2]
+	return Resonance{
+		Freq:       freq,
+		Q:          freq / (above - below),
+		Prominence: 10 * math.Log10(p[best]/median),
+	}, nil
+}
+
+// Notch returns a notch biquad for the resonance at sample rate fs, as wide as
+// the resonance
+func (r Resonance) Notch(fs float64) *Biquad {
+	return NewBiquadNotch(fs, r.Freq, r.Q, 0)
+}
+
+// PlaceNotch retunes s, a running notch, to the resonance r at sample rate fs.
+// The retune ramps over s.Ramp samples, so the notch moves without a
+// transient while the loop runs.  Call it from the goroutine which updates s.
+func PlaceNotch(s *SmoothBiquad, r Resonance, fs float64) {
+	s.Retune(r.Notch(fs))
+}
@@ -0,0 +1,45 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFindResonanceAndPlaceNotch(t *testing.T) {
+	// white noise through a resonance at 123 Hz, Q 10
+	const fs, f0, q = 2000., 123., 10.
+	r := rand.New(rand.NewSource(11))
+	res := NewBiquadBandpass(fs, f0, q, 0)
+	x := make([]float64, 1<<16)
+	for i := range x {
+		x[i] = res.Update(r.NormFloat64()) + 0.01*r.NormFloat64()
+	}
+	got, err := FindResonance(x, fs, 1024, 20, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approxEqualAbs(got.Freq, f0, 1) || math.Abs(got.Q/q-1) > 0.2 || got.Prominence < 20 {
+		t.Errorf("expected a prominent resonance at %f Hz with Q %f, got %+v", f0, q, got)
+	}
+	// a notch at a placeholder frequency, retuned in place
+	s := NewSmoothBiquad(NewBiquadNotch(fs, 400, 5, 0), 100)
+	PlaceNotch(s, got, fs)
+	for i := 0; i < 200; i++ {
+		s.Update(0)
+	}
+	a0, a1, a2, b1, b2 := s.Coefs()
+	if g := NewBiquad(a0, a1, a2, b1, b2).Response(got.Freq, fs); real(g)*real(g)+imag(g)*imag(g) > 1e-12 {
+		t.Errorf("expected the notch to null the resonance, gain %v", g)
+	}
+}
+
+func TestFindResonanceNoPeak(t *testing.T) {
+	x := make([]float64, 4096)
+	for i := range x {
+		x[i] = float64(i) // a ramp, whose spectrum only falls
+	}
+	if _, err := FindResonance(x, 1000, 256, 10, 400); err != ErrNoResonance {
+		t.Errorf("expected ErrNoResonance, got %v", err)
+	}
+}
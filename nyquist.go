@@ -0,0 +1,44 @@
+package pctl
+
+import (
+	"fmt"
+	"math"
+)
+
+// CheckNyquist returns an error wrapping ErrNyquist unless f is positive and
+// below half the sample rate fs, and both are finite, or nil.
+//
+// The NewBiquadXxx designs, NewPR, and the constructors built on them, such
+// as NewHPF2, design at 0.49 times the sample rate when given a frequency at
+// or above half of it, as the Timed filters do.  Their bilinear and tangent
+// warped designs alias there, producing wrong and often unstable
+// coefficients, as they do at zero and negative frequencies.  Programs that
+// would rather have an error than a different filter than they asked for
+// check the frequency first with CheckNyquist, or design biquads with
+// CheckedBiquad.
+//
+// The first order filters of NewLPF and NewHPF are exempt; their coefficients
+// are well behaved at any cutoff, tending to a pass through or a null.
+func CheckNyquist(f, fs float64) error {
+	if f > 0 && f < fs/2 && !math.IsInf(fs, 0) {
+		return nil
+	}
+	return fmt.Errorf("%w: %g Hz at a sample rate of %g Hz", ErrNyquist, f, fs)
+}
+
+// CheckedBiquad returns the biquad designed by design, such as
+// NewBiquadLowpass, or an error from CheckNyquist in place of clamping f
+func CheckedBiquad(design NewBiquadFunc, Fs, f, Q, g float64) (*Biquad, error) {
+	if err := CheckNyquist(f, Fs); err != nil {
+		return nil, err
+	}
+	return design(Fs, f, Q, g), nil
+}
+
+// clampNyquist limits a frequency f at sample rate fs to 0.49 fs
+func clampNyquist(f, fs float64) float64 {
+	if f >= fs/2 {
+		return maxFcDT * fs
+	}
+	return f
+}
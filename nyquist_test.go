@@ -0,0 +1,42 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBiquadAboveNyquistClamps(t *testing.T) {
+	b := NewBiquadLowpass(1000, 700, 0.7071, 0)
+	if err := b.Validate(); err != nil {
+		t.Fatalf("expected a stable clamped design, got %v", err)
+	}
+	want := NewBiquadLowpass(1000, 490, 0.7071, 0)
+	if *b != *want {
+		t.Errorf("expected the design at 0.49 fs, got %v", b)
+	}
+	if p := NewPR(0, 1, 800, 5, 1e-3); p.Freq() != 490 {
+		t.Errorf("expected the PR frequency clamped to 490, got %f", p.Freq())
+	}
+}
+
+func TestCheckedBiquadErrors(t *testing.T) {
+	if _, err := CheckedBiquad(NewBiquadNotch, 1000, 500, 2, 0); !errors.Is(err, ErrNyquist) {
+		t.Errorf("expected ErrNyquist at Nyquist, got %v", err)
+	}
+	for _, f := range []float64{math.NaN(), math.Inf(1), 0, -60} {
+		if _, err := CheckedBiquad(NewBiquadLowpass, 1000, f, 0.7071, 0); !errors.Is(err, ErrNyquist) {
+			t.Errorf("expected ErrNyquist for %g Hz, got %v", f, err)
+		}
+	}
+	if err := CheckNyquist(100, math.Inf(1)); !errors.Is(err, ErrNyquist) {
+		t.Errorf("expected ErrNyquist for an infinite sample rate, got %v", err)
+	}
+	b, err := CheckedBiquad(NewBiquadNotch, 1000, 60, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *b != *NewBiquadNotch(1000, 60, 2, 0) {
+		t.Errorf("expected the unchecked design below Nyquist, got %v", b)
+	}
+}
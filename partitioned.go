@@ -0,0 +1,122 @@
+package pctl
+
+// PartitionedFIR is a long FIR filter computed by non-uniform partitioned
+// convolution.  Its cost per sample grows with the square of the logarithm
+// of the length rather than the length, so it is faster than FIRFilter for
+// filters of about a thousand taps or more, and it adds no latency.
+//
+// The first Block taps are computed directly, sample by sample.  The rest are
+// split into partitions of doubling length, Block, 2*Block, 4*Block, ..., each
+// starting no earlier than its own length, and computed by FFT convolution of
+// blocks of the input as they complete.  Because each partition's delay is at
+// least its block length, its outputs are ready before they are needed.
+//
+// Update is allocation-free, but the work is uneven: an update which
+// completes a block of a large partition takes much longer than the others.
+// Ensure the worst case fits the loop's time budget.
+type PartitionedFIR struct {
+	taps []float64
+	head *FIRFilter
+	segs []convSegment
+
+	// x is the input history and acc the accumulated partition outputs,
+	// rings of power of two length indexed by n.  n wraps at the length of
+	// the rings, a multiple of every block size, so it never overflows.
+	x   []float64
+	acc []float64
+	n   int
+}
+
+// convSegment is an FFT convolution partition of taps [off, off+b)
+type convSegment struct {
+	off, b int
+
+	// kernel is the FFT of the partition's taps, zero padded to 2b
+	kernel []complex128
+	buf    []complex128
+}
+
+// NewPartitionedFIR returns a new filter of the given taps, computing the
+// first block directly.  Smaller blocks cost more operations per sample;
+// sizes of 16 to 64 are typical.  block is rounded up to a power of two.
+func NewPartitionedFIR(taps []float64, block int) *PartitionedFIR {
+	b := 1
+	for b < block {
+		b <<= 1
+	}
+	n := len(taps)
+	p := &PartitionedFIR{taps: append([]float64(nil), taps...)}
+	head := n
+	if head > b {
+		head = b
+	}
+	p.head = NewFIRFilter(taps[:head])
+	maxB := 1
+	for off := b; off < n; off *= 2 {
+		seg := convSegment{off: off, b: off, kernel: make([]complex128, 2*off), buf: make([]complex128, 2*off)}
+		for i := off; i < 2*off && i < n; i++ {
+			seg.kernel[i-off] = complex(taps[i], 0)
+		}
+		fft(seg.kernel, false)
+		p.segs = append(p.segs, seg)
+		maxB = off
+	}
+	ring := 1
+	for ring < 2*n || ring < 2*maxB {
+		ring <<= 1
+	}
+	p.x = make([]float64, ring)
+	p.acc = make([]float64, ring)
+	return p
+}
+
+// Update processes an input value, returning the filtered output
+func (p *PartitionedFIR) Update(input float64) float64 {
+	n := p.n
+	mask := len(p.x) - 1
+	p.x[n&mask] = input
+	out := p.head.Update(input)
+	for k := range p.segs {
+		s := &p.segs[k]
+		b := s.b
+		if (n+1)%b != 0 {
+			continue
+		}
+		// overlap-save over the last 2b inputs; the second half of the
+		// circular convolution is the linear convolution of the block
+		// n+1-b ... n which completed.  The ring is at least 2b long, so
+		// before the first 2b inputs the indices wrap to zeroed entries.
+		for i := 0; i < 2*b; i++ {
+			s.buf[i] = complex(p.x[(n+1-2*b+i)&mask], 0)
+		}
+		fft(s.buf, false)
+		for i, v := range s.kernel {
+			s.buf[i] *= v
+		}
+		fft(s.buf, true)
+		for i := 0; i < b; i++ {
+			m := n + 1 - b + i
+			p.acc[(m+s.off)&mask] += real(s.buf[b+i])
+		}
+	}
+	out += p.acc[n&mask]
+	p.acc[n&mask] = 0
+	p.n = (n + 1) & mask
+	return out
+}
+
+// Response returns the complex gain of the filter at frequency f (Hz) for
+// sample rate fs
+func (p *PartitionedFIR) Response(f, fs float64) complex128 {
+	return TF{Num: p.taps, Den: []float64{1}}.Response(f, fs)
+}
+
+// Reset clears the filter's internal state
+func (p *PartitionedFIR) Reset() {
+	p.head.Reset()
+	for i := range p.x {
+		p.x[i] = 0
+		p.acc[i] = 0
+	}
+	p.n = 0
+}
@@ -0,0 +1,41 @@
+package pctl
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPartitionedFIRMatchesDirect(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	taps := make([]float64, 300)
+	for i := range taps {
+		taps[i] = r.NormFloat64()
+	}
+	direct := NewFIRFilter(taps)
+	part := NewPartitionedFIR(taps, 16)
+	for n := 0; n < 2000; n++ {
+		in := r.NormFloat64()
+		if a, b := direct.Update(in), part.Update(in); !approxEqualAbs(a, b, 1e-9) {
+			t.Fatalf("sample %d: partitioned %f != direct %f", n, b, a)
+		}
+	}
+}
+
+func TestPartitionedFIRWrapsIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	taps := make([]float64, 100)
+	for i := range taps {
+		taps[i] = r.NormFloat64()
+	}
+	direct := NewFIRFilter(taps)
+	part := NewPartitionedFIR(taps, 8)
+	for n := 0; n < 5*len(part.x); n++ {
+		in := r.NormFloat64()
+		if a, b := direct.Update(in), part.Update(in); !approxEqualAbs(a, b, 1e-9) {
+			t.Fatalf("sample %d: partitioned %f != direct %f", n, b, a)
+		}
+		if part.n < 0 || part.n >= len(part.x) {
+			t.Fatalf("sample %d: index %d outside the ring of %d", n, part.n, len(part.x))
+		}
+	}
+}
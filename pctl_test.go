@@ -66,6 +66,33 @@ func BenchmarkFIRFilter(b *testing.B) {
 	}
 }
 
+func BenchmarkFIRFilterSymmetric(b *testing.B) {
+	const filterSize = 32
+	coefs := make([]float64, filterSize)
+	for i := 0; i < filterSize/2; i++ {
+		coefs[i] = rand.Float64()
+		coefs[filterSize-1-i] = coefs[i]
+	}
+	f := NewFIRFilter(coefs)
+	for n := 0; n < b.N; n++ {
+		f.Update(3.14)
+	}
+}
+
+func BenchmarkSmoothBiquad(b *testing.B) {
+	bq := NewSmoothBiquad(&Biquad{}, 32)
+	for n := 0; n < b.N; n++ {
+		bq.Update(3.14)
+	}
+}
+
+func BenchmarkSampleHold(b *testing.B) {
+	var s SampleHold
+	for n := 0; n < b.N; n++ {
+		s.Update(3.14)
+	}
+}
+
 func TestSetpointCorrect(t *testing.T) {
 	s := Setpoint(0)
 	meas := 2. // 2 is exactly representable in fp
@@ -96,3 +123,15 @@ func TestCascade(t *testing.T) {
 		t.Error("sequential Update calls result not equal to Cascade")
 	}
 }
+
+func BenchmarkPartitionedFIR(b *testing.B) {
+	const filterSize = 1024
+	coefs := make([]float64, filterSize)
+	for i := 0; i < filterSize; i++ {
+		coefs[i] = rand.Float64()
+	}
+	f := NewPartitionedFIR(coefs, 32)
+	for n := 0; n < b.N; n++ {
+		f.Update(3.14)
+	}
+}
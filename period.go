@@ -0,0 +1,43 @@
+package pctl
+
+import "time"
+
+// The constructors in this file take the sample period as a time.Duration, or
+// the sample rate in Hertz, rather than DT in seconds, to avoid mixing up
+// seconds and milliseconds.
+
+// NewLPFPeriod returns a new low pass filter with the specified corner
+// frequency in Hertz, updated every period
+func NewLPFPeriod(cutoffFreq float64, period time.Duration) *LPF {
+	return NewLPF(cutoffFreq, period.Seconds())
+}
+
+// NewLPFRate returns a new low pass filter with the specified corner frequency
+// in Hertz, updated at sampleRate Hertz
+func NewLPFRate(cutoffFreq, sampleRate float64) *LPF {
+	return NewLPF(cutoffFreq, 1/sampleRate)
+}
+
+// NewHPFPeriod returns a new high pass filter with the specified corner
+// frequency in Hertz, updated every period
+func NewHPFPeriod(cutoffFreq float64, period time.Duration) *HPF {
+	return NewHPF(cutoffFreq, period.Seconds())
+}
+
+// NewHPFRate returns a new high pass filter with the specified corner
+// frequency in Hertz, updated at sampleRate Hertz
+func NewHPFRate(cutoffFreq, sampleRate float64) *HPF {
+	return NewHPF(cutoffFreq, 1/sampleRate)
+}
+
+// NewPIDPeriod returns a new PID controller with the given gains, updated
+// every period.  Other fields may be set on the returned value.
+func NewPIDPeriod(p, i, d float64, period time.Duration) *PID {
+	return &PID{P: p, I: i, D: d, DT: period.Seconds()}
+}
+
+// NewPIDRate returns a new PID controller with the given gains, updated at
+// sampleRate Hertz.  Other fields may be set on the returned value.
+func NewPIDRate(p, i, d, sampleRate float64) *PID {
+	return &PID{P: p, I: i, D: d, DT: 1 / sampleRate}
+}
@@ -0,0 +1,20 @@
+package pctl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodConstructorsAgree(t *testing.T) {
+	if a, b := NewLPFPeriod(10, 2*time.Millisecond), NewLPFRate(10, 500); a.DT != 2e-3 || b.DT != 2e-3 {
+		t.Errorf("expected DT of 2ms, got %g and %g", a.DT, b.DT)
+	}
+	if a, b := NewHPFPeriod(10, 2*time.Millisecond), NewHPFRate(10, 500); a.DT != 2e-3 || b.DT != 2e-3 {
+		t.Errorf("expected DT of 2ms, got %g and %g", a.DT, b.DT)
+	}
+	a := NewPIDPeriod(1, 2, 3, 250*time.Microsecond)
+	b := NewPIDRate(1, 2, 3, 4000)
+	if *a != *b || a.DT != 250e-6 {
+		t.Errorf("expected identical PIDs with DT of 250us, got %+v and %+v", a, b)
+	}
+}
@@ -1,10 +1,32 @@
 package pctl
 
+import "math"
+
+// AntiWindup selects how a PID limits the growth of its integral error
+type AntiWindup int
+
+const (
+	// WindupClamp caps the integral error at IErrMax from above only, as PID
+	// always has; a negative integral error is not limited.  It is the
+	// default.
+	WindupClamp AntiWindup = iota
+
+	// WindupBackCalc lets the integral error exceed ±IErrMax, but bleeds the
+	// excess off at rate Kt.  It recovers from saturation more smoothly than
+	// clamping.
+	WindupBackCalc
+
+	// WindupConditional stops integrating while the integral error is at
+	// ±IErrMax and the error would drive it further.
+	WindupConditional
+
+	// WindupClampSymmetric caps the integral error to ±IErrMax
+	WindupClampSymmetric
+)
+
 // PID is a Proportional, Integral, Derivative controller.
 //
-// Use IErrMax for anti windup
-//
-// PID requires approximately 16 clocks per update.
+// Use IErrMax and Windup for anti windup
 type PID struct {
 	// P is the proportional gain, unitless
 	P float64
@@ -15,22 +37,75 @@ type PID struct {
 	// D is the derivative gain, units of seconds
 	D float64
 
-	// DT is the inter-update time in seconds.  If DT == 0 and I != 0 || D != 0,
-	// output behavior is undefined.
+	// DT is the inter-update time in seconds.  It must be positive if
+	// I != 0 || D != 0; Validate reports this and other misconfigurations.
 	DT float64
 
-	// IErrMax is the cap to the integral error term
-	// if zero, it is ignored
+	// IErrMax is the cap to the integral error term, applied according to
+	// Windup; if zero, it is ignored
 	IErrMax float64
 
+	// Windup is the anti windup strategy, used when IErrMax != 0
+	Windup AntiWindup
+
+	// Kt is the tracking gain of WindupBackCalc and of external reset
+	// feedback (see Achieved), units of reciprocal seconds.
+	// If zero, the rule of thumb of a tracking time equal to the integral time
+	// is used, Kt = I/P, or 1/DT if P == 0.
+	Kt float64
+
+	// IBand is the integral separation band; the integral error only
+	// accumulates while |err| <= IBand.  If zero, it is ignored.
+	IBand float64
+
+	// OutMin and OutMax limit the output.  They apply when OutMax > OutMin,
+	// so the zero value leaves the output unlimited.  The integral is held or
+	// bled off according to Windup while the output is limited.
+	OutMin, OutMax float64
+
+	// SlewMax is the maximum rate of change of the output, in output units
+	// per second.  If zero, it is ignored.  The output slews from zero on the
+	// first update.
+	SlewMax float64
+
+	// OutputLSB is the resolution of the actuator.  If nonzero, the output is
+	// rounded to a multiple of it, and the rounding residue is carried into
+	// the next update so that sub-resolution corrections accumulate until they
+	// take effect.  Output limits should be multiples of OutputLSB.
+	OutputLSB float64
+
 	// Setpt is the setpoint, in process units
 	Setpt float64
 
+	// SetptRate is the rate at which the setpoint used by the controller
+	// ramps towards Setpt, in process units per second.  If zero, changes to
+	// Setpt take effect immediately.  The ramp begins from Setpt as of the
+	// first update.
+	SetptRate float64
+
+	// setpt is the effective (ramped) setpoint
+	setpt float64
+
+	// ramping is true once setpt has been initialized
+	ramping bool
+
 	// prevErr holds the error on the previous iteration
 	prevErr float64
 
+	// prevOut holds the output of the previous iteration
+	prevOut float64
+
+	// residue is the rounding error carried by OutputLSB
+	residue float64
+
 	// integralErr is the accumulated error
 	integralErr float64
+
+	// limited is true if the last output was limited
+	limited bool
+
+	// prevDT is the inter-update time of the last update
+	prevDT float64
 }
 
 // Update runs the loop once and returns the new output value.
@@ -38,25 +113,204 @@ type PID struct {
 // next update, it can be retrieved with pid.Output().
 // if the input is desired, it can be retrieved with pid.Input().
 func (pid *PID) Update(input float64) float64 {
-	err := pid.Setpt - input
-	pid.integralErr += err * pid.DT
-	if pid.IErrMax != 0 && pid.integralErr > pid.IErrMax {
-		pid.integralErr = pid.IErrMax
-	}
-	derivative := (err - pid.prevErr) / pid.DT
+	return pid.UpdateDT(input, pid.DT)
+}
+
+// UpdateDT runs the loop once with an inter-update time of dt seconds in place
+// of DT, and returns the new output value.  It is for measurements which
+// arrive aperiodically; dt is the time since the previous update.
+func (pid *PID) UpdateDT(input, dt float64) float64 {
+	err := pid.ramp(dt) - input
+	prevIErr := pid.integralErr
+	pid.integrate(err, dt)
+	derivative := (err - pid.prevErr) / dt
 	output := pid.P*err + pid.I*pid.integralErr + pid.D*derivative
+	limited := pid.limit(output, dt)
+	pid.limited = limited != output
+	pid.prevDT = dt
+	if pid.limited {
+		if pid.Windup == WindupBackCalc {
+			if pid.I != 0 {
+				pid.integralErr += pid.kt(dt) * (limited - output) / pid.I * dt
+			}
+		} else if (output > limited) == (pid.I*err > 0) {
+			// the integral pushed further into the limit; undo it
+			pid.integralErr = prevIErr
+		}
+		output = limited
+	}
+	if lsb := pid.OutputLSB; lsb != 0 {
+		v := output + pid.residue
+		output = math.Round(v/lsb) * lsb
+		pid.residue = v - output
+	}
 
 	pid.prevErr = err
+	pid.prevOut = output
+	return output
+}
+
+// ramp advances the effective setpoint towards Setpt by dt and returns it
+func (pid *PID) ramp(dt float64) float64 {
+	if pid.SetptRate == 0 || !pid.ramping {
+		pid.setpt = pid.Setpt
+		pid.ramping = pid.SetptRate != 0
+		return pid.setpt
+	}
+	step := pid.SetptRate * dt
+	pid.setpt = clamp(pid.Setpt, pid.setpt-step, pid.setpt+step)
+	return pid.setpt
+}
+
+// SetptTarget returns the setpoint the controller is ramping towards, Setpt
+func (pid *PID) SetptTarget() float64 {
+	return pid.Setpt
+}
+
+// SetptEffective returns the setpoint used on the last update, which lags
+// Setpt while ramping
+func (pid *PID) SetptEffective() float64 {
+	return pid.setpt
+}
+
+// limit applies the range and slew limits to an output
+func (pid *PID) limit(output, dt float64) float64 {
+	if pid.OutMax > pid.OutMin {
+		output = clamp(output, pid.OutMin, pid.OutMax)
+	}
+	if pid.SlewMax != 0 {
+		step := pid.SlewMax * dt
+		output = clamp(output, pid.prevOut-step, pid.prevOut+step)
+	}
 	return output
 }
 
+// kt returns the back calculation tracking gain
+func (pid *PID) kt(dt float64) float64 {
+	if pid.Kt != 0 {
+		return pid.Kt
+	}
+	if pid.P != 0 {
+		return pid.I / pid.P
+	}
+	return 1 / dt
+}
+
+// integrate accumulates err over dt into the integral error according to the
+// anti windup strategy
+func (pid *PID) integrate(err, dt float64) {
+	if b := pid.IBand; b != 0 && (err > b || err < -b) {
+		return
+	}
+	max := pid.IErrMax
+	if max == 0 {
+		pid.integralErr += err * dt
+		return
+	}
+	switch pid.Windup {
+	case WindupBackCalc:
+		excess := pid.integralErr - clamp(pid.integralErr, -max, max)
+		pid.integralErr += (err - pid.kt(dt)*excess) * dt
+		return
+	case WindupConditional:
+		ie := pid.integralErr
+		if (ie >= max && err > 0) || (ie <= -max && err < 0) {
+			return
+		}
+	case WindupClamp:
+		pid.integralErr = math.Min(pid.integralErr+err*dt, max)
+		return
+	}
+	pid.integralErr = clamp(pid.integralErr+err*dt, -max, max)
+}
+
+// clamp limits x to [lo, hi]
+func clamp(x, lo, hi float64) float64 {
+	if x > hi {
+		return hi
+	}
+	if x < lo {
+		return lo
+	}
+	return x
+}
+
 // IErr is the integral error.  You will only need to query this
 // if you need to debug or tune the loop
 func (pid *PID) IErr() float64 {
 	return pid.integralErr
 }
 
+// Limited returns true if the last output was limited, by OutMin and OutMax
+// or SlewMax, or by external reset feedback which differed from it
+func (pid *PID) Limited() bool {
+	return pid.limited
+}
+
 // IntegralReset zeros the integral error
 func (pid *PID) IntegralReset() {
 	pid.integralErr = 0
 }
+
+// IncrementalPID is the velocity (incremental) form of a PID controller.  It
+// returns the change in command on each update rather than the command
+// itself, which suits integrating actuators such as stepper driven valves.
+//
+// The absolute command is tracked internally so that limits can be enforced
+// on it; the returned change never drives the command past them, which also
+// means the velocity form does not wind up.
+type IncrementalPID struct {
+	// P is the proportional gain, unitless
+	P float64
+
+	// I is the integral gain, units of reciprocal seconds
+	I float64
+
+	// D is the derivative gain, units of seconds
+	D float64
+
+	// DT is the inter-update time in seconds.  It must be positive; see
+	// Validate.
+	DT float64
+
+	// OutMin and OutMax limit the absolute command.  They apply when
+	// OutMax > OutMin, so the zero value leaves the command unlimited.
+	OutMin, OutMax float64
+
+	// Setpt is the setpoint, in process units
+	Setpt float64
+
+	// prevErr and prevErr2 hold the error one and two iterations ago
+	prevErr, prevErr2 float64
+
+	// cmd is the absolute command
+	cmd float64
+}
+
+// Update runs the loop once and returns the change in command
+func (pid *IncrementalPID) Update(input float64) float64 {
+	err := pid.Setpt - input
+	du := pid.P*(err-pid.prevErr) +
+		pid.I*pid.DT*err +
+		pid.D*(err-2*pid.prevErr+pid.prevErr2)/pid.DT
+	cmd := pid.cmd + du
+	if pid.OutMax > pid.OutMin {
+		cmd = clamp(cmd, pid.OutMin, pid.OutMax)
+	}
+	du = cmd - pid.cmd
+	pid.cmd = cmd
+	pid.prevErr2 = pid.prevErr
+	pid.prevErr = err
+	return du
+}
+
+// Command returns the absolute command, the sum of all changes returned
+func (pid *IncrementalPID) Command() float64 {
+	return pid.cmd
+}
+
+// SetCommand sets the absolute command, e.g. to the actuator's actual
+// position after homing
+func (pid *IncrementalPID) SetCommand(cmd float64) {
+	pid.cmd = cmd
+}
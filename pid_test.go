@@ -24,3 +24,169 @@ func TestPIDConverges(t *testing.T) {
 		t.Errorf("expected state to converge to setpoint, got %f with error of %f", state, stateErr)
 	}
 }
+
+func TestPIDWindupStrategies(t *testing.T) {
+	// drive each controller into windup with a large persistent error,
+	// then see how far the integral error is from the cap
+	cases := []struct {
+		windup AntiWindup
+		check  func(ierr float64) bool
+	}{
+		{WindupClamp, func(ierr float64) bool { return ierr == 1 }},
+		{WindupConditional, func(ierr float64) bool { return ierr == 1 }},
+		{WindupClampSymmetric, func(ierr float64) bool { return ierr == 1 }},
+		// back calculation settles where err = kt*excess, 10 = 10*(ierr-1)
+		{WindupBackCalc, func(ierr float64) bool { return math.Abs(ierr-2) < 1e-3 }},
+	}
+	for _, c := range cases {
+		ctl := PID{I: 1, DT: 1e-2, IErrMax: 1, Windup: c.windup, Kt: 10, Setpt: 10}
+		for i := 0; i < 1000; i++ {
+			ctl.Update(0)
+		}
+		if !c.check(ctl.IErr()) {
+			t.Errorf("windup strategy %d had integral error %f", c.windup, ctl.IErr())
+		}
+	}
+}
+
+func TestPIDClampIsOneSidedByDefault(t *testing.T) {
+	ctl := PID{I: 1, DT: 1, IErrMax: 1, Setpt: -5}
+	for i := 0; i < 10; i++ {
+		ctl.Update(0)
+	}
+	if ctl.IErr() != -50 {
+		t.Errorf("expected a negative integral error not to be clamped, got %f", ctl.IErr())
+	}
+	ctl = PID{I: 1, DT: 1, IErrMax: 1, Windup: WindupClampSymmetric, Setpt: -5}
+	for i := 0; i < 10; i++ {
+		ctl.Update(0)
+	}
+	if ctl.IErr() != -1 {
+		t.Errorf("expected integral error clamped to -1, got %f", ctl.IErr())
+	}
+}
+
+func TestPIDOutputLimitsAsymmetric(t *testing.T) {
+	ctl := PID{P: 10, DT: 1, OutMin: 0, OutMax: 100, Setpt: 50}
+	if out := ctl.Update(0); out != 100 {
+		t.Errorf("expected output limited to 100, got %f", out)
+	}
+	if out := ctl.Update(100); out != 0 {
+		t.Errorf("expected output limited to 0, got %f", out)
+	}
+}
+
+func TestPIDIntegralHeldWhileLimited(t *testing.T) {
+	ctl := PID{P: 1, I: 1, DT: 0.1, OutMin: -1, OutMax: 1, Setpt: 10}
+	for i := 0; i < 100; i++ {
+		ctl.Update(0)
+	}
+	if ctl.IErr() != 0 {
+		t.Errorf("expected integral to be held while saturated, got %f", ctl.IErr())
+	}
+	// the output comes off the limit as soon as the error reverses
+	if out := ctl.Update(10.5); out >= 1 {
+		t.Errorf("expected output to leave the limit immediately, got %f", out)
+	}
+}
+
+func TestPIDSlewLimit(t *testing.T) {
+	ctl := PID{P: 1, DT: 0.1, SlewMax: 5, Setpt: 10}
+	var out float64
+	for i := 1; i <= 4; i++ {
+		out = ctl.Update(0)
+		if expect := 0.5 * float64(i); math.Abs(out-expect) > 1e-12 {
+			t.Errorf("step %d: output %f, expected slew limited to %f", i, out, expect)
+		}
+	}
+}
+
+func TestPIDIntegralSeparation(t *testing.T) {
+	ctl := PID{I: 1, DT: 1, IBand: 2, Setpt: 5}
+	ctl.Update(0) // err = 5, outside the band
+	if ctl.IErr() != 0 {
+		t.Errorf("expected no integration outside the band, got %f", ctl.IErr())
+	}
+	ctl.Update(4) // err = 1, inside the band
+	if ctl.IErr() != 1 {
+		t.Errorf("expected integration inside the band, got %f", ctl.IErr())
+	}
+}
+
+func TestIncrementalPIDMatchesPositionalPID(t *testing.T) {
+	// without limits, the sum of increments equals the positional output
+	pos := PID{P: 1.5, I: 2, D: 0.01, DT: 0.1, Setpt: 3}
+	inc := IncrementalPID{P: 1.5, I: 2, D: 0.01, DT: 0.1, Setpt: 3}
+	for i := 0; i < 20; i++ {
+		in := float64(i) / 10
+		p := pos.Update(in)
+		inc.Update(in)
+		if math.Abs(p-inc.Command()) > 1e-9 {
+			t.Errorf("update %d: positional %f != incremental %f", i, p, inc.Command())
+		}
+	}
+}
+
+func TestIncrementalPIDLimitsCommand(t *testing.T) {
+	ctl := IncrementalPID{P: 1, DT: 1, OutMin: 0, OutMax: 2, Setpt: 10}
+	du := ctl.Update(0)
+	if du != 2 || ctl.Command() != 2 {
+		t.Errorf("expected change of 2 to the limit, got %f with command %f", du, ctl.Command())
+	}
+	if du = ctl.Update(0); du != 0 {
+		t.Errorf("expected no change at the limit, got %f", du)
+	}
+}
+
+func TestPIDUpdateDTMatchesFixedDT(t *testing.T) {
+	fixed := PID{P: 1, I: 2, D: 0.1, DT: 0.03}
+	varying := PID{P: 1, I: 2, D: 0.1}
+	for i := 0; i < 10; i++ {
+		in := math.Sin(float64(i))
+		if a, b := fixed.Update(in), varying.UpdateDT(in, 0.03); a != b {
+			t.Errorf("update %d: Update %f != UpdateDT %f", i, a, b)
+		}
+	}
+	// a longer interval integrates proportionally more error
+	ctl := PID{I: 1, Setpt: 1}
+	ctl.UpdateDT(0, 0.02)
+	ctl.UpdateDT(0, 0.04)
+	if math.Abs(ctl.IErr()-0.06) > 1e-12 {
+		t.Errorf("expected integral error of 0.06, got %f", ctl.IErr())
+	}
+}
+
+func TestPIDSetpointRamp(t *testing.T) {
+	ctl := PID{P: 1, DT: 0.1, SetptRate: 2, Setpt: 1}
+	ctl.Update(0)
+	if ctl.SetptEffective() != 1 {
+		t.Errorf("expected ramp to start from the initial setpoint, got %f", ctl.SetptEffective())
+	}
+	ctl.Setpt = 2
+	for i := 1; i <= 5; i++ {
+		ctl.Update(0)
+		if expect := 1 + 0.2*float64(i); math.Abs(ctl.SetptEffective()-expect) > 1e-12 {
+			t.Errorf("step %d: effective setpoint %f, expected %f", i, ctl.SetptEffective(), expect)
+		}
+	}
+	ctl.Update(0)
+	if ctl.SetptEffective() != 2 || ctl.SetptTarget() != 2 {
+		t.Errorf("expected ramp to finish at 2, got %f", ctl.SetptEffective())
+	}
+}
+
+func TestPIDOutputQuantizationAccumulates(t *testing.T) {
+	// a constant demand of 0.3 LSB must produce an average of 0.3 LSB
+	ctl := PID{P: 0.3, DT: 1, OutputLSB: 1, Setpt: 1}
+	var sum float64
+	for i := 0; i < 10; i++ {
+		out := ctl.Update(0)
+		if out != math.Trunc(out) {
+			t.Fatalf("output %f is not a multiple of the LSB", out)
+		}
+		sum += out
+	}
+	if sum != 3 {
+		t.Errorf("expected sub-LSB demand to accumulate to 3, got %f", sum)
+	}
+}
@@ -0,0 +1,174 @@
+package pctl
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotMonotonic is returned when inverting a Piecewise function whose
+// values are not strictly monotonic
+var ErrNotMonotonic = errors.New("pctl: piecewise function is not monotonic, cannot invert")
+
+// Point is a breakpoint of a Piecewise function
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Piecewise is a piecewise linear function of breakpoints which may be edited
+// at runtime, e.g. by commissioning engineers.  Every edit is validated, so
+// the breakpoints are always strictly increasing in X.  If the values are
+// strictly monotonic, the function may be inverted.
+//
+// Piecewise marshals to and from the params of a config "piecewise" block,
+// {"points": [{"x": 0, "y": 0}, ...], "extrapolate": false}.
+type Piecewise struct {
+	// Extrapolate extends the end segments beyond the breakpoints instead of
+	// clamping
+	Extrapolate bool
+
+	x, y []float64
+
+	// ix and iy are the breakpoints of the inverse, if it exists
+	ix, iy []float64
+}
+
+// NewPiecewise returns a new function through pts, which must be strictly
+// increasing in X
+func NewPiecewise(pts ...Point) (*Piecewise, error) {
+	p := new(Piecewise)
+	if err := p.SetPoints(pts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update returns the function value at input
+func (p *Piecewise) Update(input float64) float64 {
+	return interp(p.x, p.y, input, p.Extrapolate)
+}
+
+// Inverse returns the input at which the function takes the value y, or
+// ErrNotMonotonic
+func (p *Piecewise) Inverse(y float64) (float64, error) {
+	if p.ix == nil {
+		return 0, ErrNotMonotonic
+	}
+	return interp(p.ix, p.iy, y, p.Extrapolate), nil
+}
+
+// Monotonic returns true if the values are strictly increasing or decreasing,
+// so that Inverse exists
+func (p *Piecewise) Monotonic() bool {
+	return p.ix != nil
+}
+
+// Points returns a copy of the breakpoints
+func (p *Piecewise) Points() []Point {
+	pts := make([]Point, len(p.x))
+	for i := range pts {
+		pts[i] = Point{p.x[i], p.y[i]}
+	}
+	return pts
+}
+
+// SetPoints replaces all of the breakpoints.  The function is unchanged if pts
+// are invalid.
+func (p *Piecewise) SetPoints(pts []Point) error {
+	const typ = "Piecewise"
+	if len(pts) == 0 {
+		return paramErr(typ, "points", "must not be empty")
+	}
+	x := make([]float64, len(pts))
+	y := make([]float64, len(pts))
+	for i, pt := range pts {
+		if !finite(pt.X) || !finite(pt.Y) {
+			return paramErr(typ, "points", "must be finite")
+		}
+		if i > 0 && pt.X <= pts[i-1].X {
+			return paramErr(typ, "points", "must be strictly increasing in x")
+		}
+		x[i], y[i] = pt.X, pt.Y
+	}
+	p.x, p.y = x, y
+	p.invert()
+	return nil
+}
+
+// Set moves breakpoint i
+func (p *Piecewise) Set(i int, pt Point) error {
+	pts := p.Points()
+	if i < 0 || i >= len(pts) {
+		return paramErr("Piecewise", "points", "index out of range")
+	}
+	pts[i] = pt
+	return p.SetPoints(pts)
+}
+
+// Insert adds a breakpoint in order of X
+func (p *Piecewise) Insert(pt Point) error {
+	pts := p.Points()
+	i := 0
+	for i < len(pts) && pts[i].X < pt.X {
+		i++
+	}
+	pts = append(pts[:i], append([]Point{pt}, pts[i:]...)...)
+	return p.SetPoints(pts)
+}
+
+// Remove deletes breakpoint i.  The last breakpoint cannot be removed.
+func (p *Piecewise) Remove(i int) error {
+	pts := p.Points()
+	if i < 0 || i >= len(pts) {
+		return paramErr("Piecewise", "points", "index out of range")
+	}
+	return p.SetPoints(append(pts[:i], pts[i+1:]...))
+}
+
+// invert computes the breakpoints of the inverse, or clears them if the
+// values are not strictly monotonic
+func (p *Piecewise) invert() {
+	p.ix, p.iy = nil, nil
+	n := len(p.y)
+	inc, dec := true, true
+	for i := 1; i < n; i++ {
+		inc = inc && p.y[i] > p.y[i-1]
+		dec = dec && p.y[i] < p.y[i-1]
+	}
+	if !inc && !dec {
+		return
+	}
+	ix := make([]float64, n)
+	iy := make([]float64, n)
+	for i := range ix {
+		j := i
+		if dec {
+			j = n - 1 - i
+		}
+		ix[i], iy[i] = p.y[j], p.x[j]
+	}
+	p.ix, p.iy = ix, iy
+}
+
+type piecewiseJSON struct {
+	Points      []Point `json:"points"`
+	Extrapolate bool    `json:"extrapolate"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (p *Piecewise) MarshalJSON() ([]byte, error) {
+	return json.Marshal(piecewiseJSON{p.Points(), p.Extrapolate})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, validating the breakpoints
+func (p *Piecewise) UnmarshalJSON(b []byte) error {
+	var v piecewiseJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := p.SetPoints(v.Points); err != nil {
+		return err
+	}
+	p.Extrapolate = v.Extrapolate
+	return nil
+}
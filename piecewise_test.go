@@ -0,0 +1,48 @@
+package pctl
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPiecewiseEditAndInverse(t *testing.T) {
+	p, err := NewPiecewise(Point{0, 100}, Point{10, 50}, Point{20, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, err := p.Inverse(75); err != nil || x != 5 {
+		t.Errorf("expected inverse of 75 to be 5, got %f, %v", x, err)
+	}
+	if err := p.Set(1, Point{25, 50}); err == nil {
+		t.Error("expected moving a breakpoint out of order to fail")
+	}
+	if err := p.Insert(Point{15, 60}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Inverse(55); !errors.Is(err, ErrNotMonotonic) {
+		t.Errorf("expected ErrNotMonotonic, got %v", err)
+	}
+	if got := p.Update(12.5); got != 55 {
+		t.Errorf("expected 55 between inserted breakpoints, got %f", got)
+	}
+}
+
+func TestPiecewiseJSONRoundTrip(t *testing.T) {
+	p, _ := NewPiecewise(Point{0, 0}, Point{1, 2})
+	p.Extrapolate = true
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q Piecewise
+	if err := json.Unmarshal(b, &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Update(2) != 4 {
+		t.Errorf("round trip lost points or extrapolation: %s", b)
+	}
+	if err := json.Unmarshal([]byte(`{"points": [{"x": 1}, {"x": 0}]}`), &q); err == nil {
+		t.Error("expected unordered breakpoints to fail to unmarshal")
+	}
+}
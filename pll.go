@@ -0,0 +1,99 @@
+package pctl
+
+import "math"
+
+// PLL is a phase locked loop tracking the phase and frequency of a sinusoidal
+// input, e.g. a tachometer or accelerometer signal, for synchronizing control
+// actions to rotating machinery.
+//
+// The phase detector is a second order generalized integrator (SOGI), which
+// forms the quadrature of the input with a resonator tuned to the tracked
+// frequency.  The phase error is normalized by the input amplitude, so the
+// loop dynamics do not depend on it.  The loop filter is a PI controller with a
+// damping ratio of 1/sqrt(2) and natural frequency Bandwidth, driving a
+// numerically controlled oscillator.
+type PLL struct {
+	// Center is the free running frequency in Hz, from which the loop starts
+	Center float64
+
+	// Bandwidth is the natural frequency of the loop in Hz.  Wider loops lock
+	// and follow changes faster, but pass more noise into the estimates.
+	Bandwidth float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	v1, v2  float64 // SOGI in phase and quadrature
+	theta   float64 // NCO phase
+	integ   float64 // loop filter integral, rad/s
+	omega   float64 // tracked frequency, rad/s
+	errAvg  float64 // smoothed squared phase error
+	started bool
+}
+
+// NewPLL returns a new PLL starting at the center frequency, with the given
+// loop bandwidth, both in Hz
+func NewPLL(center, bandwidth, dt float64) *PLL {
+	return &PLL{Center: center, Bandwidth: bandwidth, DT: dt}
+}
+
+// Update processes an input sample and returns the tracked phase of the
+// input, in radians in [0, 2pi), taking the input to be A sin(phase)
+func (p *PLL) Update(input float64) float64 {
+	dt := p.DT
+	if !p.started {
+		p.started = true
+		p.omega = 2 * math.Pi * p.Center
+	} else {
+		// predict the NCO and the SOGI resonator forward to this sample; the
+		// resonator is rotated exactly, so the quadrature has no frequency
+		// warping
+		rs, rc := math.Sincos(p.omega * dt)
+		p.v1, p.v2 = rc*p.v1-rs*p.v2, rs*p.v1+rc*p.v2
+		p.theta = math.Mod(p.theta+p.omega*dt, 2*math.Pi)
+		if p.theta < 0 {
+			p.theta += 2 * math.Pi
+		}
+	}
+	// SOGI damping, by Euler
+	const k = math.Sqrt2
+	p.v1 += dt * p.omega * k * (input - p.v1)
+	// v1 = A sin(phi), v2 = -A cos(phi); e = sin(phi - theta)
+	s, c := math.Sincos(p.theta)
+	e := p.v1*c + p.v2*s
+	if a := math.Hypot(p.v1, p.v2); a > 0 {
+		e /= a
+	}
+	wn := 2 * math.Pi * p.Bandwidth
+	p.integ += wn * wn * e * dt
+	p.omega = 2*math.Pi*p.Center + math.Sqrt2*wn*e + p.integ
+	// average the error over about ten loop time constants
+	alpha := clamp(wn*dt/10, 0, 1)
+	p.errAvg += alpha * (e*e - p.errAvg)
+	return p.theta
+}
+
+// Phase returns the tracked phase in radians
+func (p *PLL) Phase() float64 {
+	return p.theta
+}
+
+// Freq returns the tracked frequency in Hz
+func (p *PLL) Freq() float64 {
+	return p.omega / (2 * math.Pi)
+}
+
+// Amplitude returns the amplitude of the input
+func (p *PLL) Amplitude() float64 {
+	return math.Hypot(p.v1, p.v2)
+}
+
+// Locked returns true if the RMS phase error has settled below about 0.1 rad
+func (p *PLL) Locked() bool {
+	return p.started && p.errAvg < 0.01
+}
+
+// Reset returns the loop to the center frequency and forgets the input
+func (p *PLL) Reset() {
+	*p = PLL{Center: p.Center, Bandwidth: p.Bandwidth, DT: p.DT}
+}
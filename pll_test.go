@@ -0,0 +1,26 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPLLLocksToSinusoid(t *testing.T) {
+	const fs, f, phi0 = 10000., 50.5, 1.
+	p := NewPLL(48, 5, 1/fs)
+	var ph float64
+	for n := 0; n < 3*fs; n++ {
+		ph = 2*math.Pi*f*float64(n)/fs + phi0
+		p.Update(2 * math.Sin(ph))
+	}
+	if !approxEqualAbs(p.Freq(), f, 0.01) {
+		t.Errorf("expected frequency %f, got %f", f, p.Freq())
+	}
+	d := math.Remainder(p.Phase()-ph, 2*math.Pi)
+	if math.Abs(d) > 0.02 {
+		t.Errorf("phase error %f rad", d)
+	}
+	if !approxEqualAbs(p.Amplitude(), 2, 0.01) || !p.Locked() {
+		t.Errorf("expected lock on amplitude 2, got %f, locked %v", p.Amplitude(), p.Locked())
+	}
+}
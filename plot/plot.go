@@ -0,0 +1,157 @@
+/*
+Package plot renders step responses, Bode plots, and recorded telemetry of
+pctl types to PNG or SVG images with gonum/plot.
+
+It is a separate module so that pctl itself stays dependency-free; importing
+pctl does not bring in gonum.
+*/
+package plot
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/sim"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	_ "gonum.org/v1/plot/vg/vgimg" // png
+	_ "gonum.org/v1/plot/vg/vgsvg" // svg
+)
+
+// Series is a named sequence of values
+type Series struct {
+	Name string
+	Y    []float64
+}
+
+// lines adds a line per series to p, sharing the abscissa x
+func lines(p *plot.Plot, x []float64, series ...Series) error {
+	var args []interface{}
+	for _, s := range series {
+		n := len(s.Y)
+		if len(x) < n {
+			n = len(x)
+		}
+		pts := make(plotter.XYs, n)
+		for i := range pts {
+			pts[i].X = x[i]
+			pts[i].Y = s.Y[i]
+		}
+		args = append(args, s.Name, pts)
+	}
+	return plotutil.AddLines(p, args...)
+}
+
+// Telemetry plots each series against the time vector t, in seconds
+func Telemetry(title string, t []float64, series ...Series) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = "time (s)"
+	p.Add(plotter.NewGrid())
+	if err := lines(p, t, series...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Step plots the response of u to a unit step over duration seconds with
+// inter-update time dt.  u is updated in place.
+func Step(u pctl.Updater, dt, duration float64) (*plot.Plot, error) {
+	n := int(math.Round(duration / dt))
+	t := make([]float64, n)
+	y := make([]float64, n)
+	for i := range t {
+		t[i] = float64(i) * dt
+		y[i] = u.Update(1)
+	}
+	p, err := Telemetry("Step Response", t, Series{Name: "output", Y: y})
+	if err != nil {
+		return nil, err
+	}
+	p.Legend.Top = false
+	return p, nil
+}
+
+// SimResult plots the setpoint, measurement, and command of a simulation
+func SimResult(r *sim.Result) (*plot.Plot, error) {
+	return Telemetry("Closed Loop Response", r.T,
+		Series{Name: "setpoint", Y: r.Setpoint},
+		Series{Name: "measurement", Y: r.Measurement},
+		Series{Name: "command", Y: r.Command},
+	)
+}
+
+// magFloor is the lowest magnitude plotted, dB.  Perfect zeros such as a
+// lowpass at Nyquist would otherwise be -Inf.
+const magFloor = -300
+
+// Bode plots the magnitude and phase of r at sample rate fs, over points
+// logarithmically spaced frequencies from fmin to Nyquist.  The phase is
+// unwrapped.  The magnitude plot is returned first.
+func Bode(r pctl.Responder, fs, fmin float64, points int) (mag, phase *plot.Plot, err error) {
+	if fmin <= 0 || fmin >= fs/2 || points < 2 {
+		return nil, nil, fmt.Errorf("plot: bode requires 0 < fmin < fs/2 and at least two points")
+	}
+	f := make([]float64, points)
+	m := make([]float64, points)
+	for i := range f {
+		f[i] = fmin * math.Pow(fs/2/fmin, float64(i)/float64(points-1))
+		h := r.Response(f[i], fs)
+		m[i] = math.Max(pctl.MagDB(h), magFloor)
+	}
+	ph := pctl.PhaseResponse(r, fs, f, 0)
+	mag = plot.New()
+	mag.Title.Text = "Bode Plot"
+	mag.Y.Label.Text = "magnitude (dB)"
+	phase = plot.New()
+	phase.Y.Label.Text = "phase (deg)"
+	phase.X.Label.Text = "frequency (Hz)"
+	for _, p := range []*plot.Plot{mag, phase} {
+		p.X.Scale = plot.LogScale{}
+		p.X.Tick.Marker = plot.LogTicks{}
+		p.Add(plotter.NewGrid())
+	}
+	if err := lines(mag, f, Series{Y: m}); err != nil {
+		return nil, nil, err
+	}
+	if err := lines(phase, f, Series{Y: ph}); err != nil {
+		return nil, nil, err
+	}
+	mag.Legend.Top = true
+	return mag, phase, nil
+}
+
+// Save writes the plots stacked vertically to path, in the format implied by
+// its extension (png or svg).  w and h are the size of the whole image.
+func Save(path string, w, h vg.Length, plots ...*plot.Plot) error {
+	format := strings.TrimPrefix(filepath.Ext(path), ".")
+	c, err := draw.NewFormattedCanvas(w, h, format)
+	if err != nil {
+		return err
+	}
+	rows := make([][]*plot.Plot, len(plots))
+	for i, p := range plots {
+		rows[i] = []*plot.Plot{p}
+	}
+	tiles := draw.Tiles{Rows: len(plots), Cols: 1, PadTop: vg.Points(4), PadBottom: vg.Points(4)}
+	canvases := plot.Align(rows, tiles, draw.New(c))
+	for i, p := range plots {
+		p.Draw(canvases[i][0])
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := c.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,43 @@
+package plot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brandondube/pctl"
+	"gonum.org/v1/plot/vg"
+)
+
+func TestSaveBodeAndStep(t *testing.T) {
+	dir := t.TempDir()
+	bq := pctl.NewBiquadLowpass(1000, 50, 0.7071, 0)
+	mag, phase, err := Bode(bq, 1000, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, err := Step(bq, 1e-3, 0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"out.png", "out.svg"} {
+		path := filepath.Join(dir, name)
+		if err := Save(path, 6*vg.Inch, 8*vg.Inch, mag, phase, step); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() == 0 {
+			t.Errorf("%s is empty", name)
+		}
+	}
+}
+
+func TestBodeRejectsBadRange(t *testing.T) {
+	bq := pctl.NewBiquadLowpass(1000, 50, 0.7071, 0)
+	if _, _, err := Bode(bq, 1000, 600, 10); err == nil {
+		t.Error("expected error for fmin above nyquist")
+	}
+}
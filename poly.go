@@ -0,0 +1,15 @@
+package pctl
+
+// Polynomial is a polynomial in ascending powers, p[0] + p[1]*x + p[2]*x^2 +
+// ..., for sensor linearization fits such as the Callendar-Van Dusen equation
+// of RTDs or the NIST thermocouple polynomials
+type Polynomial []float64
+
+// Update evaluates the polynomial at input by Horner's method
+func (p Polynomial) Update(input float64) float64 {
+	var y float64
+	for i := len(p) - 1; i >= 0; i-- {
+		y = y*input + p[i]
+	}
+	return y
+}
@@ -0,0 +1,15 @@
+package pctl
+
+import "testing"
+
+func TestPolynomialCallendarVanDusen(t *testing.T) {
+	// a Pt100 above 0 C, R(T) = R0 (1 + A T + B T^2)
+	const r0, a, b = 100, 3.9083e-3, -5.775e-7
+	cvd := Polynomial{r0, r0 * a, r0 * b}
+	if got := cvd.Update(100); !approxEqualAbs(got, 138.5055, 1e-4) {
+		t.Errorf("expected Pt100 resistance 138.5055 ohm at 100 C, got %f", got)
+	}
+	if got := (Polynomial{}).Update(3); got != 0 {
+		t.Errorf("empty polynomial should be zero, got %f", got)
+	}
+}
@@ -0,0 +1,92 @@
+package pctl
+
+import "math"
+
+// PR is a proportional resonant controller.  The resonant term has a high
+// gain at a single frequency, so a loop closed around it tracks a sinusoidal
+// reference at that frequency, or rejects a sinusoidal disturbance, with zero
+// steady state error, as an integral does at DC.  It is used for inverter
+// current control and vibration tables.
+//
+// The resonant term is
+//
+//	Kr s / (s^2 + 2 wc s + w0^2)
+//
+// which peaks at w0 = 2 pi Freq with a gain of Kr / 2 wc, and has a bandwidth
+// of wc = 2 pi Cutoff.  A zero Cutoff is the ideal resonator, of infinite gain
+// at w0; a nonzero one tolerates some error in the frequency.  It is
+// discretized by the bilinear transform prewarped to w0, so the peak is at
+// Freq exactly.
+//
+// Unlike PID, PR has no setpoint; its input is the error, setpt - meas.  The
+// reference is usually itself sinusoidal, so the error is formed upstream.
+type PR struct {
+	// P is the proportional gain, unitless
+	P float64
+
+	// Kr is the resonant gain, units of reciprocal seconds, as PID.I.  Near
+	// Freq, the resonant term acts as an integral of the error's envelope with
+	// gain Kr/2.
+	Kr float64
+
+	freq, cutoff, dt float64
+
+	// c holds the coefficients of the resonant term, a0, a1, a2, b1, b2, in
+	// the convention of NewBiquad
+	c [5]float64
+
+	x1, x2, y1, y2 float64
+}
+
+// NewPR returns a new PR controller resonant at freq Hertz with bandwidth
+// cutoff Hertz, updated every dt seconds
+func NewPR(p, kr, freq, cutoff, dt float64) *PR {
+	pr := &PR{P: p, Kr: kr, cutoff: cutoff, dt: dt}
+	pr.SetFreq(freq)
+	return pr
+}
+
+// SetFreq changes the resonant frequency in Hertz, preserving the state, so
+// that it may follow a varying frequency from e.g. a PLL.  The resonant term
+// is in direct form I, whose state does not depend on the coefficients.
+// Frequencies at or above Nyquist are clamped to 0.49 times the sample rate.
+func (pr *PR) SetFreq(freq float64) {
+	freq = clampNyquist(freq, 1/pr.dt)
+	pr.freq = freq
+	w0 := 2 * math.Pi * freq
+	wc := 2 * math.Pi * pr.cutoff
+	// the bilinear transform constant, prewarped to w0
+	k := 2 / pr.dt
+	if w0 != 0 {
+		k = w0 / math.Tan(w0*pr.dt/2)
+	}
+	k2, w02 := k*k, w0*w0
+	norm := 1 / (k2 + 2*wc*k + w02)
+	a0 := k * norm
+	pr.c = [5]float64{a0, 0, -a0, 2 * (w02 - k2) * norm, (k2 - 2*wc*k + w02) * norm}
+}
+
+// Freq returns the resonant frequency in Hertz
+func (pr *PR) Freq() float64 {
+	return pr.freq
+}
+
+// Update runs the controller once on the error and returns the output
+func (pr *PR) Update(err float64) float64 {
+	c := &pr.c
+	r := c[0]*err + c[1]*pr.x1 + c[2]*pr.x2 - c[3]*pr.y1 - c[4]*pr.y2
+	pr.x2, pr.x1 = pr.x1, err
+	pr.y2, pr.y1 = pr.y1, r
+	return pr.P*err + pr.Kr*r
+}
+
+// Resonant returns the output of the resonant term on the last update,
+// before the gain Kr
+func (pr *PR) Resonant() float64 {
+	return pr.y1
+}
+
+// Reset zeros the state of the resonant term
+func (pr *PR) Reset() {
+	pr.x1, pr.x2, pr.y1, pr.y2 = 0, 0, 0, 0
+}
@@ -0,0 +1,38 @@
+package pctl
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestPRPeakAtFreq(t *testing.T) {
+	const fs = 10000.
+	pr := NewPR(0.5, 400*math.Pi, 1234, 5, 1/fs)
+	if g := pr.Response(1234, fs); !approxEqualAbs(real(g), 20.5, 1e-9) || !approxEqualAbs(imag(g), 0, 1e-9) {
+		t.Errorf("expected a gain of P+Kr = 20.5 at the resonance, got %v", g)
+	}
+	if g := cmplx.Abs(pr.Response(100, fs)); g > 0.6 {
+		t.Errorf("expected about P away from the resonance, got %f", g)
+	}
+}
+
+func TestPRRejectsSinusoidalDisturbance(t *testing.T) {
+	// a first order plant with a 50 Hz disturbance at its output; the
+	// resonator drives the error at 50 Hz to zero
+	const fs, f = 5000., 50.
+	pr := NewPR(1, 50, f, 0, 1/fs)
+	plant := NewLPF(100, 1/fs)
+	var y, peak float64
+	for n := 0; n < 2*fs; n++ {
+		d := math.Sin(2 * math.Pi * f * float64(n) / fs)
+		e := -(y + d)
+		y = plant.Update(pr.Update(e))
+		if n > 3*fs/2 {
+			peak = math.Max(peak, math.Abs(e))
+		}
+	}
+	if peak > 1e-3 {
+		t.Errorf("expected the disturbance rejected, residual %g", peak)
+	}
+}
@@ -0,0 +1,92 @@
+package pctl
+
+// Predictor advances a delayed measurement by the latency of the sensor or
+// network it arrives through, so that the controller acts on an estimate of
+// the present rather than the past and loses less phase to the delay.
+//
+// The prediction is a linear extrapolation, from the value and slope of a
+// least squares line through the last Window samples, or from the value and
+// rate of a Kalman filter if Kalman is set.  A longer window or a smaller
+// Kalman.Q rejects more noise, at the cost of following changes of slope more
+// slowly.  Extrapolation amplifies noise by roughly the ratio of Latency to
+// the averaging time, so Latency should not be much longer than the window.
+type Predictor struct {
+	// Latency is the delay to compensate, in seconds
+	Latency float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	// Kalman, if non-nil, estimates the value and rate in place of the
+	// window.  It is updated by Predictor.
+	Kalman *Kalman
+
+	buf  []float64
+	head int
+	n    int
+	pred float64
+}
+
+// NewPredictor returns a new predictor advancing by latency seconds, fitting a
+// line through window samples taken every dt seconds.  window < 2 is treated
+// as 2, which extrapolates the last two samples.
+func NewPredictor(latency float64, window int, dt float64) *Predictor {
+	if window < 2 {
+		window = 2
+	}
+	return &Predictor{Latency: latency, DT: dt, buf: make([]float64, window)}
+}
+
+// NewKalmanPredictor returns a new predictor advancing by latency seconds,
+// estimating the value and rate with k
+func NewKalmanPredictor(latency float64, k *Kalman) *Predictor {
+	return &Predictor{Latency: latency, DT: k.DT, Kalman: k}
+}
+
+// Update processes a measurement and returns the prediction of its value
+// Latency seconds later
+func (p *Predictor) Update(meas float64) float64 {
+	if k := p.Kalman; k != nil {
+		k.Update(meas)
+		p.pred = k.Value() + k.Rate()*p.Latency
+		return p.pred
+	}
+	w := len(p.buf)
+	p.buf[p.head] = meas
+	p.head = (p.head + 1) % w
+	if p.n < w {
+		p.n++
+	}
+	if p.n < 2 {
+		p.pred = meas
+		return meas
+	}
+	// fit y = a + b t over t = 0 (oldest) ... n-1 (newest)
+	n := float64(p.n)
+	var sy, sty float64
+	oldest := (p.head - p.n + w) % w
+	for i := 0; i < p.n; i++ {
+		y := p.buf[(oldest+i)%w]
+		sy += y
+		sty += float64(i) * y
+	}
+	st := n * (n - 1) / 2
+	stt := (n - 1) * n * (2*n - 1) / 6
+	b := (n*sty - st*sy) / (n*stt - st*st)
+	a := (sy - b*st) / n
+	p.pred = a + b*(n-1+p.Latency/p.DT)
+	return p.pred
+}
+
+// Prediction returns the prediction of the last update
+func (p *Predictor) Prediction() float64 {
+	return p.pred
+}
+
+// Reset forgets the measurements
+func (p *Predictor) Reset() {
+	p.head, p.n, p.pred = 0, 0, 0
+	if p.Kalman != nil {
+		p.Kalman.Reset()
+	}
+}
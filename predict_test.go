@@ -0,0 +1,46 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPredictorCompensatesRampDelay(t *testing.T) {
+	// a ramp seen through a 5 sample delay is recovered exactly by the window,
+	// and nearly by the Kalman filter once it has learned the rate
+	const dt, delay, rate = 1e-3, 5, 2.
+	lin := NewPredictor(delay*dt, 8, dt)
+	kal := NewKalmanPredictor(delay*dt, NewKalman(1, 1e-6, dt))
+	var truth float64
+	for n := 0; n < 2000; n++ {
+		truth = rate * float64(n) * dt
+		meas := rate * float64(n-delay) * dt
+		lin.Update(meas)
+		kal.Update(meas)
+	}
+	if !approxEqualAbs(lin.Prediction(), truth, 1e-9) {
+		t.Errorf("expected the window to predict %f, got %f", truth, lin.Prediction())
+	}
+	if !approxEqualAbs(kal.Prediction(), truth, 1e-4) {
+		t.Errorf("expected the Kalman filter to predict %f, got %f", truth, kal.Prediction())
+	}
+}
+
+func TestPredictorReducesSineLag(t *testing.T) {
+	const dt, delay, f = 1e-3, 10, 2.
+	p := NewPredictor(delay*dt, 4, dt)
+	var errPred, errRaw float64
+	for n := 0; n < 2000; n++ {
+		meas := math.Sin(2 * math.Pi * f * float64(n-delay) * dt)
+		truth := math.Sin(2 * math.Pi * f * float64(n) * dt)
+		pred := p.Update(meas)
+		if n < 100 {
+			continue
+		}
+		errPred = math.Max(errPred, math.Abs(pred-truth))
+		errRaw = math.Max(errRaw, math.Abs(meas-truth))
+	}
+	if errPred > errRaw/10 {
+		t.Errorf("expected the prediction to cut the error by 10x, got %f vs %f", errPred, errRaw)
+	}
+}
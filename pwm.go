@@ -0,0 +1,56 @@
+package pctl
+
+import "math"
+
+// TimeProportion converts a 0-100% controller output to on/off actuation over
+// a fixed cycle, i.e. slow PWM, for heaters driven by solid state relays and
+// other actuators which cannot accept an analog command.  The output is 1 for
+// on and 0 for off.
+//
+// The duty is sampled at the start of each cycle.  On times shorter than MinOn
+// are skipped and off times shorter than MinOff are filled in, and the on time
+// so lost or gained is carried into later cycles so that the average duty is
+// preserved.
+type TimeProportion struct {
+	// Cycle is the cycle time in seconds
+	Cycle float64
+
+	// MinOn and MinOff are the shortest on and off times, in seconds.  If
+	// zero, they are ignored.
+	MinOn, MinOff float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	step, n, on int
+	residue     float64
+}
+
+// Update processes a duty in percent and returns the on/off output
+func (tp *TimeProportion) Update(input float64) float64 {
+	if tp.step == 0 {
+		tp.n = int(math.Round(tp.Cycle / tp.DT))
+		if tp.n < 1 {
+			tp.n = 1
+		}
+		n := float64(tp.n)
+		want := clamp(input/100, 0, 1)*n + tp.residue
+		on := clamp(math.Round(want), 0, n)
+		if on > 0 && on*tp.DT < tp.MinOn {
+			on = 0
+		}
+		if on < n && (n-on)*tp.DT < tp.MinOff {
+			on = n
+		}
+		tp.residue = clamp(want-on, -n, n)
+		tp.on = int(on)
+	}
+	out := 0.
+	if tp.step < tp.on {
+		out = 1
+	}
+	if tp.step++; tp.step >= tp.n {
+		tp.step = 0
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package pctl
+
+import "testing"
+
+func TestTimeProportionDuty(t *testing.T) {
+	tp := &TimeProportion{Cycle: 1, DT: 0.01}
+	var on float64
+	for i := 0; i < 100; i++ {
+		on += tp.Update(30)
+	}
+	if on != 30 {
+		t.Errorf("expected 30 on steps per cycle at 30%%, got %f", on)
+	}
+}
+
+func TestTimeProportionMinOnPreservesAverage(t *testing.T) {
+	tp := &TimeProportion{Cycle: 1, DT: 0.01, MinOn: 0.05}
+	var on float64
+	pulses := 0
+	prev := 0.
+	for i := 0; i < 1000; i++ {
+		v := tp.Update(1)
+		if v == 1 && prev == 0 {
+			pulses++
+		}
+		on += v
+		prev = v
+	}
+	if on != 10 || pulses != 2 {
+		t.Errorf("expected 10 on steps in 2 pulses over 10 cycles, got %f in %d", on, pulses)
+	}
+}
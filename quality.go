@@ -0,0 +1,135 @@
+package pctl
+
+// Quality is the status of a value, as carried by industrial data systems
+// such as OPC alongside every measurement.  Qualities are ordered from best to
+// worst, so the quality of a value computed from several is the Worst of
+// theirs.
+type Quality uint8
+
+const (
+	// QualityGood values may be used without reservation
+	QualityGood Quality = iota
+
+	// QualityUncertain values are usable, but suspect, e.g. a sensor outside
+	// its calibrated range or a held or substituted value
+	QualityUncertain
+
+	// QualityStale values were good, but have not been refreshed in time
+	QualityStale
+
+	// QualityBad values must not be used, e.g. a failed sensor
+	QualityBad
+)
+
+// String implements fmt.Stringer
+func (q Quality) String() string {
+	switch q {
+	case QualityGood:
+		return "good"
+	case QualityUncertain:
+		return "uncertain"
+	case QualityStale:
+		return "stale"
+	case QualityBad:
+		return "bad"
+	}
+	return "unknown"
+}
+
+// Worst returns the worst of the qualities, or QualityGood if there are none
+func Worst(qs ...Quality) Quality {
+	w := QualityGood
+	for _, q := range qs {
+		if q > w {
+			w = q
+		}
+	}
+	return w
+}
+
+// QSample is a value with its quality
+type QSample struct {
+	Value   float64
+	Quality Quality
+}
+
+// QUpdater is implemented by blocks which process values along with their
+// quality
+type QUpdater interface {
+	UpdateQ(QSample) QSample
+}
+
+// CascadeQ applies a chain of QUpdaters in the sequence given, as Cascade
+func CascadeQ(input QSample, chain ...QUpdater) QSample {
+	for _, elem := range chain {
+		input = elem.UpdateQ(input)
+	}
+	return input
+}
+
+// Degraded selects what a QualityGate does with an input of degraded quality
+type Degraded int
+
+const (
+	// DegradedPass updates the block as normal, and passes the input's
+	// quality to the output
+	DegradedPass Degraded = iota
+
+	// DegradedHold does not update the block, and repeats its last output,
+	// as QualityUncertain.  A controller holds its output and its integrator
+	// does not wind on a bad measurement.
+	DegradedHold
+
+	// DegradedSubstitute does not update the block, and outputs the
+	// gate's Substitute value, as QualityUncertain, e.g. a safe command
+	DegradedSubstitute
+)
+
+// QualityGate is a QUpdater wrapping an Updater, with configurable behavior
+// on inputs of each degraded quality.  Good inputs always update the block,
+// with a good output.
+type QualityGate struct {
+	// Block is the wrapped block
+	Block Updater
+
+	// Uncertain, Stale, and Bad are the behaviors for inputs of each quality
+	Uncertain, Stale, Bad Degraded
+
+	// Substitute is the output for DegradedSubstitute
+	Substitute float64
+
+	out float64
+}
+
+// NewQualityGate returns a QualityGate around u which passes uncertain inputs
+// and holds on stale and bad ones
+func NewQualityGate(u Updater) *QualityGate {
+	return &QualityGate{Block: u, Uncertain: DegradedPass, Stale: DegradedHold, Bad: DegradedHold}
+}
+
+// UpdateQ processes a sample according to its quality
+func (g *QualityGate) UpdateQ(in QSample) QSample {
+	act := DegradedPass
+	switch in.Quality {
+	case QualityGood:
+	case QualityUncertain:
+		act = g.Uncertain
+	case QualityStale:
+		act = g.Stale
+	default:
+		act = g.Bad
+	}
+	switch act {
+	case DegradedHold:
+		return QSample{Value: g.out, Quality: QualityUncertain}
+	case DegradedSubstitute:
+		return QSample{Value: g.Substitute, Quality: QualityUncertain}
+	}
+	g.out = g.Block.Update(in.Value)
+	return QSample{Value: g.out, Quality: in.Quality}
+}
+
+// Update implements Updater, for a good input
+func (g *QualityGate) Update(input float64) float64 {
+	return g.UpdateQ(QSample{Value: input}).Value
+}
@@ -0,0 +1,43 @@
+package pctl
+
+import "testing"
+
+func TestQualityGateHoldsOnBad(t *testing.T) {
+	pid := &PID{P: 1, I: 1, DT: 0.1}
+	g := NewQualityGate(pid)
+	var out QSample
+	for i := 0; i < 5; i++ {
+		out = g.UpdateQ(QSample{Value: -1})
+	}
+	before := pid.SaveState()
+	held := g.UpdateQ(QSample{Value: -100, Quality: QualityBad})
+	if held.Value != out.Value || held.Quality != QualityUncertain {
+		t.Errorf("expected the last output %g held as uncertain, got %v", out.Value, held)
+	}
+	if after := pid.SaveState(); after[0] != before[0] {
+		t.Error("expected the controller not updated on a bad input")
+	}
+	if got := g.UpdateQ(QSample{Value: -1, Quality: QualityUncertain}); got.Quality != QualityUncertain || got.Value == out.Value {
+		t.Errorf("expected an uncertain input processed and passed through, got %v", got)
+	}
+}
+
+func TestQualityGateSubstitute(t *testing.T) {
+	g := &QualityGate{Block: NewLPF(1, 0.1), Stale: DegradedSubstitute, Substitute: 7}
+	if got := g.UpdateQ(QSample{Value: 1, Quality: QualityStale}); got.Value != 7 {
+		t.Errorf("expected the substitute value, got %v", got)
+	}
+	// zero value behavior passes bad inputs, marked bad
+	if got := CascadeQ(QSample{Value: 1, Quality: QualityBad}, g, g); got.Quality != QualityBad {
+		t.Errorf("expected bad quality propagated through the chain, got %v", got)
+	}
+}
+
+func TestWorst(t *testing.T) {
+	if q := Worst(QualityGood, QualityStale, QualityUncertain); q != QualityStale {
+		t.Errorf("expected stale, got %v", q)
+	}
+	if q := Worst(); q != QualityGood {
+		t.Errorf("expected good for no qualities, got %v", q)
+	}
+}
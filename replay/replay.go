@@ -0,0 +1,194 @@
+/*
+Package replay records the inputs of a live control loop and replays them
+bit-exactly through the same blocks under go test, turning field incidents
+into regression tests.
+
+A loop is made reproducible by building it from a Build function, which
+receives the clock and random source the blocks must use.  Live, a Recorder
+supplies a clock which reads the system time once per update, and a random
+source from a recorded seed, and records every input, time, and output.
+Replaying supplies the recorded times and the same seed, so a deterministic
+pipeline produces the recorded outputs to the bit; any difference means its
+behavior has changed.
+*/
+package replay
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// Build returns the pipeline under test, whose blocks must take any time from
+// clock and any randomness from rng
+type Build func(clock pctl.Clock, rng *rand.Rand) pctl.Updater
+
+// Sample is one update of a recorded pipeline
+type Sample struct {
+	// T is the time of the update, in nanoseconds since the Unix epoch
+	T int64
+
+	In, Out float64
+}
+
+// Recording is the record of a run
+type Recording struct {
+	// Seed seeded the pipeline's random source
+	Seed int64
+
+	Samples []Sample
+}
+
+// Mismatch is returned by Replay when an output differs from the recording
+type Mismatch struct {
+	// Index is the sample which differed
+	Index int
+
+	Got, Want float64
+}
+
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("replay: sample %d: output %v, recorded %v", m.Index, m.Got, m.Want)
+}
+
+// Recorder is an Updater which runs a pipeline and records it.  It must be
+// updated from one goroutine.
+//
+// The recording is held in a buffer allocated up front, so Update does not
+// allocate.  When it is full, the pipeline keeps running but further samples
+// are dropped rather than overwriting the first ones: a replay must start
+// from the first update, when the pipeline was built.
+type Recorder struct {
+	clock   pctl.Clock
+	now     time.Time
+	u       pctl.Updater
+	rec     Recording
+	dropped int
+}
+
+// NewRecorder builds the pipeline to record, with a random source seeded by
+// seed and time from clock, or pctl.SystemClock if nil.  It records up to n
+// samples.
+func NewRecorder(build Build, clock pctl.Clock, seed int64, n int) *Recorder {
+	if clock == nil {
+		clock = pctl.SystemClock
+	}
+	r := &Recorder{clock: clock, rec: Recording{Seed: seed, Samples: make([]Sample, 0, n)}}
+	r.u = build(tickClock{&r.now}, rand.New(rand.NewSource(seed)))
+	return r
+}
+
+// Update reads the clock, updates the pipeline, and records the sample if
+// there is room
+func (r *Recorder) Update(input float64) float64 {
+	r.now = r.clock.Now()
+	out := r.u.Update(input)
+	if len(r.rec.Samples) < cap(r.rec.Samples) {
+		r.rec.Samples = append(r.rec.Samples, Sample{T: r.now.UnixNano(), In: input, Out: out})
+	} else {
+		r.dropped++
+	}
+	return out
+}
+
+// Recording returns the recording so far.  It is shared with the Recorder,
+// which appends to it.
+func (r *Recorder) Recording() *Recording {
+	return &r.rec
+}
+
+// Dropped returns the number of samples not recorded because the recording
+// was full
+func (r *Recorder) Dropped() int {
+	return r.dropped
+}
+
+// tickClock reads the time of the update in progress, so that every block
+// sees the same time within an update, and a replay can reproduce it
+type tickClock struct {
+	t *time.Time
+}
+
+func (c tickClock) Now() time.Time {
+	return *c.t
+}
+
+// Replay builds the pipeline afresh and runs the recorded inputs through it
+// at the recorded times, returning a *Mismatch at the first output which
+// differs in any bit from the recording.  NaN outputs match NaN.
+func (rec *Recording) Replay(build Build) error {
+	var now time.Time
+	u := build(tickClock{&now}, rand.New(rand.NewSource(rec.Seed)))
+	for i, s := range rec.Samples {
+		now = time.Unix(0, s.T)
+		out := u.Update(s.In)
+		if math.Float64bits(out) != math.Float64bits(s.Out) && !(math.IsNaN(out) && math.IsNaN(s.Out)) {
+			return &Mismatch{Index: i, Got: out, Want: s.Out}
+		}
+	}
+	return nil
+}
+
+// Write encodes the recording.  The encoding preserves every bit of the
+// values, including NaN and infinities.
+func (rec *Recording) Write(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(rec)
+}
+
+// Read decodes a recording written by Write
+func Read(r io.Reader) (*Recording, error) {
+	rec := &Recording{}
+	if err := gob.NewDecoder(r).Decode(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// SaveFile writes the recording to path.  The file is replaced atomically.
+func (rec *Recording) SaveFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := rec.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadFile reads a recording written by SaveFile
+func LoadFile(path string) (*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Check loads the recording at path, conventionally under testdata, and
+// fails the test if the pipeline does not reproduce it
+func Check(t testing.TB, path string, build Build) {
+	t.Helper()
+	rec, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Replay(build); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,79 @@
+package replay
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// pipeline is a dithered, wall clock filtered PID loop, which depends on both
+// the clock and the random source
+func pipeline(gain float64) Build {
+	return func(clock pctl.Clock, rng *rand.Rand) pctl.Updater {
+		lpf := pctl.NewTimedLPF(5, clock)
+		pid := &pctl.PID{P: gain, I: 0.5, DT: 0.01, Setpt: 1}
+		return updaterFunc(func(x float64) float64 {
+			return pid.Update(lpf.Update(x + 1e-3*rng.NormFloat64()))
+		})
+	}
+}
+
+type updaterFunc func(float64) float64
+
+func (f updaterFunc) Update(x float64) float64 { return f(x) }
+
+func record(t *testing.T) *Recording {
+	clock := &pctl.ManualClock{}
+	clock.Set(time.Unix(1e9, 0))
+	r := NewRecorder(pipeline(2), clock, 42, 200)
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		// irregular sampling, as in the field
+		clock.Advance(time.Duration(5+rng.Intn(10)) * time.Millisecond)
+		r.Update(float64(i%20) / 10)
+	}
+	return r.Recording()
+}
+
+func TestReplayIsBitExact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "incident.gob")
+	if err := record(t).SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	Check(t, path, pipeline(2))
+}
+
+func TestReplayDetectsChange(t *testing.T) {
+	err := record(t).Replay(pipeline(2.0001))
+	m, ok := err.(*Mismatch)
+	if !ok || m.Index != 0 {
+		t.Errorf("expected a mismatch at the first sample for a changed gain, got %v", err)
+	}
+}
+
+func TestRecorderIsBounded(t *testing.T) {
+	r := NewRecorder(pipeline(2), &pctl.ManualClock{}, 1, 10)
+	allocs := testing.AllocsPerRun(50, func() { r.Update(1) })
+	if allocs != 0 {
+		t.Errorf("expected Update not to allocate, got %v allocations", allocs)
+	}
+	if n := len(r.Recording().Samples); n != 10 {
+		t.Errorf("expected 10 samples recorded, got %d", n)
+	}
+	if r.Dropped() != 41 {
+		t.Errorf("expected 41 samples dropped, got %d", r.Dropped())
+	}
+	if err := r.Recording().Replay(pipeline(2)); err != nil {
+		t.Errorf("expected the first samples to replay, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package pctl
+
+// ResetFeedback is implemented by blocks which take external reset feedback:
+// the value actually realized for their last output, after downstream limits,
+// override selectors, and inner loops have had their say.  A controller moves
+// its integral to agree with it, so it does not wind up against a constraint
+// it cannot see, and composites pass it on to the blocks which produced
+// their output.
+//
+// Unlike Tracker, which preloads an idle controller to take over from
+// another source, reset feedback is given on every update to controllers
+// which are in control, or contending for it.
+type ResetFeedback interface {
+	// Achieved reports the value realized for the last output
+	Achieved(output float64)
+}
+
+// limiter is implemented by controllers such as PID which report that their
+// own output was limited
+type limiter interface {
+	Limited() bool
+}
+
+// Achieved implements ResetFeedback by back calculation.  The integral error
+// is moved towards the value which would have produced output at the
+// tracking gain Kt, as for WindupBackCalc, and the slew limit continues from
+// output.  A controller fed back its own output is unchanged.  One which is
+// overridden settles at the realized output plus its own proportional
+// action, as with classic external reset feedback, so it takes over when its
+// error calls for it.
+func (pid *PID) Achieved(output float64) {
+	if output != pid.prevOut {
+		pid.limited = true
+	}
+	dt := pid.prevDT
+	if dt == 0 {
+		dt = pid.DT
+	}
+	if pid.I != 0 {
+		pid.integralErr += pid.kt(dt) * (output - pid.prevOut) / pid.I * dt
+	}
+	pid.prevOut = output
+}
+
+// Select chooses the output of an Override
+type Select int
+
+const (
+	// SelectLow uses the lowest output
+	SelectLow Select = iota
+
+	// SelectHigh uses the highest output
+	SelectHigh
+)
+
+// Override is the selector control of one actuator by several controllers,
+// such as a flow controller with a high pressure limit controller overriding
+// it.  The lowest or highest output is used, and every controller which
+// takes ResetFeedback is fed it back, so that those not selected do not wind
+// up and take over without a bump when their error calls for it.
+type Override struct {
+	// Controllers are the contending controllers
+	Controllers []Updater
+
+	// Select is the selection rule
+	Select Select
+
+	sel int
+	out float64
+}
+
+// NewOverride returns a new Override of the controllers
+func NewOverride(sel Select, controllers ...Updater) *Override {
+	return &Override{Controllers: controllers, Select: sel}
+}
+
+// Update runs each controller on its input, in the order of Controllers, and
+// returns the selected output
+func (o *Override) Update(inputs []float64) float64 {
+	for i, c := range o.Controllers {
+		v := c.Update(inputs[i])
+		if i == 0 || (o.Select == SelectLow && v < o.out) || (o.Select == SelectHigh && v > o.out) {
+			o.sel, o.out = i, v
+		}
+	}
+	o.feedback()
+	return o.out
+}
+
+// Achieved implements ResetFeedback, passing output on to every controller,
+// for when the selected output is itself limited downstream
+func (o *Override) Achieved(output float64) {
+	o.out = output
+	o.feedback()
+}
+
+func (o *Override) feedback() {
+	for _, c := range o.Controllers {
+		if r, ok := c.(ResetFeedback); ok {
+			r.Achieved(o.out)
+		}
+	}
+}
+
+// Selected returns the index of the controller selected on the last update
+func (o *Override) Selected() int {
+	return o.sel
+}
+
+// Output returns the last output
+func (o *Override) Output() float64 {
+	return o.out
+}
+
+// CascadeLoop is a cascade of two controllers: the output of Outer is the
+// setpoint of Inner.  Both take errors in the convention of Setpoint,
+// meas - setpt, so PIDs should be left with a zero Setpt.
+//
+// When the inner loop cannot follow its setpoint, because Inner limited its
+// output or Achieved reports a downstream constraint, Outer is fed the inner
+// measurement as external reset feedback, the setpoint the inner loop is
+// actually achieving, so its integral does not wind up.
+type CascadeLoop struct {
+	Outer, Inner Updater
+
+	// Setpt is the setpoint of the outer loop
+	Setpt float64
+
+	sp, innerMeas, out float64
+}
+
+// Update runs both controllers on their measurements and returns the output
+// of the inner one
+func (c *CascadeLoop) Update(outerMeas, innerMeas float64) float64 {
+	c.sp = c.Outer.Update(outerMeas - c.Setpt)
+	c.out = c.Inner.Update(innerMeas - c.sp)
+	c.innerMeas = innerMeas
+	if l, ok := c.Inner.(limiter); ok && l.Limited() {
+		c.resetOuter()
+	}
+	return c.out
+}
+
+// Achieved implements ResetFeedback, passing output on to Inner.  If it
+// differs from the last output, the inner loop is constrained and Outer is
+// fed back the inner measurement.
+func (c *CascadeLoop) Achieved(output float64) {
+	if r, ok := c.Inner.(ResetFeedback); ok {
+		r.Achieved(output)
+	}
+	if output != c.out {
+		c.out = output
+		c.resetOuter()
+	}
+}
+
+func (c *CascadeLoop) resetOuter() {
+	if r, ok := c.Outer.(ResetFeedback); ok {
+		r.Achieved(c.innerMeas)
+	}
+}
+
+// InnerSetpt returns the setpoint of the inner loop on the last update, the
+// output of Outer
+func (c *CascadeLoop) InnerSetpt() float64 {
+	return c.sp
+}
+
+// Output returns the last output
+func (c *CascadeLoop) Output() float64 {
+	return c.out
+}
@@ -0,0 +1,96 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPIDAchievedOwnOutputIsNoOp(t *testing.T) {
+	a := &PID{P: 2, I: 5, D: 0.01, DT: 1e-3}
+	b := &PID{P: 2, I: 5, D: 0.01, DT: 1e-3}
+	for i := 0; i < 100; i++ {
+		in := math.Sin(0.1 * float64(i))
+		out := a.Update(in)
+		a.Achieved(out)
+		if got := b.Update(in); got != out {
+			t.Fatalf("sample %d: reset feedback of its own output changed the PID, %g != %g", i, out, got)
+		}
+	}
+	if a.Limited() {
+		t.Error("expected an unlimited PID fed back its own output not to report Limited")
+	}
+}
+
+func TestOverrideDoesNotWindUp(t *testing.T) {
+	flow := &PID{P: 1, I: 2, DT: 1e-2}
+	limit := &PID{P: 0.5, I: 2, DT: 1e-2}
+	o := NewOverride(SelectLow, flow, limit)
+	// the flow controller holds zero error; the limit controller sees a
+	// steady error of +1, so it wants a higher output and is not selected
+	for i := 0; i < 5000; i++ {
+		o.Update([]float64{0, -1})
+	}
+	if o.Selected() != 0 {
+		t.Fatalf("expected the flow controller selected, got %d", o.Selected())
+	}
+	// without reset feedback the limit integral would be 5000*1e-2 = 50
+	want := o.Output() + limit.P*1
+	if got := limit.Update(-1); math.Abs(got-want) > 0.05 {
+		t.Errorf("expected the overridden controller to sit at the selected output plus its proportional action, %g, got %g", want, got)
+	}
+	limit.Achieved(o.Output())
+	// the limit is exceeded; the limit controller takes over at once
+	o.Update([]float64{0, 0.2})
+	if o.Selected() != 1 {
+		t.Errorf("expected the limit controller to take over on the first update, got %d", o.Selected())
+	}
+}
+
+func TestCascadeLoopResetsOuterWhenInnerSaturates(t *testing.T) {
+	outer := &PID{P: 2, I: 1, DT: 1e-2}
+	inner := &PID{P: 4, I: 10, DT: 1e-2, OutMin: -1, OutMax: 1}
+	c := &CascadeLoop{Outer: outer, Inner: inner, Setpt: 10}
+	// the inner measurement is stuck at 3 against a saturated actuator
+	for i := 0; i < 5000; i++ {
+		c.Update(0, 3)
+	}
+	if c.Output() != 1 {
+		t.Fatalf("expected the inner loop saturated high, got %g", c.Output())
+	}
+	// the outer loop asks for the inner measurement plus its proportional
+	// action, rather than winding up
+	if sp, want := c.InnerSetpt(), 3+outer.P*10; math.Abs(sp-want) > 0.5 {
+		t.Errorf("expected an inner setpoint near %g, got %g", want, sp)
+	}
+}
+
+func TestCascadeLoopAchievedResetsOuter(t *testing.T) {
+	outer := &PID{P: 1, I: 1, DT: 1e-2}
+	inner := &PID{P: 1, I: 1, DT: 1e-2}
+	c := &CascadeLoop{Outer: outer, Inner: inner}
+	for i := 0; i < 5000; i++ {
+		out := c.Update(-1, 0)
+		c.Achieved(math.Min(out, 0.5)) // a downstream limit
+	}
+	if ie := outer.IErr(); ie > 5 {
+		t.Errorf("expected the outer integral held by reset feedback, got %g", ie)
+	}
+	if !inner.Limited() {
+		t.Error("expected the inner PID to report the downstream limit")
+	}
+}
+
+func TestModeManagerPassesAchievedInAuto(t *testing.T) {
+	pid := &PID{P: 1, I: 1, DT: 1e-2}
+	m := &ModeManager{Controller: pid}
+	if err := m.SetMode(ModeAuto); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		m.Update(-1)
+		m.Achieved(0.5)
+	}
+	if out := m.Update(-1); math.Abs(out-(0.5+pid.P)) > 0.05 {
+		t.Errorf("expected the controller held at the achieved output plus its proportional action, got %g", out)
+	}
+}
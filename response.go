@@ -0,0 +1,146 @@
+package pctl
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// The methods in this file evaluate the frequency response of filters from
+// their coefficients.  They are meant for analysis and are not allocation-free
+// or fast; do not call them in a real-time loop.
+
+// Responder is a filter whose frequency response may be computed.
+// Response returns the complex gain at frequency f (Hz) for sample rate fs.
+type Responder interface {
+	Response(f, fs float64) complex128
+}
+
+// zInv returns z^-1 evaluated at frequency f and sample rate fs
+func zInv(f, fs float64) complex128 {
+	return cmplx.Rect(1, -2*math.Pi*f/fs)
+}
+
+// Response returns the complex gain of the biquad at frequency f (Hz) for
+// sample rate fs
+func (b *Biquad) Response(f, fs float64) complex128 {
+	z1 := zInv(f, fs)
+	z2 := z1 * z1
+	num := complex(b.a0, 0) + complex(b.a1, 0)*z1 + complex(b.a2, 0)*z2
+	den := 1 + complex(b.b1, 0)*z1 + complex(b.b2, 0)*z2
+	return num / den
+}
+
+// Response returns the complex gain of the filter at frequency f (Hz) for
+// sample rate fs
+func (s *SOSFilter) Response(f, fs float64) complex128 {
+	out := complex(1, 0)
+	for i := range s.sections {
+		out *= s.sections[i].Response(f, fs)
+	}
+	return out
+}
+
+// Response returns the complex gain of the filter at frequency f (Hz) for
+// sample rate fs
+func (f *FIRFilter) Response(freq, fs float64) complex128 {
+	z1 := zInv(freq, fs)
+	zn := complex(1, 0)
+	var out complex128
+	// h is stored reversed; walk it backwards to go forward in time
+	l := len(f.x)
+	for i := l - 1; i >= 0; i-- {
+		out += complex(f.h[i], 0) * zn
+		zn *= z1
+	}
+	return out
+}
+
+// Response returns the complex gain of the controller from the error,
+// Setpt - input, to the output at frequency f (Hz) for sample rate fs, using
+// DT and ignoring limits.  With a plant model in a Series it forms the loop
+// transfer function for LoopMargins.
+func (pid *PID) Response(f, fs float64) complex128 {
+	z1 := zInv(f, fs)
+	dt := complex(pid.DT, 0)
+	return complex(pid.P, 0) + complex(pid.I, 0)*dt/(1-z1) + complex(pid.D, 0)*(1-z1)/dt
+}
+
+// Response returns the complex gain of the controller at frequency f (Hz) for
+// sample rate fs
+func (pr *PR) Response(f, fs float64) complex128 {
+	c := pr.c
+	r := (&Biquad{a0: c[0], a1: c[1], a2: c[2], b1: c[3], b2: c[4]}).Response(f, fs)
+	return complex(pr.P, 0) + complex(pr.Kr, 0)*r
+}
+
+// Response returns the complex gain of the transfer function at frequency f
+// (Hz) for sample rate fs
+func (tf TF) Response(f, fs float64) complex128 {
+	return polyZInv(tf.Num, zInv(f, fs)) / polyZInv(tf.Den, zInv(f, fs))
+}
+
+// polyZInv evaluates coefficients c in ascending powers of z^-1 at z1 = z^-1
+func polyZInv(c []float64, z1 complex128) complex128 {
+	var out complex128
+	for i := len(c) - 1; i >= 0; i-- {
+		out = out*z1 + complex(c[i], 0)
+	}
+	return out
+}
+
+// MagDB returns the magnitude of a complex gain in decibels
+func MagDB(h complex128) float64 {
+	return 20 * math.Log10(cmplx.Abs(h))
+}
+
+// PhaseDeg returns the phase of a complex gain in degrees, in (-180, 180]
+func PhaseDeg(h complex128) float64 {
+	return cmplx.Phase(h) * 180 / math.Pi
+}
+
+// UnwrapDeg unwraps a sequence of phases in degrees in place, adding
+// multiples of 360 so that successive values differ by at most 180, and
+// returns it
+func UnwrapDeg(phase []float64) []float64 {
+	var offset float64
+	for i := 1; i < len(phase); i++ {
+		d := phase[i] + offset - phase[i-1]
+		offset -= 360 * math.Round(d/360)
+		phase[i] += offset
+	}
+	return phase
+}
+
+// PhaseResponse returns the unwrapped phase of r in degrees at each of freqs,
+// which should be ascending and close enough that the phase changes by less
+// than 180 degrees between them.  If delay is nonzero, the linear phase of a
+// pure delay of that many seconds is removed, e.g. to see the shape of the
+// phase of a linear phase FIR.
+func PhaseResponse(r Responder, fs float64, freqs []float64, delay float64) []float64 {
+	out := make([]float64, len(freqs))
+	for i, f := range freqs {
+		// remove the delay before unwrapping, as it is often most of the
+		// phase change between frequencies
+		h := r.Response(f, fs) * cmplx.Rect(1, 2*math.Pi*f*delay)
+		out[i] = PhaseDeg(h)
+	}
+	return UnwrapDeg(out)
+}
+
+// GroupDelay returns the group delay of r at frequency f (Hz) for sample rate
+// fs, in seconds; the latency a narrowband signal at f suffers passing through
+// the filter.  It is computed from the phase slope by central difference, so
+// is inaccurate within a small distance of a zero of r.
+func GroupDelay(r Responder, f, fs float64) float64 {
+	df := fs * 1e-6
+	lo, hi := f-df, f+df
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > fs/2 {
+		hi = fs / 2
+	}
+	// the phase of the ratio needs no unwrapping
+	dphi := cmplx.Phase(r.Response(hi, fs) / r.Response(lo, fs))
+	return -dphi / (2 * math.Pi * (hi - lo))
+}
@@ -0,0 +1,42 @@
+package pctl
+
+import "testing"
+
+func TestGroupDelay(t *testing.T) {
+	const fs = 1000
+	// a symmetric FIR of 11 taps delays by 5 samples at all frequencies
+	fir := NewFIRFilter([]float64{1, 2, 3, 4, 5, 6, 5, 4, 3, 2, 1})
+	for _, f := range []float64{0, 10, 100, 300} {
+		if got := GroupDelay(fir, f, fs); !approxEqualAbs(got, 5./fs, 1e-9) {
+			t.Errorf("at %f Hz, expected 5 ms group delay, got %g", f, got)
+		}
+	}
+	// a first order lowpass y = a*y + (1-a)*x delays by a/(1-a) samples at DC
+	const a = 0.9
+	lpf := TF{Num: []float64{1 - a}, Den: []float64{1, -a}}
+	if got := GroupDelay(lpf, 0, fs); !approxEqualAbs(got, a/(1-a)/fs, 1e-8) {
+		t.Errorf("expected DC group delay %g, got %g", a/(1-a)/fs, got)
+	}
+}
+
+func TestPhaseResponseUnwraps(t *testing.T) {
+	const fs = 1000
+	// a delay of 10 samples has a phase of -3.6 degrees per Hz
+	delay := NewFIRFilter([]float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+	var freqs []float64
+	for f := 0.; f <= 400; f += 5 {
+		freqs = append(freqs, f)
+	}
+	ph := PhaseResponse(delay, fs, freqs, 0)
+	for i, f := range freqs {
+		if !approxEqualAbs(ph[i], -3.6*f, 1e-9) {
+			t.Fatalf("at %f Hz, expected unwrapped phase %f, got %f", f, -3.6*f, ph[i])
+		}
+	}
+	ph = PhaseResponse(delay, fs, freqs, 10./fs)
+	for i := range ph {
+		if !approxEqualAbs(ph[i], 0, 1e-9) {
+			t.Fatalf("expected zero phase with the delay removed, got %f", ph[i])
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package pctl
+
+// RangeFlags report a raw input outside its valid range
+type RangeFlags uint8
+
+const (
+	// UnderRange is set when the raw input is below Scale.Min
+	UnderRange RangeFlags = 1 << iota
+
+	// OverRange is set when the raw input is above Scale.Max
+	OverRange
+)
+
+// Scale maps raw values, such as ADC counts, to engineering units by the line
+// through two calibration points (Raw0, Eng0) and (Raw1, Eng1).
+//
+// If Max > Min, raw values outside [Min, Max] are flagged, e.g. to detect an
+// open 4-20 mA loop, and clamped to the range if Clamp is true.
+type Scale struct {
+	// Raw0, Eng0 and Raw1, Eng1 are the calibration points
+	Raw0, Eng0 float64
+	Raw1, Eng1 float64
+
+	// Min and Max are the valid range of the raw input.  They apply when
+	// Max > Min.
+	Min, Max float64
+
+	// Clamp limits raw inputs to [Min, Max] before scaling
+	Clamp bool
+
+	flags RangeFlags
+}
+
+// NewScale returns a new Scale through the calibration points (raw0, eng0)
+// and (raw1, eng1), with no range checking
+func NewScale(raw0, eng0, raw1, eng1 float64) *Scale {
+	return &Scale{Raw0: raw0, Eng0: eng0, Raw1: raw1, Eng1: eng1}
+}
+
+// Update maps a raw input to engineering units
+func (s *Scale) Update(input float64) float64 {
+	s.flags = 0
+	if s.Max > s.Min {
+		switch {
+		case input < s.Min:
+			s.flags = UnderRange
+		case input > s.Max:
+			s.flags = OverRange
+		}
+		if s.Clamp {
+			input = clamp(input, s.Min, s.Max)
+		}
+	}
+	return s.Eng0 + (input-s.Raw0)*(s.Eng1-s.Eng0)/(s.Raw1-s.Raw0)
+}
+
+// Flags returns the range flags of the last update
+func (s *Scale) Flags() RangeFlags {
+	return s.flags
+}
@@ -0,0 +1,24 @@
+package pctl
+
+import "testing"
+
+func TestScaleFourToTwentyMilliamps(t *testing.T) {
+	// a 16 bit ADC reading 0-20 mA, calibrated 4 mA = 0 bar, 20 mA = 10 bar
+	const perMA = 65535. / 20
+	s := NewScale(4*perMA, 0, 20*perMA, 10)
+	s.Min, s.Max = 3.8*perMA, 20.5*perMA
+	if got := s.Update(12 * perMA); !approxEqualAbs(got, 5, 1e-12) || s.Flags() != 0 {
+		t.Errorf("expected 5 bar in range at 12 mA, got %f, flags %v", got, s.Flags())
+	}
+	s.Update(1 * perMA)
+	if s.Flags() != UnderRange {
+		t.Errorf("expected open loop to flag under range, got %v", s.Flags())
+	}
+	s.Clamp = true
+	if got := s.Update(21 * perMA); s.Flags() != OverRange || !approxEqualAbs(got, 10.3125, 1e-12) {
+		t.Errorf("expected clamped 10.3125 bar over range, got %f, flags %v", got, s.Flags())
+	}
+	if NewScale(1, 0, 1, 10).Validate() == nil {
+		t.Error("expected coincident calibration points to fail validation")
+	}
+}
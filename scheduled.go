@@ -0,0 +1,151 @@
+package pctl
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrDesignPoints is returned when the design points of a scheduled
+// controller are empty, out of order, or of differing orders
+var ErrDesignPoints = errors.New("pctl: design points must be nonempty, increasing in At, and of one order")
+
+// SSDesign is a state space controller designed at one operating point
+type SSDesign struct {
+	// At is the value of the scheduling variable at the design point
+	At float64
+
+	A    [][]float64
+	B, C []float64
+	D    float64
+}
+
+// ScheduledStateSpace is a gain scheduled state space controller for plants
+// whose dynamics vary over a wide envelope.  Its A, B, C, and D are linearly
+// interpolated element by element between the design points bracketing the
+// scheduling variable, and held at the nearest end beyond them.
+//
+// The state is carried across changes in the schedule, so the output is
+// continuous as the matrices move.  For that to be meaningful, the designs
+// must share a state coordinate system, such as a canonical form of the same
+// order; interpolating between arbitrary realizations of good controllers
+// need not give a good controller.
+type ScheduledStateSpace struct {
+	designs []SSDesign
+	sched   float64
+
+	a       [][]float64
+	b, c    []float64
+	d       float64
+	x       []float64
+	scratch []float64
+}
+
+// NewScheduledStateSpace returns a new scheduled controller through the given
+// design points, which are copied with their matrices, with the scheduling
+// variable at the first
+func NewScheduledStateSpace(designs ...SSDesign) (*ScheduledStateSpace, error) {
+	if len(designs) == 0 {
+		return nil, ErrDesignPoints
+	}
+	n := len(designs[0].B)
+	for i, d := range designs {
+		if i > 0 && d.At <= designs[i-1].At {
+			return nil, ErrDesignPoints
+		}
+		if len(d.A) != n || len(d.B) != n || len(d.C) != n {
+			return nil, ErrDesignPoints
+		}
+		for _, row := range d.A {
+			if len(row) != n {
+				return nil, ErrDesignPoints
+			}
+		}
+	}
+	s := &ScheduledStateSpace{
+		designs: make([]SSDesign, len(designs)),
+		a:       make([][]float64, n),
+		b:       make([]float64, n),
+		c:       make([]float64, n),
+		x:       make([]float64, n),
+		scratch: make([]float64, n),
+	}
+	for i := range s.a {
+		s.a[i] = make([]float64, n)
+	}
+	for i, d := range designs {
+		a := make([][]float64, n)
+		for j, row := range d.A {
+			a[j] = append([]float64(nil), row...)
+		}
+		s.designs[i] = SSDesign{At: d.At, A: a, B: append([]float64(nil), d.B...), C: append([]float64(nil), d.C...), D: d.D}
+	}
+	s.SetSchedule(designs[0].At)
+	return s, nil
+}
+
+// SetSchedule sets the scheduling variable, interpolating the matrices used
+// by the following updates
+func (s *ScheduledStateSpace) SetSchedule(v float64) {
+	s.sched = v
+	ds := s.designs
+	i := sort.Search(len(ds), func(i int) bool { return ds[i].At >= v })
+	var lo, hi *SSDesign
+	var t float64
+	switch {
+	case i == 0:
+		lo, hi = &ds[0], &ds[0]
+	case i == len(ds):
+		lo, hi = &ds[i-1], &ds[i-1]
+	default:
+		lo, hi = &ds[i-1], &ds[i]
+		t = (v - lo.At) / (hi.At - lo.At)
+	}
+	lerp := func(a, b float64) float64 { return a + t*(b-a) }
+	for r := range s.a {
+		for c := range s.a[r] {
+			s.a[r][c] = lerp(lo.A[r][c], hi.A[r][c])
+		}
+		s.b[r] = lerp(lo.B[r], hi.B[r])
+		s.c[r] = lerp(lo.C[r], hi.C[r])
+	}
+	s.d = lerp(lo.D, hi.D)
+}
+
+// Schedule returns the scheduling variable
+func (s *ScheduledStateSpace) Schedule() float64 {
+	return s.sched
+}
+
+// Update advances the controller one step at the current schedule and returns
+// its output
+func (s *ScheduledStateSpace) Update(input float64) float64 {
+	vectorMatrixProductSumScale(s.x, s.a, s.b, input, s.scratch)
+	out := vectorDot(s.x, s.c) + s.d*input
+	s.x, s.scratch = s.scratch, s.x
+	return out
+}
+
+// UpdateAt sets the scheduling variable to v, then updates the controller
+func (s *ScheduledStateSpace) UpdateAt(input, v float64) float64 {
+	s.SetSchedule(v)
+	return s.Update(input)
+}
+
+// Matrices returns the interpolated A, B, C, and D at the current schedule.
+// They must not be modified.
+func (s *ScheduledStateSpace) Matrices() (A [][]float64, B, C []float64, D float64) {
+	return s.a, s.b, s.c, s.d
+}
+
+// State returns the state vector.  It must not be modified.
+func (s *ScheduledStateSpace) State() []float64 {
+	return s.x
+}
+
+// Reset zeros the controller's state
+func (s *ScheduledStateSpace) Reset() {
+	for i := range s.x {
+		s.x[i] = 0
+		s.scratch[i] = 0
+	}
+}
@@ -0,0 +1,64 @@
+package pctl
+
+import "testing"
+
+// piDesign returns a PI controller in state space form, x' = x + e,
+// u = ki x + kp e
+func piDesign(at, kp, ki float64) SSDesign {
+	return SSDesign{At: at, A: [][]float64{{1}}, B: []float64{1}, C: []float64{ki}, D: kp}
+}
+
+func TestScheduledStateSpaceInterpolates(t *testing.T) {
+	s, err := NewScheduledStateSpace(piDesign(0, 1, 0.1), piDesign(10, 3, 0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ at, kp, ki float64 }{
+		{-5, 1, 0.1},
+		{0, 1, 0.1},
+		{5, 2, 0.3},
+		{10, 3, 0.5},
+		{20, 3, 0.5},
+	} {
+		s.SetSchedule(tc.at)
+		_, _, c, d := s.Matrices()
+		if !approxEqualAbs(d, tc.kp, 1e-12) || !approxEqualAbs(c[0], tc.ki, 1e-12) {
+			t.Errorf("at %g expected kp %g and ki %g, got %g and %g", tc.at, tc.kp, tc.ki, d, c[0])
+		}
+	}
+}
+
+func TestScheduledStateSpaceKeepsState(t *testing.T) {
+	s, _ := NewScheduledStateSpace(piDesign(0, 0, 1), piDesign(1, 0, 2))
+	for i := 0; i < 10; i++ {
+		s.Update(1)
+	}
+	// the integral of the error carries over, and is reweighted
+	if got := s.UpdateAt(0, 1); !approxEqualAbs(got, 20, 1e-12) {
+		t.Errorf("expected the accumulated state of 10 at the new gain of 2, got %g", got)
+	}
+}
+
+func TestScheduledStateSpaceRejectsMismatchedDesigns(t *testing.T) {
+	bad := SSDesign{At: 1, A: [][]float64{{1, 0}, {0, 1}}, B: []float64{1, 0}, C: []float64{1, 0}}
+	if _, err := NewScheduledStateSpace(piDesign(0, 1, 1), bad); err != ErrDesignPoints {
+		t.Errorf("expected ErrDesignPoints for designs of differing order, got %v", err)
+	}
+	if _, err := NewScheduledStateSpace(piDesign(1, 1, 1), piDesign(0, 1, 1)); err != ErrDesignPoints {
+		t.Errorf("expected ErrDesignPoints for decreasing design points, got %v", err)
+	}
+}
+
+func TestScheduledStateSpaceCopiesMatrices(t *testing.T) {
+	lo, hi := piDesign(0, 1, 0.1), piDesign(10, 3, 0.5)
+	s, err := NewScheduledStateSpace(lo, hi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lo.A[0][0], lo.C[0], hi.B[0] = 9, 9, 9
+	s.SetSchedule(5)
+	a, b, c, _ := s.Matrices()
+	if a[0][0] != 1 || b[0] != 1 || !approxEqualAbs(c[0], 0.3, 1e-12) {
+		t.Errorf("expected the schedule unchanged by edits to the designs, got A %v B %v C %v", a, b, c)
+	}
+}
@@ -0,0 +1,102 @@
+package pctl
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Sensitivity is the sensitivity function of a feedback loop, S = 1 / (1 + L)
+// for the loop transfer function L, e.g. Series{controller, plant}.  It is the
+// transfer function from an output disturbance to the output, so |S| < 1
+// where the loop rejects disturbances and |S| > 1 where it amplifies them.
+type Sensitivity struct {
+	Loop Responder
+}
+
+// Response returns the complex gain of S at frequency f (Hz) for sample rate
+// fs
+func (s Sensitivity) Response(f, fs float64) complex128 {
+	return 1 / (1 + s.Loop.Response(f, fs))
+}
+
+// CompSensitivity is the complementary sensitivity function of a feedback
+// loop, T = L / (1 + L).  It is the transfer function from the setpoint to the
+// output, and from sensor noise to the output, so where |T| is near 1 noise
+// passes to the process.  S + T = 1, so the two cannot both be small at any
+// frequency.
+type CompSensitivity struct {
+	Loop Responder
+}
+
+// Response returns the complex gain of T at frequency f (Hz) for sample rate
+// fs
+func (t CompSensitivity) Response(f, fs float64) complex128 {
+	l := t.Loop.Response(f, fs)
+	return l / (1 + l)
+}
+
+// SensitivityResponse returns S and T of the loop formed by controller and
+// plant at each of freqs (Hz), for sample rate fs
+func SensitivityResponse(controller, plant Responder, fs float64, freqs []float64) (s, t []complex128) {
+	loop := Series{controller, plant}
+	s = make([]complex128, len(freqs))
+	t = make([]complex128, len(freqs))
+	for i, f := range freqs {
+		l := loop.Response(f, fs)
+		s[i] = 1 / (1 + l)
+		t[i] = l / (1 + l)
+	}
+	return s, t
+}
+
+// MaxSensitivity returns Ms, the peak of |S| for the loop transfer function
+// between lo and hi Hz at sample rate fs, and the frequency of the peak.  1/Ms
+// is the distance from L to the critical point -1, so Ms is a single measure
+// of robustness; it bounds the gain margin to at least Ms/(Ms-1) and the
+// phase margin to at least 2 asin(1/(2 Ms)).  Values of 1.2 to 2 are typical of
+// robust tunings.
+//
+// The peak is found on the same log spaced scan as LoopMargins, refined by
+// golden section search.
+func MaxSensitivity(loop Responder, fs, lo, hi float64) (ms, f float64) {
+	if hi > fs/2 {
+		hi = fs / 2
+	}
+	s := func(f float64) float64 {
+		return cmplx.Abs(1 / (1 + loop.Response(f, fs)))
+	}
+	ratio := math.Pow(hi/lo, 1/float64(marginPoints-1))
+	best, bestF := -1., lo
+	for i, fi := 0, lo; i < marginPoints; i, fi = i+1, fi*ratio {
+		if v := s(math.Min(fi, hi)); v > best {
+			best, bestF = v, math.Min(fi, hi)
+		}
+	}
+	// golden section over the neighbouring scan points
+	a, b := math.Max(lo, bestF/ratio), math.Min(hi, bestF*ratio)
+	const g = 0.6180339887498949
+	for i := 0; i < 60; i++ {
+		c, d := b-g*(b-a), a+g*(b-a)
+		if s(c) > s(d) {
+			b = d
+		} else {
+			a = c
+		}
+	}
+	if f := (a + b) / 2; s(f) > best {
+		best, bestF = s(f), f
+	}
+	return best, bestF
+}
+
+// CheckMs returns an error if the maximum sensitivity of the loop transfer
+// function, searched from 1e-4 fs to Nyquist, exceeds bound, or nil, so that
+// autotuners and tests can reject tunings which are not robust
+func CheckMs(loop Responder, fs, bound float64) error {
+	ms, f := MaxSensitivity(loop, fs, 1e-4*fs, fs/2)
+	if ms > bound {
+		return fmt.Errorf("pctl: maximum sensitivity of %.3f at %g Hz exceeds %.3f", ms, f, bound)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package pctl
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSensitivityOfIntegratorLoop(t *testing.T) {
+	// L = k T z^-1 / (1 - z^-1) gives S = (1 - z^-1) / (1 - (1 - k T) z^-1)
+	const fs, k = 1000., 50.
+	integ := TF{Num: []float64{0, k / fs}, Den: []float64{1, -1}}
+	want := TF{Num: []float64{1, -1}, Den: []float64{1, -(1 - k/fs)}}
+	freqs := []float64{0.1, 1, 10, 100, 400}
+	s, tt := SensitivityResponse(&PID{P: 1, DT: 1 / fs}, integ, fs, freqs)
+	for i, f := range freqs {
+		if d := cmplx.Abs(s[i] - want.Response(f, fs)); d > 1e-12 {
+			t.Errorf("%f Hz: expected S %v, got %v", f, want.Response(f, fs), s[i])
+		}
+		if d := cmplx.Abs(s[i] + tt[i] - 1); d > 1e-12 {
+			t.Errorf("%f Hz: expected S + T = 1, got %v", f, s[i]+tt[i])
+		}
+		loop := Series{integ}
+		if d := cmplx.Abs(Sensitivity{loop}.Response(f, fs) - s[i]); d > 1e-12 {
+			t.Errorf("%f Hz: Sensitivity disagrees with SensitivityResponse", f)
+		}
+		if d := cmplx.Abs(CompSensitivity{loop}.Response(f, fs) - tt[i]); d > 1e-12 {
+			t.Errorf("%f Hz: CompSensitivity disagrees with SensitivityResponse", f)
+		}
+	}
+}
+
+func TestMaxSensitivityBoundsMargins(t *testing.T) {
+	// the delayed integrator of TestLoopMarginsDelayedIntegrator
+	const fs, k = 1000., 100.
+	loop := Series{
+		TF{Num: []float64{0, k / fs}, Den: []float64{1, -1}},
+		TF{Num: []float64{0, 0, 0, 0, 1}, Den: []float64{1}},
+	}
+	ms, f := MaxSensitivity(loop, fs, 0.1, fs/2)
+	// brute force over a fine linear grid
+	var want float64
+	for fi := 0.1; fi < fs/2; fi += 0.01 {
+		want = math.Max(want, cmplx.Abs(Sensitivity{loop}.Response(fi, fs)))
+	}
+	if ms < want || ms > want*(1+1e-6) || f <= 0 {
+		t.Errorf("expected Ms of %f, got %f at %f Hz", want, ms, f)
+	}
+	m := LoopMargins(loop, fs, 0.1, fs/2)
+	if gm := 20 * math.Log10(ms/(ms-1)); m.GainMargin < gm {
+		t.Errorf("expected a gain margin of at least %f dB, got %f", gm, m.GainMargin)
+	}
+	if CheckMs(loop, fs, ms*1.01) != nil || CheckMs(loop, fs, ms*0.99) == nil {
+		t.Error("expected CheckMs to pass just above Ms and fail just below")
+	}
+}
@@ -0,0 +1,132 @@
+package pctl
+
+// Shadow runs candidate controllers alongside the live one, for evaluating new
+// tunings against production data before promoting them.  Every candidate
+// receives the same input as the live controller, but only the live output is
+// returned; the candidates' would-be outputs are passed to Log and their
+// differences from the live output are accumulated.
+//
+// A shadow controller sees the loop closed through the live one, not itself,
+// so an integrating candidate would wind up on any steady difference.  With
+// Track set, candidates which are Trackers track the live output after each
+// update, and what is compared is their response to each new error, as it
+// would be just after a bumpless switch to them.
+type Shadow struct {
+	// Live is the controller whose output is used
+	Live Updater
+
+	// Track makes Tracker candidates track the live output
+	Track bool
+
+	// Log, if not nil, is called after every update with the input, the live
+	// output, and the candidates' outputs in the order of Names.  The slice
+	// is reused by the next update.
+	Log func(input, live float64, candidates []float64)
+
+	names []string
+	cands []Updater
+	outs  []float64
+	diffs []*Stats
+	last  float64
+}
+
+// NewShadow returns a Shadow around the live controller
+func NewShadow(live Updater) *Shadow {
+	return &Shadow{Live: live}
+}
+
+// Add adds a candidate under name, replacing any of the same name along with
+// its statistics
+func (s *Shadow) Add(name string, u Updater) {
+	if i := s.index(name); i >= 0 {
+		s.cands[i] = u
+		s.diffs[i].Reset()
+		return
+	}
+	s.names = append(s.names, name)
+	s.cands = append(s.cands, u)
+	s.outs = append(s.outs, 0)
+	s.diffs = append(s.diffs, NewStats(0))
+}
+
+// Remove removes the named candidate, returning it, or nil if there is none
+func (s *Shadow) Remove(name string) Updater {
+	i := s.index(name)
+	if i < 0 {
+		return nil
+	}
+	u := s.cands[i]
+	s.names = append(s.names[:i], s.names[i+1:]...)
+	s.cands = append(s.cands[:i], s.cands[i+1:]...)
+	s.outs = append(s.outs[:i], s.outs[i+1:]...)
+	s.diffs = append(s.diffs[:i], s.diffs[i+1:]...)
+	return u
+}
+
+// Promote makes the named candidate the live controller, removing it from
+// the candidates, and returns the previous live controller.  If the candidate
+// is a Tracker, it first tracks the last live output so the change is
+// bumpless.  It returns nil if there is no such candidate.
+func (s *Shadow) Promote(name string) Updater {
+	i := s.index(name)
+	if i < 0 {
+		return nil
+	}
+	u := s.Remove(name)
+	if t, ok := u.(Tracker); ok {
+		t.Track(s.last)
+	}
+	old := s.Live
+	s.Live = u
+	return old
+}
+
+// Names returns the names of the candidates.  It must not be modified.
+func (s *Shadow) Names() []string {
+	return s.names
+}
+
+// Outputs returns the candidates' last outputs, in the order of Names.  It
+// must not be modified.
+func (s *Shadow) Outputs() []float64 {
+	return s.outs
+}
+
+// Diff returns the statistics of the named candidate's output minus the live
+// output, or nil if there is no such candidate
+func (s *Shadow) Diff(name string) *Stats {
+	if i := s.index(name); i >= 0 {
+		return s.diffs[i]
+	}
+	return nil
+}
+
+// Update runs the live controller and every candidate, returning the live
+// output
+func (s *Shadow) Update(input float64) float64 {
+	live := s.Live.Update(input)
+	s.last = live
+	for i, c := range s.cands {
+		out := c.Update(input)
+		s.outs[i] = out
+		s.diffs[i].Update(out - live)
+		if s.Track {
+			if t, ok := c.(Tracker); ok {
+				t.Track(live)
+			}
+		}
+	}
+	if s.Log != nil {
+		s.Log(input, live, s.outs)
+	}
+	return live
+}
+
+func (s *Shadow) index(name string) int {
+	for i, n := range s.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
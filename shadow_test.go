@@ -0,0 +1,41 @@
+package pctl
+
+import "testing"
+
+func TestShadowReturnsLiveOnly(t *testing.T) {
+	s := NewShadow(constant(1))
+	s.Add("two", constant(2))
+	s.Add("four", constant(4))
+	var logged []float64
+	s.Log = func(in, live float64, c []float64) { logged = append(logged[:0], c...) }
+	for i := 0; i < 10; i++ {
+		if out := s.Update(0); out != 1 {
+			t.Fatalf("expected the live output 1, got %g", out)
+		}
+	}
+	if len(logged) != 2 || logged[0] != 2 || logged[1] != 4 {
+		t.Errorf("expected the candidate outputs [2 4] logged, got %v", logged)
+	}
+	if d := s.Diff("four"); d.Count() != 10 || d.Mean() != 3 {
+		t.Errorf("expected 10 differences of 3, got %d with mean %g", d.Count(), d.Mean())
+	}
+}
+
+func TestShadowPromote(t *testing.T) {
+	live := &PID{P: 1, I: 1, DT: 0.1}
+	cand := &PID{P: 1, I: 2, DT: 0.1}
+	s := NewShadow(live)
+	s.Track = true
+	s.Add("fast", cand)
+	var prev float64
+	for i := 0; i < 20; i++ {
+		prev = s.Update(-1)
+	}
+	if old := s.Promote("fast"); old != live || s.Live != cand || len(s.Names()) != 0 {
+		t.Fatal("expected the candidate promoted and removed")
+	}
+	// the promoted controller continues from the live output
+	if out := s.Update(-1); !approxEqualAbs(out, prev+2*0.1, 1e-9) {
+		t.Errorf("expected a bumpless promotion from %g, got %g", prev, out)
+	}
+}
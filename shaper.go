@@ -0,0 +1,134 @@
+package pctl
+
+import "math"
+
+// ShaperKind selects an input shaper
+type ShaperKind int
+
+const (
+	// ZV is the zero vibration shaper of two impulses spanning half a period.
+	// It is the shortest, but most sensitive to error in the frequency.
+	ZV ShaperKind = iota
+
+	// ZVD is the zero vibration and derivative shaper of three impulses
+	// spanning a period, insensitive to small errors in the frequency
+	ZVD
+
+	// EI is the extra insensitive shaper of three impulses spanning a period,
+	// which allows 5% residual vibration at the frequency in exchange for
+	// keeping it below 5% over a still wider range of frequency
+	EI
+)
+
+// eiTolerance is the residual vibration the EI shaper is designed to allow
+const eiTolerance = 0.05
+
+// ShaperImpulses returns the times, in seconds, and amplitudes of the
+// impulses of an input shaper for a mode of frequency freq Hertz (undamped
+// natural) and damping ratio damping.  The amplitudes sum to one.
+//
+// The EI shaper is exact for zero damping; for light damping its impulses are
+// placed and weighted as those of ZVD are, which keeps its residual vibration
+// near 5%.
+func ShaperImpulses(kind ShaperKind, freq, damping float64) (t, a []float64) {
+	wd := math.Sqrt(1 - damping*damping)
+	td := 1 / (freq * wd) // damped period
+	k := math.Exp(-damping * math.Pi / wd)
+	switch kind {
+	case ZV:
+		t, a = []float64{0, td / 2}, []float64{1, k}
+	case ZVD:
+		t, a = []float64{0, td / 2, td}, []float64{1, 2 * k, k * k}
+	case EI:
+		v := eiTolerance
+		t, a = []float64{0, td / 2, td}, []float64{(1 + v) / 4, (1 - v) / 2 * k, (1 + v) / 4 * k * k}
+	default:
+		return []float64{0}, []float64{1}
+	}
+	var sum float64
+	for _, v := range a {
+		sum += v
+	}
+	for i := range a {
+		a[i] /= sum
+	}
+	return t, a
+}
+
+// Shaper is an input shaping prefilter.  It convolves its input, e.g. a
+// setpoint, with a sequence of impulses timed so that the vibration each
+// excites in a flexible load cancels that of the others, so that moves end
+// without residual vibration.  It delays the move by the duration of the
+// shaper, Duration.
+//
+// Impulses which fall between samples are split between the two in
+// proportion, which keeps the timing of the cancellation correct to first
+// order.  Shaper is normally placed ahead of the setpoint, not in the loop.
+type Shaper struct {
+	delays []int
+	amps   []float64
+	buf    []float64
+	head   int
+	dur    float64
+}
+
+// NewShaper returns a new input shaper of the given kind for a mode of
+// frequency freq Hertz and damping ratio damping, updated every dt seconds
+func NewShaper(kind ShaperKind, freq, damping, dt float64) *Shaper {
+	t, a := ShaperImpulses(kind, freq, damping)
+	return NewShaperImpulses(t, a, dt)
+}
+
+// NewShaperImpulses returns a new input shaper from impulse times in seconds
+// and their amplitudes, e.g. from ShaperImpulses, updated every dt seconds.
+// Several shapers may be convolved, for several modes, by cascading them.
+func NewShaperImpulses(t, a []float64, dt float64) *Shaper {
+	s := &Shaper{}
+	max := 0
+	for i := range t {
+		pos := t[i] / dt
+		m := int(math.Floor(pos))
+		frac := pos - float64(m)
+		s.delays = append(s.delays, m)
+		s.amps = append(s.amps, a[i]*(1-frac))
+		if frac > 0 {
+			s.delays = append(s.delays, m+1)
+			s.amps = append(s.amps, a[i]*frac)
+			m++
+		}
+		if m > max {
+			max = m
+		}
+		if t[i] > s.dur {
+			s.dur = t[i]
+		}
+	}
+	s.buf = make([]float64, max+1)
+	return s
+}
+
+// Update processes an input value, returning the shaped output
+func (s *Shaper) Update(input float64) float64 {
+	n := len(s.buf)
+	s.buf[s.head] = input
+	var out float64
+	for i, d := range s.delays {
+		out += s.amps[i] * s.buf[(s.head-d+n)%n]
+	}
+	s.head = (s.head + 1) % n
+	return out
+}
+
+// Duration returns the time from the first impulse to the last, in seconds,
+// which is the delay the shaper adds to a move
+func (s *Shaper) Duration() float64 {
+	return s.dur
+}
+
+// Reset zeros the shaper's history
+func (s *Shaper) Reset() {
+	for i := range s.buf {
+		s.buf[i] = 0
+	}
+	s.head = 0
+}
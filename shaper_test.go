@@ -0,0 +1,62 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+// residualVibration returns the peak vibration of a damped mode left after a
+// unit step is shaped by s, relative to that of an unshaped step
+func residualVibration(s Updater, freq, damping, dt float64) float64 {
+	run := func(shape bool) float64 {
+		// the mode, x'' = wn^2 (u - x) - 2 z wn x', by semi-implicit Euler
+		wn := 2 * math.Pi * freq
+		var x, v, peak float64
+		for n := 0; n < int(4/dt); n++ {
+			u := 1.
+			if shape {
+				u = s.Update(1)
+			}
+			v += dt * (wn*wn*(u-x) - 2*damping*wn*v)
+			x += dt * v
+			if n > int(3/dt) {
+				peak = math.Max(peak, math.Abs(x-1))
+			}
+		}
+		return peak
+	}
+	return run(true) / run(false)
+}
+
+func TestShapersSuppressResidualVibration(t *testing.T) {
+	const f, z, dt = 5., 0.02, 1e-4
+	for _, c := range []struct {
+		kind ShaperKind
+		max  float64
+	}{{ZV, 0.01}, {ZVD, 0.01}, {EI, 0.06}} {
+		if r := residualVibration(NewShaper(c.kind, f, z, dt), f, z, dt); r > c.max {
+			t.Errorf("kind %d: expected residual vibration under %.2f, got %f", c.kind, c.max, r)
+		}
+	}
+	// at a 10% error in the frequency, ZVD and EI hold up where ZV does not
+	zv := residualVibration(NewShaper(ZV, f, z, dt), 1.1*f, z, dt)
+	zvd := residualVibration(NewShaper(ZVD, f, z, dt), 1.1*f, z, dt)
+	ei := residualVibration(NewShaper(EI, f, z, dt), 1.1*f, z, dt)
+	if !(zvd < zv && ei < zv && ei < 0.06) {
+		t.Errorf("expected ZVD and EI more robust than ZV, got ZV %f ZVD %f EI %f", zv, zvd, ei)
+	}
+}
+
+func TestShaperDurationAndGain(t *testing.T) {
+	s := NewShaper(ZVD, 10, 0, 1e-3)
+	if !approxEqualAbs(s.Duration(), 0.1, 1e-12) {
+		t.Errorf("expected a ZVD of one period, 0.1 s, got %f", s.Duration())
+	}
+	var y float64
+	for n := 0; n < 200; n++ {
+		y = s.Update(3)
+	}
+	if !approxEqualAbs(y, 3, 1e-12) {
+		t.Errorf("expected unity DC gain, got %f", y)
+	}
+}
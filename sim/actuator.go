@@ -0,0 +1,97 @@
+package sim
+
+import "math"
+
+// The actuator models are Updaters from command to actuator position, to be
+// placed in front of a plant model, e.g. with config.Chain, so that the loop
+// reproduces the pathologies of real actuators.  Each starts at rest at zero.
+
+// Saturation limits its input to [Min, Max]
+type Saturation struct {
+	Min, Max float64
+}
+
+// Update returns the input, limited
+func (s *Saturation) Update(input float64) float64 {
+	return math.Max(s.Min, math.Min(s.Max, input))
+}
+
+// RateLimit follows its input at no more than Rate units per second
+type RateLimit struct {
+	// Rate is the maximum rate of change, in units per second
+	Rate float64
+
+	// DT is the inter-update time in seconds
+	DT float64
+
+	out float64
+}
+
+// Update moves the output towards input and returns it
+func (r *RateLimit) Update(input float64) float64 {
+	step := r.Rate * r.DT
+	r.out += math.Max(-step, math.Min(step, input-r.out))
+	return r.out
+}
+
+// Backlash is the play of a gear train or leadscrew: the output follows the
+// input only once the input has taken up a gap of Width, so that every
+// reversal of the input loses Width of travel
+type Backlash struct {
+	// Width is the total play, in input units
+	Width float64
+
+	out float64
+}
+
+// Update returns the position of the driven side
+func (b *Backlash) Update(input float64) float64 {
+	h := b.Width / 2
+	b.out = math.Max(input-h, math.Min(input+h, b.out))
+	return b.out
+}
+
+// Stiction is the stick-slip of a valve or a mechanism with static friction,
+// by the two parameter model of Choudhury, Thornhill, and Shah.  When stuck,
+// the output holds until the input moves Band from where it stuck, or Jump if
+// it continues in the direction of the last move; it then slips, jumping
+// towards the input, and follows it less half the deadband, (Band - Jump)/2,
+// until the input stops or reverses.
+type Stiction struct {
+	// Band is the deadband plus stickband, in input units
+	Band float64
+
+	// Jump is the slip jump, in input units; Jump < Band.  If Jump is zero,
+	// the model is a pure deadband of Band.
+	Jump float64
+
+	out, stuckAt, prev float64
+	dir                float64
+	moving             bool
+}
+
+// Update returns the actuator position
+func (s *Stiction) Update(input float64) float64 {
+	vel := input - s.prev
+	s.prev = input
+	lag := (s.Band - s.Jump) / 2
+	if s.moving {
+		if vel != 0 && math.Signbit(vel) == math.Signbit(s.dir) {
+			s.out = input - s.dir*lag
+			return s.out
+		}
+		s.moving = false
+		s.stuckAt = input - vel
+	}
+	e := input - s.stuckAt
+	thresh := s.Band
+	if s.dir != 0 && math.Signbit(e) == math.Signbit(s.dir) {
+		thresh = s.Jump
+	}
+	if e != 0 && math.Abs(e) > thresh {
+		s.dir = math.Copysign(1, e)
+		s.moving = true
+		s.out = input - s.dir*lag
+	}
+	return s.out
+}
@@ -0,0 +1,94 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/config"
+)
+
+func TestSaturationAndRateLimit(t *testing.T) {
+	s := Saturation{Min: -1, Max: 2}
+	if s.Update(5) != 2 || s.Update(-5) != -1 || s.Update(0.5) != 0.5 {
+		t.Errorf("saturation did not clamp to [-1, 2]")
+	}
+	r := RateLimit{Rate: 10, DT: 0.01}
+	var out float64
+	for i := 0; i < 5; i++ {
+		out = r.Update(1)
+	}
+	if math.Abs(out-0.5) > 1e-12 {
+		t.Errorf("expected the rate limit to reach 0.5 in 5 steps, got %g", out)
+	}
+	for i := 0; i < 10; i++ {
+		out = r.Update(1)
+	}
+	if out != 1 {
+		t.Errorf("expected the rate limit to settle on its input, got %g", out)
+	}
+}
+
+func TestBacklashLosesTravelOnReversal(t *testing.T) {
+	b := Backlash{Width: 0.2}
+	if out := b.Update(1); math.Abs(out-0.9) > 1e-12 {
+		t.Errorf("expected the output to trail by half the play, got %g", out)
+	}
+	// reversing within the play does not move the output
+	if out := b.Update(0.85); math.Abs(out-0.9) > 1e-12 {
+		t.Errorf("expected the output held within the play, got %g", out)
+	}
+	if out := b.Update(0.5); math.Abs(out-0.6) > 1e-12 {
+		t.Errorf("expected the output to lead by half the play, got %g", out)
+	}
+}
+
+func TestStictionStickSlip(t *testing.T) {
+	s := Stiction{Band: 0.4, Jump: 0.1}
+	var out float64
+	for u := 0.; u <= 0.39; u += 0.01 {
+		if out = s.Update(u); out != 0 {
+			t.Fatalf("expected the actuator stuck below the band, moved to %g at %g", out, u)
+		}
+	}
+	// slips past the band, jumping to within half the deadband of the input
+	out = s.Update(0.41)
+	if math.Abs(out-(0.41-0.15)) > 1e-12 {
+		t.Errorf("expected a slip to 0.26, got %g", out)
+	}
+	out = s.Update(0.5)
+	if math.Abs(out-0.35) > 1e-12 {
+		t.Errorf("expected the moving actuator to follow, got %g", out)
+	}
+	// it sticks on stopping, and resists reversal by the whole band
+	s.Update(0.5)
+	if out = s.Update(0.2); out != 0.35 {
+		t.Errorf("expected the actuator stuck on reversal, got %g", out)
+	}
+	if out = s.Update(0.05); math.Abs(out-0.2) > 1e-12 {
+		t.Errorf("expected a slip on reversal past the band to 0.2, got %g", out)
+	}
+}
+
+func TestStictionCausesLimitCycle(t *testing.T) {
+	const dt = 1e-3
+	// ripple is the peak to peak measurement over the last two seconds
+	ripple := func(act pctl.Updater) float64 {
+		l := Loop{
+			Controller: &pctl.PID{P: 1, I: 60, DT: dt},
+			Plant:      config.Chain{act, pctl.NewLPF(5, dt)},
+		}
+		r := Run(l, Scenario{DT: dt, Duration: 5, Setpoint: []Event{{T: 0.1, Value: 1}}})
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, m := range r.Measurement[3000:] {
+			lo, hi = math.Min(lo, m), math.Max(hi, m)
+		}
+		return hi - lo
+	}
+	if p := ripple(&Saturation{Min: -10, Max: 10}); p > 1e-3 {
+		t.Fatalf("expected the loop to settle without stiction, ripple %g", p)
+	}
+	if p := ripple(&Stiction{Band: 0.1, Jump: 0.05}); p < 0.01 {
+		t.Errorf("expected stiction to keep the loop hunting, ripple %g", p)
+	}
+}
@@ -0,0 +1,154 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Metric selects a scalar from Metrics, e.g. for summarizing a Monte Carlo
+// study or as the cost of a tuner
+type Metric func(Metrics) float64
+
+// Common metrics
+var (
+	MetricIAE          Metric = func(m Metrics) float64 { return m.IAE }
+	MetricISE          Metric = func(m Metrics) float64 { return m.ISE }
+	MetricITAE         Metric = func(m Metrics) float64 { return m.ITAE }
+	MetricOvershoot    Metric = func(m Metrics) float64 { return m.Overshoot }
+	MetricSettlingTime Metric = func(m Metrics) float64 { return m.SettlingTime }
+)
+
+// Param is a plant model parameter perturbed by a Monte Carlo study
+type Param struct {
+	// Name identifies the parameter in reports
+	Name string `json:"name"`
+
+	// Min and Max bound the values drawn, uniformly
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+
+	// Log draws uniformly in the logarithm of the value instead, for scale
+	// parameters such as gains and time constants spanning a decade or more.
+	// Min and Max must then be positive.
+	Log bool `json:"log"`
+}
+
+// draw returns a random value of the parameter
+func (p Param) draw(r *rand.Rand) float64 {
+	u := r.Float64()
+	if p.Log {
+		lo, hi := math.Log(p.Min), math.Log(p.Max)
+		return math.Exp(lo + u*(hi-lo))
+	}
+	return p.Min + u*(p.Max-p.Min)
+}
+
+// MonteCarlo validates a tuning against plant uncertainty: it draws the
+// plant parameters at random over their ranges, simulates the closed loop for
+// each draw, and reports the distribution of the metrics
+type MonteCarlo struct {
+	// Params are the perturbed parameters
+	Params []Param
+
+	// Build returns a new loop, with a fresh controller and a plant built from
+	// the parameter values, in the order of Params
+	Build func(params []float64) Loop
+
+	// Scenario is simulated for every trial
+	Scenario Scenario
+
+	// Trials is the number of draws
+	Trials int
+
+	// Seed seeds the random draws, so that a study is repeatable
+	Seed int64
+}
+
+// Trial is the outcome of one draw of a Monte Carlo study
+type Trial struct {
+	Params  []float64 `json:"params"`
+	Metrics Metrics   `json:"metrics"`
+}
+
+// Report holds the trials of a Monte Carlo study
+type Report struct {
+	Params []Param `json:"params"`
+	Trials []Trial `json:"trials"`
+}
+
+// Run runs the study
+func (mc MonteCarlo) Run() *Report {
+	r := rand.New(rand.NewSource(mc.Seed))
+	rep := &Report{Params: mc.Params, Trials: make([]Trial, mc.Trials)}
+	for i := range rep.Trials {
+		p := make([]float64, len(mc.Params))
+		for j, par := range mc.Params {
+			p[j] = par.draw(r)
+		}
+		rep.Trials[i] = Trial{Params: p, Metrics: Run(mc.Build(p), mc.Scenario).Metrics()}
+	}
+	return rep
+}
+
+// values returns the metric of every trial, ascending, with NaN (a loop
+// which blew up) sorted as +Inf
+func (rep *Report) values(m Metric) []float64 {
+	v := make([]float64, len(rep.Trials))
+	for i, t := range rep.Trials {
+		v[i] = m(t.Metrics)
+		if math.IsNaN(v[i]) {
+			v[i] = math.Inf(1)
+		}
+	}
+	sort.Float64s(v)
+	return v
+}
+
+// Percentile returns the p-th percentile (0 to 100) of the metric over the
+// trials, interpolating between them
+func (rep *Report) Percentile(m Metric, p float64) float64 {
+	v := rep.values(m)
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	x := p / 100 * float64(len(v)-1)
+	i := int(math.Floor(x))
+	if i >= len(v)-1 {
+		return v[len(v)-1]
+	}
+	if i < 0 {
+		return v[0]
+	}
+	frac := x - float64(i)
+	if frac == 0 {
+		return v[i]
+	}
+	return v[i] + frac*(v[i+1]-v[i])
+}
+
+// Mean returns the mean of the metric over the trials
+func (rep *Report) Mean(m Metric) float64 {
+	var sum float64
+	for _, v := range rep.values(m) {
+		sum += v
+	}
+	return sum / float64(len(rep.Trials))
+}
+
+// Worst returns the trial with the largest value of the metric, e.g. to
+// reproduce it
+func (rep *Report) Worst(m Metric) Trial {
+	var worst Trial
+	best := math.Inf(-1)
+	for _, t := range rep.Trials {
+		v := m(t.Metrics)
+		if math.IsNaN(v) {
+			v = math.Inf(1)
+		}
+		if v > best || best == math.Inf(-1) {
+			best, worst = v, t
+		}
+	}
+	return worst
+}
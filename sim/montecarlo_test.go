@@ -0,0 +1,54 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brandondube/pctl"
+)
+
+// gainLag is a plant of a gain and a first order lag
+type gainLag struct {
+	g   float64
+	lpf *pctl.LPF
+}
+
+func (p *gainLag) Update(u float64) float64 {
+	return p.lpf.Update(p.g * u)
+}
+
+func TestMonteCarloSpreadsWithPlantGain(t *testing.T) {
+	const dt = 1e-3
+	mc := MonteCarlo{
+		Params: []Param{{Name: "gain", Min: 0.5, Max: 2, Log: true}, {Name: "corner", Min: 3, Max: 8}},
+		Build: func(p []float64) Loop {
+			return Loop{
+				Controller: &pctl.PID{P: 2, I: 20, DT: dt},
+				Plant:      &gainLag{g: p[0], lpf: pctl.NewLPF(p[1], dt)},
+			}
+		},
+		Scenario: Scenario{DT: dt, Duration: 3, Setpoint: []Event{{T: 0.1, Value: 1}}},
+		Trials:   50,
+		Seed:     1,
+	}
+	rep := mc.Run()
+	if len(rep.Trials) != 50 {
+		t.Fatalf("expected 50 trials, got %d", len(rep.Trials))
+	}
+	for _, tr := range rep.Trials {
+		if g := tr.Params[0]; g < 0.5 || g > 2 {
+			t.Fatalf("gain %f drawn outside its range", g)
+		}
+	}
+	lo, med, hi := rep.Percentile(MetricIAE, 0), rep.Percentile(MetricIAE, 50), rep.Percentile(MetricIAE, 100)
+	if !(lo < med && med < hi) || math.IsInf(hi, 1) {
+		t.Errorf("expected a finite spread of IAE, got %f %f %f", lo, med, hi)
+	}
+	// the lowest loop gain is the most sluggish
+	if w := rep.Worst(MetricIAE); w.Metrics.IAE != hi || w.Params[0] > 1 {
+		t.Errorf("expected the worst IAE %f from a low plant gain, got %+v", hi, w)
+	}
+	if again := mc.Run(); again.Trials[7].Metrics != rep.Trials[7].Metrics {
+		t.Error("expected a repeatable study for a fixed seed")
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Package sim runs closed-loop simulations of pctl controllers against plant
+models.
+
+The loop follows the convention of pctl.Setpoint: the controller is fed the
+process error, measurement - setpoint, and its output is summed with the
+disturbance and fed to the plant.  A PID with Setpt = 0 therefore acts on
+setpoint - measurement, as usual.
+*/
+package sim
+
+import (
+	"math"
+	"sort"
+
+	"github.com/brandondube/pctl"
+)
+
+// Event is a step change to a signal at time T, in seconds
+type Event struct {
+	T     float64 `json:"t"`
+	Value float64 `json:"value"`
+}
+
+// Scenario describes the inputs to a simulation
+type Scenario struct {
+	// DT is the inter-update time in seconds
+	DT float64 `json:"dt"`
+
+	// Duration is the length of the simulation in seconds
+	Duration float64 `json:"duration"`
+
+	// Setpoint is the sequence of setpoint steps.  The setpoint is zero
+	// before the first event.
+	Setpoint []Event `json:"setpoint"`
+
+	// Disturbance is the sequence of steps in the disturbance, which is added
+	// to the plant input.  The disturbance is zero before the first event.
+	Disturbance []Event `json:"disturbance"`
+
+	// DisturbanceTrace is a recorded disturbance, one sample per update from
+	// the start, added to the steps of Disturbance.  It is zero beyond its
+	// end.  Replaying one trace through several controllers compares them on
+	// identical conditions.
+	DisturbanceTrace []float64 `json:"disturbance_trace,omitempty"`
+}
+
+// disturbance returns the disturbance on update i, at time t
+func (s Scenario) disturbance(d schedule, i int, t float64) float64 {
+	v := d.at(t)
+	if i < len(s.DisturbanceTrace) {
+		v += s.DisturbanceTrace[i]
+	}
+	return v
+}
+
+// Loop is a controller closed around a plant
+type Loop struct {
+	// Controller maps process error (meas - setpt) to a command
+	Controller pctl.Updater
+
+	// Plant maps its input (command + disturbance) to a measurement
+	Plant pctl.Updater
+}
+
+// Result holds the time series of a simulation, one element per update
+type Result struct {
+	T           []float64
+	Setpoint    []float64
+	Measurement []float64
+	Command     []float64
+	Disturbance []float64
+}
+
+// Len is the number of samples in the result
+func (r *Result) Len() int {
+	return len(r.T)
+}
+
+// Run simulates the loop over the scenario
+func Run(l Loop, s Scenario) *Result {
+	n := 0
+	if s.DT > 0 {
+		n = int(math.Round(s.Duration / s.DT))
+	}
+	r := &Result{
+		T:           make([]float64, n),
+		Setpoint:    make([]float64, n),
+		Measurement: make([]float64, n),
+		Command:     make([]float64, n),
+		Disturbance: make([]float64, n),
+	}
+	setpt := newSchedule(s.Setpoint)
+	dist := newSchedule(s.Disturbance)
+	var meas float64
+	for i := 0; i < n; i++ {
+		t := float64(i) * s.DT
+		sp := setpt.at(t)
+		d := s.disturbance(dist, i, t)
+		cmd := l.Controller.Update(meas - sp)
+		r.T[i] = t
+		r.Setpoint[i] = sp
+		r.Measurement[i] = meas
+		r.Command[i] = cmd
+		r.Disturbance[i] = d
+		meas = l.Plant.Update(cmd + d)
+	}
+	return r
+}
+
+// Inputs returns the setpoint and disturbance of the scenario on each update,
+// for running it outside of Run, such as on the live plant
+func (s Scenario) Inputs() (setpt, dist []float64) {
+	n := 0
+	if s.DT > 0 {
+		n = int(math.Round(s.Duration / s.DT))
+	}
+	setpt, dist = make([]float64, n), make([]float64, n)
+	sp, d := newSchedule(s.Setpoint), newSchedule(s.Disturbance)
+	for i := range setpt {
+		t := float64(i) * s.DT
+		setpt[i], dist[i] = sp.at(t), s.disturbance(d, i, t)
+	}
+	return setpt, dist
+}
+
+// schedule is a sorted sequence of events, read forward in time
+type schedule []Event
+
+func newSchedule(ev []Event) schedule {
+	out := make(schedule, len(ev))
+	copy(out, ev)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].T < out[j].T })
+	return out
+}
+
+// at returns the value of the schedule at time t
+func (s schedule) at(t float64) float64 {
+	var v float64
+	for _, e := range s {
+		if e.T > t {
+			break
+		}
+		v = e.Value
+	}
+	return v
+}
+
+// Metrics summarizes the tracking performance of a simulation
+type Metrics struct {
+	// IAE is the integral of absolute error
+	IAE float64 `json:"iae"`
+
+	// ISE is the integral of squared error
+	ISE float64 `json:"ise"`
+
+	// ITAE is the integral of time-weighted absolute error
+	ITAE float64 `json:"itae"`
+
+	// Overshoot is the peak excursion past the final setpoint, as a fraction
+	// of the last setpoint step
+	Overshoot float64 `json:"overshoot"`
+
+	// SettlingTime is the time from the last setpoint step until the
+	// measurement stays within SettleBand of the final setpoint, in seconds.
+	// It is +Inf if the measurement never settles.
+	SettlingTime float64 `json:"settling_time"`
+
+	// FinalError is setpoint - measurement on the last sample
+	FinalError float64 `json:"final_error"`
+}
+
+// SettleBand is the fraction of the last setpoint step used to define
+// the settling time.
+const SettleBand = 0.02
+
+// Metrics computes summary metrics of the result
+func (r *Result) Metrics() Metrics {
+	var m Metrics
+	n := r.Len()
+	if n == 0 {
+		return m
+	}
+	dt := 0.
+	if n > 1 {
+		dt = r.T[1] - r.T[0]
+	}
+	for i := 0; i < n; i++ {
+		e := r.Setpoint[i] - r.Measurement[i]
+		m.IAE += math.Abs(e) * dt
+		m.ISE += e * e * dt
+		m.ITAE += r.T[i] * math.Abs(e) * dt
+	}
+	final := r.Setpoint[n-1]
+	m.FinalError = final - r.Measurement[n-1]
+
+	// locate the last setpoint step
+	start := 0
+	prev := 0.
+	for i := 0; i < n; i++ {
+		if r.Setpoint[i] != prev {
+			start = i
+		}
+		prev = r.Setpoint[i]
+	}
+	step := final
+	if start > 0 {
+		step = final - r.Setpoint[start-1]
+	}
+	band := SettleBand * math.Abs(step)
+	if band == 0 {
+		band = SettleBand
+	}
+	sign := 1.
+	if step < 0 {
+		sign = -1
+	}
+	settled := n
+	for i := n - 1; i >= start; i-- {
+		if math.Abs(r.Measurement[i]-final) > band {
+			break
+		}
+		settled = i
+	}
+	if settled == n {
+		m.SettlingTime = math.Inf(1)
+	} else {
+		m.SettlingTime = r.T[settled] - r.T[start]
+	}
+	if step != 0 {
+		for i := start; i < n; i++ {
+			over := sign * (r.Measurement[i] - final) / math.Abs(step)
+			if over > m.Overshoot {
+				m.Overshoot = over
+			}
+		}
+	}
+	return m
+}
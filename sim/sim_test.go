@@ -0,0 +1,62 @@
+package sim
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brandondube/pctl"
+)
+
+func TestPIControlOfFirstOrderPlantSettles(t *testing.T) {
+	const dt = 1e-3
+	l := Loop{
+		Controller: &pctl.PID{P: 2, I: 20, DT: dt},
+		Plant:      pctl.NewLPF(5, dt),
+	}
+	s := Scenario{
+		DT:       dt,
+		Duration: 3,
+		Setpoint: []Event{{T: 0.1, Value: 1}},
+	}
+	res := Run(l, s)
+	if res.Len() != 3000 {
+		t.Fatalf("expected 3000 samples, got %d", res.Len())
+	}
+	m := res.Metrics()
+	if math.Abs(m.FinalError) > 1e-3 {
+		t.Errorf("PI loop has final error of %f, expected to converge", m.FinalError)
+	}
+	if math.IsInf(m.SettlingTime, 1) || m.SettlingTime > 2 {
+		t.Errorf("PI loop settling time of %f is too long", m.SettlingTime)
+	}
+	if m.IAE <= 0 {
+		t.Errorf("expected nonzero IAE for a step, got %f", m.IAE)
+	}
+}
+
+func TestScheduleHoldsLastValue(t *testing.T) {
+	s := newSchedule([]Event{{T: 2, Value: 3}, {T: 1, Value: 1}})
+	cases := []struct{ t, v float64 }{{0, 0}, {1, 1}, {1.5, 1}, {2, 3}, {9, 3}}
+	for _, c := range cases {
+		if got := s.at(c.t); got != c.v {
+			t.Errorf("schedule at t=%f was %f, expected %f", c.t, got, c.v)
+		}
+	}
+}
+
+func TestScenarioInputs(t *testing.T) {
+	s := Scenario{DT: 0.1, Duration: 1,
+		Setpoint:         []Event{{T: 0.5, Value: 2}, {T: 0.2, Value: 1}},
+		Disturbance:      []Event{{T: 0.7, Value: -1}},
+		DisturbanceTrace: []float64{0.5, 0.25}}
+	setpt, dist := s.Inputs()
+	if len(setpt) != 10 || len(dist) != 10 {
+		t.Fatalf("expected 10 samples, got %d and %d", len(setpt), len(dist))
+	}
+	if setpt[1] != 0 || setpt[3] != 1 || setpt[6] != 2 {
+		t.Errorf("setpoint schedule wrong: %v", setpt)
+	}
+	if dist[0] != 0.5 || dist[1] != 0.25 || dist[6] != 0 || dist[8] != -1 {
+		t.Errorf("disturbance schedule wrong: %v", dist)
+	}
+}
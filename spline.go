@@ -0,0 +1,85 @@
+package pctl
+
+import "sort"
+
+// Spline is a natural cubic spline through calibration points, for smooth
+// feedforward and compensation curves where the kinks of a piecewise linear
+// map cause artifacts in the loop.  Inputs beyond the points are clamped to
+// the end values, or extrapolated along the end slopes if Extrapolate is true.
+type Spline struct {
+	// Extrapolate extends the spline linearly beyond the end points instead
+	// of clamping
+	Extrapolate bool
+
+	x, y []float64
+
+	// m are the second derivatives at the points
+	m []float64
+}
+
+// NewSpline returns a new natural cubic spline through pts, which must be
+// strictly increasing in X
+func NewSpline(pts ...Point) (*Spline, error) {
+	const typ = "Spline"
+	n := len(pts)
+	if n < 2 {
+		return nil, paramErr(typ, "points", "must have at least two points")
+	}
+	s := &Spline{x: make([]float64, n), y: make([]float64, n), m: make([]float64, n)}
+	for i, pt := range pts {
+		if !finite(pt.X) || !finite(pt.Y) {
+			return nil, paramErr(typ, "points", "must be finite")
+		}
+		if i > 0 && pt.X <= pts[i-1].X {
+			return nil, paramErr(typ, "points", "must be strictly increasing in x")
+		}
+		s.x[i], s.y[i] = pt.X, pt.Y
+	}
+	// solve the tridiagonal system for the interior second derivatives with
+	// the Thomas algorithm; the natural end conditions are m[0] = m[n-1] = 0
+	c := make([]float64, n)
+	d := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		h0, h1 := s.x[i]-s.x[i-1], s.x[i+1]-s.x[i]
+		a, b := h0, 2*(h0+h1)
+		rhs := 6 * ((s.y[i+1]-s.y[i])/h1 - (s.y[i]-s.y[i-1])/h0)
+		w := b - a*c[i-1]
+		c[i] = h1 / w
+		d[i] = (rhs - a*d[i-1]) / w
+	}
+	for i := n - 2; i > 0; i-- {
+		s.m[i] = d[i] - c[i]*s.m[i+1]
+	}
+	return s, nil
+}
+
+// Update returns the spline value at input
+func (s *Spline) Update(input float64) float64 {
+	n := len(s.x)
+	x := input
+	if x <= s.x[0] || x >= s.x[n-1] {
+		i, e := 0, 0
+		if x >= s.x[n-1] {
+			i, e = n-2, n-1
+		}
+		if !s.Extrapolate {
+			return s.y[e]
+		}
+		return s.y[e] + (x-s.x[e])*s.slope(i, s.x[e])
+	}
+	i := sort.SearchFloat64s(s.x, x) - 1
+	h := s.x[i+1] - s.x[i]
+	a := (s.x[i+1] - x) / h
+	b := (x - s.x[i]) / h
+	return a*s.y[i] + b*s.y[i+1] +
+		((a*a*a-a)*s.m[i]+(b*b*b-b)*s.m[i+1])*h*h/6
+}
+
+// slope returns the first derivative of segment i at x
+func (s *Spline) slope(i int, x float64) float64 {
+	h := s.x[i+1] - s.x[i]
+	a := (s.x[i+1] - x) / h
+	b := (x - s.x[i]) / h
+	return (s.y[i+1]-s.y[i])/h +
+		((1-3*a*a)*s.m[i]+(3*b*b-1)*s.m[i+1])*h/6
+}
@@ -0,0 +1,41 @@
+package pctl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSplineInterpolatesSmoothly(t *testing.T) {
+	var pts []Point
+	for i := 0; i <= 8; i++ {
+		x := float64(i) * math.Pi / 8
+		pts = append(pts, Point{x, math.Sin(x)})
+	}
+	s, err := NewSpline(pts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pt := range pts {
+		if got := s.Update(pt.X); !approxEqualAbs(got, pt.Y, 1e-12) {
+			t.Errorf("spline misses knot (%f, %f), got %f", pt.X, pt.Y, got)
+		}
+	}
+	for x := 0.1; x < math.Pi; x += 0.1 {
+		if got := s.Update(x); !approxEqualAbs(got, math.Sin(x), 2e-3) {
+			t.Errorf("spline at %f is %f, expected near sin = %f", x, got, math.Sin(x))
+		}
+	}
+	if s.Update(-1) != 0 || s.Update(4) != pts[8].Y {
+		t.Error("expected clamping beyond the ends")
+	}
+}
+
+func TestSplineLinearData(t *testing.T) {
+	s, _ := NewSpline(Point{0, 1}, Point{1, 3}, Point{4, 9})
+	s.Extrapolate = true
+	for _, x := range []float64{-1, 0.5, 2, 6} {
+		if got := s.Update(x); !approxEqualAbs(got, 1+2*x, 1e-12) {
+			t.Errorf("spline of a line at %f is %f, expected %f", x, got, 1+2*x)
+		}
+	}
+}
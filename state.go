@@ -0,0 +1,316 @@
+package pctl
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStateSize is returned by RestoreState when the saved state is not the
+// size of the block's state, i.e. it was saved from a different block
+var ErrStateSize = errors.New("pctl: saved state does not match the block")
+
+// Stateful is implemented by blocks whose internal state can be saved and
+// restored, e.g. to checkpoint a running loop and resume it after a restart.
+// The state is the memory of the block, such as integrators and delay lines,
+// not its parameters; it is restored into a block built with the same
+// parameters.
+type Stateful interface {
+	// SaveState returns a copy of the block's state
+	SaveState() []float64
+
+	// RestoreState replaces the block's state with one from SaveState
+	RestoreState([]float64) error
+}
+
+func bool2f(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SaveState returns the filter's state
+func (l *LPF) SaveState() []float64 {
+	return []float64{l.prev}
+}
+
+// RestoreState restores the filter's state
+func (l *LPF) RestoreState(s []float64) error {
+	if len(s) != 1 {
+		return ErrStateSize
+	}
+	l.prev = s[0]
+	return nil
+}
+
+// SaveState returns the filter's state
+func (h *HPF) SaveState() []float64 {
+	if h.bq != nil {
+		return h.bq.SaveState()
+	}
+	return []float64{h.prev, h.prevIn}
+}
+
+// RestoreState restores the filter's state
+func (h *HPF) RestoreState(s []float64) error {
+	if h.bq != nil {
+		return h.bq.RestoreState(s)
+	}
+	if len(s) != 2 {
+		return ErrStateSize
+	}
+	h.prev, h.prevIn = s[0], s[1]
+	return nil
+}
+
+// SaveState returns the filter's state
+func (c *LPFCascade) SaveState() []float64 {
+	out := make([]float64, len(c.stages))
+	for i := range c.stages {
+		out[i] = c.stages[i].prev
+	}
+	return out
+}
+
+// RestoreState restores the filter's state
+func (c *LPFCascade) RestoreState(s []float64) error {
+	if len(s) != len(c.stages) {
+		return ErrStateSize
+	}
+	for i := range c.stages {
+		c.stages[i].prev = s[i]
+	}
+	return nil
+}
+
+// SaveState returns the filter's state
+func (c *HPFCascade) SaveState() []float64 {
+	out := make([]float64, 0, 2*len(c.stages))
+	for i := range c.stages {
+		out = append(out, c.stages[i].prev, c.stages[i].prevIn)
+	}
+	return out
+}
+
+// RestoreState restores the filter's state
+func (c *HPFCascade) RestoreState(s []float64) error {
+	if len(s) != 2*len(c.stages) {
+		return ErrStateSize
+	}
+	for i := range c.stages {
+		c.stages[i].prev, c.stages[i].prevIn = s[2*i], s[2*i+1]
+	}
+	return nil
+}
+
+// SaveState returns the filter's state
+func (b *Biquad) SaveState() []float64 {
+	return []float64{b.z1, b.z2}
+}
+
+// RestoreState restores the filter's state
+func (b *Biquad) RestoreState(s []float64) error {
+	if len(s) != 2 {
+		return ErrStateSize
+	}
+	b.z1, b.z2 = s[0], s[1]
+	return nil
+}
+
+// SaveState returns the filter's state
+func (s *SOSFilter) SaveState() []float64 {
+	out := make([]float64, 0, 2*len(s.sections))
+	for i := range s.sections {
+		out = append(out, s.sections[i].z1, s.sections[i].z2)
+	}
+	return out
+}
+
+// RestoreState restores the filter's state
+func (s *SOSFilter) RestoreState(st []float64) error {
+	if len(st) != 2*len(s.sections) {
+		return ErrStateSize
+	}
+	for i := range s.sections {
+		s.sections[i].z1, s.sections[i].z2 = st[2*i], st[2*i+1]
+	}
+	return nil
+}
+
+// SaveState returns the filter's state vector
+func (s *StateSpaceFilter) SaveState() []float64 {
+	return append([]float64(nil), s.x...)
+}
+
+// RestoreState restores the filter's state vector
+func (s *StateSpaceFilter) RestoreState(st []float64) error {
+	if len(st) != len(s.x) {
+		return ErrStateSize
+	}
+	copy(s.x, st)
+	return nil
+}
+
+// SaveState returns the filter's position in its circular buffer, followed
+// by its input history
+func (f *FIRFilter) SaveState() []float64 {
+	buf := f.x
+	if f.sym {
+		buf = f.xx[:len(f.x)]
+	}
+	return append([]float64{float64(f.j)}, buf...)
+}
+
+// RestoreState restores the filter's input history
+func (f *FIRFilter) RestoreState(s []float64) error {
+	l := len(f.x)
+	if len(s) != l+1 || s[0] < 0 || int(s[0]) >= l {
+		return ErrStateSize
+	}
+	f.j = int(s[0])
+	copy(f.x, s[1:])
+	if f.sym {
+		copy(f.xx, s[1:])
+		copy(f.xx[l:], s[1:])
+	}
+	return nil
+}
+
+// SaveState returns the controller's integral, derivative, setpoint ramp, and
+// output memory
+func (pid *PID) SaveState() []float64 {
+	return []float64{pid.integralErr, pid.prevErr, pid.prevOut, pid.residue, pid.setpt, bool2f(pid.ramping)}
+}
+
+// RestoreState restores the controller's state
+func (pid *PID) RestoreState(s []float64) error {
+	if len(s) != 6 {
+		return ErrStateSize
+	}
+	pid.integralErr, pid.prevErr, pid.prevOut, pid.residue, pid.setpt = s[0], s[1], s[2], s[3], s[4]
+	pid.ramping = s[5] != 0
+	return nil
+}
+
+// SaveState returns the filter's estimate and covariance.  The timestamp of
+// the last UpdateAt is not saved; the first UpdateAt after a restore only
+// records its time.
+func (k *Kalman) SaveState() []float64 {
+	return []float64{k.x[0], k.x[1], k.p[0][0], k.p[0][1], k.p[1][1], bool2f(k.started)}
+}
+
+// RestoreState restores the filter's estimate and covariance
+func (k *Kalman) RestoreState(s []float64) error {
+	if len(s) != 6 {
+		return ErrStateSize
+	}
+	k.x = [2]float64{s[0], s[1]}
+	k.p = [2][2]float64{{s[2], s[3]}, {s[3], s[4]}}
+	k.started = s[5] != 0
+	k.last = time.Time{}
+	return nil
+}
+
+// SaveState returns the manager's mode, its held outputs and setpoints, and
+// the state of its controller, if it is Stateful
+func (m *ModeManager) SaveState() []float64 {
+	out := []float64{float64(m.mode), m.Setpt, m.manual, m.remote, m.track, m.out}
+	if s, ok := m.Controller.(Stateful); ok {
+		out = append(out, s.SaveState()...)
+	}
+	return out
+}
+
+// RestoreState restores the manager's mode and state, without calling
+// OnModeChange
+func (m *ModeManager) RestoreState(s []float64) error {
+	if len(s) < 6 {
+		return ErrStateSize
+	}
+	if mode := Mode(s[0]); float64(mode) != s[0] || mode < ModeManual || mode > ModeTracking {
+		return ErrStateSize
+	}
+	if c, ok := m.Controller.(Stateful); ok {
+		if err := c.RestoreState(s[6:]); err != nil {
+			return err
+		}
+	} else if len(s) != 6 {
+		return ErrStateSize
+	}
+	m.mode = Mode(s[0])
+	m.Setpt, m.manual, m.remote, m.track, m.out = s[1], s[2], s[3], s[4], s[5]
+	return nil
+}
+
+// restoreNone restores the empty state of a memoryless block
+func restoreNone(s []float64) error {
+	if len(s) != 0 {
+		return ErrStateSize
+	}
+	return nil
+}
+
+// SaveState returns no state; the setpoint is memoryless
+func (s *Setpoint) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (s *Setpoint) RestoreState(st []float64) error { return restoreNone(st) }
+
+// SaveState returns no state; the polynomial is memoryless
+func (p Polynomial) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (p Polynomial) RestoreState(s []float64) error { return restoreNone(s) }
+
+// SaveState returns no state; the table is memoryless
+func (t *LookupTable) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (t *LookupTable) RestoreState(s []float64) error { return restoreNone(s) }
+
+// SaveState returns no state; the function is memoryless
+func (p *Piecewise) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (p *Piecewise) RestoreState(s []float64) error { return restoreNone(s) }
+
+// SaveState returns no state; the spline is memoryless
+func (s *Spline) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (s *Spline) RestoreState(st []float64) error { return restoreNone(st) }
+
+// SaveState returns no state; the scaling is memoryless, and its range flags
+// are those of the next update
+func (s *Scale) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (s *Scale) RestoreState(st []float64) error { return restoreNone(st) }
+
+// SaveState returns the values of the inputs, in order
+func (s *Sum) SaveState() []float64 {
+	return append([]float64(nil), s.values...)
+}
+
+// RestoreState restores the values of the inputs
+func (s *Sum) RestoreState(st []float64) error {
+	if len(st) != len(s.values) {
+		return ErrStateSize
+	}
+	copy(s.values, st)
+	return nil
+}
+
+// SaveState returns the controller's error history and absolute command
+func (pid *IncrementalPID) SaveState() []float64 {
+	return []float64{pid.prevErr, pid.prevErr2, pid.cmd}
+}
+
+// RestoreState restores the controller's state
+func (pid *IncrementalPID) RestoreState(s []float64) error {
+	if len(s) != 3 {
+		return ErrStateSize
+	}
+	pid.prevErr, pid.prevErr2, pid.cmd = s[0], s[1], s[2]
+	return nil
+}
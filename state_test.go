@@ -0,0 +1,104 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// restoreMatches runs a and b, built alike, on the same signal, restoring a's
+// state into b partway, and fails unless their outputs then agree
+func restoreMatches(t *testing.T, name string, a, b interface {
+	Updater
+	Stateful
+}) {
+	t.Helper()
+	in := func(i int) float64 { return float64(i%7) - 3 + 0.1*float64(i) }
+	for i := 0; i < 50; i++ {
+		a.Update(in(i))
+	}
+	if err := b.RestoreState(a.SaveState()); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	for i := 50; i < 100; i++ {
+		if ya, yb := a.Update(in(i)), b.Update(in(i)); ya != yb {
+			t.Errorf("%s: restored block diverged at sample %d, %g != %g", name, i, yb, ya)
+			return
+		}
+	}
+}
+
+func TestStatefulRestoreResumes(t *testing.T) {
+	taps := []float64{0.1, 0.2, 0.4, 0.2, 0.1}
+	sos := func() *SOSFilter {
+		return NewSOSFilter(NewBiquadLowpass(1000, 50, 0.7, 0), NewBiquadNotch(1000, 120, 2, 0))
+	}
+	ss := func() *StateSpaceFilter {
+		return NewStateSpaceFilter([][]float64{{0.5, 0.1}, {0, 0.9}}, []float64{1, 1}, []float64{1, -1}, 0.2, nil)
+	}
+	pid := func() *PID { return &PID{P: 1, I: 2, D: 0.01, DT: 1e-3, Setpt: 1, SetptRate: 10} }
+	restoreMatches(t, "LPF", NewLPF(10, 1e-3), NewLPF(10, 1e-3))
+	restoreMatches(t, "HPF", NewHPF(10, 1e-3), NewHPF(10, 1e-3))
+	restoreMatches(t, "HPF2", NewHPF2(10, 1e-3), NewHPF2(10, 1e-3))
+	restoreMatches(t, "LPFCascade", NewLPFOrder(3, 10, 1e-3), NewLPFOrder(3, 10, 1e-3))
+	restoreMatches(t, "HPFCascade", NewHPFOrder(3, 10, 1e-3), NewHPFOrder(3, 10, 1e-3))
+	restoreMatches(t, "SOSFilter", sos(), sos())
+	restoreMatches(t, "StateSpaceFilter", ss(), ss())
+	restoreMatches(t, "FIR", NewFIRFilter(taps), NewFIRFilter(taps))
+	restoreMatches(t, "FIR asymmetric", NewFIRFilter(taps[1:]), NewFIRFilter(taps[1:]))
+	restoreMatches(t, "PID", pid(), pid())
+	restoreMatches(t, "Kalman", NewKalman(1, 0.1, 1e-3), NewKalman(1, 0.1, 1e-3))
+	ipid := func() *IncrementalPID { return &IncrementalPID{P: 1, I: 2, D: 0.01, DT: 1e-3, Setpt: 1} }
+	restoreMatches(t, "IncrementalPID", ipid(), ipid())
+	m := func() *ModeManager { return &ModeManager{Controller: pid(), Setpt: 2} }
+	ma, mb := m(), m()
+	ma.SetMode(ModeAuto)
+	restoreMatches(t, "ModeManager", ma, mb)
+	if mb.Mode() != ModeAuto {
+		t.Errorf("expected the mode restored, got %v", mb.Mode())
+	}
+}
+
+func TestModeManagerRejectsUnknownMode(t *testing.T) {
+	pid := &PID{P: 1, I: 1, DT: 0.1}
+	m := &ModeManager{Controller: pid}
+	m.SetMode(ModeAuto)
+	m.Update(1)
+	want := pid.SaveState()
+	for _, mode := range []float64{7, -1, 1.5, math.NaN()} {
+		s := m.SaveState()
+		s[0] = mode
+		for i := 6; i < len(s); i++ {
+			s[i] = 42
+		}
+		if err := m.RestoreState(s); !errors.Is(err, ErrStateSize) {
+			t.Errorf("mode %v: expected ErrStateSize, got %v", mode, err)
+		}
+		if m.Mode() != ModeAuto {
+			t.Errorf("mode %v: expected the mode unchanged, got %v", mode, m.Mode())
+		}
+		for i, v := range pid.SaveState() {
+			if v != want[i] && !(math.IsNaN(v) && math.IsNaN(want[i])) {
+				t.Errorf("mode %v: controller state %d changed from %v to %v", mode, i, want[i], v)
+			}
+		}
+	}
+	if err := m.SetMode(ModeManual); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMemorylessBlocksSaveNoState(t *testing.T) {
+	sp := Setpoint(3)
+	for _, s := range []Stateful{&sp, Polynomial{1, 2}, NewScale(0, 0, 1, 1)} {
+		if len(s.SaveState()) != 0 || s.RestoreState(nil) != nil || s.RestoreState([]float64{1}) != ErrStateSize {
+			t.Errorf("%T: expected an empty state", s)
+		}
+	}
+}
+
+func TestRestoreStateRejectsWrongSize(t *testing.T) {
+	if err := NewLPFOrder(2, 10, 1e-3).RestoreState(NewLPFOrder(3, 10, 1e-3).SaveState()); err != ErrStateSize {
+		t.Errorf("expected ErrStateSize restoring a third order state into a second order filter, got %v", err)
+	}
+}
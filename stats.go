@@ -0,0 +1,176 @@
+package pctl
+
+import "math"
+
+// Stats accumulates the count, mean, variance, minimum, and maximum of a
+// signal.  It passes its input through unchanged, so it may be dropped into a
+// chain anywhere as a health monitor.
+//
+// With a zero Window the statistics are cumulative.  Otherwise they are over
+// the last Window samples; the mean and variance are then updated by adding
+// the newest sample and removing the oldest, and the extremes are kept by
+// monotonic queues, so the cost of an update does not depend on the window.
+//
+// The variance is by Welford's algorithm, which does not suffer the
+// cancellation of the sum of squares.
+type Stats struct {
+	n        int
+	mean, m2 float64
+	min, max float64
+
+	// windowed mode
+	buf    []float64
+	head   int
+	seq    int // number of the next sample, wrapping at twice the window
+	lo, hi monoQueue
+}
+
+// NewStats returns a new Stats over a sliding window of the given number of
+// samples, or cumulative if window is zero
+func NewStats(window int) *Stats {
+	s := &Stats{}
+	if window > 0 {
+		s.buf = make([]float64, window)
+		s.lo = newMonoQueue(window, false)
+		s.hi = newMonoQueue(window, true)
+	}
+	s.Reset()
+	return s
+}
+
+// Update adds a sample and returns it
+func (s *Stats) Update(input float64) float64 {
+	if s.buf == nil {
+		s.add(input)
+		s.min = math.Min(s.min, input)
+		s.max = math.Max(s.max, input)
+		return input
+	}
+	w := len(s.buf)
+	if s.n == w {
+		s.remove(s.buf[s.head])
+	}
+	s.buf[s.head] = input
+	s.head = (s.head + 1) % w
+	s.add(input)
+	// expire before pushing, so a full queue has room for the new entry
+	s.lo.expire(s.seq, w)
+	s.hi.expire(s.seq, w)
+	s.lo.push(s.seq, input)
+	s.hi.push(s.seq, input)
+	s.seq = (s.seq + 1) % (2 * w)
+	s.min, s.max = s.lo.front(), s.hi.front()
+	return input
+}
+
+func (s *Stats) add(x float64) {
+	s.n++
+	d := x - s.mean
+	s.mean += d / float64(s.n)
+	s.m2 += d * (x - s.mean)
+}
+
+func (s *Stats) remove(x float64) {
+	s.n--
+	if s.n == 0 {
+		s.mean, s.m2 = 0, 0
+		return
+	}
+	d := x - s.mean
+	s.mean -= d / float64(s.n)
+	s.m2 -= d * (x - s.mean)
+	if s.m2 < 0 {
+		s.m2 = 0
+	}
+}
+
+// Count returns the number of samples the statistics are over
+func (s *Stats) Count() int {
+	return s.n
+}
+
+// Mean returns the mean
+func (s *Stats) Mean() float64 {
+	return s.mean
+}
+
+// Variance returns the sample variance, with Bessel's correction, or zero for
+// fewer than two samples
+func (s *Stats) Variance() float64 {
+	if s.n < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.n-1)
+}
+
+// Std returns the sample standard deviation
+func (s *Stats) Std() float64 {
+	return math.Sqrt(s.Variance())
+}
+
+// Min returns the smallest sample, or +Inf if there are none
+func (s *Stats) Min() float64 {
+	return s.min
+}
+
+// Max returns the largest sample, or -Inf if there are none
+func (s *Stats) Max() float64 {
+	return s.max
+}
+
+// Reset forgets all samples
+func (s *Stats) Reset() {
+	s.n, s.mean, s.m2 = 0, 0, 0
+	s.min, s.max = math.Inf(1), math.Inf(-1)
+	s.head, s.seq = 0, 0
+	s.lo.clear()
+	s.hi.clear()
+}
+
+// monoQueue is a fixed capacity ring of (sequence number, value) pairs whose
+// values are monotonic, so the front is the extreme of those in the window:
+// the maximum if max is true, else the minimum
+type monoQueue struct {
+	seq     []int
+	val     []float64
+	head, n int
+	max     bool
+}
+
+func newMonoQueue(capacity int, max bool) monoQueue {
+	return monoQueue{seq: make([]int, capacity), val: make([]float64, capacity), max: max}
+}
+
+// push appends v, first dropping from the back every entry it supersedes
+func (q *monoQueue) push(seq int, v float64) {
+	c := len(q.val)
+	for q.n > 0 {
+		back := q.val[(q.head+q.n-1)%c]
+		if (q.max && v < back) || (!q.max && v > back) {
+			break
+		}
+		q.n--
+	}
+	i := (q.head + q.n) % c
+	q.seq[i], q.val[i] = seq, v
+	q.n++
+}
+
+// expire drops entries from the front which are window or more samples
+// older than sample seq.  The numbers wrap at 2*window, so ages are taken
+// modulo it; no entry is kept long enough to be older than that.
+func (q *monoQueue) expire(seq, window int) {
+	m := 2 * window
+	for q.n > 0 && (seq-q.seq[q.head]+m)%m >= window {
+		q.head = (q.head + 1) % len(q.val)
+		q.n--
+	}
+}
+
+func (q *monoQueue) front() float64 {
+	return q.val[q.head]
+}
+
+func (q *monoQueue) clear() {
+	q.head, q.n = 0, 0
+}
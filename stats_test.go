@@ -0,0 +1,82 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestStatsCumulative(t *testing.T) {
+	s := NewStats(0)
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		if s.Update(x) != x {
+			t.Fatal("expected Stats to pass its input through")
+		}
+	}
+	if s.Count() != 8 || s.Mean() != 5 || !approxEqualAbs(s.Variance(), 32./7, 1e-12) {
+		t.Errorf("expected n=8, mean 5, variance 32/7, got %d %f %f", s.Count(), s.Mean(), s.Variance())
+	}
+	if s.Min() != 2 || s.Max() != 9 {
+		t.Errorf("expected min 2 max 9, got %f %f", s.Min(), s.Max())
+	}
+}
+
+func TestStatsWindowMatchesDirect(t *testing.T) {
+	const w = 17
+	s := NewStats(w)
+	r := rand.New(rand.NewSource(1))
+	x := make([]float64, 500)
+	for i := range x {
+		x[i] = 1e3 + r.NormFloat64()
+		s.Update(x[i])
+		if i < w {
+			continue
+		}
+		win := x[i-w+1 : i+1]
+		var mean, min, max = 0., math.Inf(1), math.Inf(-1)
+		for _, v := range win {
+			mean += v / w
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		var ss float64
+		for _, v := range win {
+			ss += (v - mean) * (v - mean)
+		}
+		if !approxEqualAbs(s.Mean(), mean, 1e-9) || !approxEqualAbs(s.Variance(), ss/(w-1), 1e-9) ||
+			s.Min() != min || s.Max() != max {
+			t.Fatalf("sample %d: got mean %f var %f [%f, %f], expected %f %f [%f, %f]",
+				i, s.Mean(), s.Variance(), s.Min(), s.Max(), mean, ss/(w-1), min, max)
+		}
+	}
+}
+
+func TestStatsWindowMonotonicRamps(t *testing.T) {
+	down := NewStats(3)
+	for _, v := range []float64{5, 4, 3, 2} {
+		down.Update(v)
+	}
+	if down.Max() != 4 || down.Min() != 2 {
+		t.Errorf("falling ramp: expected [2, 4], got [%g, %g]", down.Min(), down.Max())
+	}
+	up := NewStats(3)
+	for _, v := range []float64{0, 1, 2, 3} {
+		up.Update(v)
+	}
+	if up.Min() != 1 || up.Max() != 3 {
+		t.Errorf("rising ramp: expected [1, 3], got [%g, %g]", up.Min(), up.Max())
+	}
+}
+
+func TestStatsWindowSequenceWraps(t *testing.T) {
+	const w = 4
+	s := NewStats(w)
+	for i := 0; i < 50*w; i++ {
+		s.Update(float64(i))
+		if s.seq < 0 || s.seq >= 2*w {
+			t.Fatalf("sample %d: sequence number %d outside [0, %d)", i, s.seq, 2*w)
+		}
+		if lo := math.Max(0, float64(i-w+1)); s.Min() != lo || s.Max() != float64(i) {
+			t.Fatalf("sample %d: expected [%g, %d], got [%g, %g]", i, lo, i, s.Min(), s.Max())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package pctl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSigns is returned when the signs of a Sum are not one + or - per input
+var ErrSigns = errors.New("pctl: sum needs one sign, + or -, per input")
+
+// Sum is a summing junction of named inputs, each added or subtracted, as the
+// circle of a block diagram.  The first input is the one fed to Update, so a
+// Sum may sit in a chain of Updaters, e.g. to subtract a setpoint, add a
+// feedforward term, or inject a disturbance; the others hold the last value
+// Set for them.
+//
+// A Sum is not safe for concurrent use: Set must be called from the goroutine
+// which calls Update, e.g. between updates of the loop.
+type Sum struct {
+	names  []string
+	signs  []float64
+	values []float64
+}
+
+// NewSum returns a Sum of the named inputs, signed by the characters of signs
+// in order, e.g. NewSum("+-", "meas", "setpt") for the process error.  The
+// names must be unique.
+func NewSum(signs string, names ...string) (*Sum, error) {
+	if len(signs) != len(names) || len(names) == 0 {
+		return nil, ErrSigns
+	}
+	s := &Sum{names: append([]string(nil), names...), signs: make([]float64, len(names)), values: make([]float64, len(names))}
+	for i, c := range []byte(signs) {
+		switch c {
+		case '+':
+			s.signs[i] = 1
+		case '-':
+			s.signs[i] = -1
+		default:
+			return nil, ErrSigns
+		}
+		for _, n := range names[:i] {
+			if n == names[i] {
+				return nil, fmt.Errorf("pctl: sum input %q is repeated", n)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Names returns the names of the inputs, in order
+func (s *Sum) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// index returns the index of the named input, or -1 if there is none
+func (s *Sum) index(name string) int {
+	for i, n := range s.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Set sets the value of the named input.  It returns false if there is no
+// such input.  Call it from the goroutine which updates s.
+func (s *Sum) Set(name string, v float64) bool {
+	i := s.index(name)
+	if i < 0 {
+		return false
+	}
+	s.values[i] = v
+	return true
+}
+
+// Input returns the value of the named input, and false if there is none
+func (s *Sum) Input(name string) (float64, bool) {
+	i := s.index(name)
+	if i < 0 {
+		return 0, false
+	}
+	return s.values[i], true
+}
+
+// Output returns the signed sum of the inputs
+func (s *Sum) Output() float64 {
+	var out float64
+	for i, v := range s.values {
+		out += s.signs[i] * v
+	}
+	return out
+}
+
+// Update sets the first input to input and returns the sum
+func (s *Sum) Update(input float64) float64 {
+	s.values[0] = input
+	return s.Output()
+}
@@ -0,0 +1,66 @@
+package pctl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSumSignsInputs(t *testing.T) {
+	s, err := NewSum("+-+", "meas", "setpt", "ff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Set("setpt", 50) || !s.Set("ff", 2) {
+		t.Fatal("expected the named inputs to be set")
+	}
+	if got := s.Update(45); got != 45-50+2 {
+		t.Errorf("expected 45 - 50 + 2 = -3, got %g", got)
+	}
+	if s.Set("bias", 1) {
+		t.Error("expected an unknown input not to be set")
+	}
+	if v, ok := s.Input("meas"); !ok || v != 45 {
+		t.Errorf("expected the first input to hold the last update, got %g", v)
+	}
+}
+
+func TestSumMatchesSetpointInChain(t *testing.T) {
+	// meas - setpt is the process error of Setpoint
+	s, _ := NewSum("+-", "meas", "setpt")
+	s.Set("setpt", 3)
+	sp := Setpoint(3)
+	lpf1, lpf2 := NewLPF(10, 1e-3), NewLPF(10, 1e-3)
+	for i := 0; i < 10; i++ {
+		in := float64(i)
+		if a, b := Cascade(in, s, lpf1), Cascade(in, &sp, lpf2); a != b {
+			t.Fatalf("sample %d: sum chain %g, setpoint chain %g", i, a, b)
+		}
+	}
+}
+
+func TestSumBadSigns(t *testing.T) {
+	for _, signs := range []string{"+", "+*", "+-+"} {
+		if _, err := NewSum(signs, "a", "b"); !errors.Is(err, ErrSigns) {
+			t.Errorf("%q: expected ErrSigns, got %v", signs, err)
+		}
+	}
+	if _, err := NewSum("++", "a", "a"); err == nil {
+		t.Error("expected an error for repeated inputs")
+	}
+}
+
+func TestSumNamesAreCopied(t *testing.T) {
+	names := []string{"meas", "setpt"}
+	s, err := NewSum("+-", names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names[1] = "ff"
+	s.Names()[0] = "dist"
+	if got := s.Names(); got[0] != "meas" || got[1] != "setpt" {
+		t.Errorf("expected the names to be unchanged, got %v", got)
+	}
+	if !s.Set("setpt", 1) {
+		t.Error("expected setpt to remain an input")
+	}
+}
@@ -0,0 +1,52 @@
+package pctl
+
+import "testing"
+
+func TestSwappableTransfersState(t *testing.T) {
+	old := &PID{P: 1, I: 1, DT: 0.1, Setpt: 1}
+	ref := *old
+	s := NewSwappable(old)
+	for i := 0; i < 10; i++ {
+		s.Update(0)
+		ref.Update(0)
+	}
+	next := &PID{P: 1, I: 1, DT: 0.1, Setpt: 1}
+	done := s.Swap(next, TransferState)
+	if s.Current() != old {
+		t.Error("expected the swap to wait for the next Update")
+	}
+	want := ref.Update(0)
+	if got := s.Update(0); got != want || s.Current() != next {
+		t.Errorf("expected the new controller to continue at %g with the old state, got %g", want, got)
+	}
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSwappableTrackIsBumpless(t *testing.T) {
+	s := NewSwappable(&PID{P: 2, I: 1, DT: 0.1, Setpt: 1})
+	var last float64
+	for i := 0; i < 10; i++ {
+		last = s.Update(0.5)
+	}
+	s.Swap(&PID{I: 3, DT: 0.1, Setpt: 1}, TransferTrack)
+	// with an unchanged error, the output moves only by one integration step
+	if got := s.Update(0.5); !approxEqualAbs(got, last+3*0.5*0.1, 1e-12) {
+		t.Errorf("expected a bumpless swap from %g, got %g", last, got)
+	}
+}
+
+func TestSwappableRejectsMismatchedState(t *testing.T) {
+	s := NewSwappable(NewLPFOrder(2, 1, 0.1))
+	done := s.Swap(NewLPFOrder(3, 1, 0.1), TransferState)
+	s.Update(1)
+	if err := <-done; err != ErrStateSize {
+		t.Errorf("expected ErrStateSize, got %v", err)
+	}
+	first := s.Swap(NewLPF(1, 0.1), TransferNone)
+	s.Swap(NewLPF(2, 0.1), TransferNone)
+	if err := <-first; err != ErrSwapSuperseded {
+		t.Errorf("expected ErrSwapSuperseded, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package sysid
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	pio "github.com/brandondube/pctl/io"
+)
+
+var (
+	// ErrStepAmplitude is returned by Characterizer.Run when a step exceeds
+	// MaxStep
+	ErrStepAmplitude = errors.New("sysid: step exceeds the amplitude limit")
+
+	// ErrAborted is returned by Characterizer.Run when the measurement left
+	// its safe range
+	ErrAborted = errors.New("sysid: characterization aborted, measurement out of range")
+)
+
+// Characterizer is the guided characterization of a live plant: it holds the
+// command at Base, applies a sequence of steps about it, records the
+// response, and fits a model.  It is the "press to characterize" of
+// commissioning; tune from the Model it finds, or simulate with its Plant.
+type Characterizer struct {
+	// Sensor and Actuator connect to the plant
+	Sensor   pio.Sensor
+	Actuator pio.Actuator
+
+	// DT is the sample period, in seconds
+	DT float64
+
+	// Base is the command about which the steps are made.  The plant should
+	// be at rest at Base when Run is called; it is written again when the
+	// run ends or is aborted.
+	Base float64
+
+	// Steps are the commands applied in turn, relative to Base, each held for
+	// Dwell seconds.  The run is preceded by Dwell/10 seconds at Base.  If
+	// nil, +MaxStep then -MaxStep are applied.
+	Steps []float64
+
+	// Dwell is how long each step is held, in seconds.  It should be several
+	// times the settling time of the plant.
+	Dwell float64
+
+	// MaxStep is the largest step allowed, in command units
+	MaxStep float64
+
+	// MeasMin and MeasMax are the safe range of the measurement, outside of
+	// which the run is aborted.  They apply when MeasMax > MeasMin.
+	MeasMin, MeasMax float64
+
+	// Order is the order of the model fit, 1 (FOPDT) or 2 (SOPDT)
+	Order int
+}
+
+// Characterization is the result of Characterizer.Run
+type Characterization struct {
+	// Model is the fit of the response
+	Model Model
+
+	// U and Y are the commands and measurements recorded, every DT
+	U, Y []float64
+}
+
+// Run characterizes the plant.  On error, including ErrAborted, the record
+// up to the error is returned, without a model.
+func (c *Characterizer) Run() (ch Characterization, err error) {
+	if c.Order != 1 && c.Order != 2 {
+		return ch, ErrOrder
+	}
+	steps := c.Steps
+	if steps == nil {
+		steps = []float64{c.MaxStep, -c.MaxStep}
+	}
+	for _, s := range steps {
+		if math.Abs(s) > c.MaxStep {
+			return ch, ErrStepAmplitude
+		}
+	}
+	dwell := int(math.Round(c.Dwell / c.DT))
+	lead := dwell / 10
+	n := lead + dwell*len(steps)
+	ch.U = make([]float64, 0, n)
+	ch.Y = make([]float64, 0, n)
+	defer func() {
+		if werr := c.Actuator.Write(c.Base); werr != nil && err == nil {
+			err = fmt.Errorf("sysid: actuator: %w", werr)
+		}
+	}()
+
+	tick := time.NewTicker(time.Duration(c.DT * float64(time.Second)))
+	defer tick.Stop()
+	for k := 0; k < n; k++ {
+		if k > 0 {
+			<-tick.C
+		}
+		y, _, rerr := c.Sensor.Read()
+		if rerr != nil {
+			return ch, fmt.Errorf("sysid: sensor: %w", rerr)
+		}
+		if c.MeasMax > c.MeasMin && (y < c.MeasMin || y > c.MeasMax) {
+			return ch, ErrAborted
+		}
+		u := c.Base
+		if k >= lead {
+			u += steps[(k-lead)/dwell]
+		}
+		ch.U = append(ch.U, u)
+		ch.Y = append(ch.Y, y)
+		if werr := c.Actuator.Write(u); werr != nil {
+			return ch, fmt.Errorf("sysid: actuator: %w", werr)
+		}
+	}
+	ch.Model, err = Fit(ch.U, ch.Y, c.DT, c.Order)
+	return ch, err
+}
@@ -0,0 +1,106 @@
+package sysid
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+	pio "github.com/brandondube/pctl/io"
+)
+
+// livePlant is a model behind a Sensor and Actuator, as the live plant would
+// be, at an operating point of command 5 and measurement 20
+type livePlant struct {
+	plant       pctl.Updater
+	meas, wrote float64
+}
+
+func newLivePlant(m Model, dt float64) *livePlant {
+	return &livePlant{plant: m.Plant(dt), meas: 20, wrote: 5}
+}
+
+func (p *livePlant) sensor() pio.Sensor {
+	return pio.SensorFunc(func() (float64, time.Time, error) { return p.meas, time.Now(), nil })
+}
+
+func (p *livePlant) actuator() pio.Actuator {
+	return pio.ActuatorFunc(func(v float64) error {
+		p.wrote = v
+		p.meas = 20 + p.plant.Update(v-5)
+		return nil
+	})
+}
+
+func TestCharacterizerFitsAndStores(t *testing.T) {
+	const dt = 1e-3
+	truth := Model{K: 3, T1: 0.02, L: 0.004}
+	p := newLivePlant(truth, dt)
+	c := Characterizer{
+		Sensor:   p.sensor(),
+		Actuator: p.actuator(),
+		DT:       dt,
+		Base:     5,
+		Dwell:    0.15,
+		MaxStep:  1,
+		Order:    1,
+	}
+	ch, err := c.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := ch.Model
+	if len(ch.U) != 315 || math.Abs(m.K-truth.K) > 1e-3 || math.Abs(m.T1-truth.T1) > 1e-4 || math.Abs(m.L-truth.L) > 1e-4 {
+		t.Errorf("expected %+v from 315 samples, got %+v from %d", truth, m, len(ch.U))
+	}
+	if p.wrote != 5 {
+		t.Errorf("expected the base command restored, got %g", p.wrote)
+	}
+
+	dir, err := ioutil.TempDir("", "sysid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plant.json")
+	if err := m.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	back, err := LoadModelFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.K != m.K || back.T1 != m.T1 || back.L != m.L || !back.Time.Equal(m.Time) {
+		t.Errorf("model %+v did not survive a round trip, got %+v", m, back)
+	}
+}
+
+func TestCharacterizerLimits(t *testing.T) {
+	const dt = 1e-3
+	p := newLivePlant(Model{K: 3, T1: 0.02}, dt)
+	c := Characterizer{
+		Sensor:   p.sensor(),
+		Actuator: p.actuator(),
+		DT:       dt,
+		Base:     5,
+		Steps:    []float64{2},
+		Dwell:    0.1,
+		MaxStep:  1,
+		Order:    1,
+	}
+	if _, err := c.Run(); err != ErrStepAmplitude {
+		t.Errorf("expected ErrStepAmplitude, got %v", err)
+	}
+	c.Steps = nil
+	c.MeasMin, c.MeasMax = 15, 21
+	ch, err := c.Run()
+	if err != ErrAborted {
+		t.Fatalf("expected ErrAborted, got %v", err)
+	}
+	if p.wrote != 5 || ch.Y[len(ch.Y)-1] > 21 {
+		t.Errorf("expected the base command restored within range, wrote %g", p.wrote)
+	}
+}
@@ -0,0 +1,96 @@
+package sysid
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/brandondube/pctl/tune"
+)
+
+var (
+	// ErrOrder is returned when a model order other than 1 or 2 is requested
+	ErrOrder = errors.New("sysid: model order must be 1 or 2")
+
+	// ErrNoExcitation is returned when a record is too short or its command
+	// never changes
+	ErrNoExcitation = errors.New("sysid: record has no change in command")
+)
+
+// Fit identifies a model of the given order from commands u and measurements
+// y sampled every dt seconds.  The plant should be at rest at the start of
+// the record, whose first samples are taken as the operating point.
+//
+// Fit minimizes the squared error of the simulated response with
+// tune.NelderMead, from several starting time constants to avoid local
+// minima.
+func Fit(u, y []float64, dt float64, order int) (Model, error) {
+	if order != 1 && order != 2 {
+		return Model{}, ErrOrder
+	}
+	n := len(u)
+	if len(y) < n {
+		n = len(y)
+	}
+	du := make([]float64, n)
+	dy := make([]float64, n)
+	var umax, ymax float64
+	for k := 0; k < n; k++ {
+		du[k], dy[k] = u[k]-u[0], y[k]-y[0]
+		if math.Abs(du[k]) > math.Abs(umax) {
+			umax = du[k]
+		}
+		if math.Abs(dy[k]) > math.Abs(ymax) {
+			ymax = dy[k]
+		}
+	}
+	if umax == 0 || n < 4 {
+		return Model{}, ErrNoExcitation
+	}
+
+	// x is K, log T1, optionally log T2, and L, which is folded positive
+	model := func(x []float64) Model {
+		m := Model{K: x[0], T1: math.Exp(x[1]), L: math.Abs(x[len(x)-1])}
+		if order == 2 {
+			m.T2 = math.Exp(x[2])
+		}
+		return m
+	}
+	cost := func(x []float64) float64 {
+		sim := model(x).Simulate(du, dt)
+		var sse float64
+		for k := range sim {
+			e := sim[k] - dy[k]
+			sse += e * e
+		}
+		return sse
+	}
+
+	dur := float64(n) * dt
+	k0 := ymax / umax
+	var best []float64
+	bestCost := math.Inf(1)
+	for _, frac := range []float64{0.01, 0.05, 0.2} {
+		t0 := math.Log(frac * dur)
+		x0 := []float64{k0, t0, 0.01 * dur}
+		step := []float64{0.2 * math.Abs(k0), 1, 0.02 * dur}
+		if order == 2 {
+			x0 = []float64{k0, t0, t0 - math.Log(4), 0.01 * dur}
+			step = []float64{0.2 * math.Abs(k0), 1, 1, 0.02 * dur}
+		}
+		x, c := tune.NelderMead(cost, x0, step, 2000, 1e-10)
+		// restart from the result, refreshing the simplex
+		if x2, c2 := tune.NelderMead(cost, x, step, 2000, 1e-12); c2 < c {
+			x, c = x2, c2
+		}
+		if c < bestCost {
+			best, bestCost = x, c
+		}
+	}
+	m := model(best)
+	if m.T2 > m.T1 {
+		m.T1, m.T2 = m.T2, m.T1
+	}
+	m.Time = time.Now()
+	return m, nil
+}
@@ -0,0 +1,61 @@
+package sysid
+
+import (
+	"math"
+	"testing"
+)
+
+func steps(n int) []float64 {
+	u := make([]float64, n)
+	for k := range u {
+		switch {
+		case k >= n/2:
+			u[k] = -0.5
+		case k >= n/10:
+			u[k] = 1
+		}
+	}
+	return u
+}
+
+func TestFitRecoversFOPDT(t *testing.T) {
+	const dt = 1e-3
+	truth := Model{K: 2.5, T1: 0.05, L: 0.0123}
+	u := steps(1000)
+	y := truth.Simulate(u, dt)
+	for k := range y {
+		y[k] += 3 // an operating point
+		u[k] += 10
+	}
+	m, err := Fit(u, y, dt, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.K-truth.K) > 1e-3 || math.Abs(m.T1-truth.T1) > 1e-4 || math.Abs(m.L-truth.L) > 1e-4 || m.T2 != 0 {
+		t.Errorf("expected %+v, got %+v", truth, m)
+	}
+}
+
+func TestFitRecoversSOPDT(t *testing.T) {
+	const dt = 1e-3
+	truth := Model{K: -1.5, T1: 0.08, T2: 0.02, L: 0.005}
+	u := steps(1500)
+	m, err := Fit(u, truth.Simulate(u, dt), dt, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.K-truth.K) > 1e-3 || math.Abs(m.T1-truth.T1) > 1e-3 ||
+		math.Abs(m.T2-truth.T2) > 1e-3 || math.Abs(m.L-truth.L) > 1e-3 {
+		t.Errorf("expected %+v, got %+v", truth, m)
+	}
+}
+
+func TestFitErrors(t *testing.T) {
+	u := make([]float64, 100)
+	if _, err := Fit(u, u, 1e-3, 1); err != ErrNoExcitation {
+		t.Errorf("expected ErrNoExcitation, got %v", err)
+	}
+	if _, err := Fit(steps(100), u, 1e-3, 3); err != ErrOrder {
+		t.Errorf("expected ErrOrder, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Package sysid identifies models of plants from measured responses, for
+tuning and simulation.
+
+The models are low order and continuous time, with dead time, which are what
+most tuning rules and commissioning tools speak in.  Records follow the
+conventions of package sim: the measurement y[k+1] is the first to respond to
+the command u[k].
+*/
+package sysid
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/brandondube/pctl"
+)
+
+// Model is a first or second order plus dead time (FOPDT or SOPDT) model,
+//
+//	K e^(-L s) / ((T1 s + 1)(T2 s + 1))
+//
+// which is first order when T2 is zero.  Its input and output are deviations
+// from the operating point it was identified at.
+type Model struct {
+	// K is the steady-state gain, in output units per input unit
+	K float64 `json:"k"`
+
+	// T1 and T2 are the time constants, in seconds
+	T1 float64 `json:"t1"`
+	T2 float64 `json:"t2,omitempty"`
+
+	// L is the dead time, in seconds
+	L float64 `json:"l"`
+
+	// Time is when the model was identified
+	Time time.Time `json:"time"`
+}
+
+// Order is the number of time constants of the model, 1 or 2
+func (m Model) Order() int {
+	if m.T2 == 0 {
+		return 1
+	}
+	return 2
+}
+
+// Plant returns an Updater which simulates the model, sampled with a zero
+// order hold every dt seconds.  Its Update returns the measurement on the
+// next sample, as the plants of package sim do.
+func (m Model) Plant(dt float64) pctl.Updater {
+	d := m.L / dt
+	n := int(d)
+	p := &plant{k: m.K, frac: d - float64(n), buf: make([]float64, n+2)}
+	p.a1 = lagPole(m.T1, dt)
+	p.a2 = lagPole(m.T2, dt)
+	p.second = m.T2 != 0
+	return p
+}
+
+// lagPole is the discrete pole of a first order lag of time constant t
+func lagPole(t, dt float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	return math.Exp(-dt / t)
+}
+
+// plant is the discrete simulation of a Model
+type plant struct {
+	k, a1, a2 float64
+	second    bool
+
+	// buf is a ring of past inputs, for the dead time, which is interpolated
+	// between its whole and fractional samples
+	buf  []float64
+	head int
+	frac float64
+
+	x1, x2 float64
+}
+
+func (p *plant) Update(u float64) float64 {
+	n := len(p.buf)
+	p.buf[p.head] = u
+	// the ring holds u[k] back to u[k-n-1], the oldest just after head
+	whole := p.buf[(p.head+2)%n]
+	older := p.buf[(p.head+1)%n]
+	p.head = (p.head + 1) % n
+	ud := (1-p.frac)*whole + p.frac*older
+	p.x1 = p.a1*p.x1 + (1-p.a1)*ud
+	if !p.second {
+		return p.k * p.x1
+	}
+	p.x2 = p.a2*p.x2 + (1-p.a2)*p.x1
+	return p.k * p.x2
+}
+
+// Simulate returns the response of the model to the commands u, sampled every
+// dt seconds, from rest: y[0] is zero, and y[k+1] follows u[k].
+func (m Model) Simulate(u []float64, dt float64) []float64 {
+	y := make([]float64, len(u))
+	p := m.Plant(dt)
+	for k := 0; k < len(u)-1; k++ {
+		y[k+1] = p.Update(u[k])
+	}
+	return y
+}
+
+// SaveFile writes the model to path as JSON.  The file is replaced
+// atomically, so a crash cannot leave a truncated model behind.
+func (m Model) SaveFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LoadModelFile reads a model written by SaveFile
+func LoadModelFile(path string) (Model, error) {
+	var m Model
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	err = json.NewDecoder(f).Decode(&m)
+	return m, err
+}
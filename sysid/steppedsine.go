@@ -0,0 +1,198 @@
+package sysid
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"time"
+
+	pio "github.com/brandondube/pctl/io"
+)
+
+// ResponsePoint is the frequency response of a plant measured at one
+// frequency
+type ResponsePoint struct {
+	// Freq is the frequency, in Hz
+	Freq float64 `json:"freq"`
+
+	// H is the response, output over input.  In JSON, which has no complex
+	// numbers, it is encoded as its parts "re" and "im".
+	H complex128 `json:"-"`
+
+	// Coherence is the magnitude squared coherence of input and output over
+	// the averages, from 0 to 1.  Values well below 1 indicate noise,
+	// disturbances, or nonlinearity.
+	Coherence float64 `json:"coherence"`
+
+	// Averages is the number of blocks the point was averaged over
+	Averages int `json:"averages"`
+}
+
+// responsePointJSON is the encoding of a ResponsePoint
+type responsePointJSON struct {
+	Freq      float64 `json:"freq"`
+	Re        float64 `json:"re"`
+	Im        float64 `json:"im"`
+	Coherence float64 `json:"coherence"`
+	Averages  int     `json:"averages"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (p ResponsePoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(responsePointJSON{p.Freq, real(p.H), imag(p.H), p.Coherence, p.Averages})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *ResponsePoint) UnmarshalJSON(b []byte) error {
+	var v responsePointJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*p = ResponsePoint{Freq: v.Freq, H: complex(v.Re, v.Im), Coherence: v.Coherence, Averages: v.Averages}
+	return nil
+}
+
+// SteppedSine identifies the frequency response of a live plant by exciting it
+// with a sine at one frequency at a time, dwelling at each, and averaging the
+// response over several blocks.  It is slower than a swept excitation but
+// concentrates all of its energy at each frequency, which suits lightly
+// damped mechanical plants whose resonances a sweep passes too quickly.
+type SteppedSine struct {
+	// Sensor and Actuator connect to the plant
+	Sensor   pio.Sensor
+	Actuator pio.Actuator
+
+	// DT is the sample period, in seconds
+	DT float64
+
+	// Base is the command the excitation is made about; it is written when
+	// the run ends or is aborted
+	Base float64
+
+	// Amplitude is the amplitude of the sine, in command units
+	Amplitude float64
+
+	// Freqs are the frequencies measured, in Hz, in order.  Frequencies with
+	// a whole number of samples per period avoid leakage.
+	Freqs []float64
+
+	// Settle is how long the excitation runs at each frequency before it is
+	// measured, in seconds, for the transient to die out
+	Settle float64
+
+	// Cycles is the number of periods per block.  If zero, 10 are used.
+	Cycles int
+
+	// Averages is the number of blocks averaged per frequency.  If zero, 4
+	// are used.  The coherence is meaningful only for more than one.
+	Averages int
+
+	// MeasMin and MeasMax are the safe range of the measurement, outside of
+	// which the run is aborted.  They apply when MeasMax > MeasMin.
+	MeasMin, MeasMax float64
+}
+
+// Run measures the response at each frequency.  On error, including
+// ErrAborted, the points measured so far are returned.
+func (s *SteppedSine) Run() (pts []ResponsePoint, err error) {
+	cycles, avgs := s.Cycles, s.Averages
+	if cycles == 0 {
+		cycles = 10
+	}
+	if avgs == 0 {
+		avgs = 4
+	}
+	defer func() {
+		if werr := s.Actuator.Write(s.Base); werr != nil && err == nil {
+			err = fmt.Errorf("sysid: actuator: %w", werr)
+		}
+	}()
+	tick := time.NewTicker(time.Duration(s.DT * float64(time.Second)))
+	defer tick.Stop()
+	first := true
+	for _, f := range s.Freqs {
+		w := 2 * math.Pi * f * s.DT
+		settle := int(math.Round(s.Settle / s.DT))
+		block := int(math.Round(float64(cycles) / (f * s.DT)))
+		var syu complex128
+		var suu, syy float64
+		var u0, y0, se complex128
+		var sy float64
+		for k := 0; k < settle+block*avgs; k++ {
+			if !first {
+				<-tick.C
+			}
+			first = false
+			y, _, rerr := s.Sensor.Read()
+			if rerr != nil {
+				return pts, fmt.Errorf("sysid: sensor: %w", rerr)
+			}
+			if s.MeasMax > s.MeasMin && (y < s.MeasMin || y > s.MeasMax) {
+				return pts, ErrAborted
+			}
+			u := s.Amplitude * math.Sin(w*float64(k))
+			if werr := s.Actuator.Write(s.Base + u); werr != nil {
+				return pts, fmt.Errorf("sysid: actuator: %w", werr)
+			}
+			if k < settle {
+				continue
+			}
+			// correlate with the excitation frequency, one block at a time,
+			// removing the mean of the output so that the operating point
+			// does not leak into the correlation
+			e := cmplx.Exp(complex(0, -w*float64(k)))
+			u0 += complex(u, 0) * e
+			y0 += complex(y, 0) * e
+			se += e
+			sy += y
+			if (k-settle)%block == block-1 {
+				y0 -= complex(sy/float64(block), 0) * se
+				syu += y0 * cmplx.Conj(u0)
+				suu += real(u0 * cmplx.Conj(u0))
+				syy += real(y0 * cmplx.Conj(y0))
+				u0, y0, se, sy = 0, 0, 0, 0
+			}
+		}
+		pts = append(pts, ResponsePoint{
+			Freq:      f,
+			H:         syu / complex(suu, 0),
+			Coherence: real(syu*cmplx.Conj(syu)) / (suu * syy),
+			Averages:  avgs,
+		})
+	}
+	return pts, nil
+}
+
+// AverageResponses combines repeated measurements of a response, such as
+// several SteppedSine runs, weighting each point by the inverse of the
+// variance implied by its coherence, n γ²/(1-γ²) for n averages.  Points of
+// poor coherence thus contribute little.  The sweeps must be of the same
+// frequencies; the coherence of the result is that of the combined weight.
+func AverageResponses(sweeps ...[]ResponsePoint) []ResponsePoint {
+	if len(sweeps) == 0 {
+		return nil
+	}
+	out := make([]ResponsePoint, len(sweeps[0]))
+	for i := range out {
+		var h complex128
+		var wsum float64
+		var n int
+		for _, sw := range sweeps {
+			p := sw[i]
+			wt := float64(p.Averages) * p.Coherence / math.Max(1-p.Coherence, 1e-12)
+			h += complex(wt, 0) * p.H
+			wsum += wt
+			n += p.Averages
+		}
+		out[i] = ResponsePoint{
+			Freq:      sweeps[0][i].Freq,
+			Coherence: wsum / (float64(n) + wsum),
+			Averages:  n,
+		}
+		if wsum > 0 {
+			out[i].H = h / complex(wsum, 0)
+		}
+	}
+	return out
+}
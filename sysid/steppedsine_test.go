@@ -0,0 +1,99 @@
+package sysid
+
+import (
+	"encoding/json"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+	"time"
+
+	pio "github.com/brandondube/pctl/io"
+)
+
+func TestSteppedSineMatchesModel(t *testing.T) {
+	const dt = 2e-4
+	m := Model{K: 3, T1: 1e-3}
+	p := newLivePlant(m, dt)
+	s := SteppedSine{
+		Sensor:    p.sensor(),
+		Actuator:  p.actuator(),
+		DT:        dt,
+		Base:      5,
+		Amplitude: 0.5,
+		Freqs:     []float64{125, 250, 500},
+		Settle:    0.012,
+		Cycles:    2,
+		Averages:  3,
+	}
+	pts, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := math.Exp(-dt / m.T1)
+	for _, pt := range pts {
+		// the zero order hold lag, delayed a sample by the record convention
+		z := cmplx.Exp(complex(0, 2*math.Pi*pt.Freq*dt))
+		want := complex(m.K*(1-a), 0) / (z - complex(a, 0))
+		if cmplx.Abs(pt.H-want) > 1e-4 || math.Abs(pt.Coherence-1) > 1e-9 || pt.Averages != 3 {
+			t.Errorf("at %g Hz expected %v with unit coherence, got %+v", pt.Freq, want, pt)
+		}
+	}
+	if p.wrote != 5 {
+		t.Errorf("expected the base command restored, got %g", p.wrote)
+	}
+}
+
+func TestSteppedSineCoherenceOfNoise(t *testing.T) {
+	const dt = 2e-4
+	p := newLivePlant(Model{K: 3, T1: 1e-3}, dt)
+	r := rand.New(rand.NewSource(1))
+	noisy := pio.SensorFunc(func() (float64, time.Time, error) {
+		return p.meas + r.NormFloat64(), time.Now(), nil
+	})
+	s := SteppedSine{
+		Sensor:    noisy,
+		Actuator:  p.actuator(),
+		DT:        dt,
+		Amplitude: 0.5,
+		Freqs:     []float64{125},
+		Cycles:    2,
+		Averages:  4,
+	}
+	pts, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := pts[0].Coherence; c > 0.9 || c <= 0 {
+		t.Errorf("expected noise to lower the coherence, got %g", c)
+	}
+}
+
+func TestAverageResponsesWeightsByCoherence(t *testing.T) {
+	good := []ResponsePoint{{Freq: 10, H: 1, Coherence: 0.99, Averages: 4}}
+	bad := []ResponsePoint{{Freq: 10, H: 2i, Coherence: 0.1, Averages: 4}}
+	avg := AverageResponses(good, bad)
+	wg, wb := 0.99/0.01, 0.1/0.9
+	want := (complex(wg, 0)*1 + complex(wb, 0)*2i) / complex(wg+wb, 0)
+	if cmplx.Abs(avg[0].H-want) > 1e-12 || avg[0].Averages != 8 {
+		t.Errorf("expected %v over 8 averages, got %+v", want, avg[0])
+	}
+	if avg[0].Coherence <= 0.99*0.9 || avg[0].Coherence >= 1 {
+		t.Errorf("expected the combined coherence near the good sweep's, got %g", avg[0].Coherence)
+	}
+}
+
+func TestResponsePointJSONRoundTrip(t *testing.T) {
+	in := []ResponsePoint{{Freq: 10, H: complex(0.5, -1.25), Coherence: 0.98, Averages: 4}}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []ResponsePoint
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0] != in[0] {
+		t.Errorf("expected %+v after a round trip through %s, got %+v", in, b, out)
+	}
+}
@@ -0,0 +1,88 @@
+"""Reference decoder for pctl telemetry streams.
+
+The format is described in the documentation of the Go package
+github.com/brandondube/pctl/telemetry.  This module uses only the standard
+library; with numpy installed, ``read_numpy`` returns the records as a
+structured array.
+
+Usage::
+
+    import pctl_telemetry
+    header, records = pctl_telemetry.read('run.tlm')
+    for t_ns, values, quality in records:
+        ...
+"""
+import json
+import struct
+
+MAGIC = b'PCTLTLM\n'
+VERSION = 1
+QUALITY = ('good', 'uncertain', 'stale', 'bad')
+
+
+def read_header(f):
+    """Read the header of the stream in binary file f, returning it as a dict.
+
+    f is left positioned at the first record.
+    """
+    if f.read(len(MAGIC)) != MAGIC:
+        raise ValueError('not a pctl telemetry stream')
+    version, length = struct.unpack('<HI', f.read(6))
+    if version == 0 or version > VERSION:
+        raise ValueError('unsupported telemetry version %d' % version)
+    return json.loads(f.read(length).decode('utf-8'))
+
+
+def iter_records(f, header):
+    """Yield (time_ns, values, quality) tuples from binary file f.
+
+    values is a tuple of floats and quality a tuple of ints, one per signal,
+    in the order of header['signals'].  A truncated final record is ignored.
+    """
+    n = len(header['signals'])
+    rec = struct.Struct('<q%dd%dB' % (n, n))
+    while True:
+        b = f.read(rec.size)
+        if len(b) < rec.size:
+            return
+        fields = rec.unpack(b)
+        yield fields[0], fields[1:1 + n], fields[1 + n:]
+
+
+def read(path):
+    """Read a whole stream, returning (header, list of records)."""
+    with open(path, 'rb') as f:
+        header = read_header(f)
+        return header, list(iter_records(f, header))
+
+
+def read_columns(path):
+    """Read a whole stream as columns.
+
+    Returns (header, columns), where columns maps 'time_ns' to the list of
+    times, and each signal name to its list of values and name + '.quality'
+    to its list of qualities.
+    """
+    header, records = read(path)
+    names = [s['name'] for s in header['signals']]
+    cols = {'time_ns': [r[0] for r in records]}
+    for i, name in enumerate(names):
+        cols[name] = [r[1][i] for r in records]
+        cols[name + '.quality'] = [r[2][i] for r in records]
+    return header, cols
+
+
+def read_numpy(path):
+    """Read a whole stream into a numpy structured array.
+
+    Returns (header, array) with fields 'time_ns', and 'v0', 'v1', ... and
+    'q0', 'q1', ... for the values and qualities of each signal in order.
+    """
+    import numpy as np
+    with open(path, 'rb') as f:
+        header = read_header(f)
+        n = len(header['signals'])
+        fields = [('time_ns', '<i8')]
+        fields += [('v%d' % i, '<f8') for i in range(n)]
+        fields += [('q%d' % i, 'u1') for i in range(n)]
+        return header, np.fromfile(f, dtype=np.dtype(fields))
@@ -0,0 +1,221 @@
+/*
+Package telemetry defines pctl's versioned, self-describing telemetry format,
+for logging and streaming the signals of a running system, and reads and
+writes it.
+
+A stream is a header followed by records, all little endian:
+
+	magic    8 bytes, "PCTLTLM\n"
+	version  uint16, Version
+	length   uint32, the length of the header JSON in bytes
+	header   JSON, a Header: the names, units, and rates of the signals
+	records  each of int64 time in nanoseconds since the Unix epoch, then
+	         one float64 value per signal, then one uint8 pctl.Quality per
+	         signal, in the order of the header
+
+The records are of fixed size, 8 + 9n bytes for n signals, so a file may be
+read with e.g. numpy.fromfile using a structured dtype after the header.  A
+reference decoder in Python, using only the standard library, is in
+pctl_telemetry.py alongside this package.
+
+Readers accept any version up to their own; fields are only ever added to the
+header, and the record layout changes only with the version.
+*/
+package telemetry
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/brandondube/pctl"
+	pio "github.com/brandondube/pctl/io"
+)
+
+// Magic begins every telemetry stream
+const Magic = "PCTLTLM\n"
+
+// Version is the version of the format written by this package
+const Version = 1
+
+// maxHeader bounds the header length accepted by NewReader, so a corrupt
+// stream cannot demand an enormous allocation
+const maxHeader = 16 << 20
+
+var (
+	// ErrMagic is returned when a stream does not begin with Magic
+	ErrMagic = errors.New("telemetry: not a pctl telemetry stream")
+
+	// ErrVersion is returned when a stream is of a newer version than this
+	// package reads
+	ErrVersion = errors.New("telemetry: unsupported version")
+
+	// ErrSignals is returned when a record does not have one value per
+	// signal
+	ErrSignals = errors.New("telemetry: record does not match the signals of the header")
+)
+
+// Signal describes one signal of a stream
+type Signal struct {
+	// Name identifies the signal, e.g. "oven/temp"
+	Name string `json:"name"`
+
+	// Unit is the engineering unit of the values, e.g. "degC"
+	Unit string `json:"unit,omitempty"`
+
+	// Rate is the nominal sample rate in Hz, or zero if irregular
+	Rate float64 `json:"rate,omitempty"`
+}
+
+// Header describes a stream
+type Header struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Signals []Signal  `json:"signals"`
+
+	// Meta holds any other annotations, e.g. the host or config hash
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// Record is one sample of every signal
+type Record struct {
+	Time    time.Time
+	Values  []float64
+	Quality []pctl.Quality
+}
+
+// Writer writes a telemetry stream
+type Writer struct {
+	w   io.Writer
+	n   int
+	buf []byte
+}
+
+// NewWriter writes the header to w and returns a Writer of records.  The
+// header's Version is set, and its Created if zero.  Writes are not
+// buffered; wrap w in a bufio.Writer for small records at high rates.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	h.Version = Version
+	if h.Created.IsZero() {
+		h.Created = time.Now()
+	}
+	js, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	pre := make([]byte, len(Magic)+6)
+	copy(pre, Magic)
+	binary.LittleEndian.PutUint16(pre[len(Magic):], Version)
+	binary.LittleEndian.PutUint32(pre[len(Magic)+2:], uint32(len(js)))
+	if _, err := w.Write(append(pre, js...)); err != nil {
+		return nil, err
+	}
+	n := len(h.Signals)
+	return &Writer{w: w, n: n, buf: make([]byte, 8+9*n)}, nil
+}
+
+// Write writes a record of values at time t.  quality may be nil, for all
+// good values.
+func (w *Writer) Write(t time.Time, values []float64, quality []pctl.Quality) error {
+	if len(values) != w.n || (quality != nil && len(quality) != w.n) {
+		return ErrSignals
+	}
+	b := w.buf
+	binary.LittleEndian.PutUint64(b, uint64(t.UnixNano()))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[8+8*i:], math.Float64bits(v))
+	}
+	q := b[8+8*w.n:]
+	for i := range q {
+		q[i] = byte(pctl.QualityGood)
+		if quality != nil {
+			q[i] = byte(quality[i])
+		}
+	}
+	_, err := w.w.Write(b)
+	return err
+}
+
+// WriteBus writes a record of the last values of the named signals on bus, at
+// time t.  Signals which have not been published are recorded as NaN of
+// QualityBad.
+func (w *Writer) WriteBus(t time.Time, bus *pio.Bus, names []string) error {
+	if len(names) != w.n {
+		return ErrSignals
+	}
+	values := make([]float64, w.n)
+	quality := make([]pctl.Quality, w.n)
+	for i, n := range names {
+		s := bus.Last(n)
+		values[i] = s.Value
+		if s.Err != nil {
+			values[i], quality[i] = math.NaN(), pctl.QualityBad
+		}
+	}
+	return w.Write(t, values, quality)
+}
+
+// Reader reads a telemetry stream
+type Reader struct {
+	r   *bufio.Reader
+	h   Header
+	buf []byte
+}
+
+// NewReader reads the header of a stream and returns a Reader of its records
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	pre := make([]byte, len(Magic)+6)
+	if _, err := io.ReadFull(br, pre); err != nil {
+		return nil, err
+	}
+	if string(pre[:len(Magic)]) != Magic {
+		return nil, ErrMagic
+	}
+	if v := binary.LittleEndian.Uint16(pre[len(Magic):]); v == 0 || v > Version {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, v)
+	}
+	n := binary.LittleEndian.Uint32(pre[len(Magic)+2:])
+	if n > maxHeader {
+		return nil, fmt.Errorf("telemetry: header of %d bytes is too long", n)
+	}
+	js := make([]byte, n)
+	if _, err := io.ReadFull(br, js); err != nil {
+		return nil, err
+	}
+	rd := &Reader{r: br}
+	if err := json.Unmarshal(js, &rd.h); err != nil {
+		return nil, err
+	}
+	rd.buf = make([]byte, 8+9*len(rd.h.Signals))
+	return rd, nil
+}
+
+// Header returns the stream's header
+func (r *Reader) Header() Header {
+	return r.h
+}
+
+// Next reads the next record.  It returns io.EOF at the end of the stream,
+// and io.ErrUnexpectedEOF for a truncated final record.
+func (r *Reader) Next() (Record, error) {
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return Record{}, err
+	}
+	n := len(r.h.Signals)
+	rec := Record{
+		Time:    time.Unix(0, int64(binary.LittleEndian.Uint64(r.buf))),
+		Values:  make([]float64, n),
+		Quality: make([]pctl.Quality, n),
+	}
+	for i := range rec.Values {
+		rec.Values[i] = math.Float64frombits(binary.LittleEndian.Uint64(r.buf[8+8*i:]))
+		rec.Quality[i] = pctl.Quality(r.buf[8+8*n+i])
+	}
+	return rec, nil
+}
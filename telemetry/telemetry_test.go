@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+	pio "github.com/brandondube/pctl/io"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	h := Header{Signals: []Signal{{Name: "temp", Unit: "degC", Rate: 10}, {Name: "heater", Unit: "%"}}}
+	w, err := NewWriter(&buf, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Unix(1700000000, 123)
+	w.Write(t0, []float64{21.5, 40}, nil)
+	w.Write(t0.Add(time.Second), []float64{math.NaN(), 41}, []pctl.Quality{pctl.QualityBad, pctl.QualityGood})
+	if err := w.Write(t0, []float64{1}, nil); err != ErrSignals {
+		t.Errorf("expected ErrSignals for a short record, got %v", err)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Header(); got.Version != Version || len(got.Signals) != 2 || got.Signals[0].Unit != "degC" {
+		t.Errorf("header not preserved: %+v", got)
+	}
+	rec, _ := r.Next()
+	if !rec.Time.Equal(t0) || rec.Values[0] != 21.5 || rec.Quality[0] != pctl.QualityGood {
+		t.Errorf("first record not preserved: %+v", rec)
+	}
+	rec, _ = r.Next()
+	if !math.IsNaN(rec.Values[0]) || rec.Quality[0] != pctl.QualityBad || rec.Values[1] != 41 {
+		t.Errorf("second record not preserved: %+v", rec)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF at the end, got %v", err)
+	}
+}
+
+func TestReaderRejectsNewerVersion(t *testing.T) {
+	var buf bytes.Buffer
+	NewWriter(&buf, Header{})
+	b := buf.Bytes()
+	b[len(Magic)] = Version + 1
+	if _, err := NewReader(bytes.NewReader(b)); err == nil {
+		t.Error("expected an error reading a newer version")
+	}
+	if _, err := NewReader(bytes.NewReader([]byte("not telemetry at all"))); err != ErrMagic {
+		t.Errorf("expected ErrMagic, got %v", err)
+	}
+}
+
+func TestWriteBus(t *testing.T) {
+	bus := pio.NewBus()
+	bus.Publish("a", 3)
+	var buf bytes.Buffer
+	w, _ := NewWriter(&buf, Header{Signals: []Signal{{Name: "a"}, {Name: "b"}}})
+	if err := w.WriteBus(time.Now(), bus, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewReader(&buf)
+	rec, _ := r.Next()
+	if rec.Values[0] != 3 || rec.Quality[0] != pctl.QualityGood || rec.Quality[1] != pctl.QualityBad {
+		t.Errorf("expected a good and an unpublished bad signal, got %+v", rec)
+	}
+}
@@ -0,0 +1,49 @@
+package pctl
+
+// TempComp corrects a measurement for the temperature of its sensor, as is
+// needed for most pressure sensors and strain gauges.  It removes a zero
+// shift and a span change which are linear in the temperature difference from
+// Ref, the coefficients given on most datasheets, and optionally an arbitrary
+// zero shift curve:
+//
+//	corrected = (input - Zero*dT - Curve(T)) / (1 + Span*dT), dT = T - Ref
+//
+// The temperature is set with SetTemp or supplied with each measurement
+// through UpdateTemp.
+type TempComp struct {
+	// Ref is the reference temperature, at which no correction is made
+	Ref float64
+
+	// Zero is the zero shift, in measurement units per degree
+	Zero float64
+
+	// Span is the fractional change in span per degree
+	Span float64
+
+	// Curve, if not nil, maps the temperature to an additional zero shift,
+	// e.g. a LookupTable from a characterization run
+	Curve Updater
+
+	temp float64
+}
+
+// SetTemp sets the temperature used by Update
+func (c *TempComp) SetTemp(t float64) {
+	c.temp = t
+}
+
+// Update returns the compensated measurement
+func (c *TempComp) Update(input float64) float64 {
+	dt := c.temp - c.Ref
+	v := input - c.Zero*dt
+	if c.Curve != nil {
+		v -= c.Curve.Update(c.temp)
+	}
+	return v / (1 + c.Span*dt)
+}
+
+// UpdateTemp sets the temperature to temp and then updates with input
+func (c *TempComp) UpdateTemp(input, temp float64) float64 {
+	c.temp = temp
+	return c.Update(input)
+}
@@ -0,0 +1,22 @@
+package pctl
+
+import "testing"
+
+func TestTempCompRemovesZeroAndSpanDrift(t *testing.T) {
+	// a sensor reading p*(1 + 1e-3 dT) + 0.02 dT
+	const ref = 25
+	sensor := func(p, temp float64) float64 {
+		dt := temp - ref
+		return p*(1+1e-3*dt) + 0.02*dt
+	}
+	c := &TempComp{Ref: ref, Zero: 0.02, Span: 1e-3}
+	for _, temp := range []float64{-10, 25, 85} {
+		if got := c.UpdateTemp(sensor(7, temp), temp); !approxEqualAbs(got, 7, 1e-12) {
+			t.Errorf("at %f C expected 7, got %f", temp, got)
+		}
+	}
+	c = &TempComp{Ref: ref, Curve: NewLookupTable([]float64{0, 100}, []float64{-1, 1})}
+	if got := c.UpdateTemp(5, 75); !approxEqualAbs(got, 4.5, 1e-12) {
+		t.Errorf("expected curve correction to 4.5, got %f", got)
+	}
+}
@@ -0,0 +1,125 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNearSingular is returned when a transfer function's leading denominator
+// coefficient is so small relative to the others that normalizing by it would
+// amplify rounding error into the result
+var ErrNearSingular = errors.New("pctl: transfer function leading denominator coefficient is nearly zero")
+
+// nearSingular is the ratio of the leading denominator coefficient to the
+// largest below which ErrNearSingular is reported
+const nearSingular = 1e-12
+
+// Normalize returns the transfer function scaled so that Den[0] = 1, with
+// trailing zero coefficients removed.  It returns ErrLeadingDen if Den[0] is
+// zero, which makes the system improper (non-causal), ErrNearSingular if it is
+// nearly so, or a *ParamError for empty or non-finite coefficients.
+func (tf TF) Normalize() (TF, error) {
+	const typ = "TF"
+	if len(tf.Num) == 0 {
+		return TF{}, paramErr(typ, "Num", "must not be empty")
+	}
+	if len(tf.Den) == 0 {
+		return TF{}, paramErr(typ, "Den", "must not be empty")
+	}
+	var max float64
+	for _, v := range tf.Den {
+		if !finite(v) {
+			return TF{}, paramErr(typ, "Den", "must be finite")
+		}
+		max = math.Max(max, math.Abs(v))
+	}
+	for _, v := range tf.Num {
+		if !finite(v) {
+			return TF{}, paramErr(typ, "Num", "must be finite")
+		}
+	}
+	d0 := tf.Den[0]
+	switch {
+	case d0 == 0:
+		return TF{}, ErrLeadingDen
+	case math.Abs(d0) < nearSingular*max:
+		return TF{}, ErrNearSingular
+	}
+	out := TF{Num: trimTrailing(tf.Num), Den: trimTrailing(tf.Den)}
+	for i := range out.Num {
+		out.Num[i] /= d0
+	}
+	for i := range out.Den {
+		out.Den[i] /= d0
+	}
+	return out, nil
+}
+
+// trimTrailing returns a copy of c without trailing zeros, keeping at least
+// one element
+func trimTrailing(c []float64) []float64 {
+	n := len(c)
+	for n > 1 && c[n-1] == 0 {
+		n--
+	}
+	return append([]float64(nil), c[:n]...)
+}
+
+// TFFilter runs a transfer function, in direct form II transposed.  High order
+// transfer functions are sensitive to rounding of their coefficients; prefer
+// an SOSFilter, e.g. from TF.ZPK and ZPK.SOS, beyond about fourth order.
+type TFFilter struct {
+	tf TF
+
+	// s is the state of the transposed direct form II
+	s []float64
+}
+
+// NewTFFilter returns a new filter running tf, which is normalized first;
+// errors from Normalize are returned
+func NewTFFilter(tf TF) (*TFFilter, error) {
+	tf, err := tf.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	n := len(tf.Num)
+	if len(tf.Den) > n {
+		n = len(tf.Den)
+	}
+	// pad to a common length so Update need not check bounds
+	num := make([]float64, n)
+	den := make([]float64, n)
+	copy(num, tf.Num)
+	copy(den, tf.Den)
+	return &TFFilter{tf: TF{Num: num, Den: den}, s: make([]float64, n)}, nil
+}
+
+// Update processes an input value, returning the filtered output
+func (f *TFFilter) Update(input float64) float64 {
+	b, a, s := f.tf.Num, f.tf.Den, f.s
+	y := b[0]*input + s[0]
+	n := len(s)
+	for i := 1; i < n; i++ {
+		s[i-1] = b[i]*input - a[i]*y + s[i]
+	}
+	s[n-1] = 0
+	return y
+}
+
+// TF returns the normalized transfer function of the filter
+func (f *TFFilter) TF() TF {
+	return TF{Num: trimTrailing(f.tf.Num), Den: trimTrailing(f.tf.Den)}
+}
+
+// Response returns the complex gain of the filter at frequency f (Hz) for
+// sample rate fs
+func (f *TFFilter) Response(freq, fs float64) complex128 {
+	return f.tf.Response(freq, fs)
+}
+
+// Reset zeros the filter's internal state
+func (f *TFFilter) Reset() {
+	for i := range f.s {
+		f.s[i] = 0
+	}
+}
@@ -0,0 +1,42 @@
+package pctl
+
+import "testing"
+
+func TestTFNormalize(t *testing.T) {
+	tf, err := TF{Num: []float64{2, 4, 0, 0}, Den: []float64{2, -1, 0}}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tf.Num) != 2 || len(tf.Den) != 2 || tf.Num[1] != 2 || tf.Den[1] != -0.5 {
+		t.Errorf("unexpected normalized tf %v", tf)
+	}
+	bad := []struct {
+		tf  TF
+		err error
+	}{
+		{TF{Num: []float64{1}, Den: []float64{0, 1}}, ErrLeadingDen},
+		{TF{Num: []float64{1}, Den: []float64{1e-15, 1}}, ErrNearSingular},
+	}
+	for _, c := range bad {
+		if _, err := NewTFFilter(c.tf); err != c.err {
+			t.Errorf("%v: expected %v, got %v", c.tf, c.err, err)
+		}
+	}
+	if _, err := NewTFFilter(TF{Den: []float64{1}}); err == nil {
+		t.Error("expected empty numerator to be rejected")
+	}
+}
+
+func TestTFFilterMatchesStateSpace(t *testing.T) {
+	f, err := NewTFFilter(testTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss, _ := testTF.SS()
+	for i := 0; i < 20; i++ {
+		in := float64(i%3) - 1
+		if a, b := f.Update(in), ss.Update(in); !approxEqualAbs(a, b, 1e-12) {
+			t.Errorf("sample %d: tf filter %f != state space %f", i, a, b)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package pctl
+
+import (
+	"math"
+	"time"
+)
+
+// The filters in this file measure the time between updates with a Clock
+// instead of assuming a fixed DT, for use with aperiodic sampling.  Each
+// recomputes its coefficients on every update, so they are somewhat slower
+// than their fixed rate counterparts.  The first update only initializes the
+// filter.  Updates at the same instant as the previous return the previous
+// output.
+
+// interval tracks the time between updates
+type interval struct {
+	clock   Clock
+	last    time.Time
+	started bool
+}
+
+// elapsed returns the seconds since the last call, and false on the first
+// call
+func (iv *interval) elapsed() (float64, bool) {
+	c := iv.clock
+	if c == nil {
+		c = SystemClock
+	}
+	now := c.Now()
+	if !iv.started {
+		iv.started = true
+		iv.last = now
+		return 0, false
+	}
+	dt := now.Sub(iv.last).Seconds()
+	iv.last = now
+	return dt, true
+}
+
+// TimedLPF is a first order low pass filter timed by a Clock
+type TimedLPF struct {
+	iv   interval
+	rc   float64
+	prev float64
+}
+
+// NewTimedLPF returns a new low pass filter with the specified corner
+// frequency in Hertz.  If clock is nil, SystemClock is used.
+func NewTimedLPF(cutoffFreq float64, clock Clock) *TimedLPF {
+	return &TimedLPF{iv: interval{clock: clock}, rc: 1 / (2 * math.Pi * cutoffFreq)}
+}
+
+// Update processes an input value, returning the filtered output
+func (l *TimedLPF) Update(input float64) float64 {
+	dt, ok := l.iv.elapsed()
+	if !ok {
+		l.prev = input
+		return l.prev
+	}
+	if dt > 0 {
+		l.prev += dt / (l.rc + dt) * (input - l.prev)
+	}
+	return l.prev
+}
+
+// TimedHPF is a first order high pass filter timed by a Clock
+type TimedHPF struct {
+	iv     interval
+	rc     float64
+	prev   float64
+	prevIn float64
+}
+
+// NewTimedHPF returns a new high pass filter with the specified corner
+// frequency in Hertz.  If clock is nil, SystemClock is used.
+func NewTimedHPF(cutoffFreq float64, clock Clock) *TimedHPF {
+	return &TimedHPF{iv: interval{clock: clock}, rc: 1 / (2 * math.Pi * cutoffFreq)}
+}
+
+// Update processes an input value, returning the filtered output
+func (h *TimedHPF) Update(input float64) float64 {
+	dt, ok := h.iv.elapsed()
+	if !ok {
+		h.prevIn = input
+		return 0
+	}
+	if dt > 0 {
+		h.prev = h.rc / (h.rc + dt) * (h.prev + input - h.prevIn)
+		h.prevIn = input
+	}
+	return h.prev
+}
+
+// svf is a trapezoidal integrated state variable filter, which is well behaved
+// when its coefficients change every sample.  See A. Simper, "Linear
+// Trapezoidal Integrated State Variable Filter With Low Noise Optimisation,"
+// Cytomic, 2011.
+type svf struct {
+	iv       interval
+	fc       float64
+	k        float64
+	ic1, ic2 float64
+	prev     float64
+}
+
+// maxFcDT limits fc*dt below Nyquist (0.5), where tan blows up
+const maxFcDT = 0.49
+
+// step advances the filter by the elapsed time and returns the band (v1) and
+// low (v2) outputs.  ok is false if no time has elapsed.
+func (s *svf) step(v0 float64) (v1, v2 float64, ok bool) {
+	dt, started := s.iv.elapsed()
+	if !started || dt <= 0 {
+		return 0, 0, false
+	}
+	g := math.Tan(math.Pi * math.Min(s.fc*dt, maxFcDT))
+	a1 := 1 / (1 + g*(g+s.k))
+	a2 := g * a1
+	a3 := g * a2
+	v3 := v0 - s.ic2
+	v1 = a1*s.ic1 + a2*v3
+	v2 = s.ic2 + a2*s.ic1 + a3*v3
+	s.ic1 = 2*v1 - s.ic1
+	s.ic2 = 2*v2 - s.ic2
+	return v1, v2, true
+}
+
+// TimedBandpass is a second order band pass filter with unity gain at its
+// center frequency, timed by a Clock
+type TimedBandpass struct {
+	svf
+}
+
+// NewTimedBandpass returns a new band pass filter with the specified center
+// frequency in Hertz and quality factor.  If clock is nil, SystemClock is used.
+func NewTimedBandpass(centerFreq, Q float64, clock Clock) *TimedBandpass {
+	return &TimedBandpass{svf{iv: interval{clock: clock}, fc: centerFreq, k: 1 / Q}}
+}
+
+// Update processes an input value, returning the filtered output
+func (b *TimedBandpass) Update(input float64) float64 {
+	if v1, _, ok := b.step(input); ok {
+		b.prev = b.k * v1
+	}
+	return b.prev
+}
+
+// TimedNotch is a second order notch (band stop) filter timed by a Clock
+type TimedNotch struct {
+	svf
+}
+
+// NewTimedNotch returns a new notch filter with the specified center frequency
+// in Hertz and quality factor.  If clock is nil, SystemClock is used.
+func NewTimedNotch(centerFreq, Q float64, clock Clock) *TimedNotch {
+	return &TimedNotch{svf{iv: interval{clock: clock}, fc: centerFreq, k: 1 / Q}}
+}
+
+// Update processes an input value, returning the filtered output
+func (n *TimedNotch) Update(input float64) float64 {
+	if v1, _, ok := n.step(input); ok {
+		n.prev = input - n.k*v1
+	}
+	return n.prev
+}
@@ -0,0 +1,64 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestTimedFirstOrderMatchesFixedDT(t *testing.T) {
+	const fc, dt = 10., 1e-3
+	var clk ManualClock
+	tl := NewTimedLPF(fc, &clk)
+	th := NewTimedHPF(fc, &clk)
+	l := NewLPF(fc, dt)
+	h := NewHPF(fc, dt)
+	// the timed filters initialize on their first update
+	tl.Update(0)
+	th.Update(0)
+	for i := 0; i < 100; i++ {
+		clk.Advance(time.Millisecond)
+		in := math.Sin(float64(i) / 10)
+		if a, b := tl.Update(in), l.Update(in); !approxEqualAbs(a, b, 1e-12) {
+			t.Fatalf("sample %d: timed LPF %f != LPF %f", i, a, b)
+		}
+		if a, b := th.Update(in), h.Update(in); !approxEqualAbs(a, b, 1e-12) {
+			t.Fatalf("sample %d: timed HPF %f != HPF %f", i, a, b)
+		}
+	}
+}
+
+// timedGain drives u with a sinusoid of frequency f sampled at jittered
+// intervals of 0.5-1.5ms and returns the peak output after settling
+func timedGain(u Updater, clk *ManualClock, f float64) float64 {
+	r := rand.New(rand.NewSource(1))
+	var tt, peak float64
+	for i := 0; i < 40000; i++ {
+		dt := (0.5 + r.Float64()) * 1e-3
+		clk.Advance(time.Duration(dt * float64(time.Second)))
+		tt += dt
+		out := u.Update(math.Sin(2 * math.Pi * f * tt))
+		if i > 20000 && math.Abs(out) > peak {
+			peak = math.Abs(out)
+		}
+	}
+	return peak
+}
+
+func TestTimedBandFiltersWithJitter(t *testing.T) {
+	const fc = 20.
+	var clk ManualClock
+	if g := timedGain(NewTimedBandpass(fc, 2, &clk), &clk, fc); !approxEqualAbs(g, 1, 0.05) {
+		t.Errorf("band pass gain at center %f, expected 1", g)
+	}
+	if g := timedGain(NewTimedBandpass(fc, 2, &clk), &clk, 10*fc); g > 0.15 {
+		t.Errorf("band pass gain a decade above center %f, expected attenuation", g)
+	}
+	if g := timedGain(NewTimedNotch(fc, 2, &clk), &clk, fc); g > 0.1 {
+		t.Errorf("notch gain at center %f, expected rejection", g)
+	}
+	if g := timedGain(NewTimedNotch(fc, 2, &clk), &clk, fc/20); !approxEqualAbs(g, 1, 0.05) {
+		t.Errorf("notch gain well below center %f, expected 1", g)
+	}
+}
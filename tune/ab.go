@@ -0,0 +1,209 @@
+package tune
+
+import (
+	"math"
+
+	"github.com/brandondube/pctl/sim"
+)
+
+// ABTest compares two controller parameter vectors by running an Experiment
+// on each in matched segments.  On the live plant, the runs alternate in the
+// order ABBA ABBA..., so a slow drift of the plant affects both alike.  In
+// simulation, a SimExperiment whose scenario carries a recorded
+// DisturbanceTrace replays identical conditions through both.
+type ABTest struct {
+	// Experiment runs one segment
+	Experiment Experiment
+
+	// A and B are the parameters compared
+	A, B []float64
+
+	// Pairs is the number of segments run for each
+	Pairs int
+}
+
+// ABReport holds the outcomes of an ABTest, in pairs: A[i] and B[i] were run
+// back to back
+type ABReport struct {
+	A []Outcome `json:"a"`
+	B []Outcome `json:"b"`
+}
+
+// ABStats is a paired statistical comparison of a metric of A and B, over the
+// pairs in which neither run was aborted
+type ABStats struct {
+	// N is the number of pairs compared
+	N int `json:"n"`
+
+	// AbortedA and AbortedB count the aborted runs of each
+	AbortedA int `json:"aborted_a"`
+	AbortedB int `json:"aborted_b"`
+
+	// MeanA and MeanB are the means of the metric
+	MeanA float64 `json:"mean_a"`
+	MeanB float64 `json:"mean_b"`
+
+	// Diff is the mean of B - A over the pairs, and StdErr its standard
+	// error
+	Diff   float64 `json:"diff"`
+	StdErr float64 `json:"std_err"`
+
+	// T is the paired t statistic, Diff/StdErr, and P its two sided p-value:
+	// the probability of a difference at least as large if A and B perform
+	// alike.  P is NaN with fewer than two pairs.
+	T float64 `json:"t"`
+	P float64 `json:"p"`
+}
+
+// Run runs the test, stopping at the first error with the pairs completed so
+// far
+func (ab ABTest) Run() (*ABReport, error) {
+	rep := &ABReport{}
+	for i := 0; i < ab.Pairs; i++ {
+		first, second := ab.A, ab.B
+		if i%2 == 1 {
+			first, second = second, first
+		}
+		o1, err := ab.Experiment.Run(append([]float64(nil), first...))
+		if err != nil {
+			return rep, err
+		}
+		o2, err := ab.Experiment.Run(append([]float64(nil), second...))
+		if err != nil {
+			return rep, err
+		}
+		if i%2 == 1 {
+			o1, o2 = o2, o1
+		}
+		rep.A = append(rep.A, o1)
+		rep.B = append(rep.B, o2)
+	}
+	return rep, nil
+}
+
+// Compare returns the paired comparison of metric m.  A negative Diff favors
+// B for a cost such as sim.MetricIAE.
+func (rep *ABReport) Compare(m sim.Metric) ABStats {
+	var st ABStats
+	var d []float64
+	for i := range rep.A {
+		a, b := rep.A[i], rep.B[i]
+		if a.Aborted {
+			st.AbortedA++
+		}
+		if b.Aborted {
+			st.AbortedB++
+		}
+		if a.Aborted || b.Aborted {
+			continue
+		}
+		va, vb := m(a.Metrics), m(b.Metrics)
+		st.MeanA += va
+		st.MeanB += vb
+		d = append(d, vb-va)
+	}
+	st.N = len(d)
+	st.T, st.P = math.NaN(), math.NaN()
+	if st.N == 0 {
+		st.MeanA, st.MeanB, st.Diff, st.StdErr = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+		return st
+	}
+	n := float64(st.N)
+	st.MeanA /= n
+	st.MeanB /= n
+	for _, v := range d {
+		st.Diff += v
+	}
+	st.Diff /= n
+	if st.N < 2 {
+		st.StdErr = math.NaN()
+		return st
+	}
+	var ss float64
+	for _, v := range d {
+		ss += (v - st.Diff) * (v - st.Diff)
+	}
+	st.StdErr = math.Sqrt(ss / (n - 1) / n)
+	st.T = st.Diff / st.StdErr
+	switch {
+	case st.StdErr == 0 && st.Diff == 0:
+		st.T, st.P = 0, 1
+	case st.StdErr == 0:
+		st.P = 0
+	default:
+		st.P = studentTwoSided(st.T, n-1)
+	}
+	return st
+}
+
+// studentTwoSided returns the two sided p-value of t under Student's t
+// distribution with nu degrees of freedom, I_x(nu/2, 1/2) for
+// x = nu/(nu+t^2)
+func studentTwoSided(t, nu float64) float64 {
+	return incBeta(nu/2, 0.5, nu/(nu+t*t))
+}
+
+// incBeta is the regularized incomplete beta function I_x(a, b), by its
+// continued fraction
+func incBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// the fraction converges quickly for x below the mean; use the symmetry
+	// I_x(a, b) = 1 - I_(1-x)(b, a) above it
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaCF(b, a, 1-x)/b
+	}
+	return front * betaCF(a, b, x) / a
+}
+
+// betaCF evaluates the continued fraction of the incomplete beta function by
+// the modified Lentz method
+func betaCF(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1., 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		// even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}
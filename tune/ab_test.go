@@ -0,0 +1,79 @@
+package tune
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/sim"
+)
+
+// driftingExperiment scores x[0] plus noise, on a plant whose performance
+// drifts worse with every run
+type driftingExperiment struct {
+	r     *rand.Rand
+	runs  int
+	order []float64
+}
+
+func (e *driftingExperiment) Run(x []float64) (Outcome, error) {
+	e.order = append(e.order, x[0])
+	e.runs++
+	iae := x[0] + 0.01*float64(e.runs) + 0.02*e.r.NormFloat64()
+	return Outcome{Params: x, Metrics: sim.Metrics{IAE: iae}}, nil
+}
+
+func TestABTestAlternatesAndDetects(t *testing.T) {
+	e := &driftingExperiment{r: rand.New(rand.NewSource(1))}
+	rep, err := ABTest{Experiment: e, A: []float64{1}, B: []float64{0.9}, Pairs: 10}.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.order[0] != 1 || e.order[1] != 0.9 || e.order[2] != 0.9 || e.order[3] != 1 {
+		t.Errorf("expected the runs in the order ABBA, got %v", e.order[:4])
+	}
+	st := rep.Compare(sim.MetricIAE)
+	if st.N != 10 || !(st.Diff < 0) || st.P > 0.01 {
+		t.Errorf("expected B significantly better over 10 pairs, got %+v", st)
+	}
+	same, _ := ABTest{Experiment: e, A: []float64{1}, B: []float64{1}, Pairs: 10}.Run()
+	if st := same.Compare(sim.MetricIAE); st.P < 0.05 {
+		t.Errorf("expected no significant difference between identical parameters, got p = %g", st.P)
+	}
+}
+
+func TestABTestReplaysTrace(t *testing.T) {
+	const dt = 1e-3
+	r := rand.New(rand.NewSource(2))
+	trace := make([]float64, 2000)
+	for i := range trace {
+		trace[i] = r.NormFloat64()
+	}
+	e := SimExperiment{
+		Build: func(x []float64) sim.Loop {
+			return sim.Loop{Controller: piController(dt)(x), Plant: pctl.NewLPF(20, dt)}
+		},
+		Scenario: sim.Scenario{DT: dt, Duration: 2, DisturbanceTrace: trace},
+	}
+	rep, err := ABTest{Experiment: e, A: []float64{1, 10}, B: []float64{1, 10}, Pairs: 2}.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := rep.Compare(sim.MetricIAE); st.Diff != 0 || st.P != 1 {
+		t.Errorf("expected identical controllers to score identically on a replayed trace, got %+v", st)
+	}
+}
+
+func TestStudentTwoSided(t *testing.T) {
+	for _, c := range []struct{ t, nu, p float64 }{
+		{0, 5, 1},
+		{2, 10, 0.073388},
+		{-2.228139, 10, 0.05},
+		{12.706205, 1, 0.05},
+	} {
+		if got := studentTwoSided(c.t, c.nu); math.Abs(got-c.p) > 1e-5 {
+			t.Errorf("p(t=%g, nu=%g) = %g, expected %g", c.t, c.nu, got, c.p)
+		}
+	}
+}
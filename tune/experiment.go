@@ -0,0 +1,143 @@
+package tune
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/brandondube/pctl"
+	pio "github.com/brandondube/pctl/io"
+	"github.com/brandondube/pctl/sim"
+)
+
+// Experiment evaluates a vector of controller parameters, in simulation or on
+// the live plant.  It is the unit of work of a tuning campaign, and suits
+// external Bayesian optimization services, which propose parameters and want
+// back only their metrics.
+type Experiment interface {
+	// Run evaluates x.  An error is returned only if the experiment could
+	// not be carried out, such as for a failed sensor; a run stopped for
+	// safety is an Outcome with Aborted set.
+	Run(x []float64) (Outcome, error)
+}
+
+// Outcome is the result of an experiment
+type Outcome struct {
+	// Params are the parameters evaluated
+	Params []float64 `json:"params"`
+
+	// Metrics summarize the run.  If it was aborted, they are of the samples
+	// taken before the abort.
+	Metrics sim.Metrics `json:"metrics"`
+
+	// Aborted is true if the run was stopped early because the measurement
+	// left its safe range.  Optimizers should treat it as infeasible.
+	Aborted bool `json:"aborted"`
+}
+
+// SimExperiment is an Experiment in simulation
+type SimExperiment struct {
+	// Build returns the loop to simulate for parameters x, with a new plant
+	Build func(x []float64) sim.Loop
+
+	// Scenario is the simulation run
+	Scenario sim.Scenario
+}
+
+// Run simulates the loop built from x
+func (e SimExperiment) Run(x []float64) (Outcome, error) {
+	res := sim.Run(e.Build(x), e.Scenario)
+	return Outcome{Params: x, Metrics: res.Metrics()}, nil
+}
+
+// PlantExperiment is an Experiment on the live plant.  It runs the setpoint
+// and disturbance of Scenario in real time, closing the loop through Sensor
+// and Actuator with the conventions of package sim, and aborts if the
+// measurement leaves [MeasMin, MeasMax].
+type PlantExperiment struct {
+	// Controller returns the controller for parameters x
+	Controller func(x []float64) pctl.Updater
+
+	// Sensor and Actuator connect to the plant
+	Sensor   pio.Sensor
+	Actuator pio.Actuator
+
+	// Scenario is the experiment run; its DT is the update period
+	Scenario sim.Scenario
+
+	// MeasMin and MeasMax are the safe range of the measurement.  They apply
+	// when MeasMax > MeasMin.
+	MeasMin, MeasMax float64
+
+	// Safe is the command written when the run ends or is aborted
+	Safe float64
+}
+
+// Run closes the loop on the plant with the controller for x
+func (e PlantExperiment) Run(x []float64) (out Outcome, err error) {
+	out.Params = x
+	s := e.Scenario
+	setpt, dist := s.Inputs()
+	res := &sim.Result{}
+	defer func() {
+		if werr := e.Actuator.Write(e.Safe); werr != nil && err == nil {
+			err = fmt.Errorf("tune: actuator: %w", werr)
+		}
+		out.Metrics = res.Metrics()
+	}()
+	ctrl := e.Controller(x)
+	tick := time.NewTicker(time.Duration(s.DT * float64(time.Second)))
+	defer tick.Stop()
+	for i := range setpt {
+		if i > 0 {
+			<-tick.C
+		}
+		meas, _, rerr := e.Sensor.Read()
+		if rerr != nil {
+			return out, fmt.Errorf("tune: sensor: %w", rerr)
+		}
+		if e.MeasMax > e.MeasMin && (meas < e.MeasMin || meas > e.MeasMax) {
+			out.Aborted = true
+			return out, nil
+		}
+		sp, d := setpt[i], dist[i]
+		cmd := ctrl.Update(meas - sp)
+		res.T = append(res.T, float64(i)*s.DT)
+		res.Setpoint = append(res.Setpoint, sp)
+		res.Measurement = append(res.Measurement, meas)
+		res.Command = append(res.Command, cmd)
+		res.Disturbance = append(res.Disturbance, d)
+		if werr := e.Actuator.Write(cmd + d); werr != nil {
+			return out, fmt.Errorf("tune: actuator: %w", werr)
+		}
+	}
+	return out, nil
+}
+
+// Campaign minimizes the cost of experiments with opt, for at most maxEvals
+// runs, and returns the outcome of every run in order.  Aborted runs are
+// observed as +Inf.  It stops at the first error, returning the outcomes so
+// far.
+func Campaign(opt Optimizer, e Experiment, cost sim.Metric, maxEvals int) ([]Outcome, error) {
+	var outs []Outcome
+	for i := 0; i < maxEvals; i++ {
+		x := opt.Suggest()
+		if x == nil {
+			break
+		}
+		out, err := e.Run(append([]float64(nil), x...))
+		if err != nil {
+			return outs, err
+		}
+		outs = append(outs, out)
+		c := math.Inf(1)
+		if !out.Aborted {
+			c = cost(out.Metrics)
+		}
+		if math.IsNaN(c) {
+			c = math.Inf(1)
+		}
+		opt.Observe(x, c)
+	}
+	return outs, nil
+}
@@ -0,0 +1,109 @@
+package tune
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brandondube/pctl"
+	pio "github.com/brandondube/pctl/io"
+	"github.com/brandondube/pctl/sim"
+)
+
+// simPlant is a plant model behind a Sensor and Actuator, as the live plant
+// would be
+type simPlant struct {
+	plant       pctl.Updater
+	meas, wrote float64
+}
+
+func (p *simPlant) sensor() pio.Sensor {
+	return pio.SensorFunc(func() (float64, time.Time, error) { return p.meas, time.Now(), nil })
+}
+
+func (p *simPlant) actuator() pio.Actuator {
+	return pio.ActuatorFunc(func(v float64) error {
+		p.wrote = v
+		p.meas = p.plant.Update(v)
+		return nil
+	})
+}
+
+func piController(dt float64) func([]float64) pctl.Updater {
+	return func(x []float64) pctl.Updater {
+		return &pctl.PID{P: x[0], I: x[1], DT: dt}
+	}
+}
+
+func TestPlantExperimentMatchesSimulation(t *testing.T) {
+	const dt = 1e-3
+	scn := sim.Scenario{DT: dt, Duration: 0.1, Setpoint: []sim.Event{{T: 0.01, Value: 1}}}
+	ctrl := piController(dt)
+	x := []float64{2, 40}
+	want, _ := SimExperiment{
+		Build:    func(x []float64) sim.Loop { return sim.Loop{Controller: ctrl(x), Plant: pctl.NewLPF(20, dt)} },
+		Scenario: scn,
+	}.Run(x)
+	p := &simPlant{plant: pctl.NewLPF(20, dt)}
+	got, err := PlantExperiment{
+		Controller: ctrl,
+		Sensor:     p.sensor(),
+		Actuator:   p.actuator(),
+		Scenario:   scn,
+		Safe:       -1,
+	}.Run(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Aborted || got.Metrics != want.Metrics {
+		t.Errorf("plant experiment %+v differs from simulation %+v", got, want)
+	}
+	if p.wrote != -1 {
+		t.Errorf("expected the safe command written at the end, last write was %g", p.wrote)
+	}
+}
+
+func TestPlantExperimentAborts(t *testing.T) {
+	const dt = 1e-3
+	p := &simPlant{plant: pctl.NewLPF(20, dt)}
+	out, err := PlantExperiment{
+		Controller: piController(dt),
+		Sensor:     p.sensor(),
+		Actuator:   p.actuator(),
+		Scenario:   sim.Scenario{DT: dt, Duration: 0.1, Setpoint: []sim.Event{{T: 0, Value: 1}}},
+		MeasMin:    -0.5,
+		MeasMax:    0.5,
+	}.Run([]float64{2, 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Aborted {
+		t.Errorf("expected the run aborted on leaving the safe range")
+	}
+	if p.wrote != 0 || p.meas > 0.6 {
+		t.Errorf("expected the safe command written promptly, wrote %g with meas %g", p.wrote, p.meas)
+	}
+}
+
+func TestCampaignImproves(t *testing.T) {
+	const dt = 1e-3
+	ctrl := piController(dt)
+	e := SimExperiment{
+		Build: func(x []float64) sim.Loop {
+			return sim.Loop{Controller: ctrl(x), Plant: pctl.NewLPF(2, dt)}
+		},
+		Scenario: sim.Scenario{DT: dt, Duration: 2, Setpoint: []sim.Event{{T: 0.1, Value: 1}}},
+	}
+	outs, err := Campaign(NewNelderMead([]float64{0.5, 1}, []float64{0.25, 0.5}, 1e-6), e, sim.MetricITAE, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, best := outs[0].Metrics.ITAE, outs[0].Metrics.ITAE
+	for _, o := range outs {
+		if o.Metrics.ITAE < best {
+			best = o.Metrics.ITAE
+		}
+	}
+	if best > first/2 {
+		t.Errorf("expected the campaign to halve the ITAE of %g, best was %g", first, best)
+	}
+}
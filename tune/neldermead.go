@@ -0,0 +1,168 @@
+package tune
+
+import (
+	"math"
+	"sort"
+)
+
+// NelderMead minimizes f from x0 by the Nelder-Mead simplex method.  It is
+// Minimize with a NelderMeadOptimizer.
+func NelderMead(f func([]float64) float64, x0, step []float64, maxEvals int, tol float64) (x []float64, fx float64) {
+	return Minimize(NewNelderMead(x0, step, tol), f, maxEvals)
+}
+
+// nmPhase is the point a NelderMeadOptimizer is waiting on the cost of
+type nmPhase int
+
+const (
+	nmInit nmPhase = iota
+	nmReflect
+	nmExpand
+	nmContract
+	nmShrink
+	nmDone
+)
+
+type vertex struct {
+	x []float64
+	f float64
+}
+
+// NelderMeadOptimizer is the Nelder-Mead simplex method as an Optimizer.  It
+// is derivative-free and suits costs from simulations, which are noisy in
+// their derivatives and infinite where the loop is unstable.  It converges
+// when the costs at the vertices of the simplex agree to within Tol.
+type NelderMeadOptimizer struct {
+	// Tol is the relative tolerance of convergence
+	Tol float64
+
+	simplex []vertex
+	phase   nmPhase
+	i       int       // vertex being evaluated, in nmInit and nmShrink
+	c       []float64 // centroid of all but the worst
+	r       vertex    // the reflection, once observed
+	pending []float64
+}
+
+// NewNelderMead returns a new Nelder-Mead optimizer, whose initial simplex
+// extends step from x0 along each axis
+func NewNelderMead(x0, step []float64, tol float64) *NelderMeadOptimizer {
+	n := len(x0)
+	o := &NelderMeadOptimizer{Tol: tol, simplex: make([]vertex, n+1)}
+	for i := range o.simplex {
+		v := append([]float64(nil), x0...)
+		if i > 0 {
+			v[i-1] += step[i-1]
+		}
+		o.simplex[i].x = v
+	}
+	o.pending = o.simplex[0].x
+	return o
+}
+
+// NelderMeadFunc is a NewOptimizerFunc for NewNelderMead with a tolerance of
+// 1e-6
+func NelderMeadFunc(x0, step []float64) Optimizer {
+	return NewNelderMead(x0, step, 1e-6)
+}
+
+// Suggest returns the next point to evaluate, or nil once converged
+func (o *NelderMeadOptimizer) Suggest() []float64 {
+	return o.pending
+}
+
+// along returns c + t (w - c)
+func along(c, w []float64, t float64) []float64 {
+	out := make([]float64, len(c))
+	for i := range out {
+		out[i] = c[i] + t*(w[i]-c[i])
+	}
+	return out
+}
+
+// Observe reports the cost of the point last suggested
+func (o *NelderMeadOptimizer) Observe(x []float64, cost float64) {
+	if math.IsNaN(cost) {
+		cost = math.Inf(1)
+	}
+	n := len(o.simplex) - 1
+	worst := &o.simplex[n]
+	switch o.phase {
+	case nmInit:
+		o.simplex[o.i].f = cost
+		if o.i++; o.i <= n {
+			o.pending = o.simplex[o.i].x
+			return
+		}
+	case nmReflect:
+		o.r = vertex{x, cost}
+		switch {
+		case cost < o.simplex[0].f:
+			o.phase, o.pending = nmExpand, along(o.c, worst.x, -2)
+			return
+		case cost < o.simplex[n-1].f:
+			*worst = o.r
+		default:
+			// contract, outside if the reflection improved on the worst
+			t := 0.5
+			if cost < worst.f {
+				t = -0.5
+			}
+			o.phase, o.pending = nmContract, along(o.c, worst.x, t)
+			return
+		}
+	case nmExpand:
+		if cost < o.r.f {
+			*worst = vertex{x, cost}
+		} else {
+			*worst = o.r
+		}
+	case nmContract:
+		if cost >= math.Min(o.r.f, worst.f) {
+			// shrink towards the best
+			o.phase, o.i = nmShrink, 1
+			o.pending = along(o.simplex[0].x, o.simplex[1].x, 0.5)
+			return
+		}
+		*worst = vertex{x, cost}
+	case nmShrink:
+		o.simplex[o.i] = vertex{x, cost}
+		if o.i++; o.i <= n {
+			o.pending = along(o.simplex[0].x, o.simplex[o.i].x, 0.5)
+			return
+		}
+	case nmDone:
+		return
+	}
+	o.iterate()
+}
+
+// iterate orders the simplex, checks convergence, and suggests the
+// reflection of the worst vertex
+func (o *NelderMeadOptimizer) iterate() {
+	n := len(o.simplex) - 1
+	sort.SliceStable(o.simplex, func(i, j int) bool { return o.simplex[i].f < o.simplex[j].f })
+	best, worst := o.simplex[0].f, o.simplex[n].f
+	if math.Abs(worst-best) <= o.Tol*(math.Abs(best)+o.Tol) {
+		o.phase, o.pending = nmDone, nil
+		return
+	}
+	o.c = make([]float64, n)
+	for _, v := range o.simplex[:n] {
+		for i := range o.c {
+			o.c[i] += v.x[i] / float64(n)
+		}
+	}
+	o.phase, o.pending = nmReflect, along(o.c, o.simplex[n].x, -1)
+}
+
+// Best returns the best vertex of the simplex and its cost
+func (o *NelderMeadOptimizer) Best() ([]float64, float64) {
+	best := o.simplex[0]
+	for _, v := range o.simplex {
+		if v.f < best.f {
+			best = v
+		}
+	}
+	return best.x, best.f
+}
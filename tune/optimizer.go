@@ -0,0 +1,45 @@
+package tune
+
+import "math"
+
+// Optimizer is a minimizer driven by its caller, in the ask and tell style:
+// the caller asks for a point with Suggest, evaluates its cost, and tells the
+// optimizer with Observe.  The tuners use it so that CMA-ES, genetic
+// algorithms, or optimization services may be plugged in without pctl
+// depending on them, and so that an evaluation may be as slow as a run on the
+// live plant.
+type Optimizer interface {
+	// Suggest returns the next point to evaluate, or nil when the optimizer
+	// has converged.  Calling it again before Observe returns the same point.
+	Suggest() []float64
+
+	// Observe reports the cost of x, the point last suggested.  Costs may be
+	// +Inf for infeasible points, which an Optimizer must tolerate.
+	Observe(x []float64, cost float64)
+}
+
+// NewOptimizerFunc returns a new Optimizer starting from x0, with initial
+// steps of about step along each axis
+type NewOptimizerFunc func(x0, step []float64) Optimizer
+
+// Minimize drives opt to minimize f, for at most maxEvals evaluations, and
+// returns the best point observed and its cost.  NaN costs are observed as
+// +Inf.
+func Minimize(opt Optimizer, f func([]float64) float64, maxEvals int) (x []float64, fx float64) {
+	fx = math.Inf(1)
+	for i := 0; i < maxEvals; i++ {
+		p := opt.Suggest()
+		if p == nil {
+			break
+		}
+		c := f(p)
+		if math.IsNaN(c) {
+			c = math.Inf(1)
+		}
+		if x == nil || c < fx {
+			x, fx = append([]float64(nil), p...), c
+		}
+		opt.Observe(p, c)
+	}
+	return x, fx
+}
@@ -0,0 +1,129 @@
+/*
+Package tune tunes pctl controllers by optimization against closed-loop
+simulations of a plant model.
+
+The tuners follow the conventions of package sim: the controller is fed the
+process error, and the cost of a tuning is computed from the sim.Metrics of
+its simulation.
+*/
+package tune
+
+import (
+	"errors"
+	"math"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/sim"
+)
+
+// ErrInitialGains is returned by PIDTuner.Tune when a gain to be tuned does
+// not start positive
+var ErrInitialGains = errors.New("tune: initial gains to tune must be positive")
+
+// OvershootPenalized returns a cost of the settling time, plus weight seconds
+// per unit of overshoot beyond limit, a fraction of the step.  It finds the
+// fastest tuning that does not overshoot much.
+func OvershootPenalized(limit, weight float64) sim.Metric {
+	return func(m sim.Metrics) float64 {
+		return m.SettlingTime + weight*math.Max(0, m.Overshoot-limit)
+	}
+}
+
+// PIDTuner tunes the gains of a PID controller by minimizing a cost of its
+// closed-loop simulation against a plant model
+type PIDTuner struct {
+	// Template is the controller to tune.  Its gains are the starting point
+	// and must be positive for the terms tuned; its other fields, such as
+	// output limits, are kept.  DT is taken from Scenario.
+	Template pctl.PID
+
+	// Plant returns a new plant model for each simulation
+	Plant func() pctl.Updater
+
+	// Scenario is the simulation the cost is computed from
+	Scenario sim.Scenario
+
+	// Cost is the metric minimized, e.g. sim.MetricITAE or
+	// OvershootPenalized.  NaN costs, of unstable tunings, are taken as +Inf.
+	// The search cannot find its way off a plateau of infinite cost, so the
+	// template should be stable, and must settle if the cost includes the
+	// settling time.
+	Cost sim.Metric
+
+	// TuneD includes the derivative gain; otherwise D is held at the
+	// template's value and only P and I are tuned
+	TuneD bool
+
+	// MaxEvals is the budget of simulations.  If not positive, 200 are used.
+	MaxEvals int
+
+	// Optimizer makes the optimizer of the search, which is over the
+	// logarithms of the gains.  If nil, NelderMeadFunc is used.
+	Optimizer NewOptimizerFunc
+}
+
+// Result is a tuning found by a tuner
+type Result struct {
+	// P, I, and D are the tuned gains
+	P, I, D float64
+
+	// Cost and Metrics are of the simulation of the tuned controller
+	Cost    float64
+	Metrics sim.Metrics
+}
+
+// controller returns the template with gains from x, the logs of P, I, and
+// optionally D
+func (t *PIDTuner) controller(x []float64) *pctl.PID {
+	pid := t.Template
+	pid.DT = t.Scenario.DT
+	pid.P, pid.I = math.Exp(x[0]), math.Exp(x[1])
+	if t.TuneD {
+		pid.D = math.Exp(x[2])
+	}
+	return &pid
+}
+
+// evaluate simulates the controller with gains from x
+func (t *PIDTuner) evaluate(x []float64) (float64, sim.Metrics) {
+	res := sim.Run(sim.Loop{Controller: t.controller(x), Plant: t.Plant()}, t.Scenario)
+	m := res.Metrics()
+	return t.Cost(m), m
+}
+
+// Tune searches for the gains of least cost.  The search is over the
+// logarithms of the gains, which keeps them positive and makes its steps
+// relative to their scale; the initial steps are a factor of two.
+func (t *PIDTuner) Tune() (Result, error) {
+	x0 := []float64{t.Template.P, t.Template.I}
+	if t.TuneD {
+		x0 = append(x0, t.Template.D)
+	}
+	step := make([]float64, len(x0))
+	for i, g := range x0 {
+		if !(g > 0) {
+			return Result{}, ErrInitialGains
+		}
+		x0[i] = math.Log(g)
+		step[i] = math.Log(2)
+	}
+	evals := t.MaxEvals
+	if evals <= 0 {
+		evals = 200
+	}
+	newOpt := t.Optimizer
+	if newOpt == nil {
+		newOpt = NelderMeadFunc
+	}
+	x, _ := Minimize(newOpt(x0, step), func(x []float64) float64 {
+		c, _ := t.evaluate(x)
+		return c
+	}, evals)
+	if x == nil {
+		// the optimizer suggested nothing; keep the initial gains
+		x = x0
+	}
+	pid := t.controller(x)
+	c, m := t.evaluate(x)
+	return Result{P: pid.P, I: pid.I, D: pid.D, Cost: c, Metrics: m}, nil
+}
@@ -0,0 +1,116 @@
+package tune
+
+import (
+	"math"
+	"testing"
+
+	"github.com/brandondube/pctl"
+	"github.com/brandondube/pctl/sim"
+)
+
+func TestNelderMeadRosenbrock(t *testing.T) {
+	rosen := func(x []float64) float64 {
+		a, b := 1-x[0], x[1]-x[0]*x[0]
+		return a*a + 100*b*b
+	}
+	x, fx := NelderMead(rosen, []float64{-1.2, 1}, []float64{0.5, 0.5}, 2000, 1e-12)
+	if !(math.Abs(x[0]-1) < 1e-3 && math.Abs(x[1]-1) < 1e-3 && fx < 1e-6) {
+		t.Errorf("expected the minimum at (1, 1), got %v with cost %g", x, fx)
+	}
+}
+
+func TestPIDTunerImprovesITAE(t *testing.T) {
+	const dt = 1e-3
+	tuner := PIDTuner{
+		Template: pctl.PID{P: 0.5, I: 1},
+		Plant:    func() pctl.Updater { return pctl.NewLPF(2, dt) },
+		Scenario: sim.Scenario{DT: dt, Duration: 2, Setpoint: []sim.Event{{T: 0.1, Value: 1}}},
+		Cost:     sim.MetricITAE,
+		MaxEvals: 150,
+	}
+	initial, _ := tuner.evaluate([]float64{math.Log(0.5), math.Log(1)})
+	res, err := tuner.Tune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(res.Cost < initial/2) || math.IsInf(res.Metrics.SettlingTime, 1) {
+		t.Errorf("expected the tuning to halve the cost %f and settle, got %+v", initial, res)
+	}
+	if c := OvershootPenalized(0.02, 10)(res.Metrics); c != res.Metrics.SettlingTime+10*math.Max(0, res.Metrics.Overshoot-0.02) {
+		t.Errorf("unexpected overshoot penalized cost %f", c)
+	}
+	tuner.Template.P = 0
+	if _, err := tuner.Tune(); err != ErrInitialGains {
+		t.Errorf("expected ErrInitialGains, got %v", err)
+	}
+}
+
+func TestPIDTunerNegativeBudgetUsesDefault(t *testing.T) {
+	const dt = 1e-3
+	tuner := PIDTuner{
+		Template: pctl.PID{P: 0.5, I: 1},
+		Plant:    func() pctl.Updater { return pctl.NewLPF(2, dt) },
+		Scenario: sim.Scenario{DT: dt, Duration: 0.5, Setpoint: []sim.Event{{T: 0.1, Value: 1}}},
+		Cost:     sim.MetricITAE,
+		MaxEvals: -1,
+	}
+	res, err := tuner.Tune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(res.P > 0 && res.I > 0) || math.IsInf(res.Cost, 0) {
+		t.Errorf("expected tuned gains from the default budget, got %+v", res)
+	}
+}
+
+// gridSearch is a stand in for an external optimizer: it suggests the points
+// of a grid around x0 in turn
+type gridSearch struct {
+	points [][]float64
+	seen   int
+	best   float64
+}
+
+func (g *gridSearch) Suggest() []float64 {
+	if g.seen == len(g.points) {
+		return nil
+	}
+	return g.points[g.seen]
+}
+
+func (g *gridSearch) Observe(x []float64, cost float64) {
+	if g.seen == 0 || cost < g.best {
+		g.best = cost
+	}
+	g.seen++
+}
+
+func TestPIDTunerPluggableOptimizer(t *testing.T) {
+	const dt = 1e-3
+	var grid *gridSearch
+	tuner := PIDTuner{
+		Template: pctl.PID{P: 0.5, I: 1},
+		Plant:    func() pctl.Updater { return pctl.NewLPF(2, dt) },
+		Scenario: sim.Scenario{DT: dt, Duration: 2, Setpoint: []sim.Event{{T: 0.1, Value: 1}}},
+		Cost:     sim.MetricITAE,
+		Optimizer: func(x0, step []float64) Optimizer {
+			grid = &gridSearch{}
+			for i := -2; i <= 4; i++ {
+				for j := -2; j <= 4; j++ {
+					grid.points = append(grid.points, []float64{x0[0] + float64(i)*step[0], x0[1] + float64(j)*step[1]})
+				}
+			}
+			return grid
+		},
+	}
+	res, err := tuner.Tune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid.seen != 49 {
+		t.Errorf("expected the whole grid of 49 evaluated, got %d", grid.seen)
+	}
+	if res.Cost != grid.best {
+		t.Errorf("expected the cost of the grid's best point, %g, got %g", grid.best, res.Cost)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Package units converts between engineering units.
+
+Each unit is an affine map to the SI unit of its quantity, so any two units of
+the same quantity convert by a Conversion, which is a pctl.Updater and may be
+placed in a chain like any other block.  Configs may declare the units of
+their sensor and actuator; see config.Units.
+*/
+package units
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/brandondube/pctl"
+)
+
+// ErrUnknownUnit is returned for units which have not been defined
+var ErrUnknownUnit = errors.New("units: unknown unit")
+
+// ErrIncompatible is returned when converting between units of different
+// quantities
+var ErrIncompatible = errors.New("units: incompatible units")
+
+// Unit is a unit of measure, defined by its quantity and the affine map
+// si = value*Scale + Offset to the SI unit of that quantity
+type Unit struct {
+	Name     string
+	Quantity string
+	Scale    float64
+	Offset   float64
+}
+
+var table = map[string]Unit{}
+
+// Define adds a unit, replacing any of the same name.  It is not safe for
+// concurrent use and is meant to be called from init functions.
+func Define(u Unit) {
+	table[u.Name] = u
+}
+
+func init() {
+	for _, u := range []Unit{
+		{"K", "temperature", 1, 0},
+		{"C", "temperature", 1, 273.15},
+		{"F", "temperature", 5. / 9, 273.15 - 32*5./9},
+		{"Pa", "pressure", 1, 0},
+		{"kPa", "pressure", 1e3, 0},
+		{"MPa", "pressure", 1e6, 0},
+		{"bar", "pressure", 1e5, 0},
+		{"mbar", "pressure", 1e2, 0},
+		{"psi", "pressure", 6894.757293168361, 0},
+		{"fraction", "ratio", 1, 0},
+		{"%", "ratio", 0.01, 0},
+	} {
+		Define(u)
+	}
+}
+
+// Lookup returns the named unit
+func Lookup(name string) (Unit, error) {
+	u, ok := table[name]
+	if !ok {
+		return Unit{}, fmt.Errorf("%w %q", ErrUnknownUnit, name)
+	}
+	return u, nil
+}
+
+// Names returns the sorted names of all defined units
+func Names() []string {
+	out := make([]string, 0, len(table))
+	for k := range table {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Conversion is an affine map between units, out = in*Scale + Offset
+type Conversion struct {
+	Scale  float64
+	Offset float64
+}
+
+// Update converts input
+func (c Conversion) Update(input float64) float64 {
+	return input*c.Scale + c.Offset
+}
+
+// Then returns the conversion which applies c and then d
+func (c Conversion) Then(d Conversion) Conversion {
+	return Conversion{Scale: c.Scale * d.Scale, Offset: c.Offset*d.Scale + d.Offset}
+}
+
+// Convert returns the conversion from one named unit to another
+func Convert(from, to string) (Conversion, error) {
+	f, err := Lookup(from)
+	if err != nil {
+		return Conversion{}, err
+	}
+	t, err := Lookup(to)
+	if err != nil {
+		return Conversion{}, err
+	}
+	if f.Quantity != t.Quantity {
+		return Conversion{}, fmt.Errorf("%w: %s is %s, %s is %s", ErrIncompatible, from, f.Quantity, to, t.Quantity)
+	}
+	toSI := Conversion{Scale: f.Scale, Offset: f.Offset}
+	fromSI := Conversion{Scale: 1 / t.Scale, Offset: -t.Offset / t.Scale}
+	return toSI.Then(fromSI), nil
+}
+
+// SaveState returns no state; the conversion is memoryless
+func (c Conversion) SaveState() []float64 { return nil }
+
+// RestoreState accepts only the empty state
+func (c Conversion) RestoreState(s []float64) error {
+	if len(s) != 0 {
+		return pctl.ErrStateSize
+	}
+	return nil
+}
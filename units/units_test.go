@@ -0,0 +1,34 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		from, to string
+		in, out  float64
+	}{
+		{"C", "F", 100, 212},
+		{"F", "K", 32, 273.15},
+		{"bar", "psi", 1, 14.503773773},
+		{"%", "fraction", 50, 0.5},
+	}
+	for _, c := range cases {
+		conv, err := Convert(c.from, c.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := conv.Update(c.in); math.Abs(got-c.out) > 1e-6 {
+			t.Errorf("%f %s is %f %s, got %f", c.in, c.from, c.out, c.to, got)
+		}
+	}
+	if _, err := Convert("C", "psi"); !errors.Is(err, ErrIncompatible) {
+		t.Errorf("expected ErrIncompatible, got %v", err)
+	}
+	if _, err := Convert("C", "furlong"); !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected ErrUnknownUnit, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package pctl
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// ParamError describes an invalid parameter of a pctl type, as reported by
+// the Validate methods
+type ParamError struct {
+	// Type is the name of the type, e.g. "PID"
+	Type string
+
+	// Param is the name of the offending field or coefficient, e.g. "DT"
+	Param string
+
+	// Reason describes what is wrong, e.g. "must be positive"
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return "pctl: " + e.Type + "." + e.Param + " " + e.Reason
+}
+
+func paramErr(typ, param, reason string) error {
+	return &ParamError{Type: typ, Param: param, Reason: reason}
+}
+
+func finite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+// checkFinite returns an error for the first of vals which is NaN or Inf
+func checkFinite(typ string, names []string, vals ...float64) error {
+	for i, v := range vals {
+		if !finite(v) {
+			return paramErr(typ, names[i], "must be finite")
+		}
+	}
+	return nil
+}
+
+// checkNonNegative returns an error for the first of vals which is negative
+func checkNonNegative(typ string, names []string, vals ...float64) error {
+	for i, v := range vals {
+		if v < 0 {
+			return paramErr(typ, names[i], "must not be negative")
+		}
+	}
+	return nil
+}
+
+// Validate returns a *ParamError describing the first nonsensical parameter of
+// the controller, or nil if it is well configured
+func (pid *PID) Validate() error {
+	const typ = "PID"
+	names := []string{"P", "I", "D", "DT", "IErrMax", "Kt", "IBand", "OutMin", "OutMax", "SlewMax", "OutputLSB", "Setpt", "SetptRate"}
+	vals := []float64{pid.P, pid.I, pid.D, pid.DT, pid.IErrMax, pid.Kt, pid.IBand, pid.OutMin, pid.OutMax, pid.SlewMax, pid.OutputLSB, pid.Setpt, pid.SetptRate}
+	if err := checkFinite(typ, names, vals...); err != nil {
+		return err
+	}
+	if err := checkNonNegative(typ, names[3:], vals[3:7]...); err != nil {
+		return err
+	}
+	if err := checkNonNegative(typ, names[9:11], vals[9:11]...); err != nil {
+		return err
+	}
+	if err := checkNonNegative(typ, names[12:], vals[12:]...); err != nil {
+		return err
+	}
+	if pid.DT == 0 && (pid.I != 0 || pid.D != 0 || pid.SlewMax != 0 || pid.SetptRate != 0) {
+		return paramErr(typ, "DT", "must be positive when I, D, SlewMax, or SetptRate is nonzero")
+	}
+	if pid.OutMax < pid.OutMin {
+		return paramErr(typ, "OutMax", "must not be less than OutMin")
+	}
+	if pid.Windup < WindupClamp || pid.Windup > WindupClampSymmetric {
+		return paramErr(typ, "Windup", "is not a known AntiWindup strategy")
+	}
+	return nil
+}
+
+// Validate returns a *ParamError describing the first nonsensical parameter of
+// the controller, or nil if it is well configured
+func (pid *IncrementalPID) Validate() error {
+	const typ = "IncrementalPID"
+	names := []string{"P", "I", "D", "DT", "OutMin", "OutMax", "Setpt"}
+	if err := checkFinite(typ, names, pid.P, pid.I, pid.D, pid.DT, pid.OutMin, pid.OutMax, pid.Setpt); err != nil {
+		return err
+	}
+	if pid.DT <= 0 {
+		return paramErr(typ, "DT", "must be positive")
+	}
+	if pid.OutMax < pid.OutMin {
+		return paramErr(typ, "OutMax", "must not be less than OutMin")
+	}
+	return nil
+}
+
+func validateFirstOrder(typ string, fc, dt float64) error {
+	if !finite(fc) || fc <= 0 {
+		return paramErr(typ, "cutoff", "must be positive and finite")
+	}
+	if !finite(dt) || dt <= 0 {
+		return paramErr(typ, "DT", "must be positive and finite")
+	}
+	return nil
+}
+
+// Validate returns a *ParamError if the cutoff frequency or DT are not
+// positive
+func (l *LPF) Validate() error {
+	return validateFirstOrder("LPF", l.fc, l.DT)
+}
+
+// Validate returns a *ParamError if the cutoff frequency or DT are not
+// positive
+func (h *HPF) Validate() error {
+	return validateFirstOrder("HPF", h.fc, h.DT)
+}
+
+// Validate returns a *ParamError if any coefficient is not finite or the
+// poles of the biquad are not strictly inside the unit circle, i.e. it is
+// unstable
+func (b *Biquad) Validate() error {
+	const typ = "Biquad"
+	if err := checkFinite(typ, []string{"a0", "a1", "a2", "b1", "b2"}, b.a0, b.a1, b.a2, b.b1, b.b2); err != nil {
+		return err
+	}
+	// stability triangle of the denominator 1 + b1 z^-1 + b2 z^-2
+	if math.Abs(b.b2) >= 1 || math.Abs(b.b1) >= 1+b.b2 {
+		return paramErr(typ, "b1, b2", "place a pole on or outside the unit circle (unstable)")
+	}
+	return nil
+}
+
+// Validate returns the error of the first invalid section, or a *ParamError
+// if there are no sections
+func (s *SOSFilter) Validate() error {
+	if len(s.sections) == 0 {
+		return paramErr("SOSFilter", "sections", "must not be empty")
+	}
+	for i := range s.sections {
+		if err := s.sections[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate returns a *ParamError if the dimensions of the state space
+// matrices do not agree or any element is not finite
+func (s *StateSpaceFilter) Validate() error {
+	const typ = "StateSpaceFilter"
+	n := len(s.b)
+	if n == 0 {
+		return paramErr(typ, "B", "must not be empty")
+	}
+	if len(s.a) != n {
+		return paramErr(typ, "A", "must have as many rows as B")
+	}
+	for _, row := range s.a {
+		if len(row) != n {
+			return paramErr(typ, "A", "must be square")
+		}
+		for _, v := range row {
+			if !finite(v) {
+				return paramErr(typ, "A", "must be finite")
+			}
+		}
+	}
+	if len(s.c) != n {
+		return paramErr(typ, "C", "must have as many elements as B")
+	}
+	if len(s.x) != n {
+		return paramErr(typ, "initCond", "must have as many elements as B")
+	}
+	for i := 0; i < n; i++ {
+		if !finite(s.b[i]) {
+			return paramErr(typ, "B", "must be finite")
+		}
+		if !finite(s.c[i]) {
+			return paramErr(typ, "C", "must be finite")
+		}
+	}
+	if !finite(s.d) {
+		return paramErr(typ, "D", "must be finite")
+	}
+	return nil
+}
+
+// Validate returns a *ParamError if the filter has no taps or any tap is not
+// finite
+func (f *FIRFilter) Validate() error {
+	const typ = "FIRFilter"
+	if len(f.x) == 0 {
+		return paramErr(typ, "taps", "must not be empty")
+	}
+	for _, v := range f.h {
+		if !finite(v) {
+			return paramErr(typ, "taps", "must be finite")
+		}
+	}
+	return nil
+}
+
+// Validate returns a *ParamError describing the first nonsensical parameter of
+// the table, or nil if it is well configured
+func (t *LookupTable) Validate() error {
+	const typ = "LookupTable"
+	if len(t.x) == 0 {
+		return paramErr(typ, "x", "must not be empty")
+	}
+	if len(t.x) != len(t.y) {
+		return paramErr(typ, "y", "must be the same length as x")
+	}
+	for i := range t.x {
+		if !finite(t.x[i]) || !finite(t.y[i]) {
+			return paramErr(typ, "x", "and y must be finite")
+		}
+		if i > 0 && t.x[i] <= t.x[i-1] {
+			return paramErr(typ, "x", "must be strictly increasing")
+		}
+	}
+	return nil
+}
+
+// Validate returns a *ParamError describing the first nonsensical parameter of
+// the scale, or nil if it is well configured
+func (s *Scale) Validate() error {
+	const typ = "Scale"
+	names := []string{"Raw0", "Eng0", "Raw1", "Eng1", "Min", "Max"}
+	if err := checkFinite(typ, names, s.Raw0, s.Eng0, s.Raw1, s.Eng1, s.Min, s.Max); err != nil {
+		return err
+	}
+	if s.Raw0 == s.Raw1 {
+		return paramErr(typ, "Raw1", "must differ from Raw0")
+	}
+	return nil
+}
+
+// Validate returns a *ParamError describing the first nonsensical parameter of
+// the ZPK, or nil if it is well formed
+func (zpk ZPK) Validate() error {
+	const typ = "ZPK"
+	if !finite(zpk.K) {
+		return paramErr(typ, "K", "must be finite")
+	}
+	if err := checkConjugates(typ, "Z", zpk.Z); err != nil {
+		return err
+	}
+	return checkConjugates(typ, "P", zpk.P)
+}
+
+// checkConjugates returns an error if the complex roots r are not finite, or
+// do not appear in conjugate pairs
+func checkConjugates(typ, param string, r []complex128) error {
+	const tol = 1e-9
+	used := make([]bool, len(r))
+	for i, v := range r {
+		if cmplx.IsNaN(v) || cmplx.IsInf(v) {
+			return paramErr(typ, param, "must be finite")
+		}
+		if used[i] || math.Abs(imag(v)) <= tol*math.Max(1, cmplx.Abs(v)) {
+			continue
+		}
+		found := false
+		for j := i + 1; j < len(r); j++ {
+			if !used[j] && cmplx.Abs(r[j]-cmplx.Conj(v)) <= tol*math.Max(1, cmplx.Abs(v)) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return paramErr(typ, param, "must contain complex values in conjugate pairs")
+		}
+	}
+	return nil
+}
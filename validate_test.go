@@ -0,0 +1,52 @@
+package pctl
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPIDValidate(t *testing.T) {
+	good := PID{P: 1, I: 1, DT: 1e-3}
+	if err := good.Validate(); err != nil {
+		t.Errorf("expected well configured PID to validate, got %v", err)
+	}
+	cases := []struct {
+		pid   PID
+		param string
+	}{
+		{PID{P: 1, I: 1}, "DT"},
+		{PID{P: math.NaN()}, "P"},
+		{PID{DT: 1, IErrMax: -1}, "IErrMax"},
+		{PID{DT: 1, OutMin: 1, OutMax: -1}, "OutMax"},
+		{PID{DT: 1, Windup: 7}, "Windup"},
+	}
+	for _, c := range cases {
+		err := c.pid.Validate()
+		var pe *ParamError
+		if !errors.As(err, &pe) || pe.Param != c.param {
+			t.Errorf("%+v: expected error on %s, got %v", c.pid, c.param, err)
+		}
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	if err := NewLPF(-1, 1e-3).Validate(); err == nil {
+		t.Error("expected negative cutoff to be rejected")
+	}
+	if err := NewHPF(10, 0).Validate(); err == nil {
+		t.Error("expected zero DT to be rejected")
+	}
+	if err := NewBiquadLowpass(1000, 50, 0.7071, 0).Validate(); err != nil {
+		t.Errorf("expected designed lowpass to validate, got %v", err)
+	}
+	if err := NewBiquad(1, 0, 0, 0, 1.01).Validate(); err == nil {
+		t.Error("expected unstable biquad to be rejected")
+	}
+	if err := NewStateSpaceFilter([][]float64{{1}}, []float64{1, 2}, []float64{1, 2}, 0, nil).Validate(); err == nil {
+		t.Error("expected mismatched state space dimensions to be rejected")
+	}
+	if err := NewFIRFilter(nil).Validate(); err == nil {
+		t.Error("expected empty FIR filter to be rejected")
+	}
+}
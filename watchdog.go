@@ -0,0 +1,130 @@
+package pctl
+
+import "time"
+
+// SafeAction is what a Watchdog does with its output when measurements go
+// stale
+type SafeAction int
+
+const (
+	// SafeHold holds the last output until fresh measurements return
+	SafeHold SafeAction = iota
+
+	// SafeGoTo outputs SafeValue until fresh measurements return
+	SafeGoTo
+
+	// SafeManual holds the last output and latches in manual; the output may
+	// be changed with SetManual, and automatic control only returns on Resume
+	SafeManual
+)
+
+// Watchdog guards a controller against stale measurements.  It tracks the age
+// of the newest measurement and trips when it exceeds Timeout, forcing the
+// output to a safe state chosen by Action.
+//
+// Fresh measurements are passed to Update or UpdateAt.  On ticks without a
+// measurement, call Poll, which detects staleness and returns the output.
+// The watchdog is not armed until the first measurement.
+type Watchdog struct {
+	// Controller is the guarded controller
+	Controller Updater
+
+	// Timeout is the age beyond which a measurement is stale
+	Timeout time.Duration
+
+	// Action is the safe state entered on staleness
+	Action SafeAction
+
+	// SafeValue is the output under SafeGoTo
+	SafeValue float64
+
+	// Clock measures the age of measurements.  If nil, SystemClock is used.
+	Clock Clock
+
+	// OnTrip, if not nil, is called when the watchdog trips with the age of
+	// the newest measurement
+	OnTrip func(age time.Duration)
+
+	// OnRecover, if not nil, is called when automatic control resumes
+	OnRecover func()
+
+	last    time.Time
+	started bool
+	tripped bool
+	out     float64
+}
+
+func (w *Watchdog) now() time.Time {
+	if w.Clock == nil {
+		return SystemClock.Now()
+	}
+	return w.Clock.Now()
+}
+
+// Update processes a measurement taken now, returning the output
+func (w *Watchdog) Update(input float64) float64 {
+	return w.UpdateAt(input, w.now())
+}
+
+// UpdateAt processes a measurement taken at time t, returning the output.  A
+// measurement no newer than the previous one, or already stale, is not passed
+// to the controller.
+func (w *Watchdog) UpdateAt(input float64, t time.Time) float64 {
+	if w.started && !t.After(w.last) {
+		return w.Poll()
+	}
+	w.last = t
+	w.started = true
+	if w.Poll(); w.tripped {
+		if w.Action == SafeManual || w.now().Sub(t) > w.Timeout {
+			return w.out
+		}
+		w.tripped = false
+		if w.OnRecover != nil {
+			w.OnRecover()
+		}
+	}
+	w.out = w.Controller.Update(input)
+	return w.out
+}
+
+// Poll checks the age of the newest measurement, tripping if it is stale, and
+// returns the output
+func (w *Watchdog) Poll() float64 {
+	if !w.started || w.tripped {
+		return w.out
+	}
+	if age := w.now().Sub(w.last); age > w.Timeout {
+		w.tripped = true
+		if w.Action == SafeGoTo {
+			w.out = w.SafeValue
+		}
+		if w.OnTrip != nil {
+			w.OnTrip(age)
+		}
+	}
+	return w.out
+}
+
+// Tripped returns true while the output is in the safe state
+func (w *Watchdog) Tripped() bool {
+	return w.tripped
+}
+
+// SetManual sets the output while tripped under SafeManual
+func (w *Watchdog) SetManual(output float64) {
+	if w.tripped && w.Action == SafeManual {
+		w.out = output
+	}
+}
+
+// Resume returns a watchdog latched in manual to automatic control.  It
+// trips again on the next Poll if measurements are still stale.
+func (w *Watchdog) Resume() {
+	if w.tripped {
+		w.tripped = false
+		if w.OnRecover != nil {
+			w.OnRecover()
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pctl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdogTripsAndRecovers(t *testing.T) {
+	var clk ManualClock
+	clk.Set(time.Unix(0, 0))
+	sp := Setpoint(0)
+	trips, recovers := 0, 0
+	w := &Watchdog{
+		Controller: &sp, Timeout: 100 * time.Millisecond,
+		Action: SafeGoTo, SafeValue: 9, Clock: &clk,
+		OnTrip:    func(time.Duration) { trips++ },
+		OnRecover: func() { recovers++ },
+	}
+	if out := w.Update(2); out != 2 {
+		t.Fatalf("expected controller output 2, got %f", out)
+	}
+	clk.Advance(50 * time.Millisecond)
+	if out := w.Poll(); out != 2 || w.Tripped() {
+		t.Errorf("tripped early, output %f", out)
+	}
+	clk.Advance(100 * time.Millisecond)
+	if out := w.Poll(); out != 9 || !w.Tripped() {
+		t.Errorf("expected safe value 9 after timeout, got %f", out)
+	}
+	if out := w.Update(3); out != 3 || w.Tripped() {
+		t.Errorf("expected recovery on fresh measurement, got %f", out)
+	}
+	if trips != 1 || recovers != 1 {
+		t.Errorf("expected one trip and one recovery event, got %d and %d", trips, recovers)
+	}
+}
+
+func TestWatchdogManualLatches(t *testing.T) {
+	var clk ManualClock
+	sp := Setpoint(0)
+	w := &Watchdog{Controller: &sp, Timeout: time.Second, Action: SafeManual, Clock: &clk}
+	w.Update(1)
+	clk.Advance(2 * time.Second)
+	w.Poll()
+	w.SetManual(4)
+	if out := w.Update(5); out != 4 {
+		t.Errorf("manual latch released by fresh data, output %f", out)
+	}
+	w.Resume()
+	clk.Advance(time.Millisecond)
+	if out := w.Update(5); out != 5 {
+		t.Errorf("expected automatic control after Resume, got %f", out)
+	}
+}
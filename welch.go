@@ -0,0 +1,102 @@
+package pctl
+
+import (
+	"errors"
+	"math/cmplx"
+)
+
+// ErrSegment is returned by the spectral estimators when the segment length is
+// not a power of two, the signals are shorter than a segment, or two signals
+// differ in length
+var ErrSegment = errors.New("pctl: segment length must be a power of two no longer than the signals, which must be of equal length")
+
+// The functions in this file estimate spectra by Welch's method: the signals
+// are cut into segments of seg samples overlapping by half, each is windowed
+// and transformed, and the periodograms are averaged.  Longer segments
+// resolve finer frequencies; more segments give a less noisy estimate.  Like
+// the response functions, they allocate and are meant for analysis.
+
+// welch returns the frequencies and the one sided auto and cross spectral
+// densities of x and y
+func welch(x, y []float64, fs float64, seg int, w Window) (f, pxx, pyy []float64, pxy []complex128, err error) {
+	if seg < 1 || seg&(seg-1) != 0 || len(x) < seg || len(y) != len(x) {
+		return nil, nil, nil, nil, ErrSegment
+	}
+	win := make([]float64, seg)
+	var wss float64
+	for i := range win {
+		win[i] = w(i, seg)
+		wss += win[i] * win[i]
+	}
+	nf := seg/2 + 1
+	pxx, pyy, pxy = make([]float64, nf), make([]float64, nf), make([]complex128, nf)
+	bx, by := make([]complex128, seg), make([]complex128, seg)
+	hop := seg / 2
+	if hop == 0 {
+		hop = 1
+	}
+	segments := 0
+	for start := 0; start+seg <= len(x); start += hop {
+		for i := range win {
+			bx[i] = complex(x[start+i]*win[i], 0)
+			by[i] = complex(y[start+i]*win[i], 0)
+		}
+		fft(bx, false)
+		fft(by, false)
+		for k := 0; k < nf; k++ {
+			pxx[k] += real(bx[k] * cmplx.Conj(bx[k]))
+			pyy[k] += real(by[k] * cmplx.Conj(by[k]))
+			pxy[k] += cmplx.Conj(bx[k]) * by[k]
+		}
+		segments++
+	}
+	f = make([]float64, nf)
+	for k := range f {
+		f[k] = float64(k) * fs / float64(seg)
+		// density, doubled for the negative frequencies except DC and Nyquist
+		scale := 1 / (fs * wss * float64(segments))
+		if k != 0 && k != seg/2 {
+			scale *= 2
+		}
+		pxx[k] *= scale
+		pyy[k] *= scale
+		pxy[k] *= complex(scale, 0)
+	}
+	return f, pxx, pyy, pxy, nil
+}
+
+// Welch returns the frequencies in Hz and the one sided power spectral
+// density of x, sampled at fs, in units of x^2/Hz
+func Welch(x []float64, fs float64, seg int, w Window) (f, pxx []float64, err error) {
+	f, pxx, _, _, err = welch(x, x, fs, seg, w)
+	return f, pxx, err
+}
+
+// CSD returns the frequencies in Hz and the one sided cross spectral density
+// of x and y, sampled at fs.  Its phase is that of y relative to x.
+func CSD(x, y []float64, fs float64, seg int, w Window) (f []float64, pxy []complex128, err error) {
+	f, _, _, pxy, err = welch(x, y, fs, seg, w)
+	return f, pxy, err
+}
+
+// Coherence returns the frequencies in Hz and the magnitude squared coherence
+// of x and y, sampled at fs,
+//
+//	|Pxy|^2 / (Pxx Pyy)
+//
+// It is 1 where y is a linear function of x and falls towards 0 as noise or
+// nonlinearity account for more of y, so it tells which bands of a frequency
+// response measured from x to y are trustworthy.  It is identically 1 with a
+// single segment; use many.  Bands where x has no power report NaN.
+func Coherence(x, y []float64, fs float64, seg int, w Window) (f, coh []float64, err error) {
+	f, pxx, pyy, pxy, err := welch(x, y, fs, seg, w)
+	if err != nil {
+		return nil, nil, err
+	}
+	coh = make([]float64, len(f))
+	for k := range coh {
+		m := cmplx.Abs(pxy[k])
+		coh[k] = m * m / (pxx[k] * pyy[k])
+	}
+	return f, coh, nil
+}
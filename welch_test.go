@@ -0,0 +1,60 @@
+package pctl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestWelchWhiteNoiseDensity(t *testing.T) {
+	// white noise of variance s^2 sampled at fs has a one sided density of
+	// 2 s^2 / fs
+	const s, fs = 0.5, 1000.
+	r := rand.New(rand.NewSource(7))
+	x := make([]float64, 1<<15)
+	for i := range x {
+		x[i] = s * r.NormFloat64()
+	}
+	f, pxx, err := Welch(x, fs, 256, Hann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f) != 129 || f[128] != fs/2 {
+		t.Fatalf("expected 129 frequencies to Nyquist, got %d to %f", len(f), f[len(f)-1])
+	}
+	var mean float64
+	for _, p := range pxx[1:128] {
+		mean += p / 127
+	}
+	if want := 2 * s * s / fs; math.Abs(mean/want-1) > 0.02 {
+		t.Errorf("expected a density of %g, got %g", want, mean)
+	}
+}
+
+func TestCoherenceSeparatesNoisyBands(t *testing.T) {
+	// y is x through a low pass, plus white noise; coherence is high in the
+	// pass band and low in the stop band, where the noise dominates
+	const fs = 1000.
+	r := rand.New(rand.NewSource(8))
+	bq := NewBiquadLowpass(fs, 50, 0.7071, 0)
+	x, y := make([]float64, 1<<15), make([]float64, 1<<15)
+	for i := range x {
+		x[i] = r.NormFloat64()
+		y[i] = bq.Update(x[i]) + 0.05*r.NormFloat64()
+	}
+	f, coh, err := Coherence(x, y, fs, 256, Hann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range f {
+		if f[k] > 0 && f[k] < 20 && coh[k] < 0.95 {
+			t.Errorf("expected coherence near 1 at %f Hz, got %f", f[k], coh[k])
+		}
+		if f[k] > 300 && coh[k] > 0.2 {
+			t.Errorf("expected low coherence at %f Hz, got %f", f[k], coh[k])
+		}
+	}
+	if _, _, err := Coherence(x, y[:100], fs, 256, Hann); err != ErrSegment {
+		t.Errorf("expected ErrSegment for mismatched lengths, got %v", err)
+	}
+}